@@ -2,6 +2,7 @@ package bux
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/BuxOrg/bux/utils"
@@ -12,6 +13,16 @@ import (
 //
 // opts are options and can include "metadata"
 func (c *Client) NewAccessKey(ctx context.Context, rawXpubKey string, opts ...ModelOps) (*AccessKey, error) {
+	return c.NewScopedAccessKey(ctx, rawXpubKey, nil, time.Time{}, opts...)
+}
+
+// NewScopedAccessKey will create a new access key for the given xpub, restricted by the config
+//
+// config has the scopes and spend caps of the key (nil is full access)
+// expiresAt is when the key expires (zero time never expires)
+// opts are options and can include "metadata"
+func (c *Client) NewScopedAccessKey(ctx context.Context, rawXpubKey string, config *AccessKeyConfig,
+	expiresAt time.Time, opts ...ModelOps) (*AccessKey, error) {
 
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "new_access_key")
@@ -37,6 +48,13 @@ func (c *Client) NewAccessKey(ctx context.Context, rawXpubKey string, opts ...Mo
 	accessKey := newAccessKey(
 		xPub.ID, c.DefaultModelOptions(append(opts, New())...)...,
 	)
+	if config != nil {
+		accessKey.Config = *config
+	}
+	if !expiresAt.IsZero() {
+		accessKey.ExpiresAt.Valid = true
+		accessKey.ExpiresAt.Time = expiresAt.UTC()
+	}
 
 	// Save the model
 	if err = accessKey.Save(ctx); err != nil {
@@ -187,6 +205,13 @@ func (c *Client) RevokeAccessKey(ctx context.Context, rawXpubKey, id string, opt
 		return nil, ErrMissingXpub
 	}
 
+	// Lock the key, the spends and the usage of the key are written under the same lock
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyAccessKeySpend, id), c.Cachestore())
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
 	var accessKey *AccessKey
 	if accessKey, err = getAccessKey(
 		ctx, id, c.DefaultModelOptions(opts...)...,
@@ -204,13 +229,108 @@ func (c *Client) RevokeAccessKey(ctx context.Context, rawXpubKey, id string, opt
 	}
 
 	accessKey.RevokedAt.Valid = true
-	accessKey.RevokedAt.Time = time.Now()
+	accessKey.RevokedAt.Time = time.Now().UTC()
 
-	// Save the model
-	if err = accessKey.Save(ctx); err != nil {
+	// Only write the revoked time (the spent satoshis and the usage are counted separately)
+	if err = updateFields(ctx, accessKey, map[string]interface{}{
+		revokedAtField: &accessKey.RevokedAt,
+	}); err != nil {
 		return nil, err
 	}
 
 	// Return the updated model
 	return accessKey, nil
 }
+
+// RotateAccessKey will issue a successor for an access key and revoke the key after the grace period
+//
+// The successor has the same config (scopes, spend caps), expiry and metadata, both keys are valid during
+// the grace period. The satoshis spent by the key are carried over to the successor, and the spends of the key
+// during the grace period are counted on the successor (both keys share the spend cap).
+// The private key of the successor is only returned here.
+//
+// gracePeriod is how long the rotated key stays valid (0 revokes it right away)
+// opts are options and can include "metadata" (added to the metadata of the successor)
+func (c *Client) RotateAccessKey(ctx context.Context, rawXpubKey, id string, gracePeriod time.Duration,
+	opts ...ModelOps) (*AccessKey, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "rotate_access_key")
+
+	// Validate that the value is an xPub
+	_, err := utils.ValidateXPub(rawXpubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the key (get it fresh under the lock), so no spend is counted on the key while it is rotated
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyAccessKeySpend, id), c.Cachestore())
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var accessKey *AccessKey
+	if accessKey, err = getAccessKey(
+		ctx, id, c.DefaultModelOptions()...,
+	); err != nil {
+		return nil, err
+	} else if accessKey == nil {
+		return nil, ErrMissingAccessKey
+	} else if accessKey.XpubID != utils.Hash(rawXpubKey) {
+		return nil, utils.ErrXpubNoMatch
+	} else if accessKey.IsRevoked() {
+		return nil, ErrAccessKeyRevoked
+	} else if len(accessKey.SuccessorID) > 0 {
+		return nil, ErrAccessKeyRotated
+	}
+
+	// Issue the successor (the metadata of the options is added to the metadata of the key)
+	successor := newAccessKey(
+		accessKey.XpubID, c.DefaultModelOptions(
+			append([]ModelOps{WithMetadatas(accessKey.Metadata)}, append(opts, New())...)...,
+		)...,
+	)
+	successor.Config = accessKey.Config
+	successor.ExpiresAt = accessKey.ExpiresAt
+	successor.SpentSatoshis = accessKey.SpentSatoshis
+	if err = successor.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	// Revoke the key after the grace period (only these fields are written)
+	accessKey.SuccessorID = successor.ID
+	accessKey.RevokedAt.Valid = true
+	accessKey.RevokedAt.Time = time.Now().UTC().Add(gracePeriod)
+	if err = updateFields(ctx, accessKey, map[string]interface{}{
+		revokedAtField:   &accessKey.RevokedAt,
+		successorIDField: accessKey.SuccessorID,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Return the successor
+	return successor, nil
+}
+
+// GetIdleAccessKeys will get the (not revoked) access keys that were not used for the idle time
+//
+// Keys that were never used are idle if they were created before the idle time
+// metadataConditions is the metadata to match to the access keys being returned
+func (c *Client) GetIdleAccessKeys(ctx context.Context, idleFor time.Duration, metadataConditions *Metadata,
+	queryParams *datastore.QueryParams, opts ...ModelOps) ([]*AccessKey, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_idle_access_keys")
+
+	idleSince := time.Now().UTC().Add(-idleFor)
+	return c.GetAccessKeys(ctx, metadataConditions, &map[string]interface{}{
+		revokedAtField: nil,
+		"$or": []map[string]interface{}{{
+			lastUsedAtField: map[string]interface{}{"$lt": idleSince},
+		}, {
+			lastUsedAtField: nil,
+			createdAtField:  map[string]interface{}{"$lt": idleSince},
+		}},
+	}, queryParams, opts...)
+}
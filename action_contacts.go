@@ -0,0 +1,215 @@
+package bux
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoin-sv/go-paymail"
+	"github.com/mrz1836/go-datastore"
+)
+
+// NewContact will add the paymail as a contact of the xPub (unconfirmed), see WithPaymailPikeSupport
+//
+// The key of the contact is resolved with PKI, and the contact is invited (PIKE invite) with the paymail of the
+// xPub if the paymail server of the contact supports PIKE. Adding a contact that invited the xPub accepts it.
+//
+// opts are options and can include "metadata"
+func (c *Client) NewContact(ctx context.Context, xPubID, paymailAddress, fullName string,
+	opts ...ModelOps) (*Contact, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "new_contact")
+
+	// Standardize the paymail address (break into parts)
+	alias, domain, address := paymail.SanitizePaymail(paymailAddress)
+	if len(address) == 0 {
+		return nil, ErrPaymailAddressIsInvalid
+	}
+
+	// Make sure the xPub exists
+	xPub, err := getXpubWithCache(ctx, c, "", xPubID, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if xPub == nil {
+		return nil, ErrMissingXpub
+	}
+
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyContact, getContactID(xPubID, address)), c.Cachestore())
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Already added (unconfirmed or confirmed)
+	var contact *Contact
+	if contact, err = getContact(ctx, xPubID, address, c.DefaultModelOptions()...); err != nil {
+		return nil, err
+	} else if contact != nil && (contact.Status == ContactStatusUnconfirmed || contact.Status == ContactStatusConfirmed) {
+		return contact, nil
+	}
+
+	var pubKey string
+	if pubKey, err = getPaymailPubKey(ctx, c, alias, domain); err != nil {
+		return nil, err
+	}
+
+	// Invite the contact (needs a paymail of the xPub)
+	var fromPaymail string
+	if fromPaymail, err = getXpubPaymail(ctx, c, xPubID); err != nil {
+		return nil, err
+	} else if len(fromPaymail) > 0 {
+		if _, err = sendPikeInvite(ctx, c, &PikeContactRequest{
+			FullName: fullName,
+			Paymail:  fromPaymail,
+		}, alias, domain); err != nil {
+			return nil, err
+		}
+	}
+
+	if contact == nil {
+		contact = newContact(
+			xPubID, address, fullName, pubKey, ContactStatusUnconfirmed,
+			c.DefaultModelOptions(append(opts, New())...)...,
+		)
+	} else {
+		contact.FullName = fullName
+		contact.PubKey = pubKey
+		contact.Status = ContactStatusUnconfirmed
+	}
+
+	// Save the model
+	if err = contact.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+// AcceptContact will accept the invitation of the contact (awaiting), the key is confirmed with ConfirmContact
+func (c *Client) AcceptContact(ctx context.Context, xPubID, paymailAddress string) (*Contact, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "accept_contact")
+
+	return c.updateContactStatus(ctx, xPubID, paymailAddress, ContactStatusAwaiting, ContactStatusUnconfirmed, "")
+}
+
+// RejectContact will reject the invitation of the contact (awaiting)
+func (c *Client) RejectContact(ctx context.Context, xPubID, paymailAddress string) (*Contact, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "reject_contact")
+
+	return c.updateContactStatus(ctx, xPubID, paymailAddress, ContactStatusAwaiting, ContactStatusRejected, "")
+}
+
+// ConfirmContact will confirm the key of the contact (unconfirmed), after verifying it with the contact (out of band)
+//
+// The xPub gets a chain for the contact: the outputs issued to the contact are derived from the chain
+func (c *Client) ConfirmContact(ctx context.Context, rawXpubKey, paymailAddress string) (*Contact, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "confirm_contact")
+
+	if _, err := utils.ValidateXPub(rawXpubKey); err != nil {
+		return nil, err
+	}
+
+	return c.updateContactStatus(
+		ctx, utils.Hash(rawXpubKey), paymailAddress, ContactStatusUnconfirmed, ContactStatusConfirmed, rawXpubKey,
+	)
+}
+
+// GetContact will get the contact (paymail) of the xPub
+func (c *Client) GetContact(ctx context.Context, xPubID, paymailAddress string) (*Contact, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_contact")
+
+	// Get the contact
+	_, _, address := paymail.SanitizePaymail(paymailAddress)
+	contact, err := getContact(ctx, xPubID, address, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if contact == nil || contact.DeletedAt.Valid {
+		return nil, ErrContactNotFound
+	}
+
+	// Return the model
+	return contact, nil
+}
+
+// GetContacts will get all the contacts from the Datastore
+func (c *Client) GetContacts(ctx context.Context, metadataConditions *Metadata,
+	conditions *map[string]interface{}, queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Contact, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_contacts")
+
+	// Get the contacts
+	contacts, err := getContacts(
+		ctx, metadataConditions, conditions, queryParams,
+		c.DefaultModelOptions(opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// GetContactsByXPubID will get the contacts of the xPub
+func (c *Client) GetContactsByXPubID(ctx context.Context, xPubID string,
+	queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Contact, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_contacts")
+
+	// Get the contacts
+	contacts, err := getContacts(
+		ctx, nil, &map[string]interface{}{
+			deletedAtField: nil,
+			xPubIDField:    xPubID,
+		}, queryParams,
+		c.DefaultModelOptions(opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// updateContactStatus will change the status of the contact, the chain is set when confirming (rawXpubKey)
+func (c *Client) updateContactStatus(ctx context.Context, xPubID, paymailAddress string,
+	fromStatus, toStatus ContactStatus, rawXpubKey string) (*Contact, error) {
+
+	_, _, address := paymail.SanitizePaymail(paymailAddress)
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyContact, getContactID(xPubID, address)), c.Cachestore())
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var contact *Contact
+	if contact, err = c.GetContact(ctx, xPubID, address); err != nil {
+		return nil, err
+	} else if contact.Status != fromStatus {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidContactStatus, contact.Status)
+	}
+
+	contact.Status = toStatus
+	if toStatus == ContactStatusConfirmed {
+		if err = contact.setChain(rawXpubKey); err != nil {
+			return nil, err
+		}
+	}
+
+	// Save the model
+	if err = contact.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/BuxOrg/bux/utils"
 	"github.com/mrz1836/go-datastore"
 	customTypes "github.com/mrz1836/go-datastore/custom_types"
 )
@@ -18,6 +19,16 @@ func (c *Client) NewDestination(ctx context.Context, xPubKey string, chain uint3
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "new_destination")
 
+	// Check the scope of the access key (if the request was made with one)
+	if err := checkAccessKeyScope(ctx, AccessKeyScopeCreateDestination); err != nil {
+		return nil, err
+	}
+
+	// Check the rate limit of the xPub (and access key)
+	if err := c.checkRateLimit(ctx, RateLimitNewDestination, utils.Hash(xPubKey)); err != nil {
+		return nil, err
+	}
+
 	// Get the xPub (by key - converts to id)
 	var xPub *Xpub
 	var err error
@@ -62,11 +73,21 @@ func (c *Client) NewDestinationForLockingScript(ctx context.Context, xPubID, loc
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "new_destination_for_locking_script")
 
+	// Check the scope of the access key (if the request was made with one)
+	if err := checkAccessKeyScope(ctx, AccessKeyScopeCreateDestination); err != nil {
+		return nil, err
+	}
+
 	// Ensure locking script isn't empty
 	if len(lockingScript) == 0 {
 		return nil, ErrMissingLockingScript
 	}
 
+	// Check the rate limit of the xPub (and access key)
+	if err := c.checkRateLimit(ctx, RateLimitNewDestination, xPubID); err != nil {
+		return nil, err
+	}
+
 	// Start the new destination - will detect type
 	destination := newDestination(
 		xPubID, lockingScript,
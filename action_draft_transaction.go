@@ -2,6 +2,7 @@ package bux
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/mrz1836/go-datastore"
 )
@@ -60,3 +61,62 @@ func (c *Client) GetDraftTransactionsCount(ctx context.Context, metadataConditio
 
 	return count, nil
 }
+
+// ApproveDraftTransaction will add the signed approval of an approver to a draft that needs approvals
+//
+// The draft is approved (and can be recorded) once it has the required approvals of the spending policy.
+// signature is the bitcoin signed message of GetDraftApprovalMessage (see SignDraftApproval)
+func (c *Client) ApproveDraftTransaction(ctx context.Context, draftID, approver, signature string,
+	opts ...ModelOps) (*DraftTransaction, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "approve_draft_transaction")
+
+	return c.addDraftApprovalStatement(ctx, draftID, approver, signature, true, opts...)
+}
+
+// RejectDraftTransaction will add the signed rejection of an approver to a draft that needs approvals
+//
+// The draft is rejected (and canceled) once it can no longer get the required approvals.
+// signature is the bitcoin signed message of GetDraftApprovalMessage (see SignDraftApproval)
+func (c *Client) RejectDraftTransaction(ctx context.Context, draftID, approver, signature string,
+	opts ...ModelOps) (*DraftTransaction, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "reject_draft_transaction")
+
+	return c.addDraftApprovalStatement(ctx, draftID, approver, signature, false, opts...)
+}
+
+// addDraftApprovalStatement will add the signed statement of the approver to the draft and notify about it
+func (c *Client) addDraftApprovalStatement(ctx context.Context, draftID, approver, signature string,
+	approved bool, opts ...ModelOps) (*DraftTransaction, error) {
+
+	// Lock the draft, the statements of the approvers could come in at the same time
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyDraftApproval, draftID), c.Cachestore())
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the draft
+	var draftTransaction *DraftTransaction
+	if draftTransaction, err = getDraftTransactionID(
+		ctx, "", draftID, c.DefaultModelOptions(opts...)...,
+	); err != nil {
+		return nil, err
+	} else if draftTransaction == nil {
+		return nil, ErrDraftNotFound
+	}
+
+	if err = draftTransaction.addApprovalStatement(approver, signature, approved); err != nil {
+		return nil, err
+	}
+
+	if err = draftTransaction.Save(ctx); err != nil {
+		return nil, err
+	}
+	draftTransaction.notifyApproval()
+
+	return draftTransaction, nil
+}
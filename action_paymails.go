@@ -33,6 +33,11 @@ func (c *Client) GetPaymailAddresses(ctx context.Context, metadataConditions *Me
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "get_paymail_addresses")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionRead); err != nil {
+		return nil, err
+	}
+
 	// Get the paymail address
 	paymailAddresses, err := getPaymailAddresses(
 		ctx, metadataConditions, conditions, queryParams,
@@ -52,6 +57,11 @@ func (c *Client) GetPaymailAddressesCount(ctx context.Context, metadataCondition
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "get_paymail_addresses_count")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionRead); err != nil {
+		return 0, err
+	}
+
 	// Get the paymail address
 	count, err := getPaymailAddressesCount(
 		ctx, metadataConditions, conditions,
@@ -96,6 +106,11 @@ func (c *Client) NewPaymailAddress(ctx context.Context, xPubKey, address, public
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "new_paymail_address")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionWrite); err != nil {
+		return nil, err
+	}
+
 	// Get the xPub (make sure it exists)
 	_, err := getXpubWithCache(ctx, c, xPubKey, "", c.DefaultModelOptions()...)
 	if err != nil {
@@ -137,6 +152,11 @@ func (c *Client) DeletePaymailAddress(ctx context.Context, address string, opts
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "delete_paymail_address")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionWrite); err != nil {
+		return err
+	}
+
 	// Get the paymail address
 	paymailAddress, err := getPaymailAddress(ctx, address, append(opts, c.DefaultModelOptions()...)...)
 	if err != nil {
@@ -169,6 +189,11 @@ func (c *Client) UpdatePaymailAddressMetadata(ctx context.Context, address strin
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "update_paymail_address_metadata")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionWrite); err != nil {
+		return nil, err
+	}
+
 	// Get the paymail address
 	paymailAddress, err := getPaymailAddress(ctx, address, append(opts, c.DefaultModelOptions()...)...)
 	if err != nil {
@@ -195,6 +220,11 @@ func (c *Client) UpdatePaymailAddress(ctx context.Context, address, publicName,
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "update_paymail_address")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionWrite); err != nil {
+		return nil, err
+	}
+
 	// Get the paymail address
 	paymailAddress, err := getPaymailAddress(ctx, address, append(opts, c.DefaultModelOptions()...)...)
 	if err != nil {
@@ -220,3 +250,43 @@ func (c *Client) UpdatePaymailAddress(ctx context.Context, address, publicName,
 
 	return paymailAddress, nil
 }
+
+// UpdatePaymailAddressReceivingPolicy will set the receiving policy of the paymail address
+//
+// The policy is checked for P2P destinations and address resolutions of the paymail (see PaymailReceivingPolicy)
+func (c *Client) UpdatePaymailAddressReceivingPolicy(ctx context.Context, address string,
+	policy *PaymailReceivingPolicy, opts ...ModelOps) (*PaymailAddress, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "update_paymail_address_receiving_policy")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionWrite); err != nil {
+		return nil, err
+	}
+
+	// Validate the policy (nil removes the policy)
+	if policy == nil {
+		policy = &PaymailReceivingPolicy{}
+	} else if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	// Get the paymail address
+	paymailAddress, err := getPaymailAddress(ctx, address, append(opts, c.DefaultModelOptions()...)...)
+	if err != nil {
+		return nil, err
+	} else if paymailAddress == nil {
+		return nil, ErrMissingPaymail
+	}
+
+	// Update the receiving policy
+	paymailAddress.ReceivingPolicy = *policy
+
+	// Save the model
+	if err = paymailAddress.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return paymailAddress, nil
+}
@@ -28,7 +28,7 @@ func (ts *EmbeddedDBTestSuite) TestClient_NewPaymailAddress() {
 			require.NoError(t, err)
 
 			var paymailAddress *PaymailAddress
-			paymailAddress, err = tc.client.NewPaymailAddress(tc.ctx, testXPub, "", testPublicName, testAvatar, tc.client.DefaultModelOptions()...)
+			paymailAddress, err = tc.client.NewPaymailAddress(testSuperAdminContext(tc.ctx), testXPub, "", testPublicName, testAvatar, tc.client.DefaultModelOptions()...)
 			require.ErrorIs(t, err, ErrMissingPaymailAddress)
 			require.Nil(t, paymailAddress)
 		})
@@ -45,7 +45,7 @@ func (ts *EmbeddedDBTestSuite) TestClient_NewPaymailAddress() {
 			require.NoError(t, err)
 
 			var paymailAddress *PaymailAddress
-			paymailAddress, err = tc.client.NewPaymailAddress(tc.ctx, xPub.RawXpub(), testPaymail, testPublicName, testAvatar, opts...)
+			paymailAddress, err = tc.client.NewPaymailAddress(testSuperAdminContext(tc.ctx), xPub.RawXpub(), testPaymail, testPublicName, testAvatar, opts...)
 			require.NoError(t, err)
 			require.NotNil(t, paymailAddress)
 
@@ -80,7 +80,7 @@ func (ts *EmbeddedDBTestSuite) Test_DeletePaymailAddress() {
 			defer tc.Close(tc.ctx)
 
 			paymail := ""
-			err := tc.client.DeletePaymailAddress(tc.ctx, paymail, tc.client.DefaultModelOptions()...)
+			err := tc.client.DeletePaymailAddress(testSuperAdminContext(tc.ctx), paymail, tc.client.DefaultModelOptions()...)
 			require.ErrorIs(t, err, ErrMissingPaymail)
 		})
 
@@ -88,7 +88,7 @@ func (ts *EmbeddedDBTestSuite) Test_DeletePaymailAddress() {
 			tc := ts.genericDBClient(t, testCase.database, false)
 			defer tc.Close(tc.ctx)
 
-			err := tc.client.DeletePaymailAddress(tc.ctx, testPaymail, tc.client.DefaultModelOptions()...)
+			err := tc.client.DeletePaymailAddress(testSuperAdminContext(tc.ctx), testPaymail, tc.client.DefaultModelOptions()...)
 			require.ErrorIs(t, err, ErrMissingPaymail)
 		})
 
@@ -103,11 +103,11 @@ func (ts *EmbeddedDBTestSuite) Test_DeletePaymailAddress() {
 			require.NoError(t, err)
 
 			var paymailAddress *PaymailAddress
-			paymailAddress, err = tc.client.NewPaymailAddress(tc.ctx, testXPub, testPaymail, testPublicName, testAvatar, opts...)
+			paymailAddress, err = tc.client.NewPaymailAddress(testSuperAdminContext(tc.ctx), testXPub, testPaymail, testPublicName, testAvatar, opts...)
 			require.NoError(t, err)
 			require.NotNil(t, paymailAddress)
 
-			err = tc.client.DeletePaymailAddress(tc.ctx, testPaymail, opts...)
+			err = tc.client.DeletePaymailAddress(testSuperAdminContext(tc.ctx), testPaymail, opts...)
 			require.NoError(t, err)
 
 			var p2 *PaymailAddress
@@ -147,16 +147,16 @@ func (ts *EmbeddedDBTestSuite) TestClient_UpdatePaymailAddressMetadata() {
 			require.NoError(t, err)
 
 			var paymailAddress *PaymailAddress
-			paymailAddress, err = tc.client.NewPaymailAddress(tc.ctx, testXPub, testPaymail, testPublicName, testAvatar, opts...)
+			paymailAddress, err = tc.client.NewPaymailAddress(testSuperAdminContext(tc.ctx), testXPub, testPaymail, testPublicName, testAvatar, opts...)
 			require.NoError(t, err)
 			require.NotNil(t, paymailAddress)
 
-			paymailAddress, err = tc.client.UpdatePaymailAddressMetadata(tc.ctx, testPaymail, Metadata{"test-key-new": "new-value"}, opts...)
+			paymailAddress, err = tc.client.UpdatePaymailAddressMetadata(testSuperAdminContext(tc.ctx), testPaymail, Metadata{"test-key-new": "new-value"}, opts...)
 			require.NoError(t, err)
 			assert.Len(t, paymailAddress.Metadata, 4)
 			assert.Equal(t, "new-value", paymailAddress.Metadata["test-key-new"])
 
-			paymailAddress, err = tc.client.UpdatePaymailAddressMetadata(tc.ctx, testPaymail, Metadata{
+			paymailAddress, err = tc.client.UpdatePaymailAddressMetadata(testSuperAdminContext(tc.ctx), testPaymail, Metadata{
 				"test-key-new-2": "new-value-2",
 				"test-key-1":     nil,
 				"test-key-2":     nil,
@@ -192,13 +192,13 @@ func (ts *EmbeddedDBTestSuite) TestClient_UpdatePaymailAddress() {
 			require.NoError(t, err)
 
 			var paymailAddress *PaymailAddress
-			paymailAddress, err = tc.client.NewPaymailAddress(tc.ctx, testXPub, testPaymail, testPublicName, testAvatar, opts...)
+			paymailAddress, err = tc.client.NewPaymailAddress(testSuperAdminContext(tc.ctx), testXPub, testPaymail, testPublicName, testAvatar, opts...)
 			require.NoError(t, err)
 			require.NotNil(t, paymailAddress)
 			assert.Equal(t, testPublicName, paymailAddress.PublicName)
 			assert.Equal(t, testAvatar, paymailAddress.Avatar)
 
-			paymailAddress, err = tc.client.UpdatePaymailAddress(tc.ctx, testPaymail, testPublicName+"2", testAvatar2, opts...)
+			paymailAddress, err = tc.client.UpdatePaymailAddress(testSuperAdminContext(tc.ctx), testPaymail, testPublicName+"2", testAvatar2, opts...)
 			require.NoError(t, err)
 
 			assert.Equal(t, testPublicName+"2", paymailAddress.PublicName)
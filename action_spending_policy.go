@@ -0,0 +1,103 @@
+package bux
+
+import (
+	"context"
+	"time"
+)
+
+// NewSpendingPolicy will set (or update) the spending policy of an xPub (requires WithSpendingPolicies)
+//
+// The policy is set by an admin, updating it keeps the satoshis already spent for the daily and weekly caps.
+//
+// xPubID is the ID of the xPub
+// config is the policy, empty values are not checked
+func (c *Client) NewSpendingPolicy(ctx context.Context, xPubID string, config *SpendingPolicyConfig,
+	opts ...ModelOps) (*SpendingPolicy, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "new_spending_policy")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionWrite); err != nil {
+		return nil, err
+	} else if config == nil {
+		return nil, ErrInvalidSpendingPolicy
+	}
+
+	// Make sure the xPub exists
+	xPub, err := getXpubWithCache(ctx, c, "", xPubID, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if xPub == nil {
+		return nil, ErrMissingXpub
+	}
+
+	// Update an existing policy
+	var policy *SpendingPolicy
+	if policy, err = getSpendingPolicy(
+		ctx, xPubID, c.DefaultModelOptions(opts...)...,
+	); err != nil {
+		return nil, err
+	} else if policy != nil {
+		policy.Config = *config
+		policy.DeletedAt.Valid = false
+		if err = policy.Config.validate(); err != nil {
+			return nil, err
+		}
+	} else {
+		policy = newSpendingPolicy(
+			xPubID, config, c.DefaultModelOptions(append(opts, New())...)...,
+		)
+	}
+
+	// Save the model
+	if err = policy.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	// Return the model
+	return policy, nil
+}
+
+// GetSpendingPolicy will get the spending policy of an xPub
+func (c *Client) GetSpendingPolicy(ctx context.Context, xPubID string) (*SpendingPolicy, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_spending_policy")
+
+	// Get the policy
+	policy, err := getSpendingPolicy(
+		ctx, xPubID, c.DefaultModelOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	} else if policy == nil || policy.DeletedAt.Valid {
+		return nil, ErrMissingSpendingPolicy
+	}
+
+	// Return the model
+	return policy, nil
+}
+
+// DeleteSpendingPolicy will delete (soft) the spending policy of an xPub
+func (c *Client) DeleteSpendingPolicy(ctx context.Context, xPubID string) error {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "delete_spending_policy")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionWrite); err != nil {
+		return err
+	}
+
+	// Get the policy
+	policy, err := c.GetSpendingPolicy(ctx, xPubID)
+	if err != nil {
+		return err
+	}
+
+	policy.DeletedAt.Valid = true
+	policy.DeletedAt.Time = time.Now()
+
+	return policy.Save(ctx)
+}
@@ -27,6 +27,11 @@ import (
 func (c *Client) RecordTransaction(ctx context.Context, xPubKey, txHex, draftID string, opts ...ModelOps) (*Transaction, error) {
 	ctx = c.GetOrStartTxn(ctx, "record_transaction")
 
+	// Check the scope of the access key (if the request was made with one)
+	if err := checkAccessKeyScope(ctx, AccessKeyScopeRecordTransaction); err != nil {
+		return nil, err
+	}
+
 	rts, err := getRecordTxStrategy(ctx, c, xPubKey, txHex, draftID)
 	if err != nil {
 		return nil, err
@@ -166,9 +171,15 @@ func (c *Client) NewTransaction(ctx context.Context, rawXpubKey string, config *
 	// Check for existing NewRelic draftTransaction
 	ctx = c.GetOrStartTxn(ctx, "new_transaction")
 
+	// Check the rate limit of the xPub (and access key)
+	xPubID := utils.Hash(rawXpubKey)
+	if err := c.checkRateLimit(ctx, RateLimitNewTransaction, xPubID); err != nil {
+		return nil, err
+	}
+
 	// Create the lock and set the release for after the function completes
 	unlock, err := newWaitWriteLock(
-		ctx, fmt.Sprintf(lockKeyProcessXpub, utils.Hash(rawXpubKey)), c.Cachestore(),
+		ctx, fmt.Sprintf(lockKeyProcessXpub, xPubID), c.Cachestore(),
 	)
 	defer unlock()
 	if err != nil {
@@ -190,6 +201,121 @@ func (c *Client) NewTransaction(ctx context.Context, rawXpubKey string, config *
 	return draftTransaction, nil
 }
 
+// NewChildPaysForParentTransaction will create a new draft transaction that "bumps" a stuck transaction (CPFP)
+//
+// The draft spends the unspent outputs of the xPub in the stuck (parent) transaction back to a new change
+// destination of the xPub, with a fee large enough to cover both transactions. The parent must have been
+// broadcast (accepted by the network) and not be mined yet. Sign and record it like any other draft (RecordTransaction),
+// recording links the sync records of the two transactions.
+//
+// ctx is the context
+// rawXpubKey is the raw xPub key
+// txID is the ID of the stuck transaction
+// feeUnit is the fee unit both transactions should satisfy (chainstate fee unit if nil)
+// opts are additional model options to be applied
+func (c *Client) NewChildPaysForParentTransaction(ctx context.Context, rawXpubKey, txID string,
+	feeUnit *utils.FeeUnit, opts ...ModelOps,
+) (*DraftTransaction, error) {
+	// Check for existing NewRelic draftTransaction
+	ctx = c.GetOrStartTxn(ctx, "new_child_pays_for_parent_transaction")
+
+	// Get the stuck transaction (must be related to the xPub)
+	xPubID := utils.Hash(rawXpubKey)
+	parent, err := c.GetTransaction(ctx, xPubID, txID)
+	if err != nil {
+		return nil, err
+	} else if parent.BlockHeight > 0 {
+		return nil, ErrTransactionAlreadyMined
+	}
+
+	var syncTx *SyncTransaction
+	if syncTx, err = GetSyncTransactionByID(ctx, txID, c.DefaultModelOptions()...); err != nil {
+		return nil, err
+	} else if syncTx != nil && syncTx.SyncStatus == SyncStatusComplete {
+		return nil, ErrTransactionAlreadyMined
+	} else if syncTx == nil || syncTx.BroadcastStatus != SyncStatusComplete {
+		// a child of a parent that is not in the mempool can not be broadcast (and would never be)
+		return nil, ErrParentNotBroadcast
+	}
+
+	if feeUnit == nil {
+		feeUnit = c.Chainstate().FeeUnit()
+	}
+	cpfp := &ChildPaysForParent{
+		ParentFee:  parent.Fee,
+		ParentSize: uint64(len(parent.Hex) / 2),
+		ParentTxID: txID,
+	}
+	if cpfp.missingFee(feeUnit) == 0 {
+		return nil, ErrParentFeeSufficient
+	}
+
+	// Get the outputs of the xPub that can be spent by the child
+	var utxos []*Utxo
+	if utxos, err = getUtxosByConditions(ctx, map[string]interface{}{
+		draftIDField:       nil,
+		spendingTxIDField:  nil,
+		transactionIDField: txID,
+		typeField:          utils.ScriptTypePubKeyHash,
+		xPubIDField:        xPubID,
+	}, nil, c.DefaultModelOptions()...); err != nil {
+		return nil, err
+	} else if len(utxos) == 0 {
+		return nil, ErrMissingUTXOsSpendable
+	}
+
+	fromUtxos := make([]*UtxoPointer, 0, len(utxos))
+	for _, utxo := range utxos {
+		fromUtxos = append(fromUtxos, &utxo.UtxoPointer)
+	}
+
+	// Send everything back to a new change destination of the xPub (derived by the draft)
+	return c.NewTransaction(ctx, rawXpubKey, &TransactionConfig{
+		ChildPaysForParent: cpfp,
+		FeeUnit:            feeUnit,
+		FromUtxos:          fromUtxos,
+		SendAllTo:          &TransactionOutput{},
+	}, opts...)
+}
+
+// NewSplitTransaction will create a new draft transaction that splits (fans out) the utxos of the xPub
+//
+// The draft has count change outputs of at least satoshisEach (the reserved utxos are split evenly) to new
+// internal destinations of the xPub, so concurrent transactions of the xPub can each spend their own utxo.
+// Sign and record it like any other draft (RecordTransaction).
+//
+// ctx is the context
+// rawXpubKey is the raw xPub key
+// count is the number of outputs
+// satoshisEach is the minimum satoshis of each output
+// opts are additional model options to be applied
+func (c *Client) NewSplitTransaction(ctx context.Context, rawXpubKey string, count int,
+	satoshisEach uint64, opts ...ModelOps,
+) (*DraftTransaction, error) {
+	// Check for existing NewRelic draftTransaction
+	ctx = c.GetOrStartTxn(ctx, "new_split_transaction")
+
+	return c.newSplitTransaction(ctx, rawXpubKey, count, satoshisEach, nil, opts...)
+}
+
+// newSplitTransaction will create the split draft, funded by the given utxos (nil for any utxos of the xPub)
+func (c *Client) newSplitTransaction(ctx context.Context, rawXpubKey string, count int,
+	satoshisEach uint64, fromUtxos []*UtxoPointer, opts ...ModelOps,
+) (*DraftTransaction, error) {
+	if count <= 0 || count > maxSplitOutputs {
+		return nil, ErrInvalidSplitCount
+	} else if satoshisEach <= dustLimit {
+		return nil, ErrOutputValueTooLow
+	}
+
+	// The change destinations are derived by the draft
+	return c.NewTransaction(ctx, rawXpubKey, &TransactionConfig{
+		ChangeMinimumSatoshis:      satoshisEach,
+		ChangeNumberOfDestinations: count,
+		FromUtxos:                  fromUtxos,
+	}, opts...)
+}
+
 // GetTransaction will get a transaction from the Datastore
 //
 // ctx is the context
@@ -394,6 +520,51 @@ func (c *Client) RevertTransaction(ctx context.Context, id string) error {
 		return errors.New("transaction was found on-chain, cannot revert")
 	}
 
+	return c.revertTransaction(ctx, transaction, draftTransaction)
+}
+
+// CancelScheduledTransaction will cancel a transaction that is held for a scheduled broadcast (see SyncConfig.BroadcastAt)
+//
+// The transaction never went out, it is reverted: the utxos it spent are released and the draft is canceled
+func (c *Client) CancelScheduledTransaction(ctx context.Context, id string) error {
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "cancel_scheduled_transaction")
+
+	// Same lock as broadcasting the transaction
+	unlock, err := newWriteLock(
+		ctx, fmt.Sprintf(lockKeyProcessBroadcastTx, id), c.Cachestore(),
+	)
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	// Make sure the broadcast is still scheduled
+	var syncTransaction *SyncTransaction
+	if syncTransaction, err = GetSyncTransactionByID(ctx, id, c.DefaultModelOptions()...); err != nil {
+		return err
+	} else if syncTransaction == nil || syncTransaction.BroadcastStatus != SyncStatusScheduled {
+		return ErrTransactionNotScheduled
+	}
+
+	// Get the transaction
+	var transaction *Transaction
+	if transaction, err = c.GetTransaction(ctx, "", id); err != nil {
+		return err
+	}
+
+	var draftTransaction *DraftTransaction
+	if draftTransaction, err = c.GetDraftTransactionByID(ctx, transaction.DraftID, c.DefaultModelOptions()...); err != nil {
+		return err
+	} else if draftTransaction == nil {
+		return ErrDraftNotFound
+	}
+
+	return c.revertTransaction(ctx, transaction, draftTransaction)
+}
+
+// revertTransaction will revert a transaction (that is not on-chain) and all related elements
+func (c *Client) revertTransaction(ctx context.Context, transaction *Transaction, draftTransaction *DraftTransaction) (err error) {
 	// check that the utxos of this transaction have not been spent
 	// this transaction needs to be the tip of the chain
 	conditions := &map[string]interface{}{
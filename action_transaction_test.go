@@ -3,10 +3,13 @@ package bux
 import (
 	"context"
 	"fmt"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/BuxOrg/bux/utils"
 	"github.com/libsv/go-bk/bip32"
+	"github.com/libsv/go-bt/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -187,6 +190,265 @@ func Test_RevertTransaction(t *testing.T) {
 	})
 }
 
+func TestClient_NewChildPaysForParentTransaction(t *testing.T) {
+	feeUnit := &utils.FeeUnit{Satoshis: 1, Bytes: 20}
+
+	// the parent is stuck: accepted by the network, but not mined
+	setBroadcast := func(t *testing.T, ctx context.Context, client ClientInterface, txID string) {
+		syncTx, err := GetSyncTransactionByID(ctx, txID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		syncTx.BroadcastStatus = SyncStatusComplete
+		require.NoError(t, syncTx.Save(ctx))
+	}
+
+	t.Run("bump a stuck transaction", func(t *testing.T) {
+		ctx, client, parent, xPriv, deferMe := initRevertTransactionData(t)
+		defer deferMe()
+		setBroadcast(t, ctx, client, parent.ID)
+
+		draftTransaction, err := client.NewChildPaysForParentTransaction(ctx, testXPub, parent.ID, feeUnit)
+		require.NoError(t, err)
+		require.NotNil(t, draftTransaction.Configuration.ChildPaysForParent)
+		assert.Equal(t, parent.ID, draftTransaction.Configuration.ChildPaysForParent.ParentTxID)
+
+		// The child only spends the change of the parent
+		require.Len(t, draftTransaction.Configuration.Inputs, 1)
+		assert.Equal(t, parent.ID, draftTransaction.Configuration.Inputs[0].TransactionID)
+
+		// The fee covers the size of both transactions (the parent paid no fee)
+		parentFee := uint64(math.Ceil(float64(len(parent.Hex)/2) / 20))
+		assert.Equal(t, parentFee, draftTransaction.Configuration.ChildPaysForParent.missingFee(feeUnit))
+		assert.Equal(t, draftTransaction.estimateFee(feeUnit, 0), draftTransaction.Configuration.Fee)
+		assert.Greater(t, draftTransaction.Configuration.Fee, parentFee)
+
+		// Everything goes to a change destination derived for the draft
+		require.Len(t, draftTransaction.Configuration.Outputs, 1)
+		var destination *Destination
+		destination, err = client.GetDestinationByAddress(ctx, testXPubID, draftTransaction.Configuration.Outputs[0].To)
+		require.NoError(t, err)
+		assert.Equal(t, utils.ChainInternal, destination.Chain)
+		assert.Equal(t, draftTransaction.ID, destination.DraftID)
+
+		var hex string
+		hex, err = draftTransaction.SignInputs(xPriv)
+		require.NoError(t, err)
+
+		var child *Transaction
+		child, err = client.RecordTransaction(ctx, testXPub, hex, draftTransaction.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+
+		// The sync records are linked
+		var syncTx *SyncTransaction
+		syncTx, err = GetSyncTransactionByID(ctx, child.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, parent.ID, syncTx.ParentTxID)
+
+		syncTx, err = GetSyncTransactionByID(ctx, parent.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, child.ID, syncTx.ChildTxID)
+	})
+
+	t.Run("parent fee is sufficient", func(t *testing.T) {
+		ctx, client, parent, _, deferMe := initRevertTransactionData(t)
+		defer deferMe()
+
+		parent.Fee = 1000000
+		require.NoError(t, parent.Save(ctx))
+		setBroadcast(t, ctx, client, parent.ID)
+
+		_, err := client.NewChildPaysForParentTransaction(ctx, testXPub, parent.ID, feeUnit)
+		assert.ErrorIs(t, err, ErrParentFeeSufficient)
+	})
+
+	t.Run("spent outputs do not cover the fee", func(t *testing.T) {
+		ctx, client, parent, _, deferMe := initRevertTransactionData(t)
+		defer deferMe()
+		setBroadcast(t, ctx, client, parent.ID)
+
+		_, err := client.NewChildPaysForParentTransaction(ctx, testXPub, parent.ID, &utils.FeeUnit{Satoshis: 1000, Bytes: 1})
+		assert.ErrorIs(t, err, ErrOutputValueTooLow)
+	})
+
+	t.Run("parent is already mined", func(t *testing.T) {
+		ctx, client, parent, _, deferMe := initRevertTransactionData(t)
+		defer deferMe()
+
+		parent.BlockHeight = 800000
+		require.NoError(t, parent.Save(ctx))
+		setBroadcast(t, ctx, client, parent.ID)
+
+		_, err := client.NewChildPaysForParentTransaction(ctx, testXPub, parent.ID, feeUnit)
+		assert.ErrorIs(t, err, ErrTransactionAlreadyMined)
+	})
+
+	t.Run("parent is not broadcast", func(t *testing.T) {
+		ctx, client, parent, _, deferMe := initRevertTransactionData(t)
+		defer deferMe()
+
+		syncTx, err := GetSyncTransactionByID(ctx, parent.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		syncTx.BroadcastStatus = SyncStatusError
+		require.NoError(t, syncTx.Save(ctx))
+
+		_, err = client.NewChildPaysForParentTransaction(ctx, testXPub, parent.ID, feeUnit)
+		assert.ErrorIs(t, err, ErrParentNotBroadcast)
+	})
+
+	t.Run("unknown transaction", func(t *testing.T) {
+		ctx, client, _, _, deferMe := initRevertTransactionData(t)
+		defer deferMe()
+
+		_, err := client.NewChildPaysForParentTransaction(ctx, testXPub, testTxID2, feeUnit)
+		assert.ErrorIs(t, err, ErrMissingTransaction)
+	})
+}
+
+func TestClient_NewSplitTransaction(t *testing.T) {
+	t.Run("invalid split", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		_, err := client.NewSplitTransaction(ctx, testXPub, 0, 1000)
+		assert.ErrorIs(t, err, ErrInvalidSplitCount)
+
+		_, err = client.NewSplitTransaction(ctx, testXPub, maxSplitOutputs+1, 1000)
+		assert.ErrorIs(t, err, ErrInvalidSplitCount)
+
+		_, err = client.NewSplitTransaction(ctx, testXPub, 10, dustLimit)
+		assert.ErrorIs(t, err, ErrOutputValueTooLow)
+	})
+
+	t.Run("split into outputs of the xPub", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		draftTransaction, err := client.NewSplitTransaction(ctx, testXPub, 5, 1000)
+		require.NoError(t, err)
+		require.Len(t, draftTransaction.Configuration.Outputs, 5)
+
+		for _, output := range draftTransaction.Configuration.Outputs {
+			assert.GreaterOrEqual(t, output.Satoshis, uint64(1000))
+
+			var destination *Destination
+			destination, err = client.GetDestinationByAddress(ctx, testXPubID, output.To)
+			require.NoError(t, err)
+			assert.Equal(t, utils.ChainInternal, destination.Chain)
+			assert.Equal(t, draftTransaction.ID, destination.DraftID)
+		}
+		assert.Equal(t, 100000-draftTransaction.Configuration.Fee, draftTransaction.Configuration.ChangeSatoshis)
+	})
+}
+
+func TestClient_RecordTransaction_LockTime(t *testing.T) {
+	ctx, client, deferMe := initConsolidationTestCase(t, []uint64{100000})
+	defer deferMe()
+
+	draftTransaction, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+		LockTime: 800000,
+		Outputs: []*TransactionOutput{{
+			To:       testExternalAddress,
+			Satoshis: 1000,
+		}},
+	})
+	require.NoError(t, err)
+
+	var signedHex string
+	signedHex, err = draftTransaction.SignInputsWithKey(testXPriv)
+	require.NoError(t, err)
+
+	// the lock time of the recorded hex must be the lock time of the draft
+	var btTx *bt.Tx
+	btTx, err = bt.NewTxFromString(signedHex)
+	require.NoError(t, err)
+	btTx.LockTime = 0
+	_, err = client.RecordTransaction(ctx, testXPub, btTx.String(), draftTransaction.ID)
+	assert.ErrorIs(t, err, ErrLockTimeMismatch)
+
+	var transaction *Transaction
+	transaction, err = client.RecordTransaction(ctx, testXPub, signedHex, draftTransaction.ID)
+	require.NoError(t, err)
+	assert.Equal(t, signedHex, transaction.Hex)
+}
+
+func TestClient_CancelScheduledTransaction(t *testing.T) {
+	// initScheduledTransaction will record a transaction that is scheduled to be broadcast in an hour
+	initScheduledTransaction := func(t *testing.T) (context.Context, ClientInterface, *Transaction, func()) {
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{100000}, WithCustomChainstate(&chainStateEverythingOnChain{}))
+
+		draftTransaction, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{
+				To:       testExternalAddress,
+				Satoshis: 1000,
+			}},
+			Sync: &SyncConfig{Broadcast: true, DelayToBroadcast: time.Hour},
+		})
+		require.NoError(t, err)
+
+		var signedHex string
+		signedHex, err = draftTransaction.SignInputsWithKey(testXPriv)
+		require.NoError(t, err)
+
+		var transaction *Transaction
+		transaction, err = client.RecordTransaction(ctx, testXPub, signedHex, draftTransaction.ID)
+		require.NoError(t, err)
+
+		return ctx, client, transaction, deferMe
+	}
+
+	t.Run("held until the scheduled time", func(t *testing.T) {
+		ctx, client, transaction, deferMe := initScheduledTransaction(t)
+		defer deferMe()
+
+		syncTx, err := GetSyncTransactionByID(ctx, transaction.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, SyncStatusScheduled, syncTx.BroadcastStatus)
+		assert.True(t, syncTx.BroadcastAt.Valid)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), syncTx.BroadcastAt.Time, time.Minute)
+
+		// not due yet
+		require.NoError(t, processBroadcastTransactions(ctx, 10, client.DefaultModelOptions()...))
+		syncTx, err = GetSyncTransactionByID(ctx, transaction.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, SyncStatusScheduled, syncTx.BroadcastStatus)
+
+		// due
+		syncTx.BroadcastAt.Time = time.Now().UTC().Add(-time.Minute)
+		require.NoError(t, syncTx.Save(ctx))
+		require.NoError(t, processBroadcastTransactions(ctx, 10, client.DefaultModelOptions()...))
+		syncTx, err = GetSyncTransactionByID(ctx, transaction.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, SyncStatusComplete, syncTx.BroadcastStatus)
+
+		err = client.CancelScheduledTransaction(ctx, transaction.ID)
+		assert.ErrorIs(t, err, ErrTransactionNotScheduled)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, client, transaction, deferMe := initScheduledTransaction(t)
+		defer deferMe()
+
+		require.NoError(t, client.CancelScheduledTransaction(ctx, transaction.ID))
+
+		syncTx, err := GetSyncTransactionByID(ctx, transaction.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, SyncStatusCanceled, syncTx.BroadcastStatus)
+
+		// the utxo was released
+		var utxo *Utxo
+		utxo, err = client.GetUtxoByTransactionID(ctx, testTxID, 0)
+		require.NoError(t, err)
+		assert.False(t, utxo.SpendingTxID.Valid)
+
+		var xPub *Xpub
+		xPub, err = client.GetXpubByID(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), xPub.CurrentBalance)
+
+		err = client.CancelScheduledTransaction(ctx, transaction.ID)
+		assert.ErrorIs(t, err, ErrTransactionNotScheduled)
+	})
+}
+
 func initRevertTransactionData(t *testing.T) (context.Context, ClientInterface, *Transaction, *bip32.ExtendedKey, func()) {
 	// this creates an xpub, destination and utxo
 	ctx, client, deferMe := initSimpleTestCase(t)
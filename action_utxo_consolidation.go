@@ -0,0 +1,206 @@
+package bux
+
+import (
+	"context"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+)
+
+// NewUtxoConsolidation will enable (or update) the automatic utxo consolidation of an xPub
+//
+// rawXpubKey is the raw xPub (stored encrypted if an encryption key is set, it's needed for deriving destinations)
+// config is the consolidation config (threshold, max inputs, low-fee window, dry-run)
+func (c *Client) NewUtxoConsolidation(ctx context.Context, rawXpubKey string, config *UtxoConsolidationConfig,
+	opts ...ModelOps) (*UtxoConsolidation, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "new_utxo_consolidation")
+
+	if config == nil {
+		return nil, ErrInvalidUtxoConsolidation
+	}
+
+	// Make sure the xPub exists
+	xPubID := utils.Hash(rawXpubKey)
+	xPub, err := getXpubWithCache(ctx, c, "", xPubID, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if xPub == nil {
+		return nil, ErrMissingXpub
+	}
+
+	// Update an existing consolidation
+	var consolidation *UtxoConsolidation
+	if consolidation, err = getUtxoConsolidation(
+		ctx, xPubID, c.DefaultModelOptions(opts...)...,
+	); err != nil {
+		return nil, err
+	} else if consolidation != nil {
+		if err = config.validate(); err != nil {
+			return nil, err
+		}
+		consolidation.Config = *config
+		consolidation.DeletedAt.Valid = false
+	} else {
+		consolidation = newUtxoConsolidation(
+			rawXpubKey, config, c.DefaultModelOptions(append(opts, New())...)...,
+		)
+	}
+
+	// Save the model
+	if err = consolidation.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	// Return the model
+	return consolidation, nil
+}
+
+// GetUtxoConsolidation will get the utxo consolidation of an xPub
+func (c *Client) GetUtxoConsolidation(ctx context.Context, xPubID string) (*UtxoConsolidation, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_utxo_consolidation")
+
+	// Get the consolidation
+	consolidation, err := getUtxoConsolidation(
+		ctx, xPubID, c.DefaultModelOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	} else if consolidation == nil || consolidation.DeletedAt.Valid {
+		return nil, ErrMissingUtxoConsolidation
+	}
+
+	// Return the model
+	return consolidation, nil
+}
+
+// DeleteUtxoConsolidation will delete (soft) the utxo consolidation of an xPub
+func (c *Client) DeleteUtxoConsolidation(ctx context.Context, xPubID string) error {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "delete_utxo_consolidation")
+
+	// Get the consolidation
+	consolidation, err := c.GetUtxoConsolidation(ctx, xPubID)
+	if err != nil {
+		return err
+	}
+
+	consolidation.DeletedAt.Valid = true
+	consolidation.DeletedAt.Time = time.Now()
+
+	return consolidation.Save(ctx)
+}
+
+// ConsolidateUtxos will consolidate the smallest spendable utxos of an xPub (with a consolidation) into one utxo
+//
+// The draft is signed by the draft signer of the client (see WithDraftSigner) and recorded.
+// In dry-run mode nothing is reserved, signed or recorded: the returned config has the utxos (FromUtxos, Inputs),
+// the fee and the satoshis of the output (SendAllTo) the consolidation would have, the destination is not derived.
+//
+// xPubID is the ID of the xPub
+// dryRun will only return the transaction config
+// opts are model options and can include "metadata"
+func (c *Client) ConsolidateUtxos(ctx context.Context, xPubID string, dryRun bool,
+	opts ...ModelOps) (*TransactionConfig, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "consolidate_utxos")
+
+	// Get the consolidation
+	consolidation, err := c.GetUtxoConsolidation(ctx, xPubID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawXpubKey string
+	if rawXpubKey, err = consolidation.getRawXpubKey(); err != nil {
+		return nil, err
+	}
+
+	// Get the smallest spendable utxos
+	maxInputs := consolidation.Config.MaxInputs
+	if maxInputs == 0 {
+		maxInputs = defaultConsolidationMaxInputs
+	}
+	var utxos []*Utxo
+	if utxos, err = getUtxos(ctx, nil, &map[string]interface{}{
+		draftIDField:      nil,
+		spendingTxIDField: nil,
+		typeField:         utils.ScriptTypePubKeyHash,
+		xPubIDField:       xPubID,
+	}, &datastore.QueryParams{
+		Page:          1,
+		PageSize:      int(maxInputs),
+		OrderByField:  satoshisField,
+		SortDirection: datastore.SortAsc,
+	}, c.DefaultModelOptions()...); err != nil {
+		return nil, err
+	} else if len(utxos) < 2 {
+		return nil, ErrNothingToConsolidate
+	}
+
+	config := &TransactionConfig{
+		FeeUnit:   c.Chainstate().FeeUnit(),
+		FromUtxos: make([]*UtxoPointer, 0, len(utxos)),
+	}
+	for _, utxo := range utxos {
+		config.FromUtxos = append(config.FromUtxos, &utxo.UtxoPointer)
+	}
+
+	if dryRun {
+		return dryRunConsolidation(rawXpubKey, config, utxos, c.DefaultModelOptions()...)
+	} else if c.options.draftSigner == nil {
+		return nil, ErrMissingDraftSigner
+	}
+
+	// Send everything to a new change destination of the xPub (derived by the draft)
+	config.SendAllTo = &TransactionOutput{}
+
+	var draftTransaction *DraftTransaction
+	if draftTransaction, err = c.NewTransaction(ctx, rawXpubKey, config, opts...); err != nil {
+		return nil, err
+	}
+
+	var transaction *Transaction
+	if transaction, err = c.signAndRecordDraft(ctx, rawXpubKey, draftTransaction, opts...); err != nil {
+		return nil, err
+	}
+
+	consolidation.LastRunAt.Valid = true
+	consolidation.LastRunAt.Time = time.Now().UTC()
+	consolidation.LastTransactionID = transaction.ID
+	if err = consolidation.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return &draftTransaction.Configuration, nil
+}
+
+// dryRunConsolidation will fill the config with the inputs, fee and output satoshis of the consolidation
+func dryRunConsolidation(rawXpubKey string, config *TransactionConfig, utxos []*Utxo,
+	opts ...ModelOps) (*TransactionConfig, error) {
+
+	// The draft is only used for estimating the fee, it is never saved
+	draft := newDraftTransaction(rawXpubKey, config, opts...)
+	var satoshis uint64
+	for _, utxo := range utxos {
+		draft.Configuration.Inputs = append(draft.Configuration.Inputs, &TransactionInput{Utxo: *utxo})
+		satoshis += utxo.Satoshis
+	}
+
+	fee := draft.estimateFee(draft.Configuration.FeeUnit, changeOutputSize)
+	if satoshis <= dustLimit+fee {
+		return nil, ErrOutputValueTooLow
+	}
+	draft.Configuration.Fee = fee
+	draft.Configuration.SendAllTo = &TransactionOutput{
+		Satoshis: satoshis - fee,
+	}
+
+	return &draft.Configuration, nil
+}
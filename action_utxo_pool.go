@@ -0,0 +1,169 @@
+package bux
+
+import (
+	"context"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+)
+
+// NewUtxoPool will enable (or update) the utxo pool of an xPub, the pool task keeps it topped up
+//
+// rawXpubKey is the raw xPub (stored encrypted if an encryption key is set, it's needed for deriving destinations)
+// targetSize is the number of spendable utxos to keep in the pool
+// satoshis is the satoshis of each utxo in the pool
+func (c *Client) NewUtxoPool(ctx context.Context, rawXpubKey string, targetSize uint32, satoshis uint64,
+	opts ...ModelOps) (*UtxoPool, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "new_utxo_pool")
+
+	// Make sure the xPub exists
+	xPubID := utils.Hash(rawXpubKey)
+	xPub, err := getXpubWithCache(ctx, c, "", xPubID, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if xPub == nil {
+		return nil, ErrMissingXpub
+	}
+
+	// Update an existing pool
+	var pool *UtxoPool
+	if pool, err = getUtxoPool(
+		ctx, xPubID, c.DefaultModelOptions(opts...)...,
+	); err != nil {
+		return nil, err
+	} else if pool != nil {
+		pool.Satoshis = satoshis
+		pool.TargetSize = targetSize
+		pool.DeletedAt.Valid = false
+		if err = pool.validate(); err != nil {
+			return nil, err
+		}
+	} else {
+		pool = newUtxoPool(
+			rawXpubKey, targetSize, satoshis, c.DefaultModelOptions(append(opts, New())...)...,
+		)
+	}
+
+	// Save the model
+	if err = pool.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	// Return the model
+	return pool, nil
+}
+
+// GetUtxoPool will get the utxo pool of an xPub
+func (c *Client) GetUtxoPool(ctx context.Context, xPubID string) (*UtxoPool, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_utxo_pool")
+
+	// Get the pool
+	pool, err := getUtxoPool(
+		ctx, xPubID, c.DefaultModelOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	} else if pool == nil || pool.DeletedAt.Valid {
+		return nil, ErrMissingUtxoPool
+	}
+
+	// Return the model
+	return pool, nil
+}
+
+// DeleteUtxoPool will delete (soft) the utxo pool of an xPub, the utxos of the pool are not touched
+func (c *Client) DeleteUtxoPool(ctx context.Context, xPubID string) error {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "delete_utxo_pool")
+
+	// Get the pool
+	pool, err := c.GetUtxoPool(ctx, xPubID)
+	if err != nil {
+		return err
+	}
+
+	pool.DeletedAt.Valid = true
+	pool.DeletedAt.Time = time.Now()
+
+	return pool.Save(ctx)
+}
+
+// TopUpUtxoPool will split the utxos of the xPub that are not in the pool into the missing utxos of the pool
+//
+// Every output of the split is a pool utxo (less than twice the pool satoshis), so a large utxo is split
+// into more outputs than are missing. The split draft is signed by the draft signer of the client
+// (see WithDraftSigner) and recorded. Returns nil if the pool is full.
+//
+// xPubID is the ID of the xPub
+// opts are model options and can include "metadata"
+func (c *Client) TopUpUtxoPool(ctx context.Context, xPubID string, opts ...ModelOps) (*Transaction, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "top_up_utxo_pool")
+
+	// Get the pool
+	pool, err := c.GetUtxoPool(ctx, xPubID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawXpubKey string
+	if rawXpubKey, err = pool.getRawXpubKey(); err != nil {
+		return nil, err
+	}
+
+	// Is the pool full?
+	var size int64
+	if size, err = pool.getSize(ctx); err != nil {
+		return nil, err
+	} else if size >= int64(pool.TargetSize) {
+		return nil, nil
+	} else if c.options.draftSigner == nil {
+		return nil, ErrMissingDraftSigner
+	}
+
+	// Fund the split with the utxos that are not in the pool
+	missing := int(int64(pool.TargetSize) - size)
+	var fromUtxos []*UtxoPointer
+	var satoshis uint64
+	if fromUtxos, satoshis, err = pool.getFundingUtxos(ctx, missing); err != nil {
+		return nil, err
+	} else if len(fromUtxos) == 0 {
+		return nil, ErrMissingUTXOsSpendable
+	}
+
+	// Each output must be less than twice the pool satoshis
+	count := missing
+	if minCount := int((satoshis + 2*pool.Satoshis - 1) / (2 * pool.Satoshis)); minCount > count {
+		count = minCount
+	}
+	if count > maxSplitOutputs {
+		count = maxSplitOutputs
+	}
+
+	var draftTransaction *DraftTransaction
+	if draftTransaction, err = c.newSplitTransaction(
+		ctx, rawXpubKey, count, pool.Satoshis, fromUtxos, opts...,
+	); err != nil {
+		return nil, err
+	}
+
+	var transaction *Transaction
+	if transaction, err = c.signAndRecordDraft(ctx, rawXpubKey, draftTransaction, opts...); err != nil {
+		return nil, err
+	}
+
+	pool.LastRunAt.Valid = true
+	pool.LastRunAt.Time = time.Now().UTC()
+	pool.LastTransactionID = transaction.ID
+	if err = pool.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
@@ -0,0 +1,138 @@
+package bux
+
+import (
+	"context"
+	"time"
+
+	"github.com/BuxOrg/bux/notifications"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+)
+
+// NewWebhookSubscription will create a new webhook subscription
+//
+// xPubID limits the subscription to the events of that xPub (empty for all xPubs, admin only)
+// modelTypes and eventTypes are the filters, empty matches all of them
+// signingSecret is used to sign the payloads (HMAC-SHA256), see notifications.NewVerifier()
+//
+// opts are options and can include "metadata"
+func (c *Client) NewWebhookSubscription(ctx context.Context, xPubID, webhookURL, signingSecret string,
+	modelTypes []ModelName, eventTypes []notifications.EventType, opts ...ModelOps) (*WebhookSubscription, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "new_webhook_subscription")
+
+	// Make sure the xPub exists
+	if len(xPubID) > 0 {
+		xPub, err := getXpubWithCache(ctx, c, "", xPubID, c.DefaultModelOptions()...)
+		if err != nil {
+			return nil, err
+		} else if xPub == nil {
+			return nil, ErrMissingXpub
+		}
+	}
+
+	// Create the model & set the default options (gives options from client->model)
+	subscription := newWebhookSubscription(
+		xPubID, webhookURL, signingSecret, modelTypes, eventTypes,
+		c.DefaultModelOptions(append(opts, New())...)...,
+	)
+
+	// Save the model
+	if err := subscription.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	// Return the created model
+	return subscription, nil
+}
+
+// GetWebhookSubscription will get an existing webhook subscription from the Datastore
+//
+// xPubID must match the xPub of the subscription (empty for the subscriptions of all xPubs)
+func (c *Client) GetWebhookSubscription(ctx context.Context, xPubID, id string) (*WebhookSubscription, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_webhook_subscription")
+
+	// Get the subscription
+	subscription, err := getWebhookSubscription(
+		ctx, id, c.DefaultModelOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	} else if subscription == nil || subscription.DeletedAt.Valid {
+		return nil, ErrMissingWebhookSubscription
+	}
+
+	// make sure this is the correct subscription
+	if subscription.XpubID != xPubID {
+		return nil, utils.ErrXpubNoMatch
+	}
+
+	// Return the model
+	return subscription, nil
+}
+
+// GetWebhookSubscriptions will get all the webhook subscriptions from the Datastore
+func (c *Client) GetWebhookSubscriptions(ctx context.Context, metadataConditions *Metadata,
+	conditions *map[string]interface{}, queryParams *datastore.QueryParams,
+	opts ...ModelOps) ([]*WebhookSubscription, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_webhook_subscriptions")
+
+	// Get the subscriptions
+	subscriptions, err := getWebhookSubscriptions(
+		ctx, metadataConditions, conditions, queryParams,
+		c.DefaultModelOptions(opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// GetWebhookSubscriptionsByXPubID will get the active webhook subscriptions of the xPub
+func (c *Client) GetWebhookSubscriptionsByXPubID(ctx context.Context, xPubID string,
+	queryParams *datastore.QueryParams, opts ...ModelOps) ([]*WebhookSubscription, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "get_webhook_subscriptions")
+
+	// Get the subscriptions
+	subscriptions, err := getWebhookSubscriptions(
+		ctx, nil, &map[string]interface{}{
+			deletedAtField: nil,
+			xPubIDField:    xPubID,
+		}, queryParams,
+		c.DefaultModelOptions(opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteWebhookSubscription will delete (soft) a webhook subscription, the pending deliveries are
+// dead-lettered on their next attempt
+//
+// xPubID must match the xPub of the subscription (empty for the subscriptions of all xPubs)
+func (c *Client) DeleteWebhookSubscription(ctx context.Context, xPubID, id string) error {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "delete_webhook_subscription")
+
+	// Get the subscription (checks the xPub)
+	subscription, err := c.GetWebhookSubscription(ctx, xPubID, id)
+	if err != nil {
+		return err
+	}
+
+	subscription.DeletedAt.Valid = true
+	subscription.DeletedAt.Time = time.Now()
+
+	return subscription.Save(ctx)
+}
@@ -98,6 +98,11 @@ func (c *Client) GetXPubs(ctx context.Context, metadataConditions *Metadata,
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "get_destinations")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionRead); err != nil {
+		return nil, err
+	}
+
 	// Get the count
 	xPubs, err := getXPubs(
 		ctx, metadataConditions, conditions, queryParams, c.DefaultModelOptions(opts...)...,
@@ -116,6 +121,11 @@ func (c *Client) GetXPubsCount(ctx context.Context, metadataConditions *Metadata
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "get_destinations")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionRead); err != nil {
+		return 0, err
+	}
+
 	// Get the count
 	count, err := getXPubsCount(
 		ctx, metadataConditions, conditions, c.DefaultModelOptions(opts...)...,
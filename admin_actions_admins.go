@@ -0,0 +1,180 @@
+package bux
+
+import (
+	"context"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+)
+
+// NewAdmin will create (or update the role of) an admin identity for the given xPub
+//
+// rawXpubKey is the raw xPub of the admin (the xPub does not need to be registered)
+// role is the role of the admin (auditor, operator, super-admin)
+// opts are options and can include "metadata"
+func (c *Client) NewAdmin(ctx context.Context, rawXpubKey string, role AdminRole, opts ...ModelOps) (*Admin, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "admin_new_admin")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionManageAdmins); err != nil {
+		return nil, err
+	}
+
+	// Validate that the value is an xPub
+	if _, err := utils.ValidateXPub(rawXpubKey); err != nil {
+		return nil, err
+	} else if _, ok := adminRolePermissions[role]; !ok {
+		return nil, ErrInvalidAdminRole
+	}
+
+	// Update an existing admin
+	admin, err := getAdmin(ctx, utils.Hash(rawXpubKey), c.DefaultModelOptions(opts...)...)
+	if err != nil {
+		return nil, err
+	} else if admin != nil {
+		admin.Role = role
+		admin.DeletedAt.Valid = false
+	} else {
+		admin = newAdmin(rawXpubKey, role, c.DefaultModelOptions(append(opts, New())...)...)
+	}
+
+	// Save the model
+	if err = admin.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	// Return the model
+	return admin, nil
+}
+
+// NewAdminContext will return a context with the admin of the xPub for calling the admin actions without an
+// authenticated request (see AuthenticateRequest)
+//
+// The admin is loaded from the Datastore, unknown and deleted admins are rejected (ErrNotAdminKey)
+func (c *Client) NewAdminContext(ctx context.Context, xPubID string) (context.Context, error) {
+	admin, err := c.getRequestAdmin(ctx, xPubID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return withAdmin(ctx, admin), nil
+}
+
+// GetAdmin will get an admin identity by the xPub ID
+func (c *Client) GetAdmin(ctx context.Context, xPubID string) (*Admin, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "admin_get_admin")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionRead); err != nil {
+		return nil, err
+	}
+
+	// Get the admin
+	admin, err := getAdmin(ctx, xPubID, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if admin == nil || admin.DeletedAt.Valid {
+		return nil, ErrMissingAdmin
+	}
+
+	// Return the model
+	return admin, nil
+}
+
+// GetAdmins will get all the admin identities
+func (c *Client) GetAdmins(ctx context.Context, metadataConditions *Metadata,
+	conditions *map[string]interface{}, queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Admin, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "admin_get_admins")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionRead); err != nil {
+		return nil, err
+	}
+
+	// Get the admins (that are not deleted)
+	dbConditions := map[string]interface{}{}
+	if conditions != nil {
+		for key, value := range *conditions {
+			dbConditions[key] = value
+		}
+	}
+	dbConditions[deletedAtField] = nil
+
+	admins, err := getAdmins(
+		ctx, metadataConditions, &dbConditions, queryParams,
+		c.DefaultModelOptions(opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return admins, nil
+}
+
+// DeleteAdmin will delete (soft) an admin identity by the xPub ID
+func (c *Client) DeleteAdmin(ctx context.Context, xPubID string) error {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "admin_delete_admin")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionManageAdmins); err != nil {
+		return err
+	}
+
+	// Get the admin
+	admin, err := c.GetAdmin(ctx, xPubID)
+	if err != nil {
+		return err
+	}
+
+	admin.DeletedAt.Valid = true
+	admin.DeletedAt.Time = time.Now()
+
+	return admin.Save(ctx)
+}
+
+// RetrySyncTransaction will set the failed (error) broadcast, p2p and sync statuses of a sync transaction
+// back to ready, the sync tasks will process them again
+func (c *Client) RetrySyncTransaction(ctx context.Context, txID string) (*SyncTransaction, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "admin_retry_sync_transaction")
+
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionOperate); err != nil {
+		return nil, err
+	}
+
+	// Get the sync transaction
+	syncTx, err := GetSyncTransactionByID(ctx, txID, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if syncTx == nil {
+		return nil, ErrMissingSyncTransaction
+	}
+
+	retried := false
+	for _, status := range []*SyncStatus{&syncTx.BroadcastStatus, &syncTx.P2PStatus, &syncTx.SyncStatus} {
+		if *status == SyncStatusError {
+			*status = SyncStatusReady
+			retried = true
+		}
+	}
+	if !retried {
+		return nil, ErrSyncTransactionNotFailed
+	}
+
+	// Save the model
+	if err = syncTx.Save(ctx); err != nil {
+		return nil, err
+	}
+
+	return syncTx, nil
+}
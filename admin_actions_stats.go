@@ -22,6 +22,11 @@ func (c *Client) GetStats(ctx context.Context, opts ...ModelOps) (*AdminStats, e
 	// Check for existing NewRelic transaction
 	ctx = c.GetOrStartTxn(ctx, "admin_get_stats")
 
+	// Check the permission of the admin of the request
+	if err := checkAdminPermission(ctx, AdminPermissionRead); err != nil {
+		return nil, err
+	}
+
 	// Set the default model options
 	defaultOpts := c.DefaultModelOptions(opts...)
 
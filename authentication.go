@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -20,9 +21,20 @@ import (
 // and it will check the Key/Signature
 //
 // Sets req.Context(xpub) and req.Context(xpub_hash)
+//
+// The adminXPubs are super-admins, other admins (with a role) are loaded from the Datastore (see NewAdmin),
+// the admin of an admin request is set on req.Context(auth_admin_identity)
+//
+// Authrite (BRC-31) requests (see WithAuthrite) are always signed, the identity key is the key of
+// the xPub (also sent in the AuthHeader) or an access key
 func (c *Client) AuthenticateRequest(ctx context.Context, req *http.Request, adminXPubs []string,
 	adminRequired, requireSigning, signingDisabled bool) (*http.Request, error) {
 
+	// Authrite requests are signed with the identity key
+	if len(req.Header.Get(AuthriteHeaderIdentityKey)) > 0 {
+		return c.authenticateAuthriteRequest(ctx, req, adminXPubs, adminRequired)
+	}
+
 	// Get the xPub/Access Key from the header
 	xPub := strings.TrimSpace(req.Header.Get(AuthHeader))
 	authAccessKey := strings.TrimSpace(req.Header.Get(AuthAccessKey))
@@ -30,32 +42,14 @@ func (c *Client) AuthenticateRequest(ctx context.Context, req *http.Request, adm
 		return req, ErrMissingAuthHeader
 	}
 
-	// Check for admin key
-	if adminRequired {
-		if !utils.StringInSlice(xPub, adminXPubs) {
-			return req, ErrNotAdminKey
-		}
+	// Get the xPub ID, access key and admin of the request
+	xPubID, accessKey, admin, err := c.getRequestIdentity(ctx, xPub, authAccessKey, adminXPubs, adminRequired)
+	if err != nil {
+		return req, err
 	}
-
-	xPubID := utils.Hash(xPub)
 	xPubOrAccessKey := xPub
-	if xPub != "" {
-		// Validate that the xPub is an HD key (length, validation)
-		if _, err := utils.ValidateXPub(xPubOrAccessKey); err != nil {
-			return req, err
-		}
-	} else if authAccessKey != "" {
+	if accessKey != nil {
 		xPubOrAccessKey = authAccessKey
-
-		accessKey, err := getAccessKey(ctx, utils.Hash(authAccessKey), c.DefaultModelOptions()...)
-		if err != nil {
-			return req, err
-		}
-		if accessKey == nil || accessKey.RevokedAt.Valid {
-			return req, ErrAuthAccessKeyNotFound
-		}
-
-		xPubID = accessKey.XpubID
 	}
 
 	if req.Body == nil {
@@ -64,8 +58,8 @@ func (c *Client) AuthenticateRequest(ctx context.Context, req *http.Request, adm
 	defer func() {
 		_ = req.Body.Close()
 	}()
-	b, err := io.ReadAll(req.Body)
-	if err != nil {
+	var b []byte
+	if b, err = io.ReadAll(req.Body); err != nil {
 		return req, err
 	}
 
@@ -76,8 +70,16 @@ func (c *Client) AuthenticateRequest(ctx context.Context, req *http.Request, adm
 		AuthHash:     req.Header.Get(AuthHeaderHash),
 		AuthNonce:    req.Header.Get(AuthHeaderNonce),
 		AuthTime:     int64(authTime),
+		AuthVersion:  req.Header.Get(AuthHeaderVersion),
 		BodyContents: string(b),
 		Signature:    req.Header.Get(AuthSignature),
+		clientIP:     c.getClientIP(req),
+	}
+
+	// The v2 signing message is bound to the method, path and query of the request
+	if authData.AuthVersion == AuthVersion2 {
+		authData.RequestMethod = req.Method
+		authData.RequestURI = req.URL.RequestURI()
 	}
 
 	// adminRequired will always force checking of a signature
@@ -97,10 +99,8 @@ func (c *Client) AuthenticateRequest(ctx context.Context, req *http.Request, adm
 		}
 	}
 
-	req = setOnRequest(req, ParamAdminRequest, adminRequired)
-
 	// Set the data back onto the request
-	return setOnRequest(setOnRequest(req, ParamXPubKey, xPub), ParamXPubHashKey, xPubID), nil
+	return setRequestIdentity(req, xPub, xPubID, accessKey, admin, adminRequired), nil
 }
 
 // checkSignature check the signature for the provided auth payload
@@ -112,10 +112,142 @@ func (c *Client) checkSignature(ctx context.Context, xPubOrAccessKey string, aut
 	}
 
 	// Check xPub vs Access Key
+	var err error
 	if strings.Contains(xPubOrAccessKey, "xpub") && len(xPubOrAccessKey) > 64 {
-		return verifyKeyXPub(xPubOrAccessKey, auth)
+		err = verifyKeyXPub(xPubOrAccessKey, auth)
+	} else {
+		err = c.verifyAccessKey(ctx, xPubOrAccessKey, auth)
 	}
-	return verifyAccessKey(ctx, xPubOrAccessKey, auth, c.DefaultModelOptions()...)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the signed request is not being replayed
+	return c.useAuthNonce(ctx, xPubOrAccessKey, auth)
+}
+
+// getClientIP will get the IP of the client, the forwarded headers are only used on requests of a trusted proxy
+// (see WithTrustedProxies)
+func (c *Client) getClientIP(req *http.Request) string {
+	remoteIP := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !c.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	// The client is the last forwarded IP that is not a trusted proxy
+	if forwarded := req.Header.Get("X-Forwarded-For"); len(forwarded) > 0 {
+		ips := strings.Split(forwarded, ",")
+		for index := len(ips) - 1; index > 0; index-- {
+			if ip := strings.TrimSpace(ips[index]); !c.isTrustedProxy(ip) {
+				return ip
+			}
+		}
+		return strings.TrimSpace(ips[0])
+	} else if realIP := req.Header.Get("X-Real-Ip"); len(realIP) > 0 {
+		return strings.TrimSpace(realIP)
+	}
+	return remoteIP
+}
+
+// isTrustedProxy will return true if the IP is one of the trusted proxies
+func (c *Client) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range c.options.trustedProxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// getRequestAdmin will get the admin of the xPub (the xPubs of the admin list are super-admins)
+func (c *Client) getRequestAdmin(ctx context.Context, xPubID string, adminXPubs []string) (*Admin, error) {
+	for _, adminXPub := range adminXPubs {
+		if utils.Hash(adminXPub) == xPubID {
+			return &Admin{ID: xPubID, Role: AdminRoleSuperAdmin}, nil
+		}
+	}
+
+	admin, err := getAdmin(ctx, xPubID, c.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if admin == nil || admin.DeletedAt.Valid {
+		return nil, ErrNotAdminKey
+	}
+	return admin, nil
+}
+
+// getRequestIdentity will get the xPub ID, the access key (if the request was made with one)
+// and the admin (for admin requests) of the request
+func (c *Client) getRequestIdentity(ctx context.Context, xPub, authAccessKey string, adminXPubs []string,
+	adminRequired bool) (xPubID string, accessKey *AccessKey, admin *Admin, err error) {
+
+	// Check for admin key (an access key needs the admin read scope, see below)
+	if adminRequired && len(xPub) > 0 {
+		if admin, err = c.getRequestAdmin(ctx, utils.Hash(xPub), adminXPubs); err != nil {
+			return
+		}
+	}
+
+	xPubID = utils.Hash(xPub)
+	if xPub != "" {
+		// Validate that the xPub is an HD key (length, validation)
+		_, err = utils.ValidateXPub(xPub)
+		return
+	} else if authAccessKey == "" {
+		err = ErrMissingAuthHeader
+		return
+	}
+
+	if accessKey, err = getAccessKey(ctx, utils.Hash(authAccessKey), c.DefaultModelOptions()...); err != nil {
+		return
+	}
+	if accessKey == nil || accessKey.IsRevoked() {
+		err = ErrAuthAccessKeyNotFound
+		return
+	} else if accessKey.IsExpired() {
+		err = ErrAccessKeyExpired
+		return
+	}
+
+	// Only an access key of an admin xPub with the admin read scope can make (read) admin requests
+	if adminRequired {
+		if !accessKey.HasScope(AccessKeyScopeAdminRead) {
+			err = ErrNotAdminKey
+			return
+		} else if admin, err = c.getRequestAdmin(ctx, accessKey.XpubID, adminXPubs); err != nil {
+			return
+		}
+		admin = &Admin{ID: admin.ID, Role: AdminRoleAuditor}
+	}
+
+	xPubID = accessKey.XpubID
+	return
+}
+
+// setRequestIdentity will set the xPub, admin and access key of an authenticated request
+func setRequestIdentity(req *http.Request, xPub, xPubID string, accessKey *AccessKey, admin *Admin,
+	adminRequired bool) *http.Request {
+
+	req = setOnRequest(req, ParamAdminRequest, adminRequired)
+
+	// Set the admin (role) for authorizing the request
+	if admin != nil {
+		req = req.WithContext(withAdmin(req.Context(), admin))
+	}
+
+	// Set the access key (scopes) for authorizing the request
+	if accessKey != nil {
+		req = setOnRequest(req, ParamAccessKey, accessKey)
+	}
+
+	return setOnRequest(setOnRequest(req, ParamXPubKey, xPub), ParamXPubHashKey, xPubID)
 }
 
 // checkSignatureRequirements will check the payload for basic signature requirements
@@ -126,15 +258,29 @@ func checkSignatureRequirements(auth *AuthPayload) error {
 		return ErrMissingSignature
 	}
 
+	// Check the signing message version
+	if auth.AuthVersion != "" && auth.AuthVersion != AuthVersion2 {
+		return ErrUnsupportedAuthVersion
+	}
+
 	// Check the auth hash vs the body hash
 	bodyHash := createBodyHash(auth.BodyContents)
 	if auth.AuthHash != bodyHash {
 		return ErrAuhHashMismatch
 	}
 
-	// Check the auth timestamp
-	if time.Now().UTC().After(time.UnixMilli(auth.AuthTime).Add(AuthSignatureTTL)) {
+	// Check the auth timestamp (a time in the future would extend the TTL)
+	now := time.Now().UTC()
+	authTime := time.UnixMilli(auth.AuthTime)
+	if now.After(authTime.Add(AuthSignatureTTL)) {
 		return ErrSignatureExpired
+	} else if authTime.After(now.Add(AuthSignatureClockSkew)) {
+		return ErrSignatureTimeInFuture
+	}
+
+	// Check that we have a nonce
+	if auth.AuthNonce == "" {
+		return ErrMissingAuthNonce
 	}
 	return nil
 }
@@ -180,18 +326,20 @@ func verifyKeyXPub(xPub string, auth *AuthPayload) error {
 	return nil
 }
 
-// verifyAccessKey will verify the access key and the signature payload
-func verifyAccessKey(ctx context.Context, key string, auth *AuthPayload, opts ...ModelOps) error {
+// verifyAccessKey will verify the access key and the signature payload, and record the usage of the key
+func (c *Client) verifyAccessKey(ctx context.Context, key string, auth *AuthPayload) error {
 
 	// Get access key from DB
 	// todo: add caching in the future, faster than DB
-	accessKey, err := getAccessKey(ctx, utils.Hash(key), opts...)
+	accessKey, err := getAccessKey(ctx, utils.Hash(key), c.DefaultModelOptions()...)
 	if err != nil {
 		return err
 	} else if accessKey == nil {
 		return ErrUnknownAccessKey
-	} else if accessKey.RevokedAt.Valid {
+	} else if accessKey.IsRevoked() {
 		return ErrAccessKeyRevoked
+	} else if accessKey.IsExpired() {
+		return ErrAccessKeyExpired
 	}
 
 	var address *bscript.Address
@@ -209,6 +357,11 @@ func verifyAccessKey(ctx context.Context, key string, auth *AuthPayload, opts ..
 	); err != nil {
 		return ErrSignatureInvalid
 	}
+
+	// Failing to record the usage does not fail the request
+	if err = accessKey.recordUsage(ctx, auth.clientIP, c.options.accessKeyUsageThrottle); err != nil {
+		c.Logger().Error(ctx, "error recording access key usage: "+err.Error())
+	}
 	return nil
 }
 
@@ -253,6 +406,11 @@ func setSignatureHeaders(header *http.Header, authData *AuthPayload) error {
 	// Set the time
 	header.Set(AuthHeaderTime, fmt.Sprintf("%d", authData.AuthTime))
 
+	// Set the version of the signing message (v1 has no version header)
+	if authData.AuthVersion != "" {
+		header.Set(AuthHeaderVersion, authData.AuthVersion)
+	}
+
 	// Set the signature
 	header.Set(AuthSignature, authData.Signature)
 
@@ -268,8 +426,57 @@ func CreateSignature(xPriv *bip32.ExtendedKey, bodyString string) (string, error
 	return authData.Signature, nil
 }
 
+// SetSignatureV2 will set the v2 signature on the header for the request
+//
+// The v2 signature is bound to the method and the request URI (path and query, ie: /v1/xpub?metadata=x)
+func SetSignatureV2(header *http.Header, xPriv *bip32.ExtendedKey, method, requestURI, bodyString string) error {
+
+	// Create the signature
+	authData, err := createSignatureV2(xPriv, method, requestURI, bodyString)
+	if err != nil {
+		return err
+	}
+
+	// Set the auth header
+	header.Set(AuthHeader, authData.xPub)
+
+	return setSignatureHeaders(header, authData)
+}
+
+// SetSignatureFromAccessKeyV2 will set the v2 signature on the header for the request from an access key
+//
+// The v2 signature is bound to the method and the request URI (path and query, ie: /v1/xpub?metadata=x)
+func SetSignatureFromAccessKeyV2(header *http.Header, privateKeyHex, method, requestURI, bodyString string) error {
+
+	// Create the signature
+	authData, err := createSignatureAccessKeyV2(privateKeyHex, method, requestURI, bodyString)
+	if err != nil {
+		return err
+	}
+
+	// Set the auth header
+	header.Set(AuthAccessKey, authData.accessKey)
+
+	return setSignatureHeaders(header, authData)
+}
+
+// CreateSignatureV2 will create a v2 signature for the given key, request method & URI and body contents
+func CreateSignatureV2(xPriv *bip32.ExtendedKey, method, requestURI, bodyString string) (string, error) {
+	authData, err := createSignatureV2(xPriv, method, requestURI, bodyString)
+	if err != nil {
+		return "", err
+	}
+	return authData.Signature, nil
+}
+
 // getSigningMessage will build the signing message string
 func getSigningMessage(xPub string, auth *AuthPayload) string {
+	if auth.AuthVersion == AuthVersion2 {
+		return fmt.Sprintf(
+			"%s%s%s%s%d%s%s", AuthVersion2, xPub, auth.AuthHash, auth.AuthNonce, auth.AuthTime,
+			strings.ToUpper(auth.RequestMethod), auth.RequestURI,
+		)
+	}
 	return fmt.Sprintf("%s%s%s%d", xPub, auth.AuthHash, auth.AuthNonce, auth.AuthTime)
 }
 
@@ -288,6 +495,36 @@ func IsAdminRequest(req *http.Request) (bool, bool) {
 	return getBoolFromRequest(req, ParamAdminRequest)
 }
 
+// GetAccessKeyFromRequest gets the access key the request was authenticated with, if found
+func GetAccessKeyFromRequest(req *http.Request) (*AccessKey, bool) {
+	accessKey, ok := req.Context().Value(ParamAccessKey).(*AccessKey)
+	return accessKey, ok
+}
+
+// HasScope will return true if the request is allowed the scope
+//
+// Requests authenticated with an xPub are allowed every scope, access keys only the scopes of the key
+func HasScope(req *http.Request, scope AccessKeyScope) bool {
+	if accessKey, ok := GetAccessKeyFromRequest(req); ok {
+		return accessKey.HasScope(scope)
+	}
+	return true
+}
+
+// GetAdminFromRequest gets the admin (role) of an admin request, if found
+func GetAdminFromRequest(req *http.Request) (*Admin, bool) {
+	admin := adminFromContext(req.Context())
+	return admin, admin != nil
+}
+
+// HasAdminPermission will return true if the admin of the request has the permission
+func HasAdminPermission(req *http.Request, permission AdminPermission) bool {
+	if admin, ok := GetAdminFromRequest(req); ok {
+		return admin.HasPermission(permission)
+	}
+	return false
+}
+
 // GetXpubHashFromRequest gets the stored xPub hash from the request if found
 func GetXpubHashFromRequest(req *http.Request) (string, bool) {
 	return getFromRequest(req, ParamXPubHashKey)
@@ -0,0 +1,552 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoinschema/go-bitcoin/v2"
+	"github.com/libsv/go-bk/bec"
+	"github.com/libsv/go-bk/bip32"
+)
+
+const (
+	// AuthriteHeaderVersion is the header for the Authrite version of the request
+	AuthriteHeaderVersion = "x-authrite"
+
+	// AuthriteHeaderIdentityKey is the header for the identity (public) key of the sender
+	AuthriteHeaderIdentityKey = "x-authrite-identity-key"
+
+	// AuthriteHeaderNonce is the header for the nonce of the sender
+	AuthriteHeaderNonce = "x-authrite-nonce"
+
+	// AuthriteHeaderYourNonce is the header for the nonce of the other party (server nonce of the handshake for requests)
+	AuthriteHeaderYourNonce = "x-authrite-yournonce"
+
+	// AuthriteHeaderSignature is the header for the signature of the request (or response)
+	AuthriteHeaderSignature = "x-authrite-signature"
+
+	// AuthriteHeaderCertificates is the header for the certificates (JSON) of the sender
+	AuthriteHeaderCertificates = "x-authrite-certificates"
+
+	// AuthriteVersion is the supported version of Authrite
+	AuthriteVersion = "0.1"
+
+	// AuthriteSessionTTL is the max TTL of a server nonce (the session of the handshake)
+	AuthriteSessionTTL = 1 * time.Hour
+
+	// authriteMessageInitialRequest is the message type of the handshake request
+	authriteMessageInitialRequest = "initialRequest"
+
+	// authriteMessageInitialResponse is the message type of the handshake response
+	authriteMessageInitialResponse = "initialResponse"
+
+	// authriteInvoicePrefix is the prefix of the invoice number (BRC-42) of the signing keys
+	authriteInvoicePrefix = "2-authrite message signature-"
+
+	// authriteNonceSize is the size of the server nonce (time + random + HMAC)
+	authriteNonceSize = 8 + 16 + 16
+)
+
+// AuthriteInitialRequest is the handshake request of the client (POST /authrite/initialRequest)
+type AuthriteInitialRequest struct {
+	Authrite              string          `json:"authrite"`
+	IdentityKey           string          `json:"identityKey"`
+	MessageType           string          `json:"messageType"`
+	Nonce                 string          `json:"nonce"`
+	RequestedCertificates json.RawMessage `json:"requestedCertificates,omitempty"`
+}
+
+// AuthriteInitialResponse is the handshake response of the server
+//
+// The nonce of the server is sent back with every request (AuthriteHeaderYourNonce)
+type AuthriteInitialResponse struct {
+	Authrite              string            `json:"authrite"`
+	Certificates          []json.RawMessage `json:"certificates"`
+	IdentityKey           string            `json:"identityKey"`
+	MessageType           string            `json:"messageType"`
+	Nonce                 string            `json:"nonce"`
+	RequestedCertificates json.RawMessage   `json:"requestedCertificates,omitempty"`
+	Signature             string            `json:"signature"`
+}
+
+// AuthriteInitialResponse will create the handshake response for the initial request of a client
+//
+// The server nonce of the response is stateless (it's checked with the identity key of the server),
+// so every node of a cluster can authenticate the requests of the session.
+// The certificates of the client are not verified by the engine, see GetAuthriteCertificatesFromRequest
+func (c *Client) AuthriteInitialResponse(request *AuthriteInitialRequest) (*AuthriteInitialResponse, error) {
+
+	serverKey, err := c.authriteServerKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the request
+	if request == nil || request.MessageType != authriteMessageInitialRequest || len(request.Nonce) == 0 {
+		return nil, ErrInvalidAuthriteMessage
+	} else if request.Authrite != AuthriteVersion {
+		return nil, ErrUnsupportedAuthriteVersion
+	}
+
+	var identityKey *bec.PublicKey
+	if identityKey, err = parseAuthriteIdentityKey(request.IdentityKey); err != nil {
+		return nil, err
+	}
+
+	response := &AuthriteInitialResponse{
+		Authrite:     AuthriteVersion,
+		Certificates: []json.RawMessage{},
+		IdentityKey:  hex.EncodeToString(serverKey.PubKey().SerialiseCompressed()),
+		MessageType:  authriteMessageInitialResponse,
+	}
+	if response.Nonce, err = newAuthriteNonce(serverKey, request.IdentityKey, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Sign both nonces, the client checks that it's talking to the holder of the identity key
+	if response.Signature, err = signAuthrite(
+		serverKey, identityKey, request.Nonce, response.Nonce, request.Nonce+response.Nonce,
+	); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// authenticateAuthriteRequest will check the Authrite signature of the request and map the identity key
+// to an xPub (the identity key is the key of the xPub, sent in the AuthHeader) or an access key
+func (c *Client) authenticateAuthriteRequest(ctx context.Context, req *http.Request, adminXPubs []string,
+	adminRequired bool) (*http.Request, error) {
+
+	serverKey, err := c.authriteServerKey()
+	if err != nil {
+		return req, err
+	}
+
+	if req.Header.Get(AuthriteHeaderVersion) != AuthriteVersion {
+		return req, ErrUnsupportedAuthriteVersion
+	}
+
+	identityKeyHex := strings.TrimSpace(req.Header.Get(AuthriteHeaderIdentityKey))
+	var identityKey *bec.PublicKey
+	if identityKey, err = parseAuthriteIdentityKey(identityKeyHex); err != nil {
+		return req, err
+	}
+
+	clientNonce := req.Header.Get(AuthriteHeaderNonce)
+	if len(clientNonce) == 0 {
+		return req, ErrMissingAuthNonce
+	}
+
+	// The server nonce must be issued by this server (any node) for the identity key and not expired
+	serverNonce := req.Header.Get(AuthriteHeaderYourNonce)
+	var issuedAt time.Time
+	if issuedAt, err = checkAuthriteNonce(serverKey, identityKeyHex, serverNonce, time.Now()); err != nil {
+		return req, err
+	}
+
+	// Requests without a body sign the request URI
+	var b []byte
+	if req.Body != nil {
+		defer func() {
+			_ = req.Body.Close()
+		}()
+		if b, err = io.ReadAll(req.Body); err != nil {
+			return req, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+	data := string(b)
+	if len(data) == 0 {
+		data = req.URL.RequestURI()
+	}
+
+	if err = verifyAuthrite(
+		identityKey, serverKey, clientNonce, serverNonce, data, req.Header.Get(AuthriteHeaderSignature),
+	); err != nil {
+		return req, err
+	}
+
+	// Make sure the signed request is not being replayed (the client nonce is new for every request)
+	if err = c.useNonce(ctx, identityKeyHex, clientNonce, issuedAt.Add(AuthriteSessionTTL)); err != nil {
+		return req, err
+	}
+
+	// Map the identity key to the xPub or the access key
+	xPub := strings.TrimSpace(req.Header.Get(AuthHeader))
+	authAccessKey := ""
+	if len(xPub) > 0 {
+		if err = checkAuthriteXPub(xPub, identityKeyHex); err != nil {
+			return req, err
+		}
+	} else {
+		authAccessKey = identityKeyHex
+	}
+
+	xPubID, accessKey, admin, err := c.getRequestIdentity(ctx, xPub, authAccessKey, adminXPubs, adminRequired)
+	if err != nil {
+		return req, err
+	}
+
+	// Failing to record the usage does not fail the request
+	if accessKey != nil {
+		if err = accessKey.recordUsage(ctx, c.getClientIP(req), c.options.accessKeyUsageThrottle); err != nil {
+			c.Logger().Error(ctx, "error recording access key usage: "+err.Error())
+		}
+	}
+
+	req = setOnRequest(req, ParamAuthSigned, true)
+	req = setOnRequest(req, ParamAuthriteIdentityKey, identityKeyHex)
+	req = setOnRequest(req, ParamAuthriteNonce, clientNonce)
+	if certificates := req.Header.Get(AuthriteHeaderCertificates); len(certificates) > 0 {
+		req = setOnRequest(req, ParamAuthriteCertificates, json.RawMessage(certificates))
+	}
+
+	// Set the data back onto the request
+	return setRequestIdentity(req, xPub, xPubID, accessKey, admin, adminRequired), nil
+}
+
+// SetAuthriteResponseSignature will sign the response (body) of an Authrite request for the client
+//
+// req is the request returned by AuthenticateRequest
+func (c *Client) SetAuthriteResponseSignature(req *http.Request, header *http.Header, bodyString string) error {
+
+	serverKey, err := c.authriteServerKey()
+	if err != nil {
+		return err
+	}
+
+	identityKeyHex, _ := getFromRequest(req, ParamAuthriteIdentityKey)
+	clientNonce, _ := getFromRequest(req, ParamAuthriteNonce)
+	if len(identityKeyHex) == 0 || len(clientNonce) == 0 {
+		return ErrNotAuthriteRequest
+	}
+
+	var identityKey *bec.PublicKey
+	if identityKey, err = parseAuthriteIdentityKey(identityKeyHex); err != nil {
+		return err
+	}
+
+	var serverNonce string
+	if serverNonce, err = newAuthriteNonce(serverKey, identityKeyHex, time.Now()); err != nil {
+		return err
+	}
+
+	var signature string
+	if signature, err = signAuthrite(serverKey, identityKey, clientNonce, serverNonce, bodyString); err != nil {
+		return err
+	}
+
+	header.Set(AuthriteHeaderVersion, AuthriteVersion)
+	header.Set(AuthriteHeaderIdentityKey, hex.EncodeToString(serverKey.PubKey().SerialiseCompressed()))
+	header.Set(AuthriteHeaderNonce, serverNonce)
+	header.Set(AuthriteHeaderYourNonce, clientNonce)
+	header.Set(AuthriteHeaderSignature, signature)
+	return nil
+}
+
+// GetAuthriteCertificatesFromRequest gets the certificates (raw JSON) sent with an Authrite request, if found
+func GetAuthriteCertificatesFromRequest(req *http.Request) (json.RawMessage, bool) {
+	certificates, ok := req.Context().Value(ParamAuthriteCertificates).(json.RawMessage)
+	return certificates, ok
+}
+
+// NewAuthriteInitialRequest will create the handshake request for the identity (private) key
+func NewAuthriteInitialRequest(identityKeyHex string) (*AuthriteInitialRequest, error) {
+	privateKey, err := bitcoin.PrivateKeyFromString(identityKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &AuthriteInitialRequest{
+		Authrite:    AuthriteVersion,
+		IdentityKey: hex.EncodeToString(privateKey.PubKey().SerialiseCompressed()),
+		MessageType: authriteMessageInitialRequest,
+	}
+	if request.Nonce, err = newAuthriteClientNonce(); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// VerifyAuthriteInitialResponse will check the signature of the handshake response of the server
+func VerifyAuthriteInitialResponse(identityKeyHex string, request *AuthriteInitialRequest,
+	response *AuthriteInitialResponse) error {
+
+	if request == nil || response == nil || response.MessageType != authriteMessageInitialResponse {
+		return ErrInvalidAuthriteMessage
+	} else if response.Authrite != AuthriteVersion {
+		return ErrUnsupportedAuthriteVersion
+	}
+
+	privateKey, err := bitcoin.PrivateKeyFromString(identityKeyHex)
+	if err != nil {
+		return err
+	}
+
+	var serverKey *bec.PublicKey
+	if serverKey, err = parseAuthriteIdentityKey(response.IdentityKey); err != nil {
+		return err
+	}
+
+	return verifyAuthrite(
+		serverKey, privateKey, request.Nonce, response.Nonce, request.Nonce+response.Nonce, response.Signature,
+	)
+}
+
+// SetAuthriteSignature will set the Authrite signature on the header for the request from an identity (access) key
+//
+// session is the handshake response of the server, the requestURI is signed if the body is empty.
+// Returns the nonce of the request, needed for checking the response (VerifyAuthriteResponse)
+func SetAuthriteSignature(header *http.Header, identityKeyHex string, session *AuthriteInitialResponse,
+	requestURI, bodyString string) (string, error) {
+
+	privateKey, err := bitcoin.PrivateKeyFromString(identityKeyHex)
+	if err != nil {
+		return "", err
+	}
+	return setAuthriteSignature(header, privateKey, session, requestURI, bodyString)
+}
+
+// SetAuthriteSignatureFromXPriv will set the Authrite signature on the header for the request from an xPriv
+//
+// The identity key is the key of the xPub (sent in the AuthHeader), see SetAuthriteSignature
+func SetAuthriteSignatureFromXPriv(header *http.Header, xPriv *bip32.ExtendedKey, session *AuthriteInitialResponse,
+	requestURI, bodyString string) (string, error) {
+
+	// No key?
+	if xPriv == nil {
+		return "", ErrMissingXPriv
+	}
+
+	xPub, err := bitcoin.GetExtendedPublicKey(xPriv)
+	if err != nil {
+		return "", err
+	}
+
+	var privateKey *bec.PrivateKey
+	if privateKey, err = bitcoin.GetPrivateKeyFromHDKey(xPriv); err != nil {
+		return "", err
+	}
+
+	// Set the auth header
+	header.Set(AuthHeader, xPub)
+
+	return setAuthriteSignature(header, privateKey, session, requestURI, bodyString)
+}
+
+// VerifyAuthriteResponse will check the Authrite signature of the response (body) of the server
+//
+// clientNonce is the nonce of the request (see SetAuthriteSignature)
+func VerifyAuthriteResponse(header http.Header, identityKeyHex string, session *AuthriteInitialResponse,
+	clientNonce, bodyString string) error {
+
+	if session == nil || header.Get(AuthriteHeaderIdentityKey) != session.IdentityKey ||
+		header.Get(AuthriteHeaderYourNonce) != clientNonce {
+		return ErrInvalidAuthriteMessage
+	}
+
+	privateKey, err := bitcoin.PrivateKeyFromString(identityKeyHex)
+	if err != nil {
+		return err
+	}
+
+	var serverKey *bec.PublicKey
+	if serverKey, err = parseAuthriteIdentityKey(session.IdentityKey); err != nil {
+		return err
+	}
+
+	return verifyAuthrite(
+		serverKey, privateKey, clientNonce, header.Get(AuthriteHeaderNonce), bodyString,
+		header.Get(AuthriteHeaderSignature),
+	)
+}
+
+// setAuthriteSignature will sign the request with the identity key for the session of the server
+func setAuthriteSignature(header *http.Header, privateKey *bec.PrivateKey, session *AuthriteInitialResponse,
+	requestURI, bodyString string) (string, error) {
+
+	if session == nil {
+		return "", ErrInvalidAuthriteMessage
+	}
+
+	serverKey, err := parseAuthriteIdentityKey(session.IdentityKey)
+	if err != nil {
+		return "", err
+	}
+
+	var clientNonce string
+	if clientNonce, err = newAuthriteClientNonce(); err != nil {
+		return "", err
+	}
+
+	data := bodyString
+	if len(data) == 0 {
+		data = requestURI
+	}
+
+	var signature string
+	if signature, err = signAuthrite(privateKey, serverKey, clientNonce, session.Nonce, data); err != nil {
+		return "", err
+	}
+
+	header.Set(AuthriteHeaderVersion, AuthriteVersion)
+	header.Set(AuthriteHeaderIdentityKey, hex.EncodeToString(privateKey.PubKey().SerialiseCompressed()))
+	header.Set(AuthriteHeaderNonce, clientNonce)
+	header.Set(AuthriteHeaderYourNonce, session.Nonce)
+	header.Set(AuthriteHeaderSignature, signature)
+	return clientNonce, nil
+}
+
+// authriteServerKey will get the identity key of the server (see WithAuthrite)
+func (c *Client) authriteServerKey() (*bec.PrivateKey, error) {
+	if len(c.options.authriteKey) == 0 {
+		return nil, ErrAuthriteDisabled
+	}
+	return bitcoin.PrivateKeyFromString(c.options.authriteKey)
+}
+
+// parseAuthriteIdentityKey will parse the identity key (compressed public key hex)
+func parseAuthriteIdentityKey(identityKeyHex string) (*bec.PublicKey, error) {
+	if len(identityKeyHex) == 0 {
+		return nil, ErrMissingAuthHeader
+	}
+	b, err := hex.DecodeString(identityKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	return bec.ParsePubKey(b, bec.S256())
+}
+
+// checkAuthriteXPub will check that the identity key is the key of the xPub
+func checkAuthriteXPub(xPub, identityKeyHex string) error {
+	hdKey, err := utils.ValidateXPub(xPub)
+	if err != nil {
+		return err
+	}
+
+	var publicKey *bec.PublicKey
+	if publicKey, err = hdKey.ECPubKey(); err != nil {
+		return err
+	} else if hex.EncodeToString(publicKey.SerialiseCompressed()) != identityKeyHex {
+		return ErrAuthriteIdentityMismatch
+	}
+	return nil
+}
+
+// authriteInvoiceNumber will get the invoice number (BRC-42) of the signing key for the nonces
+func authriteInvoiceNumber(clientNonce, serverNonce string) string {
+	return authriteInvoicePrefix + clientNonce + " " + serverNonce
+}
+
+// signAuthrite will sign the data with the key derived for the counterparty and the nonces (DER hex)
+func signAuthrite(privateKey *bec.PrivateKey, counterparty *bec.PublicKey, clientNonce, serverNonce,
+	data string) (string, error) {
+
+	signingKey, err := utils.DeriveInvoicePrivateKey(
+		privateKey, counterparty, authriteInvoiceNumber(clientNonce, serverNonce),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(data))
+	var signature *bec.Signature
+	if signature, err = signingKey.Sign(hash[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signature.Serialise()), nil
+}
+
+// verifyAuthrite will verify the signature of the data with the key derived for the counterparty and the nonces
+func verifyAuthrite(publicKey *bec.PublicKey, counterparty *bec.PrivateKey, clientNonce, serverNonce,
+	data, signatureHex string) error {
+
+	if len(signatureHex) == 0 {
+		return ErrMissingSignature
+	}
+
+	signingKey, err := utils.DeriveInvoicePublicKey(
+		publicKey, counterparty, authriteInvoiceNumber(clientNonce, serverNonce),
+	)
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	if b, err = hex.DecodeString(signatureHex); err != nil {
+		return ErrSignatureInvalid
+	}
+
+	var signature *bec.Signature
+	if signature, err = bec.ParseDERSignature(b, bec.S256()); err != nil {
+		return ErrSignatureInvalid
+	}
+
+	hash := sha256.Sum256([]byte(data))
+	if !signature.Verify(hash[:], signingKey) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// newAuthriteClientNonce will create a random nonce for the client (base64)
+func newAuthriteClientNonce() (string, error) {
+	nonce, err := utils.RandomHex(32)
+	if err != nil {
+		return "", err
+	}
+	b, _ := hex.DecodeString(nonce)
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// newAuthriteNonce will create a server nonce for the identity key of the client
+// (base64 of time + random + HMAC keyed by the server key)
+func newAuthriteNonce(serverKey *bec.PrivateKey, identityKeyHex string, now time.Time) (string, error) {
+	random, err := utils.RandomHex(16)
+	if err != nil {
+		return "", err
+	}
+	b, _ := hex.DecodeString(random)
+
+	nonce := make([]byte, 8, authriteNonceSize)
+	binary.BigEndian.PutUint64(nonce, uint64(now.Unix()))
+	nonce = append(nonce, b...)
+	nonce = append(nonce, authriteNonceMAC(serverKey, identityKeyHex, nonce)...)
+	return base64.StdEncoding.EncodeToString(nonce), nil
+}
+
+// checkAuthriteNonce will check that the server nonce was issued by the server for the identity key and did not expire
+func checkAuthriteNonce(serverKey *bec.PrivateKey, identityKeyHex, serverNonce string,
+	now time.Time) (time.Time, error) {
+
+	nonce, err := base64.StdEncoding.DecodeString(serverNonce)
+	if err != nil || len(nonce) != authriteNonceSize ||
+		!hmac.Equal(nonce[24:], authriteNonceMAC(serverKey, identityKeyHex, nonce[:24])) {
+		return time.Time{}, ErrInvalidAuthriteNonce
+	}
+
+	issuedAt := time.Unix(int64(binary.BigEndian.Uint64(nonce[:8])), 0)
+	if now.After(issuedAt.Add(AuthriteSessionTTL)) {
+		return time.Time{}, ErrInvalidAuthriteNonce
+	}
+	return issuedAt, nil
+}
+
+// authriteNonceMAC will create the (truncated) HMAC of the nonce and the identity key keyed by the server key
+func authriteNonceMAC(serverKey *bec.PrivateKey, identityKeyHex string, nonce []byte) []byte {
+	mac := hmac.New(sha256.New, serverKey.Serialise())
+	_, _ = mac.Write(nonce)
+	_, _ = mac.Write([]byte(identityKeyHex))
+	return mac.Sum(nil)[:16]
+}
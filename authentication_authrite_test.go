@@ -0,0 +1,284 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/libsv/go-bk/bec"
+	"github.com/libsv/go-bk/bip32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthriteTestKey will create a random identity (private) key hex
+func newAuthriteTestKey(t *testing.T) string {
+	privateKey, err := bec.NewPrivateKey(bec.S256())
+	require.NoError(t, err)
+	return hex.EncodeToString(privateKey.Serialise())
+}
+
+// newAuthriteTestSession will make the handshake of the identity key with the client
+func newAuthriteTestSession(t *testing.T, client ClientInterface, identityKeyHex string) *AuthriteInitialResponse {
+	request, err := NewAuthriteInitialRequest(identityKeyHex)
+	require.NoError(t, err)
+
+	var response *AuthriteInitialResponse
+	response, err = client.AuthriteInitialResponse(request)
+	require.NoError(t, err)
+	require.NoError(t, VerifyAuthriteInitialResponse(identityKeyHex, request, response))
+	return response
+}
+
+func TestClient_AuthriteInitialResponse(t *testing.T) {
+	t.Run("not enabled", func(t *testing.T) {
+		_, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		request, err := NewAuthriteInitialRequest(newAuthriteTestKey(t))
+		require.NoError(t, err)
+
+		_, err = client.AuthriteInitialResponse(request)
+		assert.ErrorIs(t, err, ErrAuthriteDisabled)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferMe()
+
+		request, err := NewAuthriteInitialRequest(newAuthriteTestKey(t))
+		require.NoError(t, err)
+
+		request.Authrite = "0.2"
+		_, err = client.AuthriteInitialResponse(request)
+		assert.ErrorIs(t, err, ErrUnsupportedAuthriteVersion)
+
+		request.Authrite = AuthriteVersion
+		request.Nonce = ""
+		_, err = client.AuthriteInitialResponse(request)
+		assert.ErrorIs(t, err, ErrInvalidAuthriteMessage)
+	})
+
+	t.Run("signed by the server", func(t *testing.T) {
+		serverKey := newAuthriteTestKey(t)
+		_, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(serverKey),
+		)
+		defer deferMe()
+
+		identityKey := newAuthriteTestKey(t)
+		request, err := NewAuthriteInitialRequest(identityKey)
+		require.NoError(t, err)
+
+		var response *AuthriteInitialResponse
+		response, err = client.AuthriteInitialResponse(request)
+		require.NoError(t, err)
+		require.NoError(t, VerifyAuthriteInitialResponse(identityKey, request, response))
+
+		// not the nonce of the request
+		request.Nonce = "other"
+		assert.ErrorIs(t, VerifyAuthriteInitialResponse(identityKey, request, response), ErrSignatureInvalid)
+	})
+}
+
+func Test_checkAuthriteNonce(t *testing.T) {
+	serverKey, err := bec.NewPrivateKey(bec.S256())
+	require.NoError(t, err)
+
+	now := time.Now()
+	identityKey := hex.EncodeToString(serverKey.PubKey().SerialiseCompressed())
+	var nonce string
+	nonce, err = newAuthriteNonce(serverKey, identityKey, now)
+	require.NoError(t, err)
+
+	var issuedAt time.Time
+	issuedAt, err = checkAuthriteNonce(serverKey, identityKey, nonce, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Unix(), issuedAt.Unix())
+
+	_, err = checkAuthriteNonce(serverKey, identityKey, nonce, now.Add(AuthriteSessionTTL+time.Second))
+	assert.ErrorIs(t, err, ErrInvalidAuthriteNonce)
+
+	// issued by another server or for another identity key
+	var otherKey *bec.PrivateKey
+	otherKey, err = bec.NewPrivateKey(bec.S256())
+	require.NoError(t, err)
+	_, err = checkAuthriteNonce(otherKey, identityKey, nonce, now)
+	assert.ErrorIs(t, err, ErrInvalidAuthriteNonce)
+	_, err = checkAuthriteNonce(serverKey, "other", nonce, now)
+	assert.ErrorIs(t, err, ErrInvalidAuthriteNonce)
+
+	_, err = checkAuthriteNonce(serverKey, identityKey, "not-a-nonce", now)
+	assert.ErrorIs(t, err, ErrInvalidAuthriteNonce)
+}
+
+func TestClient_AuthenticateRequest_Authrite(t *testing.T) {
+	newRequest := func(t *testing.T, body string) *http.Request {
+		req, err := http.NewRequestWithContext(
+			context.Background(), http.MethodPost, "/v1/destination", bytes.NewReader([]byte(body)),
+		)
+		require.NoError(t, err)
+		return req
+	}
+
+	t.Run("access key", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferMe()
+
+		key := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		require.NoError(t, key.Save(ctx))
+		session := newAuthriteTestSession(t, client, key.Key)
+
+		req := newRequest(t, `{}`)
+		clientNonce, err := SetAuthriteSignature(&req.Header, key.Key, session, req.URL.RequestURI(), `{}`)
+		require.NoError(t, err)
+
+		req, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		require.NoError(t, err)
+
+		xPubID, _ := GetXpubIDFromRequest(req)
+		assert.Equal(t, testXPubID, xPubID)
+		accessKey, ok := GetAccessKeyFromRequest(req)
+		require.True(t, ok)
+		assert.Equal(t, key.ID, accessKey.ID)
+		signed, _ := getBoolFromRequest(req, ParamAuthSigned)
+		assert.True(t, signed)
+
+		// the response is signed for the client
+		header := http.Header{}
+		require.NoError(t, client.SetAuthriteResponseSignature(req, &header, `{"ok":true}`))
+		require.NoError(t, VerifyAuthriteResponse(header, key.Key, session, clientNonce, `{"ok":true}`))
+		assert.ErrorIs(t, VerifyAuthriteResponse(header, key.Key, session, clientNonce, `{}`), ErrSignatureInvalid)
+	})
+
+	t.Run("unknown access key", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferMe()
+
+		identityKey := newAuthriteTestKey(t)
+		session := newAuthriteTestSession(t, client, identityKey)
+
+		req := newRequest(t, `{}`)
+		_, err := SetAuthriteSignature(&req.Header, identityKey, session, req.URL.RequestURI(), `{}`)
+		require.NoError(t, err)
+
+		_, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		assert.ErrorIs(t, err, ErrAuthAccessKeyNotFound)
+	})
+
+	t.Run("xPub", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferMe()
+
+		xPriv, err := bip32.NewKeyFromString(testXPriv)
+		require.NoError(t, err)
+		var privateKey *bec.PrivateKey
+		privateKey, err = xPriv.ECPrivKey()
+		require.NoError(t, err)
+		session := newAuthriteTestSession(t, client, hex.EncodeToString(privateKey.Serialise()))
+
+		// a request without a body signs the request URI
+		var req *http.Request
+		req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, "/v1/xpub", nil)
+		require.NoError(t, err)
+		_, err = SetAuthriteSignatureFromXPriv(&req.Header, xPriv, session, req.URL.RequestURI(), "")
+		require.NoError(t, err)
+
+		req, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		require.NoError(t, err)
+		xPub, _ := GetXpubFromRequest(req)
+		assert.Equal(t, testXPub, xPub)
+
+		// the identity key is not the key of the xPub
+		req = newRequest(t, `{}`)
+		_, err = SetAuthriteSignature(&req.Header, newAuthriteTestKey(t), session, req.URL.RequestURI(), `{}`)
+		require.NoError(t, err)
+		req.Header.Set(AuthHeader, testXPub)
+
+		_, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		assert.ErrorIs(t, err, ErrInvalidAuthriteNonce) // the session was made with another key
+
+		identityKey := newAuthriteTestKey(t)
+		session = newAuthriteTestSession(t, client, identityKey)
+		req = newRequest(t, `{}`)
+		_, err = SetAuthriteSignature(&req.Header, identityKey, session, req.URL.RequestURI(), `{}`)
+		require.NoError(t, err)
+		req.Header.Set(AuthHeader, testXPub)
+
+		_, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		assert.ErrorIs(t, err, ErrAuthriteIdentityMismatch)
+	})
+
+	t.Run("replayed or tampered request", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferMe()
+
+		key := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		require.NoError(t, key.Save(ctx))
+		session := newAuthriteTestSession(t, client, key.Key)
+
+		req := newRequest(t, `{}`)
+		_, err := SetAuthriteSignature(&req.Header, key.Key, session, req.URL.RequestURI(), `{}`)
+		require.NoError(t, err)
+
+		replay := newRequest(t, `{}`)
+		replay.Header = req.Header.Clone()
+		tampered := newRequest(t, `{"metadata":{}}`)
+		tampered.Header = req.Header.Clone()
+
+		_, err = client.AuthenticateRequest(ctx, tampered, []string{}, false, true, false)
+		assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+		_, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		require.NoError(t, err)
+
+		_, err = client.AuthenticateRequest(ctx, replay, []string{}, false, true, false)
+		assert.ErrorIs(t, err, ErrAuthNonceReused)
+	})
+
+	t.Run("nonce of another server", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferMe()
+
+		_, other, deferOther := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferOther()
+
+		identityKey := newAuthriteTestKey(t)
+		session := newAuthriteTestSession(t, other, identityKey)
+
+		req := newRequest(t, `{}`)
+		_, err := SetAuthriteSignature(&req.Header, identityKey, session, req.URL.RequestURI(), `{}`)
+		require.NoError(t, err)
+
+		_, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		assert.ErrorIs(t, err, ErrInvalidAuthriteNonce)
+	})
+
+	t.Run("not an authrite request", func(t *testing.T) {
+		_, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithAuthrite(newAuthriteTestKey(t)),
+		)
+		defer deferMe()
+
+		header := http.Header{}
+		err := client.SetAuthriteResponseSignature(newRequest(t, `{}`), &header, `{}`)
+		assert.ErrorIs(t, err, ErrNotAuthriteRequest)
+	})
+}
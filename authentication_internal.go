@@ -3,6 +3,8 @@ package bux
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
+	"math"
 	"net/http"
 	"strings"
 	"time"
@@ -32,19 +34,32 @@ const (
 	// AuthHeaderTime the time of the request, only valid for 30 seconds
 	AuthHeaderTime = "bux-auth-time"
 
+	// AuthHeaderVersion the version of the signing message (empty for v1)
+	AuthHeaderVersion = "bux-auth-version"
+
+	// AuthVersion2 is the signing message that also covers the method, path and query of the request
+	AuthVersion2 = "2"
+
 	// AuthSignatureTTL is the max TTL for a signature to be valid
 	AuthSignatureTTL = 20 * time.Second
+
+	// AuthSignatureClockSkew is the max time a signature can be ahead of the server clock
+	AuthSignatureClockSkew = 5 * time.Second
 )
 
 // AuthPayload is the authentication payload for checking or creating a signature
 type AuthPayload struct {
-	AuthHash     string `json:"auth_hash"`
-	AuthNonce    string `json:"auth_nonce"`
-	AuthTime     int64  `json:"auth_time"`
-	BodyContents string `json:"body_contents"`
-	Signature    string `json:"signature"`
-	xPub         string
-	accessKey    string
+	AuthHash      string `json:"auth_hash"`
+	AuthNonce     string `json:"auth_nonce"`
+	AuthTime      int64  `json:"auth_time"`
+	AuthVersion   string `json:"auth_version"`
+	BodyContents  string `json:"body_contents"`
+	RequestMethod string `json:"request_method"`
+	RequestURI    string `json:"request_uri"`
+	Signature     string `json:"signature"`
+	xPub          string
+	accessKey     string
+	clientIP      string
 }
 
 // ParamRequestKey for context key
@@ -62,6 +77,18 @@ const (
 
 	// ParamAuthSigned the request parameter that says whether the request was signed
 	ParamAuthSigned ParamRequestKey = "auth_signed"
+
+	// ParamAccessKey the request parameter for the access key (model) the request was authenticated with
+	ParamAccessKey ParamRequestKey = "auth_access_key"
+
+	// ParamAuthriteIdentityKey the request parameter for the identity key of an Authrite (BRC-31) request
+	ParamAuthriteIdentityKey ParamRequestKey = "auth_authrite_identity_key"
+
+	// ParamAuthriteNonce the request parameter for the (client) nonce of an Authrite request
+	ParamAuthriteNonce ParamRequestKey = "auth_authrite_nonce"
+
+	// ParamAuthriteCertificates the request parameter for the certificates (raw JSON) sent with an Authrite request
+	ParamAuthriteCertificates ParamRequestKey = "auth_authrite_certificates"
 )
 
 // createBodyHash will create the hash of the body, removing any carriage returns
@@ -70,7 +97,27 @@ func createBodyHash(bodyContents string) string {
 }
 
 // createSignature will create a signature for the given key & body contents
-func createSignature(xPriv *bip32.ExtendedKey, bodyString string) (payload *AuthPayload, err error) {
+func createSignature(xPriv *bip32.ExtendedKey, bodyString string) (*AuthPayload, error) {
+	return createSignatureWithPayload(new(AuthPayload), xPriv, bodyString)
+}
+
+// createSignatureV2 will create a v2 signature for the given key, request method & URI and body contents
+func createSignatureV2(xPriv *bip32.ExtendedKey, method, requestURI, bodyString string) (*AuthPayload, error) {
+	return createSignatureWithPayload(newAuthPayloadV2(method, requestURI), xPriv, bodyString)
+}
+
+// newAuthPayloadV2 will start a v2 payload for the given request method & URI
+func newAuthPayloadV2(method, requestURI string) *AuthPayload {
+	return &AuthPayload{
+		AuthVersion:   AuthVersion2,
+		RequestMethod: method,
+		RequestURI:    requestURI,
+	}
+}
+
+// createSignatureWithPayload will create a signature for the given key & body contents on the payload
+func createSignatureWithPayload(payload *AuthPayload, xPriv *bip32.ExtendedKey,
+	bodyString string) (_ *AuthPayload, err error) {
 
 	// No key?
 	if xPriv == nil {
@@ -79,7 +126,6 @@ func createSignature(xPriv *bip32.ExtendedKey, bodyString string) (payload *Auth
 	}
 
 	// Get the xPub
-	payload = new(AuthPayload)
 	if payload.xPub, err = bitcoin.GetExtendedPublicKey(
 		xPriv,
 	); err != nil { // Should never error if key is correct
@@ -109,7 +155,18 @@ func createSignature(xPriv *bip32.ExtendedKey, bodyString string) (payload *Auth
 }
 
 // createSignatureAccessKey will create a signature for the given access key & body contents
-func createSignatureAccessKey(privateKeyHex, bodyString string) (payload *AuthPayload, err error) {
+func createSignatureAccessKey(privateKeyHex, bodyString string) (*AuthPayload, error) {
+	return createSignatureAccessKeyWithPayload(new(AuthPayload), privateKeyHex, bodyString)
+}
+
+// createSignatureAccessKeyV2 will create a v2 signature for the given access key, request method & URI and body contents
+func createSignatureAccessKeyV2(privateKeyHex, method, requestURI, bodyString string) (*AuthPayload, error) {
+	return createSignatureAccessKeyWithPayload(newAuthPayloadV2(method, requestURI), privateKeyHex, bodyString)
+}
+
+// createSignatureAccessKeyWithPayload will create a signature for the given access key & body contents on the payload
+func createSignatureAccessKeyWithPayload(payload *AuthPayload, privateKeyHex,
+	bodyString string) (_ *AuthPayload, err error) {
 
 	// No key?
 	if privateKeyHex == "" {
@@ -125,8 +182,7 @@ func createSignatureAccessKey(privateKeyHex, bodyString string) (payload *AuthPa
 	}
 	publicKey := privateKey.PubKey()
 
-	// Get the xPub
-	payload = new(AuthPayload)
+	// Get the access key
 	payload.accessKey = hex.EncodeToString(publicKey.SerialiseCompressed())
 
 	// auth_nonce is a random unique string to seed the signing message
@@ -166,6 +222,30 @@ func createSignatureCommon(payload *AuthPayload, bodyString string, privateKey *
 	return payload, nil
 }
 
+// useAuthNonce will store the nonce of a verified signature until the signature expires,
+// a nonce that was already used (replayed request) is rejected
+func (c *Client) useAuthNonce(ctx context.Context, xPubOrAccessKey string, auth *AuthPayload) error {
+	return c.useNonce(ctx, xPubOrAccessKey, auth.AuthNonce, time.UnixMilli(auth.AuthTime).Add(AuthSignatureTTL))
+}
+
+// useNonce will store the nonce of the key until it expires, a nonce that was already used is rejected
+func (c *Client) useNonce(ctx context.Context, key, nonce string, expiresAt time.Time) error {
+
+	// The nonce only needs to be stored while the signature is valid
+	ttl := int64(math.Ceil(time.Until(expiresAt).Seconds()))
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	// The lock fails if the nonce is already stored (fails closed if the cachestore is unavailable)
+	if _, err := c.Cachestore().WriteLock(
+		ctx, fmt.Sprintf(lockKeyAuthNonce, utils.Hash(key+nonce)), ttl,
+	); err != nil {
+		return ErrAuthNonceReused
+	}
+	return nil
+}
+
 // setOnRequest will set the value on the request with the given key
 func setOnRequest(req *http.Request, keyName ParamRequestKey, value interface{}) *http.Request {
 	return req.WithContext(context.WithValue(req.Context(), keyName, value))
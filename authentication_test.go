@@ -360,6 +360,117 @@ func TestClient_AuthenticateRequest(t *testing.T) {
 		require.NotNil(t, req)
 		assert.Equal(t, true, req.Context().Value(ParamAuthSigned))
 	})
+
+	t.Run("error - replayed request", func(t *testing.T) {
+		key, err := bitcoin.GenerateHDKey(bitcoin.SecureSeedLength)
+		require.NoError(t, err)
+
+		header := http.Header{}
+		err = SetSignature(&header, key, `{}`)
+		require.NoError(t, err)
+
+		_, client, deferMe := CreateTestSQLiteClient(t, false, false)
+		defer deferMe()
+
+		newRequest := func() *http.Request {
+			req, reqErr := http.NewRequestWithContext(context.Background(), http.MethodGet, "", bytes.NewReader([]byte(`{}`)))
+			require.NoError(t, reqErr)
+			req.Header = header.Clone()
+			return req
+		}
+
+		_, err = client.AuthenticateRequest(
+			context.Background(), newRequest(), []string{}, false, true, false,
+		)
+		require.NoError(t, err)
+
+		_, err = client.AuthenticateRequest(
+			context.Background(), newRequest(), []string{}, false, true, false,
+		)
+		assert.ErrorIs(t, err, ErrAuthNonceReused)
+
+		// a replayed request is not signed
+		var req *http.Request
+		req, err = client.AuthenticateRequest(
+			context.Background(), newRequest(), []string{}, false, false, false,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, false, req.Context().Value(ParamAuthSigned))
+	})
+
+	t.Run("xpub - valid v2 signature", func(t *testing.T) {
+		key, err := bitcoin.GenerateHDKey(bitcoin.SecureSeedLength)
+		require.NoError(t, err)
+
+		var req *http.Request
+		req, err = http.NewRequestWithContext(context.Background(), http.MethodPost,
+			"https://bux.test/v1/transaction?id=1", bytes.NewReader([]byte(`{}`)))
+		require.NoError(t, err)
+
+		err = SetSignatureV2(&req.Header, key, http.MethodPost, "/v1/transaction?id=1", `{}`)
+		require.NoError(t, err)
+		assert.Equal(t, AuthVersion2, req.Header.Get(AuthHeaderVersion))
+
+		_, client, deferMe := CreateTestSQLiteClient(t, false, false)
+		defer deferMe()
+
+		req, err = client.AuthenticateRequest(
+			context.Background(), req, []string{}, false, true, false,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, true, req.Context().Value(ParamAuthSigned))
+	})
+
+	t.Run("error - v2 signature for another request", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false)
+		defer deferMe()
+
+		accessKey := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		err := accessKey.Save(ctx)
+		require.NoError(t, err)
+
+		header := http.Header{}
+		err = SetSignatureFromAccessKeyV2(&header, accessKey.Key, http.MethodGet, "/v1/xpub", `{}`)
+		require.NoError(t, err)
+
+		for _, target := range []struct {
+			method string
+			url    string
+		}{
+			{http.MethodDelete, "https://bux.test/v1/xpub"},
+			{http.MethodGet, "https://bux.test/v1/access-key"},
+			{http.MethodGet, "https://bux.test/v1/xpub?metadata=1"},
+		} {
+			var req *http.Request
+			req, err = http.NewRequestWithContext(ctx, target.method, target.url, bytes.NewReader([]byte(`{}`)))
+			require.NoError(t, err)
+			req.Header = header.Clone()
+
+			_, err = client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+			assert.ErrorIs(t, err, ErrSignatureInvalid)
+		}
+	})
+
+	t.Run("error - unsupported version", func(t *testing.T) {
+		key, err := bitcoin.GenerateHDKey(bitcoin.SecureSeedLength)
+		require.NoError(t, err)
+
+		var req *http.Request
+		req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, "", bytes.NewReader([]byte(`{}`)))
+		require.NoError(t, err)
+
+		err = SetSignature(&req.Header, key, `{}`)
+		require.NoError(t, err)
+		req.Header.Set(AuthHeaderVersion, "3")
+
+		_, client, deferMe := CreateTestSQLiteClient(t, false, false)
+		defer deferMe()
+
+		_, err = client.AuthenticateRequest(
+			context.Background(), req, []string{}, false, true, false,
+		)
+		assert.ErrorIs(t, err, ErrUnsupportedAuthVersion)
+	})
 }
 
 // Test_verifyKeyXPub will test the method verifyKeyXPub()
@@ -400,6 +511,17 @@ func Test_verifyKeyXPub(t *testing.T) {
 		assert.ErrorIs(t, err, ErrSignatureExpired)
 	})
 
+	t.Run("error - signature time in the future", func(t *testing.T) {
+		err := checkSignatureRequirements(&AuthPayload{
+			AuthHash:     testSignatureAuthHash,
+			BodyContents: testBodyContents,
+			Signature:    testSignature,
+			AuthTime:     time.Now().Add(AuthSignatureClockSkew + time.Minute).UnixMilli(),
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrSignatureTimeInFuture)
+	})
+
 	t.Run("error - bad xpub", func(t *testing.T) {
 		err := verifyKeyXPub("invalid-key", &AuthPayload{
 			AuthHash:     testSignatureAuthHash,
@@ -591,6 +713,19 @@ func Test_getSigningMessage(t *testing.T) {
 		})
 		assert.Equal(t, fmt.Sprintf("%s%s%s%d", testXpubAuth, testXpubAuthHash, "auth-nonce", 12345678), message)
 	})
+
+	t.Run("v2 format", func(t *testing.T) {
+		message := getSigningMessage(testXpubAuth, &AuthPayload{
+			AuthHash:      testXpubAuthHash,
+			AuthNonce:     "auth-nonce",
+			AuthTime:      12345678,
+			AuthVersion:   AuthVersion2,
+			RequestMethod: "post",
+			RequestURI:    "/v1/transaction?id=1",
+		})
+		assert.Equal(t, fmt.Sprintf("%s%s%s%s%d%s%s", AuthVersion2, testXpubAuth, testXpubAuthHash,
+			"auth-nonce", 12345678, http.MethodPost, "/v1/transaction?id=1"), message)
+	})
 }
 
 // TestGetXpubFromRequest will test the method GetXpubFromRequest()
@@ -620,6 +755,45 @@ func TestGetXpubFromRequest(t *testing.T) {
 	})
 }
 
+// TestClient_getClientIP will test the method getClientIP()
+func TestClient_getClientIP(t *testing.T) {
+	t.Parallel()
+
+	c := &Client{options: &clientOptions{}}
+	WithTrustedProxies("10.0.0.0/8", "192.168.1.1", "invalid")(c.options)
+	require.Len(t, c.options.trustedProxies, 2)
+
+	newRequest := func(remoteAddr, forwarded, realIP string) *http.Request {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = remoteAddr
+		if len(forwarded) > 0 {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		if len(realIP) > 0 {
+			req.Header.Set("X-Real-Ip", realIP)
+		}
+		return req
+	}
+
+	t.Run("headers of an untrusted host are ignored", func(t *testing.T) {
+		assert.Equal(t, "203.0.113.7", c.getClientIP(newRequest("203.0.113.7:52100", "1.2.3.4", "5.6.7.8")))
+	})
+
+	t.Run("forwarded ip of a trusted proxy", func(t *testing.T) {
+		assert.Equal(t, "1.2.3.4", c.getClientIP(newRequest("10.1.2.3:52100", "1.2.3.4", "")))
+		assert.Equal(t, "5.6.7.8", c.getClientIP(newRequest("192.168.1.1:52100", "", "5.6.7.8")))
+	})
+
+	t.Run("spoofed forwarded ips are skipped", func(t *testing.T) {
+		assert.Equal(t, "1.2.3.4", c.getClientIP(newRequest("10.1.2.3:52100", "9.9.9.9, 1.2.3.4, 10.0.0.2", "")))
+	})
+
+	t.Run("no headers", func(t *testing.T) {
+		assert.Equal(t, "10.1.2.3", c.getClientIP(newRequest("10.1.2.3:52100", "", "")))
+	})
+}
+
 // TestIsAdminRequest will test the method IsAdminRequest()
 func TestIsAdminRequest(t *testing.T) {
 	t.Parallel()
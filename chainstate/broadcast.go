@@ -87,6 +87,41 @@ func (c *Client) broadcast(ctx context.Context, id, hex string, timeout time.Dur
 	}
 }
 
+// broadcastWithPolicy will broadcast to the providers of the policy, one at a time and in order,
+// until a provider accepts the transaction (no other provider is used)
+func (c *Client) broadcastWithPolicy(ctx context.Context, id, hex string, policy *BroadcastPolicy,
+	timeout time.Duration,
+) (string, error) {
+	// Index the active providers by name
+	activeProviders := make(map[string]txBroadcastProvider)
+	for _, provider := range createActiveProviders(c, id, hex) {
+		activeProviders[strings.ToLower(provider.getName())] = provider
+	}
+
+	var errorMessages []string
+	for _, name := range policy.providers() {
+		provider, ok := activeProviders[strings.ToLower(name)]
+		if !ok {
+			debugLog(c, id, "broadcast provider is not available: "+name)
+			errorMessages = append(errorMessages, name+": "+ErrUnknownBroadcastProvider.Error())
+			continue
+		}
+
+		ctxWithCancel, cancel := context.WithTimeout(ctx, timeout)
+		err := tryBroadcastToProvider(ctxWithCancel, ctx, provider, id, c, timeout)
+		cancel()
+		if err == nil {
+			debugLog(c, id, fmt.Sprintf("successful broadcast to %s", provider.getName()))
+			return provider.getName(), nil
+		}
+
+		debugLog(c, id, fmt.Sprintf("broadcast error: %s from provider %s", err, provider.getName()))
+		errorMessages = append(errorMessages, provider.getName()+": "+err.Error())
+	}
+
+	return ProviderAll, fmt.Errorf("broadcast failed, errors: %s", strings.Join(errorMessages, ", "))
+}
+
 func createActiveProviders(c *Client, txID, txHex string) []txBroadcastProvider {
 	providers := make([]txBroadcastProvider, 0, 10)
 
@@ -123,25 +158,29 @@ func broadcastToProvider(ctx, fallbackCtx context.Context, provider txBroadcastP
 	c *Client, fallbackTimeout time.Duration,
 	resultsChannel chan broadcastResult, status *broadcastStatus,
 ) {
-	bErr := provider.broadcast(ctx, c)
-
-	if bErr != nil {
-		// check in Mempool as fallback - if transaction is there -> GREAT SUCCESS
-		// Check error response for "questionable errors"/(TX FAILURE)
-		if doesErrorContain(bErr.Error(), broadcastQuestionableErrors) {
-			bErr = checkInMempool(fallbackCtx, c, txID, bErr.Error(), fallbackTimeout)
-		}
-
-		if bErr != nil {
-			resultsChannel <- newErrorResult(bErr, provider.getName())
-		}
+	if bErr := tryBroadcastToProvider(ctx, fallbackCtx, provider, txID, c, fallbackTimeout); bErr != nil {
+		resultsChannel <- newErrorResult(bErr, provider.getName())
+		return
 	}
 
 	// successful broadcast or found in mempool
-	if bErr == nil {
-		status.tryCompleteWithSuccess(provider.getName())
-		resultsChannel <- newSuccessResult(provider.getName())
+	status.tryCompleteWithSuccess(provider.getName())
+	resultsChannel <- newSuccessResult(provider.getName())
+}
+
+// tryBroadcastToProvider will broadcast to the provider, returns nil if broadcast or found in mempool
+func tryBroadcastToProvider(ctx, fallbackCtx context.Context, provider txBroadcastProvider, txID string,
+	c *Client, fallbackTimeout time.Duration,
+) error {
+	bErr := provider.broadcast(ctx, c)
+
+	// check in Mempool as fallback - if transaction is there -> GREAT SUCCESS
+	// Check error response for "questionable errors"/(TX FAILURE)
+	if bErr != nil && doesErrorContain(bErr.Error(), broadcastQuestionableErrors) {
+		bErr = checkInMempool(fallbackCtx, c, txID, bErr.Error(), fallbackTimeout)
 	}
+
+	return bErr
 }
 
 // checkInMempool is a quick check to see if the tx is in mempool (or on-chain)
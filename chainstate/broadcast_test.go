@@ -257,3 +257,79 @@ func containsAtLeastOneElement(coll1 []string, coll2 ...string) bool {
 
 	return false
 }
+
+// TestClient_BroadcastWithPolicy will test the method BroadcastWithPolicy()
+func TestClient_BroadcastWithPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("broadcast - preferred miner only", func(t *testing.T) {
+		// given
+		bc := broadcast_client_mock.Builder().
+			WithMockArc(broadcast_client_mock.MockSuccess).
+			Build()
+		c := NewTestClient(
+			context.Background(), t,
+			WithMinercraft(&minerCraftBroadcastSuccess{}),
+			WithBroadcastClient(bc),
+		)
+
+		// when
+		provider, err := c.BroadcastWithPolicy(
+			context.Background(), broadcastExample1TxID, broadcastExample1TxHex,
+			&BroadcastPolicy{Preferred: minercraft.MinerTaal}, defaultBroadcastTimeOut,
+		)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, minercraft.MinerTaal, provider)
+	})
+
+	t.Run("broadcast - failover", func(t *testing.T) {
+		// given
+		bc := broadcast_client_mock.Builder().
+			WithMockArc(broadcast_client_mock.MockFailure).
+			Build()
+		c := NewTestClient(
+			context.Background(), t,
+			WithMinercraft(&minerCraftBroadcastSuccess{}),
+			WithBroadcastClient(bc),
+		)
+
+		// when
+		provider, err := c.BroadcastWithPolicy(
+			context.Background(), broadcastExample1TxID, broadcastExample1TxHex, &BroadcastPolicy{
+				Preferred: ProviderBroadcastClient,
+				Failover:  []string{"unknown-miner", minercraft.MinerGorillaPool, minercraft.MinerTaal},
+			}, defaultBroadcastTimeOut,
+		)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, minercraft.MinerGorillaPool, provider)
+	})
+
+	t.Run("error - no provider of the policy accepts the tx", func(t *testing.T) {
+		// given
+		bc := broadcast_client_mock.Builder().
+			WithMockArc(broadcast_client_mock.MockFailure).
+			Build()
+		c := NewTestClient(
+			context.Background(), t,
+			WithMinercraft(&minerCraftBroadcastSuccess{}),
+			WithBroadcastClient(bc),
+		)
+
+		// when
+		provider, err := c.BroadcastWithPolicy(
+			context.Background(), broadcastExample1TxID, broadcastExample1TxHex, &BroadcastPolicy{
+				Preferred: ProviderBroadcastClient,
+				Failover:  []string{"unknown-miner"},
+			}, defaultBroadcastTimeOut,
+		)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrUnknownBroadcastProvider.Error())
+		assert.Equal(t, ProviderAll, provider)
+	})
+}
@@ -44,6 +44,31 @@ func (c *Client) Broadcast(ctx context.Context, id, txHex string, timeout time.D
 	return ProviderAll, fmt.Errorf("broadcast failed, errors: %s", errorMessage)
 }
 
+// BroadcastWithPolicy will attempt to broadcast a transaction using only the providers of the policy
+//
+// The preferred provider is tried first, then the failover providers (in order), returns the provider that
+// accepted the transaction. A nil (or empty) policy is the same as Broadcast (all providers, fastest wins).
+func (c *Client) BroadcastWithPolicy(ctx context.Context, id, txHex string, policy *BroadcastPolicy,
+	timeout time.Duration,
+) (string, error) {
+	if policy.isEmpty() {
+		return c.Broadcast(ctx, id, txHex, timeout)
+	}
+
+	// Basic validation
+	if len(id) < 50 {
+		return "", ErrInvalidTransactionID
+	} else if len(txHex) <= 0 { // todo: validate the tx hex
+		return "", ErrInvalidTransactionHex
+	}
+
+	// Debug the id and hex
+	c.DebugLog("tx_id: " + id)
+	c.DebugLog("tx_hex: " + txHex)
+
+	return c.broadcastWithPolicy(ctx, id, txHex, policy, timeout)
+}
+
 // QueryTransaction will get the transaction info from all providers returning the "first" valid result
 //
 // Note: this is slow, but follows a specific order: mAPI -> WhatsOnChain
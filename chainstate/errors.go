@@ -17,6 +17,9 @@ var ErrTransactionNotFound = errors.New("transaction not found using all chain p
 // ErrInvalidRequirements is when an invalid requirement was given
 var ErrInvalidRequirements = errors.New("requirements are invalid or missing")
 
+// ErrUnknownBroadcastProvider is when a provider of the broadcast policy is not an active provider
+var ErrUnknownBroadcastProvider = errors.New("broadcast provider is unknown or not active")
+
 // ErrMissingBroadcastMiners is when broadcasting miners are missing
 var ErrMissingBroadcastMiners = errors.New("missing: broadcasting miners")
 
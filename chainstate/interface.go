@@ -27,6 +27,8 @@ type Logger interface {
 // ChainService is the chain related methods
 type ChainService interface {
 	Broadcast(ctx context.Context, id, txHex string, timeout time.Duration) (string, error)
+	BroadcastWithPolicy(ctx context.Context, id, txHex string, policy *BroadcastPolicy,
+		timeout time.Duration) (string, error)
 	QueryTransaction(
 		ctx context.Context, id string, requiredIn RequiredIn, timeout time.Duration,
 	) (*TransactionInfo, error)
@@ -17,3 +17,25 @@ const PlanariaD TransactionType = "planaria-d"
 
 // RareCandyFrogCartel type
 const RareCandyFrogCartel TransactionType = "rarecandy-frogcartel"
+
+// BroadcastPolicy is the set of providers a transaction is broadcast to (instead of all the active providers)
+//
+// Provider names are the names of the broadcast miners (mAPI) or ProviderBroadcastClient (ARC)
+type BroadcastPolicy struct {
+	Failover  []string `json:"failover,omitempty"`  // Providers to try (in order) if the preferred provider fails
+	Preferred string   `json:"preferred,omitempty"` // Provider to try first
+}
+
+// isEmpty will return true if the policy has no providers
+func (p *BroadcastPolicy) isEmpty() bool {
+	return p == nil || (len(p.Preferred) == 0 && len(p.Failover) == 0)
+}
+
+// providers will return the providers of the policy in order
+func (p *BroadcastPolicy) providers() []string {
+	providers := make([]string, 0, len(p.Failover)+1)
+	if len(p.Preferred) > 0 {
+		providers = append(providers, p.Preferred)
+	}
+	return append(providers, p.Failover...)
+}
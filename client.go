@@ -3,6 +3,7 @@ package bux
 import (
 	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/BuxOrg/bux/chainstate"
@@ -26,23 +27,30 @@ type (
 
 	// clientOptions holds all the configuration for the client
 	clientOptions struct {
-		cacheStore            *cacheStoreOptions          // Configuration options for Cachestore (ristretto, redis, etc.)
-		cluster               *clusterOptions             // Configuration options for the cluster coordinator
-		chainstate            *chainstateOptions          // Configuration options for Chainstate (broadcast, sync, etc.)
-		dataStore             *dataStoreOptions           // Configuration options for the DataStore (MySQL, etc.)
-		debug                 bool                        // If the client is in debug mode
-		encryptionKey         string                      // Encryption key for encrypting sensitive information (IE: paymail xPub) (hex encoded key)
-		httpClient            HTTPInterface               // HTTP interface to use
-		importBlockHeadersURL string                      // The URL of the block headers zip file to import old block headers on startup. if block 0 is found in the DB, block headers will mpt be downloaded
-		itc                   bool                        // (Incoming Transactions Check) True will check incoming transactions via Miners (real-world)
-		iuc                   bool                        // (Input UTXO Check) True will check input utxos when saving transactions
-		logger                zLogger.GormLoggerInterface // Internal logging
-		models                *modelOptions               // Configuration options for the loaded models
-		newRelic              *newRelicOptions            // Configuration options for NewRelic
-		notifications         *notificationsOptions       // Configuration options for Notifications
-		paymail               *paymailOptions             // Paymail options & client
-		taskManager           *taskManagerOptions         // Configuration options for the TaskManager (TaskQ, etc.)
-		userAgent             string                      // User agent for all outgoing requests
+		accessKeyRateLimits    map[RateLimitAction]*RateLimit         // Token buckets of the rate limited actions (per access key)
+		accessKeyUsageThrottle time.Duration                          // Min time between saving the usage of an access key
+		authriteKey            string                                 // Identity (private) key of the server for Authrite (BRC-31) requests
+		cacheStore             *cacheStoreOptions                     // Configuration options for Cachestore (ristretto, redis, etc.)
+		cluster                *clusterOptions                        // Configuration options for the cluster coordinator
+		chainstate             *chainstateOptions                     // Configuration options for Chainstate (broadcast, sync, etc.)
+		dataStore              *dataStoreOptions                      // Configuration options for the DataStore (MySQL, etc.)
+		debug                  bool                                   // If the client is in debug mode
+		draftSigner            DraftSigner                            // Signer for the drafts created by the engine tasks (utxo consolidation, utxo pools)
+		encryptionKey          string                                 // Encryption key for encrypting sensitive information (IE: paymail xPub) (hex encoded key)
+		httpClient             HTTPInterface                          // HTTP interface to use
+		importBlockHeadersURL  string                                 // The URL of the block headers zip file to import old block headers on startup. if block 0 is found in the DB, block headers will mpt be downloaded
+		itc                    bool                                   // (Incoming Transactions Check) True will check incoming transactions via Miners (real-world)
+		iuc                    bool                                   // (Input UTXO Check) True will check input utxos when saving transactions
+		logger                 zLogger.GormLoggerInterface            // Internal logging
+		models                 *modelOptions                          // Configuration options for the loaded models
+		newRelic               *newRelicOptions                       // Configuration options for NewRelic
+		notifications          *notificationsOptions                  // Configuration options for Notifications
+		paymail                *paymailOptions                        // Paymail options & client
+		rateLimits             map[RateLimitAction]*RateLimit         // Token buckets of the rate limited actions (per xPub)
+		taskManager            *taskManagerOptions                    // Configuration options for the TaskManager (TaskQ, etc.)
+		trustedProxies         []*net.IPNet                           // Proxies whose forwarded client IP headers are trusted
+		userAgent              string                                 // User agent for all outgoing requests
+		utxoSelectors          map[UtxoSelectionStrategy]UtxoSelector // Custom utxo selection strategies
 	}
 
 	// chainstateOptions holds the chainstate configuration and client
@@ -104,10 +112,16 @@ type (
 
 	// PaymailServerOptions is the options for the Paymail server
 	PaymailServerOptions struct {
-		*server.Configuration                    // Server configuration if Paymail is enabled
-		options               []server.ConfigOps // Options for the paymail server
-		DefaultFromPaymail    string             // IE: from@domain.com
-		DefaultNote           string             // IE: some note for address resolution
+		*server.Configuration                      // Server configuration if Paymail is enabled
+		options                 []server.ConfigOps // Options for the paymail server
+		DefaultFromPaymail      string             // IE: from@domain.com
+		DefaultNote             string             // IE: some note for address resolution
+		OutputSplit             *P2POutputSplit    // Strategy for splitting the P2P payment destinations (nil for one output)
+		ReferenceExpiresIn      time.Duration      // TTL of the P2P payment references
+		RejectInvalidReferences bool               // Reject P2P transactions that fail the reference verification (else flag them)
+		RejectUnverifiedSenders []string           // Paymail addresses that reject P2P transactions of unverified senders
+		VerifySenders           bool               // Verify the sender (PKI) and the signature of the P2P transactions
+		pike                    bool               // Advertise the PIKE capabilities (see WithPaymailPikeSupport)
 	}
 
 	// taskManagerOptions holds the configuration for taskmanager
@@ -202,6 +216,11 @@ func NewClient(ctx context.Context, opts ...ClientOps) (ClientInterface, error)
 		}
 	}
 
+	// Add the PIKE capabilities to the paymail server config
+	if client.options.paymail.serverConfig.pike {
+		client.loadPikeCapabilities()
+	}
+
 	// Return the client
 	return client, nil
 }
@@ -407,6 +426,18 @@ func (c *Client) IsDebug() bool {
 	return c.options.debug
 }
 
+// GetUtxoSelector will return the selector for the utxo selection strategy (nil for the default strategy)
+//
+// Custom selectors take precedence over the built-in strategies
+func (c *Client) GetUtxoSelector(strategy UtxoSelectionStrategy) (UtxoSelector, error) {
+	if selector, ok := c.options.utxoSelectors[strategy]; ok {
+		return selector, nil
+	} else if selector = getBuiltinUtxoSelector(strategy); selector != nil || strategy == UtxoSelectionDefault {
+		return selector, nil
+	}
+	return nil, ErrUnknownUtxoSelectionStrategy
+}
+
 // IsNewRelicEnabled will return the flag (bool)
 func (c *Client) IsNewRelicEnabled() bool {
 	return c.options.newRelic.enabled
@@ -116,6 +116,15 @@ func getMongoIndexes() map[string][]mongo.IndexModel {
 				Value: bsonx.Int32(1),
 			}}},
 		},
+		"notification_deliveries": {
+			mongo.IndexModel{Keys: bsonx.Doc{{
+				Key:   "status",
+				Value: bsonx.Int32(1),
+			}, {
+				Key:   "next_attempt_at",
+				Value: bsonx.Int32(1),
+			}}},
+		},
 		"transactions": {
 			mongo.IndexModel{Keys: bsonx.Doc{{
 				Key:   "xpub_metadata.x",
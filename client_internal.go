@@ -103,6 +103,22 @@ func (c *Client) loadNotificationClient() (err error) {
 
 	// Load notification if a custom interface was NOT provided
 	if c.options.notifications.ClientInterface == nil {
+
+		// Use the datastore as the outbox for failed deliveries (if the model is loaded)
+		if c.options.modelExists(ModelNotificationDelivery.String(), modelList) {
+			c.options.notifications.options = append(
+				c.options.notifications.options,
+				notifications.WithOutbox(&notificationOutbox{client: c}),
+			)
+		}
+
+		// Notify the subscribed webhooks (if the model is loaded)
+		if c.options.modelExists(ModelWebhookSubscription.String(), modelList) {
+			c.options.notifications.options = append(
+				c.options.notifications.options,
+				notifications.WithSubscriptions(&notificationSubscriptions{client: c}),
+			)
+		}
 		c.options.notifications.ClientInterface, err = notifications.NewClient(c.options.notifications.options...)
 	}
 	return
@@ -248,3 +264,17 @@ func (c *Client) loadDefaultPaymailConfig() (err error) {
 	)
 	return
 }
+
+// loadPikeCapabilities will add the PIKE capabilities to the paymail server configuration
+func (c *Client) loadPikeCapabilities() {
+	config := c.options.paymail.serverConfig.Configuration
+	if config.Capabilities == nil {
+		config.Capabilities = &paymail.CapabilitiesPayload{BsvAlias: paymail.DefaultBsvAliasVersion}
+	}
+	if config.Capabilities.Capabilities == nil {
+		config.Capabilities.Capabilities = make(map[string]interface{})
+	}
+	for key, value := range pikeCapabilities(config) {
+		config.Capabilities.Capabilities[key] = value
+	}
+}
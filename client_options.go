@@ -3,6 +3,7 @@ package bux
 import (
 	"context"
 	"database/sql"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -39,6 +40,9 @@ func defaultClientOptions() *clientOptions {
 	// Set the default options
 	return &clientOptions{
 
+		// Save the usage of an access key at most once per minute
+		accessKeyUsageThrottle: defaultAccessKeyUsageThrottle,
+
 		// Incoming Transaction Checker (lookup external tx via miner for validity)
 		itc: true,
 
@@ -97,8 +101,9 @@ func defaultClientOptions() *clientOptions {
 		paymail: &paymailOptions{
 			client: nil,
 			serverConfig: &PaymailServerOptions{
-				Configuration: nil,
-				options:       []server.ConfigOps{},
+				Configuration:      nil,
+				options:            []server.ConfigOps{},
+				ReferenceExpiresIn: defaultP2PReferenceExpiresIn,
 			},
 		},
 
@@ -109,9 +114,13 @@ func defaultClientOptions() *clientOptions {
 				ModelDestination.String() + "_monitor":                    taskIntervalMonitorCheck,
 				ModelDraftTransaction.String() + "_clean_up":              taskIntervalDraftCleanup,
 				ModelIncomingTransaction.String() + "_process":            taskIntervalProcessIncomingTxs,
+				ModelNotificationDelivery.String() + "_process":           taskIntervalProcessDeliveries,
+				ModelPaymentReference.String() + "_clean_up":              taskIntervalPaymentRefCleanup,
 				ModelSyncTransaction.String() + "_" + syncActionBroadcast: taskIntervalSyncActionBroadcast,
 				ModelSyncTransaction.String() + "_" + syncActionSync:      taskIntervalSyncActionSync,
 				ModelTransaction.String() + "_" + TransactionActionCheck:  taskIntervalTransactionCheck,
+				ModelUtxoConsolidation.String() + "_process":              taskIntervalUtxoConsolidation,
+				ModelUtxoPool.String() + "_process":                       taskIntervalUtxoPoolTopUp,
 			},
 		},
 
@@ -253,6 +262,19 @@ func WithITCDisabled() ClientOps {
 	}
 }
 
+// WithUtxoSelector will add a custom utxo selection strategy (or replace a built-in strategy)
+func WithUtxoSelector(strategy UtxoSelectionStrategy, selector UtxoSelector) ClientOps {
+	return func(c *clientOptions) {
+		if selector == nil {
+			return
+		}
+		if c.utxoSelectors == nil {
+			c.utxoSelectors = make(map[UtxoSelectionStrategy]UtxoSelector)
+		}
+		c.utxoSelectors[strategy] = selector
+	}
+}
+
 // WithIUCDisabled will disable checking the input utxos
 func WithIUCDisabled() ClientOps {
 	return func(c *clientOptions) {
@@ -491,8 +513,10 @@ func WithPaymailSupport(domains []string, defaultFromPaymail, defaultNote string
 			c.paymail.serverConfig.DefaultNote = defaultNote
 		}
 
-		// Add the paymail_address model in bux
+		// Add the paymail_address and payment_reference models in bux
 		c.addModels(migrateList, newPaymail(""))
+		c.addModels(modelList, newPaymentReference("", nil, 0, time.Time{}))
+		c.addModels(migrateList, newPaymentReference("", nil, 0, time.Time{}))
 	}
 }
 
@@ -508,6 +532,65 @@ func WithPaymailBeefSupport(pulseURL, pulseAuthToken string) ClientOps {
 	}
 }
 
+// WithPaymailOutputSplit will split the P2P payment destinations into several outputs (see P2POutputSplit)
+//
+// Every output gets its own destination derived from the xPub of the paymail, all tied to the same reference ID
+func WithPaymailOutputSplit(split *P2POutputSplit) ClientOps {
+	return func(c *clientOptions) {
+		if split != nil {
+			if err := split.validate(); err != nil {
+				panic(err)
+			}
+		}
+		c.paymail.serverConfig.OutputSplit = split
+	}
+}
+
+// WithPaymailReferenceVerification will set how the P2P transactions are verified against their payment reference
+//
+// P2P transactions that underpay, pay the wrong scripts, or use an expired or already used reference are rejected
+// if rejectInvalid is set, else they are recorded and flagged (p2p_reference_flag metadata) without using the reference
+func WithPaymailReferenceVerification(expiresIn time.Duration, rejectInvalid bool) ClientOps {
+	return func(c *clientOptions) {
+		if expiresIn > 0 {
+			c.paymail.serverConfig.ReferenceExpiresIn = expiresIn
+		}
+		c.paymail.serverConfig.RejectInvalidReferences = rejectInvalid
+	}
+}
+
+// WithPaymailSenderVerification will verify the sender of the P2P transactions: the signature of the txid is
+// verified with the key of the sender paymail (PKI), the result is recorded in the sender_verified metadata
+//
+// P2P transactions of unverified senders are rejected for the given paymail addresses, else they are recorded
+func WithPaymailSenderVerification(rejectUnverifiedPaymails ...string) ClientOps {
+	return func(c *clientOptions) {
+		c.paymail.serverConfig.VerifySenders = true
+		for _, paymailAddress := range rejectUnverifiedPaymails {
+			if _, _, address := paymail.SanitizePaymail(paymailAddress); len(address) > 0 {
+				c.paymail.serverConfig.RejectUnverifiedSenders = append(
+					c.paymail.serverConfig.RejectUnverifiedSenders, address,
+				)
+			}
+		}
+	}
+}
+
+// WithPaymailPikeSupport will enable the PIKE contacts (invite/accept contacts and exchange per-contact outputs)
+//
+// The PIKE endpoints are served by NewPikeHandler (mount it on the service URL of the paymail server), payments
+// to a confirmed contact use the outputs issued by the contact. The sender of a PIKE outputs request is not
+// authenticated, the receiving policy of a paymail is checked against the claimed sender
+func WithPaymailPikeSupport() ClientOps {
+	return func(c *clientOptions) {
+		c.paymail.serverConfig.pike = true
+
+		// Add the contact model in bux
+		c.addModels(modelList, newContact("", "", "", "", ContactStatusUnconfirmed))
+		c.addModels(migrateList, newContact("", "", "", "", ContactStatusUnconfirmed))
+	}
+}
+
 // WithPaymailServerConfig will set the custom server configuration for Paymail
 //
 // This will allow overriding the Configuration.actions (paymail service provider)
@@ -523,8 +606,10 @@ func WithPaymailServerConfig(config *server.Configuration, defaultFromPaymail, d
 			c.paymail.serverConfig.DefaultNote = defaultNote
 		}
 
-		// Add the paymail_address model in bux
+		// Add the paymail_address and payment_reference models in bux
 		c.addModels(migrateList, newPaymail(""))
+		c.addModels(modelList, newPaymentReference("", nil, 0, time.Time{}))
+		c.addModels(migrateList, newPaymentReference("", nil, 0, time.Time{}))
 	}
 }
 
@@ -687,14 +772,123 @@ func WithMonitoringInterface(monitor chainstate.MonitorService) ClientOps {
 // -----------------------------------------------------------------
 
 // WithNotifications will set the notifications config
-func WithNotifications(webhookEndpoint string) ClientOps {
+//
+// Payloads are signed with HMAC-SHA256 using the signing secret (if set), see notifications.NewVerifier()
+// Failed deliveries are stored in the notification_delivery model and retried by the task manager
+func WithNotifications(webhookEndpoint, signingSecret string) ClientOps {
 	return func(c *clientOptions) {
 		if len(webhookEndpoint) > 0 {
 			c.notifications.webhookEndpoint = webhookEndpoint
+			c.notifications.options = append(
+				c.notifications.options,
+				notifications.WithNotifications(webhookEndpoint, signingSecret),
+			)
+
+			// Add the notification_delivery model in bux (outbox for retries)
+			c.addModels(modelList, newNotificationDelivery(&notifications.Delivery{}))
+			c.addModels(migrateList, newNotificationDelivery(&notifications.Delivery{}))
+		}
+	}
+}
+
+// WithWebhookSubscriptions will enable the webhook subscriptions (see NewWebhookSubscription())
+//
+// Failed deliveries to the subscribed webhooks are retried like the default webhook (notification_delivery model)
+func WithWebhookSubscriptions() ClientOps {
+	return func(c *clientOptions) {
+		c.addModels(modelList, newWebhookSubscription("", "", "", nil, nil))
+		c.addModels(migrateList, newWebhookSubscription("", "", "", nil, nil))
+		c.addModels(modelList, newNotificationDelivery(&notifications.Delivery{}))
+		c.addModels(migrateList, newNotificationDelivery(&notifications.Delivery{}))
+	}
+}
+
+// WithUtxoConsolidation will load the utxo consolidation model and task (see NewUtxoConsolidation)
+//
+// The consolidation drafts are signed by the draft signer (see WithDraftSigner), without one only dry-runs are possible
+func WithUtxoConsolidation() ClientOps {
+	return func(c *clientOptions) {
+		c.addModels(modelList, newUtxoConsolidation("", nil))
+		c.addModels(migrateList, newUtxoConsolidation("", nil))
+	}
+}
+
+// WithUtxoPools will load the utxo pool model and task (see NewUtxoPool)
+//
+// The split drafts are signed by the draft signer (see WithDraftSigner)
+func WithUtxoPools() ClientOps {
+	return func(c *clientOptions) {
+		c.addModels(modelList, newUtxoPool("", 0, 0))
+		c.addModels(migrateList, newUtxoPool("", 0, 0))
+	}
+}
+
+// WithSpendingPolicies will load the spending policy model (see NewSpendingPolicy)
+func WithSpendingPolicies() ClientOps {
+	return func(c *clientOptions) {
+		c.addModels(modelList, newSpendingPolicy("", nil))
+		c.addModels(migrateList, newSpendingPolicy("", nil))
+	}
+}
+
+// WithAccessKeyUsageThrottle will set the min time between saving the usage (last used, use count, IP) of an access key
+func WithAccessKeyUsageThrottle(throttle time.Duration) ClientOps {
+	return func(c *clientOptions) {
+		if throttle > 0 {
+			c.accessKeyUsageThrottle = throttle
+		}
+	}
+}
+
+// WithTrustedProxies will set the proxies (IPs or CIDRs) whose forwarded client IP headers (X-Forwarded-For, X-Real-Ip)
+// are trusted, the client IP of the requests of other hosts is the remote address
+func WithTrustedProxies(proxies ...string) ClientOps {
+	return func(c *clientOptions) {
+		for _, proxy := range proxies {
+			if !strings.Contains(proxy, "/") {
+				if ip := net.ParseIP(proxy); ip == nil {
+					continue
+				} else if ip.To4() != nil {
+					proxy += "/32"
+				} else {
+					proxy += "/128"
+				}
+			}
+			if _, ipNet, err := net.ParseCIDR(proxy); err == nil {
+				c.trustedProxies = append(c.trustedProxies, ipNet)
+			}
+		}
+	}
+}
+
+// WithAuthrite will enable Authrite (BRC-31) mutual authentication with the identity (private) key of the server
+//
+// See AuthriteInitialResponse for the handshake and SetAuthriteResponseSignature for signing responses
+func WithAuthrite(identityKeyHex string) ClientOps {
+	return func(c *clientOptions) {
+		if len(identityKeyHex) > 0 {
+			c.authriteKey = identityKeyHex
 		}
 	}
 }
 
+// WithDraftSigner will set the signer for the drafts created by the engine tasks (utxo consolidation, utxo pools)
+func WithDraftSigner(signer DraftSigner) ClientOps {
+	return func(c *clientOptions) {
+		c.draftSigner = signer
+	}
+}
+
+// WithNotificationsRetryPolicy will set the max delivery attempts and the exponential backoff for retries
+func WithNotificationsRetryPolicy(maxAttempts uint32, retryDelay, maxRetryDelay time.Duration) ClientOps {
+	return func(c *clientOptions) {
+		c.notifications.options = append(
+			c.notifications.options,
+			notifications.WithRetryPolicy(maxAttempts, retryDelay, maxRetryDelay),
+		)
+	}
+}
+
 // WithCustomNotifications will set a custom notifications interface
 func WithCustomNotifications(customNotifications notifications.ClientInterface) ClientOps {
 	return func(c *clientOptions) {
@@ -754,3 +948,36 @@ func WithBroadcastClientAPIs(apis []broadcastclient.ArcClientConfig) ClientOps {
 		c.chainstate.options = append(c.chainstate.options, chainstate.WithBroadcastClientAPIs(apis))
 	}
 }
+
+// WithRateLimit will rate limit an action (NewTransaction, NewDestination) per xPub
+//
+// Every xPub can make up to burst requests at once and gets one request back every "every",
+// the buckets are stored in the cachestore (use redis for sharing them between the nodes of a cluster)
+func WithRateLimit(action RateLimitAction, burst uint32, every time.Duration) ClientOps {
+	return func(c *clientOptions) {
+		c.rateLimits = setRateLimit(c.rateLimits, action, burst, every)
+	}
+}
+
+// WithAccessKeyRateLimit will rate limit an action per access key (requests made with an access key)
+//
+// Works like WithRateLimit, the requests of the key also count for the xPub of the key
+func WithAccessKeyRateLimit(action RateLimitAction, burst uint32, every time.Duration) ClientOps {
+	return func(c *clientOptions) {
+		c.accessKeyRateLimits = setRateLimit(c.accessKeyRateLimits, action, burst, every)
+	}
+}
+
+// setRateLimit will set the limit of the action (invalid limits are ignored)
+func setRateLimit(limits map[RateLimitAction]*RateLimit, action RateLimitAction, burst uint32,
+	every time.Duration) map[RateLimitAction]*RateLimit {
+
+	if burst == 0 || every <= 0 {
+		return limits
+	}
+	if limits == nil {
+		limits = make(map[RateLimitAction]*RateLimit)
+	}
+	limits[action] = &RateLimit{Burst: burst, Every: every}
+	return limits
+}
@@ -557,7 +557,7 @@ func TestWithModels(t *testing.T) {
 		defer CloseClient(context.Background(), t, tc)
 
 		assert.Equal(t, []string{
-			ModelXPub.String(), ModelAccessKey.String(),
+			ModelXPub.String(), ModelAccessKey.String(), ModelAdmin.String(),
 			ModelDraftTransaction.String(), ModelIncomingTransaction.String(),
 			ModelTransaction.String(), ModelBlockHeader.String(),
 			ModelSyncTransaction.String(), ModelDestination.String(),
@@ -575,7 +575,7 @@ func TestWithModels(t *testing.T) {
 		defer CloseClient(context.Background(), t, tc)
 
 		assert.Equal(t, []string{
-			ModelXPub.String(), ModelAccessKey.String(),
+			ModelXPub.String(), ModelAccessKey.String(), ModelAdmin.String(),
 			ModelDraftTransaction.String(), ModelIncomingTransaction.String(),
 			ModelTransaction.String(), ModelBlockHeader.String(),
 			ModelSyncTransaction.String(), ModelDestination.String(),
@@ -823,6 +823,7 @@ func TestWithAutoMigrate(t *testing.T) {
 		assert.Equal(t, []string{
 			ModelXPub.String(),
 			ModelAccessKey.String(),
+			ModelAdmin.String(),
 			ModelDraftTransaction.String(),
 			ModelIncomingTransaction.String(),
 			ModelTransaction.String(),
@@ -845,6 +846,7 @@ func TestWithAutoMigrate(t *testing.T) {
 		assert.Equal(t, []string{
 			ModelXPub.String(),
 			ModelAccessKey.String(),
+			ModelAdmin.String(),
 			ModelDraftTransaction.String(),
 			ModelIncomingTransaction.String(),
 			ModelTransaction.String(),
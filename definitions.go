@@ -7,7 +7,9 @@ import (
 // Defaults for engine functionality
 const (
 	changeOutputSize               = uint64(35)       // Average size in bytes of a change output
+	defaultConsolidationMaxInputs  = 100              // Default max number of utxos in a consolidation transaction
 	databaseLongReadTimeout        = 30 * time.Second // For all "GET" or "SELECT" methods
+	defaultAccessKeyUsageThrottle  = 1 * time.Minute  // Default throttle for saving the usage of an access key
 	defaultBroadcastTimeout        = 25 * time.Second // Default timeout for broadcasting
 	defaultCacheLockTTL            = 20               // in Seconds
 	defaultCacheLockTTW            = 10               // in Seconds
@@ -18,10 +20,12 @@ const (
 	defaultMonitorSleep            = 2 * time.Second
 	defaultMonitorLockTTL          = 10                // in seconds - should be larger than defaultMonitorSleep
 	defaultOverheadSize            = uint64(8)         // 8 bytes is the default overhead in a transaction = 4 bytes version + 4 bytes nLockTime
+	defaultP2PReferenceExpiresIn   = 24 * time.Hour    // Default TTL for P2P payment references
 	defaultQueryTxTimeout          = 10 * time.Second  // Default timeout for syncing on-chain information
 	defaultSleepForNewBlockHeaders = 30 * time.Second  // Default wait before checking for a new unprocessed block
 	defaultUserAgent               = "bux: " + version // Default user agent
 	dustLimit                      = uint64(1)         // Dust limit
+	maxSplitOutputs                = 1000              // Max number of outputs in a split transaction
 	mongoTestVersion               = "6.0.4"           // Mongo Testing Version
 	sqliteTestVersion              = "3.37.0"          // SQLite Testing Version (dummy version for now)
 	version                        = "v0.6.0"          // bux version
@@ -31,57 +35,85 @@ const (
 const (
 	taskIntervalDraftCleanup        = 60 * time.Second                      // Default task time for cron jobs (seconds)
 	taskIntervalMonitorCheck        = defaultMonitorHeartbeat * time.Second // Default task time for cron jobs (seconds)
+	taskIntervalPaymentRefCleanup   = 60 * time.Second                      // Default task time for cron jobs (seconds)
+	taskIntervalProcessDeliveries   = 30 * time.Second                      // Default task time for cron jobs (seconds)
 	taskIntervalProcessIncomingTxs  = 30 * time.Second                      // Default task time for cron jobs (seconds)
 	taskIntervalSyncActionBroadcast = 30 * time.Second                      // Default task time for cron jobs (seconds)
 	taskIntervalSyncActionSync      = 120 * time.Second                     // Default task time for cron jobs (seconds)
 	taskIntervalTransactionCheck    = 60 * time.Second                      // Default task time for cron jobs (seconds)
+	taskIntervalUtxoConsolidation   = 10 * time.Minute                      // Default task time for cron jobs (minutes)
+	taskIntervalUtxoPoolTopUp       = 60 * time.Second                      // Default task time for cron jobs (seconds)
 )
 
 // All the base models
 const (
-	ModelAccessKey           ModelName = "access_key"
-	ModelBlockHeader         ModelName = "block_header"
-	ModelDestination         ModelName = "destination"
-	ModelDraftTransaction    ModelName = "draft_transaction"
-	ModelIncomingTransaction ModelName = "incoming_transaction"
-	ModelMetadata            ModelName = "metadata"
-	ModelNameEmpty           ModelName = "empty"
-	ModelPaymailAddress      ModelName = "paymail_address"
-	ModelSyncTransaction     ModelName = "sync_transaction"
-	ModelTransaction         ModelName = "transaction"
-	ModelUtxo                ModelName = "utxo"
-	ModelXPub                ModelName = "xpub"
+	ModelAccessKey            ModelName = "access_key"
+	ModelAdmin                ModelName = "admin"
+	ModelBlockHeader          ModelName = "block_header"
+	ModelContact              ModelName = "contact"
+	ModelDestination          ModelName = "destination"
+	ModelDraftTransaction     ModelName = "draft_transaction"
+	ModelIncomingTransaction  ModelName = "incoming_transaction"
+	ModelMetadata             ModelName = "metadata"
+	ModelNameEmpty            ModelName = "empty"
+	ModelNotificationDelivery ModelName = "notification_delivery"
+	ModelPaymailAddress       ModelName = "paymail_address"
+	ModelPaymentReference     ModelName = "payment_reference"
+	ModelSpendingPolicy       ModelName = "spending_policy"
+	ModelSyncTransaction      ModelName = "sync_transaction"
+	ModelTransaction          ModelName = "transaction"
+	ModelUtxo                 ModelName = "utxo"
+	ModelUtxoConsolidation    ModelName = "utxo_consolidation"
+	ModelUtxoPool             ModelName = "utxo_pool"
+	ModelWebhookSubscription  ModelName = "webhook_subscription"
+	ModelXPub                 ModelName = "xpub"
 )
 
 var (
 	// AllModelNames is a list of all models
 	AllModelNames = []ModelName{
 		ModelAccessKey,
+		ModelAdmin,
 		ModelBlockHeader,
+		ModelContact,
 		ModelDestination,
 		ModelIncomingTransaction,
 		ModelMetadata,
+		ModelNotificationDelivery,
 		ModelPaymailAddress,
 		ModelPaymailAddress,
+		ModelPaymentReference,
+		ModelSpendingPolicy,
 		ModelSyncTransaction,
 		ModelTransaction,
 		ModelUtxo,
+		ModelUtxoConsolidation,
+		ModelUtxoPool,
+		ModelWebhookSubscription,
 		ModelXPub,
 	}
 )
 
 // Internal table names
 const (
-	tableAccessKeys           = "access_keys"
-	tableBlockHeaders         = "block_headers"
-	tableDestinations         = "destinations"
-	tableDraftTransactions    = "draft_transactions"
-	tableIncomingTransactions = "incoming_transactions"
-	tablePaymailAddresses     = "paymail_addresses"
-	tableSyncTransactions     = "sync_transactions"
-	tableTransactions         = "transactions"
-	tableUTXOs                = "utxos"
-	tableXPubs                = "xpubs"
+	tableAccessKeys             = "access_keys"
+	tableAdmins                 = "admins"
+	tableBlockHeaders           = "block_headers"
+	tableContacts               = "contacts"
+	tableDestinations           = "destinations"
+	tableDraftTransactions      = "draft_transactions"
+	tableIncomingTransactions   = "incoming_transactions"
+	tableNotificationDeliveries = "notification_deliveries"
+	tablePaymailAddresses       = "paymail_addresses"
+	tablePaymentReferences      = "payment_references"
+	tableSpendingPolicies       = "spending_policies"
+	tableSyncTransactions       = "sync_transactions"
+	tableTransactions           = "transactions"
+	tableUTXOs                  = "utxos"
+	tableUtxoConsolidations     = "utxo_consolidations"
+	tableUtxoPools              = "utxo_pools"
+	tableWebhookSubscriptions   = "webhook_subscriptions"
+	tableXPubs                  = "xpubs"
 )
 
 const (
@@ -90,21 +122,34 @@ const (
 
 	// Internal field names
 	aliasField           = "alias"
+	broadcastAtField     = "broadcast_at"
 	broadcastStatusField = "broadcast_status"
 	createdAtField       = "created_at"
+	lastUsedAtField      = "last_used_at"
+	revokedAtField       = "revoked_at"
 	currentBalanceField  = "current_balance"
+	deletedAtField       = "deleted_at"
 	domainField          = "domain"
 	draftIDField         = "draft_id"
+	expiresAtField       = "expires_at"
 	idField              = "id"
+	lastUsedIPField      = "last_used_ip"
 	metadataField        = "metadata"
 	nextExternalNumField = "next_external_num"
 	nextInternalNumField = "next_internal_num"
+	nextNumField         = "next_num"
+	nextAttemptAtField   = "next_attempt_at"
 	p2pStatusField       = "p2p_status"
 	satoshisField        = "satoshis"
 	spendingTxIDField    = "spending_tx_id"
+	spentSatoshisField   = "spent_satoshis"
 	statusField          = "status"
+	successorIDField     = "successor_id"
 	syncStatusField      = "sync_status"
+	transactionIDField   = "transaction_id"
 	typeField            = "type"
+	updatedAtField       = "updated_at"
+	useCountField        = "use_count"
 	xPubIDField          = "xpub_id"
 	xPubMetadataField    = "xpub_metadata"
 	blockHeightField     = "block_height"
@@ -115,12 +160,14 @@ const (
 	// Universal statuses
 	statusCanceled   = "canceled"
 	statusComplete   = "complete"
+	statusDeadLetter = "dead_letter"
 	statusDraft      = "draft"
 	statusError      = "error"
 	statusExpired    = "expired"
 	statusPending    = "pending"
 	statusProcessing = "processing"
 	statusReady      = "ready"
+	statusScheduled  = "scheduled"
 	statusSkipped    = "skipped"
 
 	// Paymail / Handles
@@ -134,6 +181,8 @@ const (
 	handleMaxLength                 = 25
 	handleRelayPrefix               = "1"
 	p2pMetadataField                = "p2p_tx_metadata"
+	p2pReferenceFlagField           = "p2p_reference_flag"
+	senderVerifiedField             = "sender_verified"
 
 	// Misc
 	gormTypeText = "text"
@@ -147,6 +196,9 @@ const (
 	cacheKeyDestinationModelByAddress       = "destination-address-%s"        // model-address-<address>
 	cacheKeyDestinationModelByLockingScript = "destination-locking-script-%s" // model-locking-script-<script>
 	cacheKeyXpubModel                       = "xpub-id-%s"                    // model-id-<xpub_id>
+	cacheKeyAccessKeyUseCount               = "access-key-use-count-%s"       // uses-<access_key_id>
+	cacheKeyRateLimitAccessKey              = "rate-limit-%s-access-key-%s"   // bucket-<action>-<access_key_id>
+	cacheKeyRateLimitXpub                   = "rate-limit-%s-xpub-%s"         // bucket-<action>-<xpub_id>
 )
 
 var (
@@ -164,6 +216,11 @@ var (
 			Model: *NewBaseModel(ModelAccessKey),
 		},
 
+		// Admin identities (xPubs) with roles
+		&Admin{
+			Model: *NewBaseModel(ModelAdmin),
+		},
+
 		// Draft transactions are created before the final transaction is completed
 		&DraftTransaction{
 			Model: *NewBaseModel(ModelDraftTransaction),
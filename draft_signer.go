@@ -0,0 +1,61 @@
+package bux
+
+import (
+	"context"
+)
+
+// DraftSigner is the interface for signing the drafts created by the engine tasks (utxo consolidation, utxo pools)
+//
+// The signer can use keys held by the server (see NewXPrivDraftSigner) or
+// delegate the signing to an external service (see NewSignerDraftSigner and DraftSignerFunc)
+type DraftSigner interface {
+	// SignDraft will sign the inputs of the draft and return the signed transaction hex
+	SignDraft(ctx context.Context, draft *DraftTransaction) (signedHex string, err error)
+}
+
+// DraftSignerFunc is a function that implements the DraftSigner interface
+type DraftSignerFunc func(ctx context.Context, draft *DraftTransaction) (string, error)
+
+// SignDraft will call the function
+func (f DraftSignerFunc) SignDraft(ctx context.Context, draft *DraftTransaction) (string, error) {
+	return f(ctx, draft)
+}
+
+// NewXPrivDraftSigner will return a signer for the drafts of the given (server-held) xPrivs
+func NewXPrivDraftSigner(rawXPrivs ...string) (DraftSigner, error) {
+	signer, err := NewXPrivSigner(rawXPrivs...)
+	if err != nil {
+		return nil, err
+	}
+	return NewSignerDraftSigner(signer), nil
+}
+
+// NewSignerDraftSigner will return a draft signer that signs the inputs of the drafts with the signer
+// (see SignInputsWithSigner), so the engine tasks can use keys held by an HSM, a KMS or a remote service
+func NewSignerDraftSigner(signer Signer) DraftSigner {
+	return DraftSignerFunc(func(ctx context.Context, draft *DraftTransaction) (string, error) {
+		return draft.SignInputsWithSigner(ctx, signer)
+	})
+}
+
+// signAndRecordDraft will sign the draft with the draft signer of the client and record the transaction
+//
+// The draft is canceled (the utxos are released) if the signer fails
+func (c *Client) signAndRecordDraft(ctx context.Context, rawXpubKey string, draft *DraftTransaction,
+	opts ...ModelOps) (*Transaction, error) {
+
+	if c.options.draftSigner == nil {
+		return nil, ErrMissingDraftSigner
+	}
+
+	signedHex, err := c.options.draftSigner.SignDraft(ctx, draft)
+	if err != nil {
+		draft.Status = DraftStatusCanceled
+		if cancelErr := draft.Save(ctx); cancelErr != nil {
+			c.Logger().Error(ctx, "failed to cancel the draft "+draft.ID+": "+cancelErr.Error())
+		}
+		return nil, err
+	}
+
+	return c.RecordTransaction(ctx, rawXpubKey, signedHex, draft.ID, opts...)
+}
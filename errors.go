@@ -61,6 +61,9 @@ var ErrMissingTransactionOutputs = errors.New("draft transaction configuration h
 // ErrNotEnoughUtxos is when a draft transaction cannot be created because of lack of utxos
 var ErrNotEnoughUtxos = errors.New("could not select enough outputs to satisfy transaction")
 
+// ErrUnknownUtxoSelectionStrategy is when the utxo selection strategy is not built-in or added to the client
+var ErrUnknownUtxoSelectionStrategy = errors.New("unknown utxo selection strategy")
+
 // ErrInvalidLockingScript is when a locking script cannot be decoded
 var ErrInvalidLockingScript = errors.New("invalid locking script")
 
@@ -91,6 +94,24 @@ var ErrMissingUTXOsSpendable = errors.New("no utxos found using spendable")
 // ErrDuplicateUTXOs is when a transaction is created using the same utxo more than once
 var ErrDuplicateUTXOs = errors.New("duplicate utxos found")
 
+// ErrInvalidSequence is when a sequence is set for a utxo that is not an input of the transaction
+var ErrInvalidSequence = errors.New("sequence is set for a utxo that is not an input of the transaction")
+
+// ErrLockTimeMismatch is when the lock time of the transaction does not match the lock time of the draft
+var ErrLockTimeMismatch = errors.New("transaction lock time does not match the draft lock time")
+
+// ErrSequenceMismatch is when the sequence of an input does not match the sequence of the draft
+var ErrSequenceMismatch = errors.New("transaction input sequence does not match the draft sequence")
+
+// ErrScheduledBroadcastP2P is when a transaction with paymail p2p outputs is scheduled to be broadcast later
+var ErrScheduledBroadcastP2P = errors.New("scheduled broadcast is not supported for paymail p2p transactions")
+
+// ErrMinerSelectionP2P is when a transaction with paymail p2p outputs selects the miners to broadcast to
+var ErrMinerSelectionP2P = errors.New("miner selection is not supported for paymail p2p transactions")
+
+// ErrTransactionNotScheduled is when the transaction is not held for a scheduled broadcast (anymore)
+var ErrTransactionNotScheduled = errors.New("transaction is not scheduled for broadcasting")
+
 // ErrPaymailAddressIsInvalid is when the paymail address is NOT alias@domain.com
 var ErrPaymailAddressIsInvalid = errors.New("paymail address is invalid")
 
@@ -151,6 +172,9 @@ var ErrAuthAccessKeyNotFound = errors.New("auth access key could not be found")
 // ErrSignatureExpired is when the signature TTL expired
 var ErrSignatureExpired = errors.New("signature has expired")
 
+// ErrSignatureTimeInFuture is when the signature time is ahead of the server clock (more than the allowed skew)
+var ErrSignatureTimeInFuture = errors.New("signature time is in the future")
+
 // ErrNotAdminKey is when the xpub being used is not considered an admin key
 var ErrNotAdminKey = errors.New("xpub provided is not an admin key")
 
@@ -163,6 +187,45 @@ var ErrMissingAccessKey = errors.New("missing access key")
 // ErrMissingBody is when the body is missing
 var ErrMissingBody = errors.New("missing body")
 
+// ErrAdminPermission is when the admin role does not have the permission for the action
+var ErrAdminPermission = errors.New("admin role does not have the permission")
+
+// ErrInvalidAdminRole is when the admin role is unknown
+var ErrInvalidAdminRole = errors.New("invalid admin role")
+
+// ErrMissingAdmin is when the admin could not be found
+var ErrMissingAdmin = errors.New("admin could not be found")
+
+// ErrMissingSyncTransaction is when the sync transaction could not be found
+var ErrMissingSyncTransaction = errors.New("sync transaction could not be found")
+
+// ErrSyncTransactionNotFailed is when retrying a sync transaction that has no failed (error) status
+var ErrSyncTransactionNotFailed = errors.New("sync transaction has no failed status to retry")
+
+// ErrAccessKeyExpired is when the access key has expired
+var ErrAccessKeyExpired = errors.New("access key has expired")
+
+// ErrAccessKeyRotated is when the access key was already rotated
+var ErrAccessKeyRotated = errors.New("access key has already been rotated")
+
+// ErrAccessKeyScope is when the access key is not allowed the scope of the request
+var ErrAccessKeyScope = errors.New("access key is not allowed this scope")
+
+// ErrAccessKeySpendLimit is when the draft would exceed the spend caps of the access key
+var ErrAccessKeySpendLimit = errors.New("access key spend limit exceeded")
+
+// ErrInvalidAccessKeyScope is when the access key config has an unknown scope
+var ErrInvalidAccessKeyScope = errors.New("invalid access key scope")
+
+// ErrMissingAuthNonce is when the nonce is missing from the signed request
+var ErrMissingAuthNonce = errors.New("auth nonce missing")
+
+// ErrAuthNonceReused is when the nonce of the signed request was already used (replayed request)
+var ErrAuthNonceReused = errors.New("auth nonce has already been used")
+
+// ErrUnsupportedAuthVersion is when the version of the signing message is not supported
+var ErrUnsupportedAuthVersion = errors.New("auth version is not supported")
+
 // ErrSignatureInvalid is when the signature failed to be valid
 var ErrSignatureInvalid = errors.New("signature invalid")
 
@@ -195,3 +258,199 @@ var ErrMissingPaymailXPubID = errors.New("missing xpub_id in paymail")
 
 // ErrMissingClient missing client from model
 var ErrMissingClient = errors.New("client is missing from model, cannot save")
+
+// ErrMissingWebhookSubscription is when the webhook subscription could not be found
+var ErrMissingWebhookSubscription = errors.New("webhook subscription could not be found")
+
+// ErrInvalidWebhookURL is when the webhook url is not an absolute http(s) url
+var ErrInvalidWebhookURL = errors.New("webhook url is invalid")
+
+// ErrUnknownModelType is when the model type of a subscription is not a bux model
+var ErrUnknownModelType = errors.New("unknown model type")
+
+// ErrUnknownEventType is when the event type of a subscription is not a bux event
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// ErrTransactionAlreadyMined is when the transaction is already mined and cannot be bumped
+var ErrTransactionAlreadyMined = errors.New("transaction is already mined")
+
+// ErrParentNotBroadcast is when the transaction to bump was not accepted by the network (the child can not be broadcast)
+var ErrParentNotBroadcast = errors.New("transaction is not broadcast, rebroadcast it before bumping its fee")
+
+// ErrParentFeeSufficient is when the fee of the parent already satisfies the fee unit (nothing to bump)
+var ErrParentFeeSufficient = errors.New("fee of the transaction already satisfies the fee unit")
+
+// ErrMissingUtxoConsolidation is when the utxo consolidation of the xPub cannot be found
+var ErrMissingUtxoConsolidation = errors.New("utxo consolidation could not be found")
+
+// ErrInvalidUtxoConsolidation is when the utxo consolidation config is missing
+var ErrInvalidUtxoConsolidation = errors.New("invalid utxo consolidation, missing config")
+
+// ErrInvalidUtxoThreshold is when the utxo threshold of a consolidation is less than 2
+var ErrInvalidUtxoThreshold = errors.New("utxo threshold must be at least 2")
+
+// ErrInvalidConsolidationWindow is when the hours of the consolidation window are not between 0 and 23
+var ErrInvalidConsolidationWindow = errors.New("consolidation window hours must be between 0 and 23")
+
+// ErrNothingToConsolidate is when the xPub has less than 2 spendable utxos
+var ErrNothingToConsolidate = errors.New("not enough spendable utxos to consolidate")
+
+// ErrMissingDraftSigner is when the engine needs to sign a draft but the client has no draft signer
+var ErrMissingDraftSigner = errors.New("missing draft signer")
+
+// ErrMissingSigningKey is when the draft signer has no key for the xPub of the draft
+var ErrMissingSigningKey = errors.New("missing key for signing the draft")
+
+// ErrInvalidSignResponse is when the signature of the signer is not valid for the sighash, or the key is not the key of the input
+var ErrInvalidSignResponse = errors.New("signer returned an invalid signature or key")
+
+// ErrSignerRequestFailed is when the signing service returned a non-2xx response
+var ErrSignerRequestFailed = errors.New("signer request failed")
+
+// ErrInvalidSplitCount is when the number of outputs of a split transaction is not between 1 and the max
+var ErrInvalidSplitCount = errors.New("invalid number of outputs for a split transaction")
+
+// ErrMissingUtxoPool is when the utxo pool of the xPub cannot be found
+var ErrMissingUtxoPool = errors.New("utxo pool could not be found")
+
+// ErrRateLimited is when an xPub or an access key made too many requests for an action (see RateLimitError)
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// ErrAuthriteDisabled is when an Authrite request is made but the client has no Authrite identity key
+var ErrAuthriteDisabled = errors.New("authrite authentication is not enabled")
+
+// ErrUnsupportedAuthriteVersion is when the Authrite version of the request is not supported
+var ErrUnsupportedAuthriteVersion = errors.New("unsupported authrite version")
+
+// ErrInvalidAuthriteMessage is when the Authrite handshake message is missing fields or has the wrong type
+var ErrInvalidAuthriteMessage = errors.New("invalid authrite message")
+
+// ErrInvalidAuthriteNonce is when the server nonce of an Authrite request was not issued by the server or expired
+var ErrInvalidAuthriteNonce = errors.New("invalid or expired authrite nonce")
+
+// ErrAuthriteIdentityMismatch is when the Authrite identity key is not the key of the xPub of the request
+var ErrAuthriteIdentityMismatch = errors.New("authrite identity key does not match the xpub")
+
+// ErrNotAuthriteRequest is when a response is signed for a request that was not authenticated with Authrite
+var ErrNotAuthriteRequest = errors.New("request was not authenticated with authrite")
+
+// ErrMissingSpendingPolicy is when the spending policy of the xPub cannot be found
+var ErrMissingSpendingPolicy = errors.New("spending policy could not be found")
+
+// ErrInvalidSpendingPolicy is when the spending policy config has an empty address, domain or metadata key
+var ErrInvalidSpendingPolicy = errors.New("invalid spending policy, empty address, domain or metadata key")
+
+// ErrSpendingPolicyMaxPerTransaction is when a transaction spends more than the max per transaction of the policy
+var ErrSpendingPolicyMaxPerTransaction = errors.New("transaction exceeds the max satoshis per transaction of the spending policy")
+
+// ErrSpendingPolicyDailyLimit is when a transaction would exceed the daily limit of the policy
+var ErrSpendingPolicyDailyLimit = errors.New("transaction exceeds the daily limit of the spending policy")
+
+// ErrSpendingPolicyWeeklyLimit is when a transaction would exceed the weekly limit of the policy
+var ErrSpendingPolicyWeeklyLimit = errors.New("transaction exceeds the weekly limit of the spending policy")
+
+// ErrSpendingPolicyDestination is when an output is sent to a destination that is blocked (or not allowed) by the policy
+var ErrSpendingPolicyDestination = errors.New("destination is not allowed by the spending policy")
+
+// ErrSpendingPolicyMetadata is when the draft is missing a metadata key required by the policy
+var ErrSpendingPolicyMetadata = errors.New("missing metadata key required by the spending policy")
+
+// ErrInvalidDraftApprovers is when an approver has no name (or a duplicate name) or an invalid public key,
+// or more approvals are required than there are approvers
+var ErrInvalidDraftApprovers = errors.New("invalid draft approvers")
+
+// ErrDraftApprovalNotRequired is when an approval is added to a draft that does not need approvals
+var ErrDraftApprovalNotRequired = errors.New("draft transaction does not need approvals")
+
+// ErrDraftApprovalClosed is when an approval is added to a draft that is no longer pending approval
+var ErrDraftApprovalClosed = errors.New("draft transaction is no longer pending approval")
+
+// ErrDraftExpired is when the draft transaction has expired
+var ErrDraftExpired = errors.New("draft transaction has expired")
+
+// ErrDraftNotApproved is when a draft that needs approvals is recorded before it is approved
+var ErrDraftNotApproved = errors.New("draft transaction is pending approval")
+
+// ErrDraftRejected is when a draft that was rejected by the approvers is recorded
+var ErrDraftRejected = errors.New("draft transaction was rejected by the approvers")
+
+// ErrUnknownDraftApprover is when the approver is not an approver of the draft
+var ErrUnknownDraftApprover = errors.New("unknown draft approver")
+
+// ErrDuplicateDraftApproval is when the approver already approved (or rejected) the draft
+var ErrDuplicateDraftApproval = errors.New("approver already signed a statement for the draft")
+
+// ErrDraftNotOpen is when the draft transaction is canceled, expired or complete
+var ErrDraftNotOpen = errors.New("draft transaction is not open")
+
+// ErrUnsupportedSigningPackageVersion is when the version of a signing package is not supported
+var ErrUnsupportedSigningPackageVersion = errors.New("unsupported signing package version")
+
+// ErrSigningPackageMismatch is when the signing package does not match the draft
+var ErrSigningPackageMismatch = errors.New("signing package does not match the draft transaction")
+
+// ErrDraftTransactionMismatch is when the signed transaction does not match the draft transaction
+var ErrDraftTransactionMismatch = errors.New("signed transaction does not match the draft transaction")
+
+// ErrInvalidSignedTransaction is when an unlocking script of the signed transaction is not valid
+var ErrInvalidSignedTransaction = errors.New("signed transaction has an invalid unlocking script")
+
+// ErrInvalidOutputSplit is when the P2P output split has an unknown strategy, no count or no (or a zero) denomination
+var ErrInvalidOutputSplit = errors.New("invalid p2p output split")
+
+// ErrUnknownPaymentReference is when the reference of a P2P transaction was not issued by a P2P destination response
+var ErrUnknownPaymentReference = errors.New("unknown payment reference")
+
+// ErrPaymentReferenceExpired is when the P2P transaction is received after the reference expired
+var ErrPaymentReferenceExpired = errors.New("payment reference has expired")
+
+// ErrPaymentReferenceUsed is when the reference was already used by another P2P transaction
+var ErrPaymentReferenceUsed = errors.New("payment reference was already used by another transaction")
+
+// ErrPaymentReferenceScriptMismatch is when the P2P transaction does not pay a destination issued for the reference
+var ErrPaymentReferenceScriptMismatch = errors.New("transaction does not pay the destinations of the payment reference")
+
+// ErrPaymentReferenceUnderpaid is when the P2P transaction pays less than the satoshis issued for a destination
+var ErrPaymentReferenceUnderpaid = errors.New("transaction underpays the payment reference")
+
+// ErrContactNotFound is when the contact (paymail) of the xPub could not be found
+var ErrContactNotFound = errors.New("contact could not be found")
+
+// ErrContactNotConfirmed is when the contact is not confirmed (no chain of the xPub for the contact)
+var ErrContactNotConfirmed = errors.New("contact is not confirmed")
+
+// ErrInvalidContactStatus is when the status of the contact does not allow the change
+var ErrInvalidContactStatus = errors.New("invalid contact status")
+
+// ErrMissingPaymailPKI is when the paymail server of the contact does not support PKI
+var ErrMissingPaymailPKI = errors.New("paymail server does not support pki")
+
+// ErrPikeRequestFailed is when the paymail server of the contact responds to a PIKE request with an error
+var ErrPikeRequestFailed = errors.New("pike request failed")
+
+// ErrInvalidPikeOutputs is when the outputs issued by the contact do not add up to the satoshis of the payment
+var ErrInvalidPikeOutputs = errors.New("pike outputs do not match the satoshis")
+
+// ErrMissingSenderSignature is when the P2P transaction has no sender or no signature of the sender
+var ErrMissingSenderSignature = errors.New("p2p transaction is missing the sender signature")
+
+// ErrSenderPubKeyMismatch is when the pubkey of the P2P transaction is not the key of the sender paymail (PKI)
+var ErrSenderPubKeyMismatch = errors.New("pubkey does not match the pki of the sender paymail")
+
+// ErrInvalidSenderSignature is when the signature of the txid is not valid for the key of the sender paymail
+var ErrInvalidSenderSignature = errors.New("invalid signature of the sender")
+
+// ErrInvalidReceivingPolicy is when the receiving policy has a min above the max or an empty sender domain
+var ErrInvalidReceivingPolicy = errors.New("invalid receiving policy, min above max or empty domain")
+
+// ErrPaymailReceivingPaused is when receiving is paused for the paymail address
+var ErrPaymailReceivingPaused = errors.New("paymail is not receiving payments")
+
+// ErrPaymailAmountBelowMinimum is when the payment is below the min satoshis of the paymail address
+var ErrPaymailAmountBelowMinimum = errors.New("amount is below the minimum of the paymail")
+
+// ErrPaymailAmountAboveMaximum is when the payment is above the max satoshis of the paymail address
+var ErrPaymailAmountAboveMaximum = errors.New("amount is above the maximum of the paymail")
+
+// ErrPaymailSenderNotAllowed is when the sender domain is blocked (or not allowed) by the paymail address
+var ErrPaymailSenderNotAllowed = errors.New("sender is not allowed by the paymail")
@@ -9,6 +9,7 @@ import (
 	"github.com/BuxOrg/bux/cluster"
 	"github.com/BuxOrg/bux/notifications"
 	"github.com/BuxOrg/bux/taskmanager"
+	"github.com/BuxOrg/bux/utils"
 	"github.com/bitcoin-sv/go-paymail"
 	"github.com/libsv/go-bc"
 	"github.com/mrz1836/go-cachestore"
@@ -28,11 +29,26 @@ type AccessKeyService interface {
 	GetAccessKeysByXPubIDCount(ctx context.Context, xPubID string, metadata *Metadata,
 		conditions *map[string]interface{}, opts ...ModelOps) (int64, error)
 	NewAccessKey(ctx context.Context, rawXpubKey string, opts ...ModelOps) (*AccessKey, error)
+	NewScopedAccessKey(ctx context.Context, rawXpubKey string, config *AccessKeyConfig,
+		expiresAt time.Time, opts ...ModelOps) (*AccessKey, error)
+	GetIdleAccessKeys(ctx context.Context, idleFor time.Duration, metadata *Metadata,
+		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*AccessKey, error)
 	RevokeAccessKey(ctx context.Context, rawXpubKey, id string, opts ...ModelOps) (*AccessKey, error)
+	RotateAccessKey(ctx context.Context, rawXpubKey, id string, gracePeriod time.Duration,
+		opts ...ModelOps) (*AccessKey, error)
 }
 
 // AdminService is the bux admin service interface comprised of all services available for admins
+//
+// The context must carry the admin of the request (see AuthenticateRequest, NewAdminContext and
+// NewSystemAdminContext), a context without an admin is denied (ErrAdminPermission). The same applies to the
+// admin actions of the other services: NewPaymailAddress, DeletePaymailAddress, UpdatePaymailAddress,
+// UpdatePaymailAddressMetadata, UpdatePaymailAddressReceivingPolicy, NewSpendingPolicy and DeleteSpendingPolicy
 type AdminService interface {
+	DeleteAdmin(ctx context.Context, xPubID string) error
+	GetAdmin(ctx context.Context, xPubID string) (*Admin, error)
+	GetAdmins(ctx context.Context, metadataConditions *Metadata, conditions *map[string]interface{},
+		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Admin, error)
 	GetStats(ctx context.Context, opts ...ModelOps) (*AdminStats, error)
 	GetPaymailAddresses(ctx context.Context, metadataConditions *Metadata, conditions *map[string]interface{},
 		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*PaymailAddress, error)
@@ -42,6 +58,9 @@ type AdminService interface {
 		conditions *map[string]interface{}, queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Xpub, error)
 	GetXPubsCount(ctx context.Context, metadataConditions *Metadata,
 		conditions *map[string]interface{}, opts ...ModelOps) (int64, error)
+	NewAdmin(ctx context.Context, rawXpubKey string, role AdminRole, opts ...ModelOps) (*Admin, error)
+	NewAdminContext(ctx context.Context, xPubID string) (context.Context, error)
+	RetrySyncTransaction(ctx context.Context, txID string) (*SyncTransaction, error)
 }
 
 // BlockHeaderService is the block header actions
@@ -70,6 +89,19 @@ type ClientService interface {
 	Taskmanager() taskmanager.ClientInterface
 }
 
+// ContactService is the contact (PIKE) actions
+type ContactService interface {
+	AcceptContact(ctx context.Context, xPubID, paymailAddress string) (*Contact, error)
+	ConfirmContact(ctx context.Context, rawXpubKey, paymailAddress string) (*Contact, error)
+	GetContact(ctx context.Context, xPubID, paymailAddress string) (*Contact, error)
+	GetContacts(ctx context.Context, metadata *Metadata, conditions *map[string]interface{},
+		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Contact, error)
+	GetContactsByXPubID(ctx context.Context, xPubID string,
+		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Contact, error)
+	NewContact(ctx context.Context, xPubID, paymailAddress, fullName string, opts ...ModelOps) (*Contact, error)
+	RejectContact(ctx context.Context, xPubID, paymailAddress string) (*Contact, error)
+}
+
 // DestinationService is the destination actions
 type DestinationService interface {
 	GetDestinationByID(ctx context.Context, xPubID, id string) (*Destination, error)
@@ -96,10 +128,17 @@ type DestinationService interface {
 
 // DraftTransactionService is the draft transactions actions
 type DraftTransactionService interface {
+	ApproveDraftTransaction(ctx context.Context, draftID, approver, signature string,
+		opts ...ModelOps) (*DraftTransaction, error)
+	ExportSigningPackage(ctx context.Context, rawXpubKey, draftID string, opts ...ModelOps) (*SigningPackage, error)
 	GetDraftTransactions(ctx context.Context, metadata *Metadata, conditions *map[string]interface{},
 		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*DraftTransaction, error)
 	GetDraftTransactionsCount(ctx context.Context, metadata *Metadata,
 		conditions *map[string]interface{}, opts ...ModelOps) (int64, error)
+	ImportSigningPackage(ctx context.Context, rawXpubKey string, signingPackage *SigningPackage,
+		opts ...ModelOps) (*Transaction, error)
+	RejectDraftTransaction(ctx context.Context, draftID, approver, signature string,
+		opts ...ModelOps) (*DraftTransaction, error)
 }
 
 // HTTPInterface is the HTTP client interface
@@ -127,10 +166,21 @@ type PaymailService interface {
 		avatar string, opts ...ModelOps) (*PaymailAddress, error)
 	UpdatePaymailAddressMetadata(ctx context.Context, address string,
 		metadata Metadata, opts ...ModelOps) (*PaymailAddress, error)
+	UpdatePaymailAddressReceivingPolicy(ctx context.Context, address string,
+		policy *PaymailReceivingPolicy, opts ...ModelOps) (*PaymailAddress, error)
+}
+
+// SpendingPolicyService is the spending policy actions
+type SpendingPolicyService interface {
+	DeleteSpendingPolicy(ctx context.Context, xPubID string) error
+	GetSpendingPolicy(ctx context.Context, xPubID string) (*SpendingPolicy, error)
+	NewSpendingPolicy(ctx context.Context, xPubID string, config *SpendingPolicyConfig,
+		opts ...ModelOps) (*SpendingPolicy, error)
 }
 
 // TransactionService is the transaction actions
 type TransactionService interface {
+	CancelScheduledTransaction(ctx context.Context, id string) error
 	GetTransaction(ctx context.Context, xPubID, txID string) (*Transaction, error)
 	GetTransactionByID(ctx context.Context, txID string) (*Transaction, error)
 	GetTransactionByHex(ctx context.Context, hex string) (*Transaction, error)
@@ -142,6 +192,10 @@ type TransactionService interface {
 		queryParams *datastore.QueryParams) ([]*Transaction, error)
 	GetTransactionsByXpubIDCount(ctx context.Context, xPubID string, metadata *Metadata,
 		conditions *map[string]interface{}) (int64, error)
+	NewChildPaysForParentTransaction(ctx context.Context, rawXpubKey, txID string, feeUnit *utils.FeeUnit,
+		opts ...ModelOps) (*DraftTransaction, error)
+	NewSplitTransaction(ctx context.Context, rawXpubKey string, count int, satoshisEach uint64,
+		opts ...ModelOps) (*DraftTransaction, error)
 	NewTransaction(ctx context.Context, rawXpubKey string, config *TransactionConfig,
 		opts ...ModelOps) (*DraftTransaction, error)
 	RecordTransaction(ctx context.Context, xPubKey, txHex, draftID string,
@@ -165,6 +219,36 @@ type UTXOService interface {
 	UnReserveUtxos(ctx context.Context, xPubID, draftID string) error
 }
 
+// UtxoConsolidationService is the utxo consolidation actions
+type UtxoConsolidationService interface {
+	ConsolidateUtxos(ctx context.Context, xPubID string, dryRun bool, opts ...ModelOps) (*TransactionConfig, error)
+	DeleteUtxoConsolidation(ctx context.Context, xPubID string) error
+	GetUtxoConsolidation(ctx context.Context, xPubID string) (*UtxoConsolidation, error)
+	NewUtxoConsolidation(ctx context.Context, rawXpubKey string, config *UtxoConsolidationConfig,
+		opts ...ModelOps) (*UtxoConsolidation, error)
+}
+
+// UtxoPoolService is the utxo pool actions
+type UtxoPoolService interface {
+	DeleteUtxoPool(ctx context.Context, xPubID string) error
+	GetUtxoPool(ctx context.Context, xPubID string) (*UtxoPool, error)
+	NewUtxoPool(ctx context.Context, rawXpubKey string, targetSize uint32, satoshis uint64,
+		opts ...ModelOps) (*UtxoPool, error)
+	TopUpUtxoPool(ctx context.Context, xPubID string, opts ...ModelOps) (*Transaction, error)
+}
+
+// WebhookSubscriptionService is the webhook subscription actions
+type WebhookSubscriptionService interface {
+	DeleteWebhookSubscription(ctx context.Context, xPubID, id string) error
+	GetWebhookSubscription(ctx context.Context, xPubID, id string) (*WebhookSubscription, error)
+	GetWebhookSubscriptions(ctx context.Context, metadata *Metadata, conditions *map[string]interface{},
+		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*WebhookSubscription, error)
+	GetWebhookSubscriptionsByXPubID(ctx context.Context, xPubID string,
+		queryParams *datastore.QueryParams, opts ...ModelOps) ([]*WebhookSubscription, error)
+	NewWebhookSubscription(ctx context.Context, xPubID, webhookURL, signingSecret string,
+		modelTypes []ModelName, eventTypes []notifications.EventType, opts ...ModelOps) (*WebhookSubscription, error)
+}
+
 // XPubService is the xPub actions
 type XPubService interface {
 	GetXpub(ctx context.Context, xPubKey string) (*Xpub, error)
@@ -179,21 +263,28 @@ type ClientInterface interface {
 	AdminService
 	BlockHeaderService
 	ClientService
+	ContactService
 	DestinationService
 	DraftTransactionService
 	ModelService
 	PaymailService
+	SpendingPolicyService
 	TransactionService
 	UTXOService
+	UtxoConsolidationService
+	UtxoPoolService
+	WebhookSubscriptionService
 	XPubService
 	AuthenticateRequest(ctx context.Context, req *http.Request, adminXPubs []string,
 		adminRequired, requireSigning, signingDisabled bool) (*http.Request, error)
+	AuthriteInitialResponse(request *AuthriteInitialRequest) (*AuthriteInitialResponse, error)
 	Close(ctx context.Context) error
 	Debug(on bool)
 	DefaultSyncConfig() *SyncConfig
 	EnableNewRelic()
 	GetOrStartTxn(ctx context.Context, name string) context.Context
 	GetTaskPeriod(name string) time.Duration
+	GetUtxoSelector(strategy UtxoSelectionStrategy) (UtxoSelector, error)
 	ImportBlockHeadersFromURL() string
 	IsDebug() bool
 	IsEncryptionKeySet() bool
@@ -202,6 +293,7 @@ type ClientInterface interface {
 	IsMigrationEnabled() bool
 	IsNewRelicEnabled() bool
 	ModifyTaskPeriod(name string, period time.Duration) error
+	SetAuthriteResponseSignature(req *http.Request, header *http.Header, bodyString string) error
 	SetNotificationsClient(notifications.ClientInterface)
 	UserAgent() string
 	Version() string
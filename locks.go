@@ -7,15 +7,25 @@ import (
 )
 
 const (
-	lockKeyMonitorLockID      = "monitor-lock-id-%s"               // + Lock ID
-	lockKeyProcessBroadcastTx = "process-broadcast-transaction-%s" // + Tx ID
-	lockKeyProcessIncomingTx  = "process-incoming-transaction-%s"  // + Tx ID
-	lockKeyProcessP2PTx       = "process-p2p-transaction-%s"       // + Tx ID
-	lockKeyProcessSyncTx      = "process-sync-transaction-task"
-	lockKeyProcessXpub        = "action-xpub-id-%s"             // + Xpub ID
-	lockKeyRecordBlockHeader  = "action-record-block-header-%s" // + Hash id
-	lockKeyRecordTx           = "action-record-transaction-%s"  // + Tx ID
-	lockKeyReserveUtxo        = "utxo-reserve-xpub-id-%s"       // + Xpub ID
+	lockKeyAccessKeySpend       = "access-key-spend-%s"              // + Access Key ID
+	lockKeyAccessKeyUsage       = "access-key-usage-%s"              // + Access Key ID
+	lockKeyAccessKeyPendingUses = "access-key-pending-uses-%s"       // + Access Key ID
+	lockKeyAuthNonce            = "auth-nonce-%s"                    // + Hash of xPub/Access Key + nonce
+	lockKeyContact              = "contact-%s"                       // + Contact ID
+	lockKeyDraftApproval        = "draft-approval-%s"                // + Draft ID
+	lockKeyMonitorLockID        = "monitor-lock-id-%s"               // + Lock ID
+	lockKeyPaymentReference     = "payment-reference-%s"             // + Reference ID
+	lockKeyProcessBroadcastTx   = "process-broadcast-transaction-%s" // + Tx ID
+	lockKeyProcessIncomingTx    = "process-incoming-transaction-%s"  // + Tx ID
+	lockKeyProcessNotification  = "process-notification-delivery-%s" // + Delivery ID
+	lockKeyProcessP2PTx         = "process-p2p-transaction-%s"       // + Tx ID
+	lockKeyProcessSyncTx        = "process-sync-transaction-task"
+	lockKeyProcessXpub          = "action-xpub-id-%s"             // + Xpub ID
+	lockKeyRateLimit            = "lock-%s"                       // + Rate limit cache key
+	lockKeyRecordBlockHeader    = "action-record-block-header-%s" // + Hash id
+	lockKeyRecordTx             = "action-record-transaction-%s"  // + Tx ID
+	lockKeyReserveUtxo          = "utxo-reserve-xpub-id-%s"       // + Xpub ID
+	lockKeySpendingPolicy       = "spending-policy-xpub-id-%s"    // + Xpub ID
 )
 
 // newWriteLock will take care of creating a lock and defer
@@ -19,6 +19,12 @@ func (c *chainStateBase) Broadcast(context.Context, string, string, time.Duratio
 	return "", nil
 }
 
+func (c *chainStateBase) BroadcastWithPolicy(context.Context, string, string, *chainstate.BroadcastPolicy,
+	time.Duration,
+) (string, error) {
+	return "", nil
+}
+
 func (c *chainStateBase) QueryTransaction(context.Context, string,
 	chainstate.RequiredIn, time.Duration,
 ) (*chainstate.TransactionInfo, error) {
@@ -168,3 +174,16 @@ func (c *chainStateEverythingOnChain) FeeUnit() *utils.FeeUnit {
 func (c *chainStateEverythingOnChain) VerifyMerkleRoots(_ context.Context, _ []chainstate.MerkleRootConfirmationRequestItem) error {
 	return nil
 }
+
+// chainStateBroadcastPolicy accepts the transaction with the preferred provider of the policy
+type chainStateBroadcastPolicy struct {
+	chainStateEverythingOnChain
+	policy *chainstate.BroadcastPolicy
+}
+
+func (c *chainStateBroadcastPolicy) BroadcastWithPolicy(_ context.Context, _, _ string,
+	policy *chainstate.BroadcastPolicy, _ time.Duration,
+) (string, error) {
+	c.policy = policy
+	return policy.Preferred, nil
+}
@@ -1,10 +1,16 @@
 package bux
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/BuxOrg/bux/utils"
 	"github.com/bitcoinschema/go-bitcoin/v2"
@@ -25,14 +31,50 @@ type AccessKey struct {
 	Model `bson:",inline"`
 
 	// Model specific fields
-	ID        string               `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the unique access key id" bson:"_id"`
-	XpubID    string               `json:"xpub_id" toml:"xpub_id" yaml:"hash" gorm:"<-:create;type:char(64);index;comment:This is the related xPub id" bson:"xpub_id"`
-	RevokedAt customTypes.NullTime `json:"revoked_at" toml:"revoked_at" yaml:"revoked_at" gorm:"<-;comment:When the key was revoked" bson:"revoked_at,omitempty"`
+	ID            string               `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the unique access key id" bson:"_id"`
+	XpubID        string               `json:"xpub_id" toml:"xpub_id" yaml:"hash" gorm:"<-:create;type:char(64);index;comment:This is the related xPub id" bson:"xpub_id"`
+	RevokedAt     customTypes.NullTime `json:"revoked_at" toml:"revoked_at" yaml:"revoked_at" gorm:"<-;comment:When the key was revoked" bson:"revoked_at,omitempty"`
+	ExpiresAt     customTypes.NullTime `json:"expires_at" toml:"expires_at" yaml:"expires_at" gorm:"<-;comment:When the key expires" bson:"expires_at,omitempty"`
+	Config        AccessKeyConfig      `json:"config" toml:"config" yaml:"config" gorm:"<-;type:text;comment:This is the access key config (scopes, spend caps) in JSON" bson:"config"`
+	SpentSatoshis uint64               `json:"spent_satoshis" toml:"spent_satoshis" yaml:"spent_satoshis" gorm:"<-;comment:This is the satoshis spent by drafts created with the key" bson:"spent_satoshis"`
+	LastUsedAt    customTypes.NullTime `json:"last_used_at" toml:"last_used_at" yaml:"last_used_at" gorm:"<-;comment:When the key was last used (throttled)" bson:"last_used_at,omitempty"`
+	LastUsedIP    string               `json:"last_used_ip" toml:"last_used_ip" yaml:"last_used_ip" gorm:"<-;type:varchar(64);comment:This is the client IP of the last use" bson:"last_used_ip,omitempty"`
+	UseCount      uint64               `json:"use_count" toml:"use_count" yaml:"use_count" gorm:"<-;comment:This is the number of authenticated requests with the key" bson:"use_count"`
+	SuccessorID   string               `json:"successor_id" toml:"successor_id" yaml:"successor_id" gorm:"<-;type:char(64);comment:This is the key that replaced this key (rotation)" bson:"successor_id,omitempty"`
 
 	// Private fields
 	Key string `json:"key" gorm:"-" bson:"-"` // Used on "CREATE", shown to the user "once" only
 }
 
+// AccessKeyScope is a permission of an access key
+type AccessKeyScope string
+
+const (
+	// AccessKeyScopeReadOnly allows reading the xPub data (every scoped key can read)
+	AccessKeyScopeReadOnly AccessKeyScope = "read-only"
+
+	// AccessKeyScopeCreateDestination allows creating new destinations
+	AccessKeyScopeCreateDestination AccessKeyScope = "create-destination"
+
+	// AccessKeyScopeCreateTransaction allows creating draft transactions
+	AccessKeyScopeCreateTransaction AccessKeyScope = "create-transaction"
+
+	// AccessKeyScopeRecordTransaction allows recording transactions
+	AccessKeyScopeRecordTransaction AccessKeyScope = "record-transaction"
+
+	// AccessKeyScopeAdminRead allows the admin read requests (the key must belong to an admin xPub)
+	AccessKeyScopeAdminRead AccessKeyScope = "admin-read"
+)
+
+// AccessKeyConfig is the configuration (restrictions) of an access key
+//
+// A key without scopes has full xPub authority (except admin requests), like before scopes existed
+type AccessKeyConfig struct {
+	MaxSatoshis               uint64           `json:"max_satoshis,omitempty" toml:"max_satoshis" yaml:"max_satoshis"`                                                 // Max satoshis spent by all the drafts created with the key (0 is no cap)
+	MaxSatoshisPerTransaction uint64           `json:"max_satoshis_per_transaction,omitempty" toml:"max_satoshis_per_transaction" yaml:"max_satoshis_per_transaction"` // Max satoshis spent by one draft (0 is no cap)
+	Scopes                    []AccessKeyScope `json:"scopes,omitempty" toml:"scopes" yaml:"scopes"`                                                                   // Scopes of the key (empty is all scopes)
+}
+
 // newAccessKey will start a new model
 func newAccessKey(xPubID string, opts ...ModelOps) *AccessKey {
 
@@ -156,6 +198,166 @@ func getAccessKeysByXPubIDCount(ctx context.Context, xPubID string, metadata *Me
 	return count, nil
 }
 
+// validate will check the scopes of the config
+func (c *AccessKeyConfig) validate() error {
+	for _, scope := range c.Scopes {
+		switch scope {
+		case AccessKeyScopeReadOnly, AccessKeyScopeCreateDestination, AccessKeyScopeCreateTransaction,
+			AccessKeyScopeRecordTransaction, AccessKeyScopeAdminRead:
+		default:
+			return ErrInvalidAccessKeyScope
+		}
+	}
+	return nil
+}
+
+// Scan will scan the value into Struct, implements sql.Scanner interface
+func (c *AccessKeyConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	xType := fmt.Sprintf("%T", value)
+	var byteValue []byte
+	if xType == ValueTypeString {
+		byteValue = []byte(value.(string))
+	} else {
+		byteValue = value.([]byte)
+	}
+	if bytes.Equal(byteValue, []byte("")) || bytes.Equal(byteValue, []byte("\"\"")) {
+		return nil
+	}
+
+	return json.Unmarshal(byteValue, &c)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (c AccessKeyConfig) Value() (driver.Value, error) {
+	marshal, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(marshal), nil
+}
+
+// HasScope will return true if the key is allowed the scope
+func (m *AccessKey) HasScope(scope AccessKeyScope) bool {
+
+	// Keys without scopes are full access and all keys can read, admin read is always explicit
+	if scope != AccessKeyScopeAdminRead && (len(m.Config.Scopes) == 0 || scope == AccessKeyScopeReadOnly) {
+		return true
+	}
+	for _, keyScope := range m.Config.Scopes {
+		if keyScope == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRevoked will return true if the key is revoked (a rotated key is revoked after the grace period)
+func (m *AccessKey) IsRevoked() bool {
+	return m.RevokedAt.Valid && !time.Now().UTC().Before(m.RevokedAt.Time)
+}
+
+// IsExpired will return true if the key has an expiry time that has passed
+func (m *AccessKey) IsExpired() bool {
+	return m.ExpiresAt.Valid && !time.Now().UTC().Before(m.ExpiresAt.Time)
+}
+
+// checkSpend will check the satoshis against the spend caps of the key
+func (m *AccessKey) checkSpend(satoshis uint64) error {
+	if m.Config.MaxSatoshisPerTransaction > 0 && satoshis > m.Config.MaxSatoshisPerTransaction {
+		return ErrAccessKeySpendLimit
+	} else if m.Config.MaxSatoshis > 0 && m.SpentSatoshis+satoshis > m.Config.MaxSatoshis {
+		return ErrAccessKeySpendLimit
+	}
+	return nil
+}
+
+// recordUsage will record the use of the key, the usage is only written once per throttle window
+//
+// The uses in between are counted in the cachestore and added to the use count on the next write
+func (m *AccessKey) recordUsage(ctx context.Context, clientIP string, throttle time.Duration) error {
+	c := m.Client()
+
+	// The lock (never released) only lets one write through per throttle window
+	ttl := int64(throttle.Seconds())
+	if ttl < 1 {
+		ttl = 1
+	}
+	if _, err := c.Cachestore().WriteLock(ctx, fmt.Sprintf(lockKeyAccessKeyUsage, m.ID), ttl); err != nil {
+		_, err = m.countPendingUses(ctx, false)
+		return err
+	}
+
+	// Take the uses that are not written yet
+	pending, err := m.countPendingUses(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	// Only the usage fields are written, the spent satoshis are counted by the drafts (see addAccessKeySpend)
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyAccessKeySpend, m.ID), c.Cachestore())
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	var useCount int64
+	if useCount, err = incrementField(ctx, m, useCountField, int64(pending+1)); err != nil {
+		return err
+	}
+
+	lastUsedAt := customTypes.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+	if err = updateFields(ctx, m, map[string]interface{}{
+		lastUsedAtField: &lastUsedAt,
+		lastUsedIPField: clientIP,
+	}); err != nil {
+		return err
+	}
+
+	m.UseCount = uint64(useCount)
+	m.LastUsedAt = lastUsedAt
+	m.LastUsedIP = clientIP
+	return nil
+}
+
+// countPendingUses will count a use of the key that is not written yet, or take (and reset) the uses that are not written yet
+func (m *AccessKey) countPendingUses(ctx context.Context, take bool) (uint64, error) {
+	c := m.Client()
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyAccessKeyPendingUses, m.ID), c.Cachestore())
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	countKey := fmt.Sprintf(cacheKeyAccessKeyUseCount, m.ID)
+	var pending uint64
+	if value, getErr := c.Cachestore().Get(ctx, countKey); getErr == nil && len(value) > 0 {
+		pending, _ = strconv.ParseUint(value, 10, 64)
+	}
+	if take {
+		return pending, c.Cachestore().Delete(ctx, countKey)
+	}
+	return pending + 1, c.Cachestore().Set(ctx, countKey, strconv.FormatUint(pending+1, 10))
+}
+
+// accessKeyFromContext will get the access key the request was authenticated with (see AuthenticateRequest)
+func accessKeyFromContext(ctx context.Context) *AccessKey {
+	accessKey, _ := ctx.Value(ParamAccessKey).(*AccessKey)
+	return accessKey
+}
+
+// checkAccessKeyScope will check the scope of the access key in the context (if any)
+func checkAccessKeyScope(ctx context.Context, scope AccessKeyScope) error {
+	if accessKey := accessKeyFromContext(ctx); accessKey != nil && !accessKey.HasScope(scope) {
+		return ErrAccessKeyScope
+	}
+	return nil
+}
+
 // GetModelName will get the name of the current model
 func (m *AccessKey) GetModelName() string {
 	return ModelAccessKey.String()
@@ -185,6 +387,10 @@ func (m *AccessKey) BeforeCreating(_ context.Context) error {
 		return ErrMissingFieldID
 	}
 
+	if err := m.Config.validate(); err != nil {
+		return err
+	}
+
 	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
 	return nil
 }
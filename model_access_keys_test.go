@@ -1,7 +1,11 @@
 package bux
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -171,3 +175,419 @@ func TestAccessKey_GetAccessKeys(t *testing.T) {
 		assert.Equal(t, "", accessKeys[0].Key)
 	})
 }
+
+func TestAccessKey_HasScope(t *testing.T) {
+	t.Run("no scopes", func(t *testing.T) {
+		key := newAccessKey(testXPubID)
+		assert.True(t, key.HasScope(AccessKeyScopeReadOnly))
+		assert.True(t, key.HasScope(AccessKeyScopeCreateTransaction))
+		assert.False(t, key.HasScope(AccessKeyScopeAdminRead))
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		key := newAccessKey(testXPubID)
+		key.Config.Scopes = []AccessKeyScope{AccessKeyScopeCreateDestination, AccessKeyScopeAdminRead}
+		assert.True(t, key.HasScope(AccessKeyScopeReadOnly))
+		assert.True(t, key.HasScope(AccessKeyScopeCreateDestination))
+		assert.True(t, key.HasScope(AccessKeyScopeAdminRead))
+		assert.False(t, key.HasScope(AccessKeyScopeCreateTransaction))
+		assert.False(t, key.HasScope(AccessKeyScopeRecordTransaction))
+	})
+}
+
+func TestAccessKey_checkSpend(t *testing.T) {
+	key := newAccessKey(testXPubID)
+	assert.NoError(t, key.checkSpend(1000000))
+
+	key.Config.MaxSatoshisPerTransaction = 1000
+	key.Config.MaxSatoshis = 1500
+	assert.NoError(t, key.checkSpend(1000))
+	assert.ErrorIs(t, key.checkSpend(1001), ErrAccessKeySpendLimit)
+
+	key.SpentSatoshis = 1000
+	assert.NoError(t, key.checkSpend(500))
+	assert.ErrorIs(t, key.checkSpend(501), ErrAccessKeySpendLimit)
+}
+
+func TestClient_NewScopedAccessKey(t *testing.T) {
+	t.Run("invalid scope", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		_, err := client.NewScopedAccessKey(ctx, testXPub, &AccessKeyConfig{
+			Scopes: []AccessKeyScope{"write-everything"},
+		}, time.Time{})
+		assert.ErrorIs(t, err, ErrInvalidAccessKeyScope)
+	})
+
+	t.Run("expiry and config are saved", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		expiresAt := time.Now().Add(time.Hour)
+		key, err := client.NewScopedAccessKey(ctx, testXPub, &AccessKeyConfig{
+			MaxSatoshis: 5000,
+			Scopes:      []AccessKeyScope{AccessKeyScopeCreateTransaction},
+		}, expiresAt)
+		require.NoError(t, err)
+
+		key, err = client.GetAccessKey(ctx, testXPubID, key.ID)
+		require.NoError(t, err)
+		assert.False(t, key.IsExpired())
+		assert.WithinDuration(t, expiresAt, key.ExpiresAt.Time, time.Second)
+		assert.Equal(t, uint64(5000), key.Config.MaxSatoshis)
+		assert.Equal(t, []AccessKeyScope{AccessKeyScopeCreateTransaction}, key.Config.Scopes)
+	})
+
+	t.Run("scopes and spend caps of a draft", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		key, err := client.NewScopedAccessKey(ctx, testXPub, &AccessKeyConfig{
+			MaxSatoshisPerTransaction: 25000,
+			Scopes:                    []AccessKeyScope{AccessKeyScopeCreateTransaction},
+		}, time.Time{})
+		require.NoError(t, err)
+
+		// the context of an authenticated request (see AuthenticateRequest)
+		keyCtx := context.WithValue(ctx, ParamAccessKey, key)
+
+		_, err = client.NewDestination(keyCtx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+		assert.ErrorIs(t, err, ErrAccessKeyScope)
+
+		// over the cap, the utxo is released
+		config := &TransactionConfig{Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 30000}}}
+		_, err = client.NewTransaction(keyCtx, testXPub, config)
+		assert.ErrorIs(t, err, ErrAccessKeySpendLimit)
+
+		config = &TransactionConfig{Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 20000}}}
+		var draft *DraftTransaction
+		draft, err = client.NewTransaction(keyCtx, testXPub, config)
+		require.NoError(t, err)
+
+		key, err = client.GetAccessKey(ctx, testXPubID, key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 20000+draft.Configuration.Fee, key.SpentSatoshis)
+		assert.Equal(t, key.ID, draft.AccessKeyID)
+	})
+
+	t.Run("spend of a canceled draft is refunded", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		key, err := client.NewScopedAccessKey(ctx, testXPub, &AccessKeyConfig{MaxSatoshis: 25000}, time.Time{})
+		require.NoError(t, err)
+		keyCtx := context.WithValue(ctx, ParamAccessKey, key)
+
+		var draft *DraftTransaction
+		draft, err = client.NewTransaction(keyCtx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 20000}},
+		})
+		require.NoError(t, err)
+
+		draft.Status = DraftStatusCanceled
+		require.NoError(t, draft.Save(ctx))
+
+		key, err = client.GetAccessKey(ctx, testXPubID, key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), key.SpentSatoshis)
+
+		// saving the canceled draft again does not refund twice
+		require.NoError(t, draft.Save(ctx))
+		key, err = client.GetAccessKey(ctx, testXPubID, key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), key.SpentSatoshis)
+
+		// the refunded satoshis can be spent again
+		_, err = client.NewTransaction(keyCtx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 20000}},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("spend of an expired draft is refunded", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		key, err := client.NewScopedAccessKey(ctx, testXPub, &AccessKeyConfig{MaxSatoshis: 25000}, time.Time{})
+		require.NoError(t, err)
+		keyCtx := context.WithValue(ctx, ParamAccessKey, key)
+
+		_, err = client.NewTransaction(keyCtx, testXPub, &TransactionConfig{
+			ExpiresIn: time.Millisecond,
+			Outputs:   []*TransactionOutput{{To: testExternalAddress, Satoshis: 20000}},
+		})
+		require.NoError(t, err)
+
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, taskCleanupDraftTransactions(ctx, client.Logger(), client.DefaultModelOptions()...))
+
+		key, err = client.GetAccessKey(ctx, testXPubID, key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), key.SpentSatoshis)
+	})
+}
+
+func TestClient_AuthenticateRequest_AccessKeyScopes(t *testing.T) {
+	newSignedRequest := func(t *testing.T, privateKey string) *http.Request {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "", bytes.NewReader([]byte(`{}`)))
+		require.NoError(t, err)
+		require.NoError(t, SetSignatureFromAccessKey(&req.Header, privateKey, `{}`))
+		return req
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		key := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		key.ExpiresAt.Valid = true
+		key.ExpiresAt.Time = time.Now().Add(-time.Minute)
+		require.NoError(t, key.Save(ctx))
+
+		_, err := client.AuthenticateRequest(ctx, newSignedRequest(t, key.Key), []string{}, false, true, false)
+		assert.ErrorIs(t, err, ErrAccessKeyExpired)
+	})
+
+	t.Run("scopes on the request", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		key := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		key.Config.Scopes = []AccessKeyScope{AccessKeyScopeReadOnly}
+		require.NoError(t, key.Save(ctx))
+
+		req, err := client.AuthenticateRequest(ctx, newSignedRequest(t, key.Key), []string{}, false, true, false)
+		require.NoError(t, err)
+
+		accessKey, ok := GetAccessKeyFromRequest(req)
+		require.True(t, ok)
+		assert.Equal(t, key.ID, accessKey.ID)
+		assert.True(t, HasScope(req, AccessKeyScopeReadOnly))
+		assert.False(t, HasScope(req, AccessKeyScopeCreateTransaction))
+	})
+
+	t.Run("usage is recorded", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		key := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		require.NoError(t, key.Save(ctx))
+
+		req := newSignedRequest(t, key.Key)
+		req.RemoteAddr = "192.168.1.10:52100"
+		_, err := client.AuthenticateRequest(ctx, req, []string{}, false, true, false)
+		require.NoError(t, err)
+
+		var accessKey *AccessKey
+		accessKey, err = getAccessKey(ctx, key.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), accessKey.UseCount)
+		assert.Equal(t, "192.168.1.10", accessKey.LastUsedIP)
+	})
+
+	t.Run("admin read", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		key := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		require.NoError(t, key.Save(ctx))
+
+		// the key needs the scope
+		_, err := client.AuthenticateRequest(ctx, newSignedRequest(t, key.Key), []string{testXPub}, true, true, false)
+		assert.ErrorIs(t, err, ErrNotAdminKey)
+
+		key = newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+		key.Config.Scopes = []AccessKeyScope{AccessKeyScopeAdminRead}
+		require.NoError(t, key.Save(ctx))
+
+		// the key needs to belong to an admin xPub
+		_, err = client.AuthenticateRequest(ctx, newSignedRequest(t, key.Key), []string{testXpubAuth}, true, true, false)
+		assert.ErrorIs(t, err, ErrNotAdminKey)
+
+		var req *http.Request
+		req, err = client.AuthenticateRequest(ctx, newSignedRequest(t, key.Key), []string{testXPub}, true, true, false)
+		require.NoError(t, err)
+		assert.True(t, HasScope(req, AccessKeyScopeAdminRead))
+	})
+}
+
+func TestAccessKey_recordUsage(t *testing.T) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+	defer deferMe()
+
+	key := newAccessKey(testXPubID, append(client.DefaultModelOptions(), New())...)
+	require.NoError(t, key.Save(ctx))
+
+	// the first use is saved, the next uses are counted until the throttle window ends
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		require.NoError(t, key.recordUsage(ctx, ip, time.Minute))
+	}
+
+	accessKey, err := getAccessKey(ctx, key.ID, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	assert.True(t, accessKey.LastUsedAt.Valid)
+	assert.Equal(t, "10.0.0.1", accessKey.LastUsedIP)
+	assert.Equal(t, uint64(1), accessKey.UseCount)
+
+	// the window ended
+	require.NoError(t, client.Cachestore().Delete(ctx, fmt.Sprintf(lockKeyAccessKeyUsage, key.ID)))
+	require.NoError(t, accessKey.recordUsage(ctx, "10.0.0.4", time.Minute))
+
+	accessKey, err = getAccessKey(ctx, key.ID, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.4", accessKey.LastUsedIP)
+	assert.Equal(t, uint64(4), accessKey.UseCount)
+
+	// the usage of a stale key does not overwrite the spent satoshis
+	_, err = incrementField(ctx, accessKey, spentSatoshisField, 1000)
+	require.NoError(t, err)
+	require.NoError(t, client.Cachestore().Delete(ctx, fmt.Sprintf(lockKeyAccessKeyUsage, key.ID)))
+	require.NoError(t, key.recordUsage(ctx, "10.0.0.5", time.Minute))
+
+	accessKey, err = getAccessKey(ctx, key.ID, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), accessKey.SpentSatoshis)
+	assert.Equal(t, uint64(5), accessKey.UseCount)
+
+	// the usage of a stale key does not overwrite the revocation
+	_, err = client.NewXpub(ctx, testXPub, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	_, err = client.RevokeAccessKey(ctx, testXPub, key.ID)
+	require.NoError(t, err)
+	require.NoError(t, client.Cachestore().Delete(ctx, fmt.Sprintf(lockKeyAccessKeyUsage, key.ID)))
+	require.NoError(t, key.recordUsage(ctx, "10.0.0.6", time.Minute))
+
+	accessKey, err = getAccessKey(ctx, key.ID, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	assert.True(t, accessKey.IsRevoked())
+	assert.Equal(t, "10.0.0.6", accessKey.LastUsedIP)
+}
+
+func TestClient_RotateAccessKey(t *testing.T) {
+	t.Run("grace period", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		key, err := client.NewScopedAccessKey(ctx, testXPub, &AccessKeyConfig{
+			Scopes: []AccessKeyScope{AccessKeyScopeReadOnly},
+		}, time.Time{}, WithMetadatas(Metadata{"name": "ci"}))
+		require.NoError(t, err)
+
+		var successor *AccessKey
+		successor, err = client.RotateAccessKey(ctx, testXPub, key.ID, time.Hour, WithMetadatas(Metadata{"rotated": true}))
+		require.NoError(t, err)
+		assert.NotEqual(t, key.ID, successor.ID)
+		assert.Len(t, successor.Key, 64)
+		assert.Equal(t, key.Config, successor.Config)
+		assert.Equal(t, "ci", successor.Metadata["name"])
+		assert.Equal(t, true, successor.Metadata["rotated"])
+
+		key, err = client.GetAccessKey(ctx, testXPubID, key.ID)
+		require.NoError(t, err)
+		assert.Equal(t, successor.ID, key.SuccessorID)
+		assert.True(t, key.RevokedAt.Valid)
+		assert.False(t, key.IsRevoked())
+
+		_, err = client.RotateAccessKey(ctx, testXPub, key.ID, time.Hour)
+		assert.ErrorIs(t, err, ErrAccessKeyRotated)
+	})
+
+	t.Run("keys share the spend cap", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		// a utxo for each draft
+		for index := uint32(1); index <= 3; index++ {
+			utxo := newUtxo(testXPubID, testTxID, testLockingScript, index, 100000,
+				append(client.DefaultModelOptions(), New())...)
+			require.NoError(t, utxo.Save(ctx))
+		}
+
+		key, err := client.NewScopedAccessKey(ctx, testXPub, &AccessKeyConfig{MaxSatoshis: 50000}, time.Time{})
+		require.NoError(t, err)
+		keyCtx := context.WithValue(ctx, ParamAccessKey, key)
+
+		config := &TransactionConfig{Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 20000}}}
+		var draft *DraftTransaction
+		draft, err = client.NewTransaction(keyCtx, testXPub, config)
+		require.NoError(t, err)
+		spent := 20000 + draft.Configuration.Fee
+
+		// the spent satoshis are carried over to the successor
+		var successor *AccessKey
+		successor, err = client.RotateAccessKey(ctx, testXPub, key.ID, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, spent, successor.SpentSatoshis)
+
+		// the spend of the rotated key (grace period) is counted on the successor
+		draft, err = client.NewTransaction(keyCtx, testXPub, config)
+		require.NoError(t, err)
+		assert.Equal(t, successor.ID, draft.AccessKeyID)
+		spent += 20000 + draft.Configuration.Fee
+
+		successor, err = client.GetAccessKey(ctx, testXPubID, successor.ID)
+		require.NoError(t, err)
+		assert.Equal(t, spent, successor.SpentSatoshis)
+
+		// the keys together can not spend more than the cap
+		successorCtx := context.WithValue(ctx, ParamAccessKey, successor)
+		_, err = client.NewTransaction(successorCtx, testXPub, config)
+		assert.ErrorIs(t, err, ErrAccessKeySpendLimit)
+		_, err = client.NewTransaction(keyCtx, testXPub, config)
+		assert.ErrorIs(t, err, ErrAccessKeySpendLimit)
+	})
+
+	t.Run("no grace period", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		key, err := client.NewAccessKey(ctx, testXPub)
+		require.NoError(t, err)
+
+		_, err = client.RotateAccessKey(ctx, testXPub, key.ID, 0)
+		require.NoError(t, err)
+
+		key, err = client.GetAccessKey(ctx, testXPubID, key.ID)
+		require.NoError(t, err)
+		assert.True(t, key.IsRevoked())
+
+		_, err = client.RotateAccessKey(ctx, testXPub, key.ID, 0)
+		assert.ErrorIs(t, err, ErrAccessKeyRevoked)
+	})
+}
+
+func TestClient_GetIdleAccessKeys(t *testing.T) {
+	ctx, client, deferMe := initSimpleTestCase(t)
+	defer deferMe()
+
+	opts := append(client.DefaultModelOptions(), New())
+	lastWeek := time.Now().UTC().Add(-7 * 24 * time.Hour)
+
+	// created last week (the created time is set on create)
+	newOldKey := func(lastUsedAt time.Time) *AccessKey {
+		key := newAccessKey(testXPubID, opts...)
+		require.NoError(t, key.Save(ctx))
+		key.CreatedAt = lastWeek
+		key.LastUsedAt.Valid = !lastUsedAt.IsZero()
+		key.LastUsedAt.Time = lastUsedAt
+		require.NoError(t, key.Save(ctx))
+		return key
+	}
+
+	newOldKey(time.Now().UTC())
+	idleKey := newOldKey(lastWeek)
+	neverUsedKey := newOldKey(time.Time{})
+
+	newKey := newAccessKey(testXPubID, opts...)
+	require.NoError(t, newKey.Save(ctx))
+
+	keys, err := client.GetIdleAccessKeys(ctx, 24*time.Hour, nil, nil)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	assert.ElementsMatch(t, []string{idleKey.ID, neverUsedKey.ID}, ids)
+}
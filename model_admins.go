@@ -0,0 +1,194 @@
+package bux
+
+import (
+	"context"
+	"errors"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+)
+
+// Admin is an admin identity (xPub) with a role
+//
+// The role gives the permissions of the admin, the engine checks the permission of the admin that is on the
+// context of the request (see AuthenticateRequest, Client.NewAdminContext and NewSystemAdminContext)
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type Admin struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID   string    `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the admin xPub id" bson:"_id"`
+	Role AdminRole `json:"role" toml:"role" yaml:"role" gorm:"<-;type:varchar(32);index;comment:This is the role of the admin" bson:"role"`
+}
+
+// AdminRole is the role of an admin
+type AdminRole string
+
+const (
+	// AdminRoleAuditor can read (stats, xPubs, paymails)
+	AdminRoleAuditor AdminRole = "auditor"
+
+	// AdminRoleOperator can read and operate the engine (retry sync jobs)
+	AdminRoleOperator AdminRole = "operator"
+
+	// AdminRoleSuperAdmin can do everything (create paymails, manage the admins)
+	AdminRoleSuperAdmin AdminRole = "super-admin"
+)
+
+// AdminPermission is a permission of an admin role
+type AdminPermission string
+
+const (
+	// AdminPermissionRead allows reading the admin data (stats, xPubs, paymails)
+	AdminPermissionRead AdminPermission = "read"
+
+	// AdminPermissionOperate allows operating the engine (retry sync jobs)
+	AdminPermissionOperate AdminPermission = "operate"
+
+	// AdminPermissionWrite allows creating data for any xPub (paymails)
+	AdminPermissionWrite AdminPermission = "write"
+
+	// AdminPermissionManageAdmins allows creating and deleting admins
+	AdminPermissionManageAdmins AdminPermission = "manage-admins"
+)
+
+// adminRolePermissions are the permissions of each role
+var adminRolePermissions = map[AdminRole][]AdminPermission{
+	AdminRoleAuditor:  {AdminPermissionRead},
+	AdminRoleOperator: {AdminPermissionRead, AdminPermissionOperate},
+	AdminRoleSuperAdmin: {
+		AdminPermissionRead, AdminPermissionOperate, AdminPermissionWrite, AdminPermissionManageAdmins,
+	},
+}
+
+// newAdmin will start a new model
+func newAdmin(rawXpubKey string, role AdminRole, opts ...ModelOps) *Admin {
+	return &Admin{
+		ID:    utils.Hash(rawXpubKey),
+		Model: *NewBaseModel(ModelAdmin, opts...),
+		Role:  role,
+	}
+}
+
+// getAdmin will get the model for the given xPub ID
+func getAdmin(ctx context.Context, xPubID string, opts ...ModelOps) (*Admin, error) {
+
+	// Construct an empty model
+	admin := &Admin{
+		ID: xPubID,
+	}
+	admin.enrich(ModelAdmin, opts...)
+
+	// Get the record
+	if err := Get(ctx, admin, nil, false, defaultDatabaseReadTimeout, false); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return admin, nil
+}
+
+// getAdmins will get all the admins with the given conditions
+func getAdmins(ctx context.Context, metadata *Metadata, conditions *map[string]interface{},
+	queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Admin, error) {
+
+	modelItems := make([]*Admin, 0)
+	if err := getModelsByConditions(ctx, ModelAdmin, &modelItems, metadata, conditions, queryParams, opts...); err != nil {
+		return nil, err
+	}
+
+	return modelItems, nil
+}
+
+// HasPermission will return true if the role of the admin has the permission
+func (m *Admin) HasPermission(permission AdminPermission) bool {
+	for _, rolePermission := range adminRolePermissions[m.Role] {
+		if rolePermission == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// adminContextKey is the (unexported) context key of the admin, so the admin can only be set by the engine
+type adminContextKey struct{}
+
+// systemAdminID is the ID of the system admin (see NewSystemAdminContext)
+const systemAdminID = "system"
+
+// withAdmin will return a context with the admin for calling the admin actions
+func withAdmin(ctx context.Context, admin *Admin) context.Context {
+	return context.WithValue(ctx, adminContextKey{}, admin)
+}
+
+// adminFromContext will get the admin of the context (see AuthenticateRequest and NewAdminContext)
+func adminFromContext(ctx context.Context) *Admin {
+	admin, _ := ctx.Value(adminContextKey{}).(*Admin)
+	return admin
+}
+
+// NewSystemAdminContext will return a context with the system admin (super admin) for calling the admin actions
+// from the code running the engine, for example to create the first admin
+//
+// The context bypasses the admin identities, never use it for the actions of a request
+func NewSystemAdminContext(ctx context.Context) context.Context {
+	return withAdmin(ctx, &Admin{ID: systemAdminID, Role: AdminRoleSuperAdmin})
+}
+
+// checkAdminPermission will check the permission of the admin in the context, a context without an admin is denied
+func checkAdminPermission(ctx context.Context, permission AdminPermission) error {
+	if admin := adminFromContext(ctx); admin == nil || !admin.HasPermission(permission) {
+		return ErrAdminPermission
+	}
+	return nil
+}
+
+// GetModelName will get the name of the current model
+func (m *Admin) GetModelName() string {
+	return ModelAdmin.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *Admin) GetModelTableName() string {
+	return tableAdmins
+}
+
+// Save will save the model into the Datastore
+func (m *Admin) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *Admin) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *Admin) BeforeCreating(_ context.Context) error {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	}
+
+	if _, ok := adminRolePermissions[m.Role]; !ok {
+		return ErrInvalidAdminRole
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// RegisterTasks will register the model specific tasks on client initialization
+func (m *Admin) RegisterTasks() error {
+	return nil
+}
+
+// Migrate model specific migration on startup
+func (m *Admin) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tableAdmins), metadataField)
+}
@@ -0,0 +1,217 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoinschema/go-bitcoin/v2"
+	"github.com/libsv/go-bk/bip32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSuperAdminContext will return the context of a super admin for calling the admin actions
+func testSuperAdminContext(ctx context.Context) context.Context {
+	return NewSystemAdminContext(ctx)
+}
+
+// testAdminContext will create the admin (testXpubAuth) with the role and return its context
+func testAdminContext(ctx context.Context, t *testing.T, client ClientInterface, role AdminRole) context.Context {
+	_, err := client.NewAdmin(testSuperAdminContext(ctx), testXpubAuth, role)
+	require.NoError(t, err)
+
+	var adminCtx context.Context
+	adminCtx, err = client.NewAdminContext(ctx, utils.Hash(testXpubAuth))
+	require.NoError(t, err)
+	return adminCtx
+}
+
+func TestAdmin_HasPermission(t *testing.T) {
+	auditor := newAdmin(testXPub, AdminRoleAuditor)
+	assert.True(t, auditor.HasPermission(AdminPermissionRead))
+	assert.False(t, auditor.HasPermission(AdminPermissionOperate))
+	assert.False(t, auditor.HasPermission(AdminPermissionWrite))
+
+	operator := newAdmin(testXPub, AdminRoleOperator)
+	assert.True(t, operator.HasPermission(AdminPermissionRead))
+	assert.True(t, operator.HasPermission(AdminPermissionOperate))
+	assert.False(t, operator.HasPermission(AdminPermissionWrite))
+
+	superAdmin := newAdmin(testXPub, AdminRoleSuperAdmin)
+	assert.True(t, superAdmin.HasPermission(AdminPermissionWrite))
+	assert.True(t, superAdmin.HasPermission(AdminPermissionManageAdmins))
+
+	unknown := newAdmin(testXPub, "root")
+	assert.False(t, unknown.HasPermission(AdminPermissionRead))
+}
+
+func TestClient_NewAdmin(t *testing.T) {
+	t.Run("invalid role", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		_, err := client.NewAdmin(testSuperAdminContext(ctx), testXPub, "root")
+		assert.ErrorIs(t, err, ErrInvalidAdminRole)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		admin, err := client.NewAdmin(testSuperAdminContext(ctx), testXPub, AdminRoleAuditor)
+		require.NoError(t, err)
+		assert.Equal(t, testXPubID, admin.ID)
+
+		_, err = client.NewAdmin(testSuperAdminContext(ctx), testXPub, AdminRoleOperator)
+		require.NoError(t, err)
+
+		admin, err = client.GetAdmin(testSuperAdminContext(ctx), testXPubID)
+		require.NoError(t, err)
+		assert.Equal(t, AdminRoleOperator, admin.Role)
+
+		var admins []*Admin
+		admins, err = client.GetAdmins(testSuperAdminContext(ctx), nil, nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, admins, 1)
+
+		require.NoError(t, client.DeleteAdmin(testSuperAdminContext(ctx), testXPubID))
+		_, err = client.GetAdmin(testSuperAdminContext(ctx), testXPubID)
+		assert.ErrorIs(t, err, ErrMissingAdmin)
+
+		admins, err = client.GetAdmins(testSuperAdminContext(ctx), nil, nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, admins, 0)
+	})
+
+	t.Run("permission of the admin of the request", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		// a context without an admin is denied
+		_, err := client.NewAdmin(ctx, testXPub, AdminRoleSuperAdmin)
+		assert.ErrorIs(t, err, ErrAdminPermission)
+
+		operatorCtx := testAdminContext(ctx, t, client, AdminRoleOperator)
+		_, err = client.NewAdmin(operatorCtx, testXPub, AdminRoleSuperAdmin)
+		assert.ErrorIs(t, err, ErrAdminPermission)
+
+		superAdminCtx := testAdminContext(ctx, t, client, AdminRoleSuperAdmin)
+		_, err = client.NewAdmin(superAdminCtx, testXPub, AdminRoleSuperAdmin)
+		require.NoError(t, err)
+	})
+
+	t.Run("admin context of unknown and deleted admins", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		_, err := client.NewAdminContext(ctx, testXPubID)
+		assert.ErrorIs(t, err, ErrNotAdminKey)
+
+		_, err = client.NewAdmin(testSuperAdminContext(ctx), testXPub, AdminRoleSuperAdmin)
+		require.NoError(t, err)
+		_, err = client.NewAdminContext(ctx, testXPubID)
+		require.NoError(t, err)
+
+		require.NoError(t, client.DeleteAdmin(testSuperAdminContext(ctx), testXPubID))
+		_, err = client.NewAdminContext(ctx, testXPubID)
+		assert.ErrorIs(t, err, ErrNotAdminKey)
+	})
+}
+
+func TestClient_adminPermissions(t *testing.T) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}), WithAutoMigrate(newPaymail(testPaymail)),
+	)
+	defer deferMe()
+
+	auditorCtx := testAdminContext(ctx, t, client, AdminRoleAuditor)
+
+	_, err := client.GetStats(auditorCtx)
+	require.NoError(t, err)
+
+	_, err = client.GetXPubs(auditorCtx, nil, nil, nil)
+	require.NoError(t, err)
+
+	_, err = client.NewPaymailAddress(auditorCtx, testXPub, "tester@"+testDomain, "", "")
+	assert.ErrorIs(t, err, ErrAdminPermission)
+
+	_, err = client.RetrySyncTransaction(auditorCtx, testTxID)
+	assert.ErrorIs(t, err, ErrAdminPermission)
+}
+
+func TestClient_RetrySyncTransaction(t *testing.T) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+	defer deferMe()
+
+	_, err := client.RetrySyncTransaction(testSuperAdminContext(ctx), testTxID)
+	assert.ErrorIs(t, err, ErrMissingSyncTransaction)
+
+	syncTx := newSyncTransaction(testTxID, &SyncConfig{Broadcast: true, SyncOnChain: true}, append(client.DefaultModelOptions(), New())...)
+	syncTx.BroadcastStatus = SyncStatusError
+	require.NoError(t, syncTx.Save(ctx))
+
+	operatorCtx := testAdminContext(ctx, t, client, AdminRoleOperator)
+	syncTx, err = client.RetrySyncTransaction(operatorCtx, testTxID)
+	require.NoError(t, err)
+	assert.Equal(t, SyncStatusReady, syncTx.BroadcastStatus)
+
+	_, err = client.RetrySyncTransaction(operatorCtx, testTxID)
+	assert.ErrorIs(t, err, ErrSyncTransactionNotFailed)
+}
+
+func TestClient_AuthenticateRequest_Admin(t *testing.T) {
+	newSignedRequest := func(t *testing.T, key *bip32.ExtendedKey) *http.Request {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "", bytes.NewReader([]byte(`{}`)))
+		require.NoError(t, err)
+		require.NoError(t, SetSignature(&req.Header, key, `{}`))
+		return req
+	}
+
+	key, err := bitcoin.GenerateHDKey(bitcoin.SecureSeedLength)
+	require.NoError(t, err)
+	var rawXPub string
+	rawXPub, err = bitcoin.GetExtendedPublicKey(key)
+	require.NoError(t, err)
+
+	t.Run("admin xPubs are super-admins", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		req, authErr := client.AuthenticateRequest(ctx, newSignedRequest(t, key), []string{rawXPub}, true, true, false)
+		require.NoError(t, authErr)
+
+		admin, ok := GetAdminFromRequest(req)
+		require.True(t, ok)
+		assert.Equal(t, AdminRoleSuperAdmin, admin.Role)
+		assert.True(t, HasAdminPermission(req, AdminPermissionManageAdmins))
+	})
+
+	t.Run("admin from the datastore", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		_, authErr := client.AuthenticateRequest(ctx, newSignedRequest(t, key), []string{}, true, true, false)
+		assert.ErrorIs(t, authErr, ErrNotAdminKey)
+
+		_, err = client.NewAdmin(testSuperAdminContext(ctx), rawXPub, AdminRoleAuditor)
+		require.NoError(t, err)
+
+		var req *http.Request
+		req, authErr = client.AuthenticateRequest(ctx, newSignedRequest(t, key), []string{}, true, true, false)
+		require.NoError(t, authErr)
+
+		admin, ok := GetAdminFromRequest(req)
+		require.True(t, ok)
+		assert.Equal(t, utils.Hash(rawXPub), admin.ID)
+		assert.True(t, HasAdminPermission(req, AdminPermissionRead))
+		assert.False(t, HasAdminPermission(req, AdminPermissionWrite))
+
+		// deleted admins are not admins
+		require.NoError(t, client.DeleteAdmin(testSuperAdminContext(ctx), utils.Hash(rawXPub)))
+		_, authErr = client.AuthenticateRequest(ctx, newSignedRequest(t, key), []string{}, true, true, false)
+		assert.ErrorIs(t, authErr, ErrNotAdminKey)
+	})
+}
@@ -0,0 +1,223 @@
+package bux
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoinschema/go-bitcoin/v2"
+	"github.com/libsv/go-bk/bip32"
+	"github.com/mrz1836/go-datastore"
+)
+
+// ContactStatus is the status of a contact
+type ContactStatus string
+
+const (
+	// ContactStatusUnconfirmed is when the contact was added (invited) or accepted, but the key is not confirmed yet
+	ContactStatusUnconfirmed ContactStatus = "unconfirmed"
+
+	// ContactStatusAwaiting is when the invitation of the contact was received and awaits the acceptance
+	ContactStatusAwaiting ContactStatus = "awaiting"
+
+	// ContactStatusConfirmed is when the key of the contact was confirmed, payments use the contact chain
+	ContactStatusConfirmed ContactStatus = "confirmed"
+
+	// ContactStatusRejected is when the invitation of the contact was rejected
+	ContactStatusRejected ContactStatus = "rejected"
+)
+
+// contactChainOffset is the first contact chain (after the external and internal chains)
+const contactChainOffset = 2
+
+// Contact is a paymail contact of an xPub, exchanged with PIKE (see WithPaymailPikeSupport)
+//
+// Confirmed contacts get their own chain of the xPub (xPub/chain/num): the outputs issued to the contact
+// are derived from the chain, and payments to the contact use the outputs issued by the contact (PIKE outputs)
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type Contact struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID              string        `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the hash of the xPub id and the paymail" bson:"_id"`
+	XpubID          string        `json:"xpub_id" toml:"xpub_id" yaml:"xpub_id" gorm:"<-:create;type:char(64);index;comment:This is the related xPub" bson:"xpub_id"`
+	Paymail         string        `json:"paymail" toml:"paymail" yaml:"paymail" gorm:"<-:create;type:varchar(255);index;comment:This is the paymail of the contact" bson:"paymail"`
+	FullName        string        `json:"full_name" toml:"full_name" yaml:"full_name" gorm:"<-;type:varchar(255);comment:This is the name of the contact" bson:"full_name"`
+	PubKey          string        `json:"pub_key" toml:"pub_key" yaml:"pub_key" gorm:"<-;type:varchar(66);comment:This is the PKI key of the contact" bson:"pub_key"`
+	Status          ContactStatus `json:"status" toml:"status" yaml:"status" gorm:"<-;type:varchar(16);index;comment:This is the status of the contact" bson:"status"`
+	Chain           uint32        `json:"chain" toml:"chain" yaml:"chain" gorm:"<-;type:int;comment:This is the chain of the xPub for the contact" bson:"chain"`
+	ExternalXpubKey string        `json:"-" toml:"-" yaml:"-" gorm:"<-;type:varchar(512);comment:This is the xPub of the chain, encryption optional" bson:"external_xpub_key"`
+	NextNum         uint32        `json:"next_num" toml:"next_num" yaml:"next_num" gorm:"<-;type:int;default:0;comment:This is the next num on the chain" bson:"next_num"`
+}
+
+// newContact will start a new model
+func newContact(xPubID, paymailAddress, fullName, pubKey string, status ContactStatus, opts ...ModelOps) *Contact {
+	return &Contact{
+		FullName: fullName,
+		ID:       getContactID(xPubID, paymailAddress),
+		Model:    *NewBaseModel(ModelContact, opts...),
+		Paymail:  paymailAddress,
+		PubKey:   pubKey,
+		Status:   status,
+		XpubID:   xPubID,
+	}
+}
+
+// getContactID will get the ID of the contact (paymail) of the xPub
+func getContactID(xPubID, paymailAddress string) string {
+	return utils.Hash(xPubID + paymailAddress)
+}
+
+// getContact will get the contact (paymail) of the xPub
+func getContact(ctx context.Context, xPubID, paymailAddress string, opts ...ModelOps) (*Contact, error) {
+
+	// Construct an empty model
+	contact := &Contact{
+		ID: getContactID(xPubID, paymailAddress),
+	}
+	contact.enrich(ModelContact, opts...)
+
+	// Get the record
+	if err := Get(ctx, contact, nil, false, defaultDatabaseReadTimeout, false); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return contact, nil
+}
+
+// getContacts will get the contacts with the given conditions
+func getContacts(ctx context.Context, metadata *Metadata, conditions *map[string]interface{},
+	queryParams *datastore.QueryParams, opts ...ModelOps) ([]*Contact, error) {
+
+	modelItems := make([]*Contact, 0)
+	if err := getModelsByConditions(
+		ctx, ModelContact, &modelItems, metadata, conditions, queryParams, opts...,
+	); err != nil {
+		return nil, err
+	}
+
+	return modelItems, nil
+}
+
+// getConfirmedContact will get the confirmed contact (paymail) of the xPub, nil if contacts are not loaded
+func getConfirmedContact(ctx context.Context, client ClientInterface, xPubID, paymailAddress string,
+	opts ...ModelOps) (*Contact, error) {
+
+	if !isContactLoaded(client) {
+		return nil, nil
+	}
+
+	contact, err := getContact(ctx, xPubID, paymailAddress, opts...)
+	if err != nil || contact == nil || contact.Status != ContactStatusConfirmed || contact.DeletedAt.Valid {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// isContactLoaded will return true if the contacts are loaded (see WithPaymailPikeSupport)
+func isContactLoaded(client ClientInterface) bool {
+	return utils.StringInSlice(ModelContact.String(), client.GetModelNames())
+}
+
+// getContactChain will get the chain of the xPub for the contact (not the external or internal chain)
+func getContactChain(contactID string) (uint32, error) {
+	id, err := hex.DecodeString(contactID)
+	if err != nil {
+		return 0, err
+	} else if len(id) < 4 {
+		return 0, ErrMissingFieldID
+	}
+	return contactChainOffset + binary.BigEndian.Uint32(id[:4])%(uint32(utils.MaxInt32)-contactChainOffset), nil
+}
+
+// setChain will set the chain of the contact and the (encrypted) xPub of the chain
+func (m *Contact) setChain(rawXpubKey string) error {
+	xPub, err := bitcoin.GetHDKeyFromExtendedPublicKey(rawXpubKey)
+	if err != nil {
+		return err
+	}
+
+	if m.Chain, err = getContactChain(m.ID); err != nil {
+		return err
+	}
+
+	var chainKey *bip32.ExtendedKey
+	if chainKey, err = bitcoin.GetHDKeyChild(xPub, m.Chain); err != nil {
+		return err
+	}
+
+	m.ExternalXpubKey, err = m.encryptXpubKey(chainKey.String())
+	return err
+}
+
+// deriveKey will derive the key of the next num on the chain of the contact
+func (m *Contact) deriveKey(ctx context.Context) (*derivedPubKey, error) {
+	if len(m.ExternalXpubKey) == 0 {
+		return nil, ErrContactNotConfirmed
+	}
+
+	rawChainKey, err := m.decryptXpubKey(m.ExternalXpubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var num int64
+	if num, err = incrementField(ctx, m, nextNumField, 1); err != nil {
+		return nil, err
+	}
+	m.NextNum = uint32(num)
+
+	// the previous num, which was the next num
+	var pubKey *derivedPubKey
+	if pubKey, err = deriveKey(rawChainKey, uint32(num-1)); err != nil {
+		return nil, err
+	}
+	pubKey.chain = m.Chain
+	return pubKey, nil
+}
+
+// GetModelName will get the name of the current model
+func (m *Contact) GetModelName() string {
+	return ModelContact.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *Contact) GetModelTableName() string {
+	return tableContacts
+}
+
+// Save will save the model into the Datastore
+func (m *Contact) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *Contact) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *Contact) BeforeCreating(_ context.Context) error {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	} else if len(m.XpubID) == 0 {
+		return ErrMissingFieldXpubID
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// Migrate model specific migration on startup
+func (m *Contact) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tableContacts), metadataField)
+}
@@ -0,0 +1,313 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoin-sv/go-paymail"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testContactPaymail = testAlias + "@" + testDomain
+	testContactPubKey  = "02ead23149a1e33df17325ec7a7ba9e0b20c674c57c630f527d69b866aa9b65b10"
+)
+
+// initContactTestCase will create the paymail of the test xPub, with the PIKE contacts loaded
+func initContactTestCase(t *testing.T) (context.Context, ClientInterface, func()) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}),
+		WithPaymailClient(newTestPaymailClient(t, []string{testDomain})),
+		WithAutoMigrate(newPaymail(testPaymail)),
+		WithPaymailPikeSupport(),
+	)
+
+	_, err := client.NewXpub(ctx, testXPub, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	_, err = client.NewPaymailAddress(testSuperAdminContext(ctx), testXPub, testPaymail, "", "", client.DefaultModelOptions()...)
+	require.NoError(t, err)
+
+	return ctx, client, deferMe
+}
+
+// mockPikeResponses will mock the capabilities (PKI, P2P and PIKE) and the PKI of the test contact
+func mockPikeResponses() {
+	httpmock.Reset()
+
+	serverURL := "https://" + testDomain + "/api/v1/" + paymail.DefaultServiceName
+	httpmock.RegisterResponder(http.MethodGet, "https://"+testDomain+":443/.well-known/"+paymail.DefaultServiceName,
+		httpmock.NewStringResponder(
+			http.StatusOK,
+			`{"`+paymail.DefaultServiceName+`": "`+paymail.DefaultBsvAliasVersion+`","capabilities":{
+"`+paymail.BRFCPki+`": "`+serverURL+`/id/{alias}@{domain.tld}",
+"`+paymail.BRFCP2PTransactions+`": "`+serverURL+`/receive-transaction/{alias}@{domain.tld}",
+"`+paymail.BRFCP2PPaymentDestination+`": "`+serverURL+`/p2p-payment-destination/{alias}@{domain.tld}",
+"`+BRFCPike+`": {"`+BRFCPikeOutputs+`": "`+serverURL+`/pike/outputs/{alias}@{domain.tld}"}}
+}`,
+		),
+	)
+
+	httpmock.RegisterResponder(http.MethodGet, serverURL+"/id/"+testContactPaymail,
+		httpmock.NewStringResponder(
+			http.StatusOK,
+			`{"`+paymail.DefaultServiceName+`": "`+paymail.DefaultBsvAliasVersion+`","handle": "`+testContactPaymail+`","pubkey": "`+testContactPubKey+`"}`,
+		),
+	)
+
+	httpmock.RegisterResponder(http.MethodPost, serverURL+"/pike/outputs/"+testContactPaymail,
+		httpmock.NewStringResponder(
+			http.StatusOK,
+			`{"outputs": [{"script": "`+testLockingScript+`","satoshis": 600},{"script": "76a9143e2d1d795f8acaa7957045cc59376177eb04a3c588ac","satoshis": 400}],"reference": "`+testReferenceID+`"}`,
+		),
+	)
+}
+
+// postPikeTestRequest will POST the PIKE request to the handler
+func postPikeTestRequest(t *testing.T, handler http.Handler, path string, payload interface{}) *httptest.ResponseRecorder {
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(
+		http.MethodPost, "/v1/"+paymail.DefaultServiceName+path+testPaymail, bytes.NewBuffer(data),
+	))
+	return w
+}
+
+func TestContact_deriveKey(t *testing.T) {
+	ctx, client, deferMe := initContactTestCase(t)
+	defer deferMe()
+
+	contact := newContact(
+		testXPubID, testContactPaymail, "Tester", testContactPubKey, ContactStatusConfirmed,
+		append(client.DefaultModelOptions(), New())...,
+	)
+	require.NoError(t, contact.setChain(testXPub))
+	require.NoError(t, contact.Save(ctx))
+	assert.GreaterOrEqual(t, contact.Chain, uint32(contactChainOffset))
+
+	hdKey, err := utils.ValidateXPub(testXPub)
+	require.NoError(t, err)
+
+	for num := uint32(0); num < 2; num++ {
+		var pubKey *derivedPubKey
+		pubKey, err = contact.deriveKey(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, contact.Chain, pubKey.chain)
+		assert.Equal(t, num, pubKey.chainNum)
+
+		// the key of the contact chain of the xPub
+		var lockingScript, address string
+		lockingScript, err = createLockingScript(pubKey.ecPubKey)
+		require.NoError(t, err)
+		address, err = utils.DeriveAddress(hdKey, contact.Chain, num)
+		require.NoError(t, err)
+		assert.Equal(t, address, utils.GetAddressFromScript(lockingScript))
+	}
+
+	t.Run("not confirmed", func(t *testing.T) {
+		_, err = newContact(testXPubID, testContactPaymail, "", "", ContactStatusUnconfirmed).deriveKey(ctx)
+		assert.ErrorIs(t, err, ErrContactNotConfirmed)
+	})
+}
+
+func TestClient_ContactStatus(t *testing.T) {
+	ctx, client, deferMe := initContactTestCase(t)
+	defer deferMe()
+
+	for _, paymailAddress := range []string{"accept@" + testDomain, "reject@" + testDomain} {
+		require.NoError(t, newContact(
+			testXPubID, paymailAddress, "", testContactPubKey, ContactStatusAwaiting,
+			append(client.DefaultModelOptions(), New())...,
+		).Save(ctx))
+	}
+
+	contact, err := client.RejectContact(ctx, testXPubID, "reject@"+testDomain)
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusRejected, contact.Status)
+
+	_, err = client.AcceptContact(ctx, testXPubID, "reject@"+testDomain)
+	assert.ErrorIs(t, err, ErrInvalidContactStatus)
+
+	_, err = client.ConfirmContact(ctx, testXPub, "accept@"+testDomain)
+	assert.ErrorIs(t, err, ErrInvalidContactStatus)
+
+	contact, err = client.AcceptContact(ctx, testXPubID, "accept@"+testDomain)
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusUnconfirmed, contact.Status)
+
+	contact, err = client.ConfirmContact(ctx, testXPub, "accept@"+testDomain)
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusConfirmed, contact.Status)
+	assert.NotEmpty(t, contact.ExternalXpubKey)
+
+	var contacts []*Contact
+	contacts, err = client.GetContactsByXPubID(ctx, testXPubID, nil)
+	require.NoError(t, err)
+	assert.Len(t, contacts, 2)
+
+	_, err = client.GetContact(ctx, testXPubID, "unknown@"+testDomain)
+	assert.ErrorIs(t, err, ErrContactNotFound)
+}
+
+func TestNewPikeHandler(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockPikeResponses()
+
+	ctx, client, deferMe := initContactTestCase(t)
+	defer deferMe()
+	handler := NewPikeHandler(client)
+
+	// the invitation of the contact
+	w := postPikeTestRequest(t, handler, pikeInvitePath, &PikeContactRequest{
+		FullName: "Tester", Paymail: testContactPaymail,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	contact, err := client.GetContact(ctx, testXPubID, testContactPaymail)
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusAwaiting, contact.Status)
+	assert.Equal(t, "Tester", contact.FullName)
+	assert.Equal(t, testContactPubKey, contact.PubKey)
+
+	// no outputs until the contact is confirmed
+	w = postPikeTestRequest(t, handler, pikeOutputsPath, &PikeOutputsRequest{
+		Amount: 1000, SenderPaymail: testContactPaymail,
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, err = client.AcceptContact(ctx, testXPubID, testContactPaymail)
+	require.NoError(t, err)
+	contact, err = client.ConfirmContact(ctx, testXPub, testContactPaymail)
+	require.NoError(t, err)
+
+	w = postPikeTestRequest(t, handler, pikeOutputsPath, &PikeOutputsRequest{
+		Amount: 1000, SenderPaymail: testContactPaymail,
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	response := new(PikeOutputsResponse)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(response))
+	require.Len(t, response.Outputs, 1)
+	assert.Equal(t, uint64(1000), response.Outputs[0].Satoshis)
+	assert.NotEmpty(t, response.Reference)
+
+	// the destination is derived from the contact chain
+	var destination *Destination
+	destination, err = getDestinationByAddress(ctx, response.Outputs[0].Address, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	require.NotNil(t, destination)
+	assert.Equal(t, contact.Chain, destination.Chain)
+	assert.Equal(t, uint32(0), destination.Num)
+}
+
+func TestTransactionOutput_processPaymailViaPike(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockPikeResponses()
+
+	ctx, client, deferMe := initContactTestCase(t)
+	defer deferMe()
+
+	output := &TransactionOutput{To: testContactPaymail, Satoshis: 1000}
+	processed, err := output.processPaymailViaPike(ctx, client, testPaymail)
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	require.Len(t, output.Scripts, 2)
+	assert.Equal(t, testLockingScript, output.Scripts[0].Script)
+	assert.Equal(t, uint64(600), output.Scripts[0].Satoshis)
+	assert.Equal(t, uint64(400), output.Scripts[1].Satoshis)
+	assert.Equal(t, ResolutionTypePike, output.PaymailP4.ResolutionType)
+	assert.Equal(t, testReferenceID, output.PaymailP4.ReferenceID)
+	assert.Equal(t, testPaymail, output.PaymailP4.FromPaymail)
+	assert.True(t, output.PaymailP4.notifiesP2P())
+
+	// the outputs are not issued (not a contact of the paymail server), not processed
+	httpmock.RegisterResponder(http.MethodPost,
+		"https://"+testDomain+"/api/v1/"+paymail.DefaultServiceName+"/pike/outputs/"+testContactPaymail,
+		httpmock.NewStringResponder(http.StatusNotFound, `{"code": "not-found"}`),
+	)
+	output = &TransactionOutput{To: testContactPaymail, Satoshis: 1000}
+	processed, err = output.processPaymailViaPike(ctx, client, testPaymail)
+	require.NoError(t, err)
+	assert.False(t, processed)
+	assert.Empty(t, output.Scripts)
+}
+
+func TestPaymailDefaultServiceProvider_AddContactInvitation(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	mockPikeResponses()
+
+	ctx, client, deferMe := initContactTestCase(t)
+	defer deferMe()
+	provider := &PaymailDefaultServiceProvider{client: client}
+
+	contact, err := provider.AddContactInvitation(ctx, "paymail", "tester.com", &PikeContactRequest{
+		FullName: "Tester", Paymail: testContactPaymail,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusAwaiting, contact.Status)
+
+	// a rejected contact stays rejected
+	_, err = client.RejectContact(ctx, testXPubID, testContactPaymail)
+	require.NoError(t, err)
+	contact, err = provider.AddContactInvitation(ctx, "paymail", "tester.com", &PikeContactRequest{
+		FullName: "Another Tester", Paymail: testContactPaymail,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusRejected, contact.Status)
+	assert.Equal(t, "Tester", contact.FullName)
+
+	// a confirmed contact is not changed
+	confirmed := newContact(
+		testXPubID, "confirmed@"+testDomain, "Confirmed", testContactPubKey, ContactStatusConfirmed,
+		append(client.DefaultModelOptions(), New())...,
+	)
+	require.NoError(t, confirmed.Save(ctx))
+	httpmock.RegisterResponder(http.MethodGet,
+		"https://"+testDomain+"/api/v1/"+paymail.DefaultServiceName+"/id/confirmed@"+testDomain,
+		httpmock.NewStringResponder(
+			http.StatusOK,
+			`{"`+paymail.DefaultServiceName+`": "`+paymail.DefaultBsvAliasVersion+`","handle": "confirmed@`+testDomain+`","pubkey": "03ead23149a1e33df17325ec7a7ba9e0b20c674c57c630f527d69b866aa9b65b10"}`,
+		),
+	)
+	contact, err = provider.AddContactInvitation(ctx, "paymail", "tester.com", &PikeContactRequest{
+		FullName: "Another Tester", Paymail: "confirmed@" + testDomain,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusConfirmed, contact.Status)
+	assert.Equal(t, "Confirmed", contact.FullName)
+	assert.Equal(t, testContactPubKey, contact.PubKey)
+
+	contact, err = client.GetContact(ctx, testXPubID, "confirmed@"+testDomain)
+	require.NoError(t, err)
+	assert.Equal(t, ContactStatusConfirmed, contact.Status)
+	assert.Equal(t, testContactPubKey, contact.PubKey)
+}
+
+func TestWithPaymailPikeSupport(t *testing.T) {
+	_, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}),
+		WithPaymailSupport([]string{testDomain}, defaultSenderPaymail, defaultAddressResolutionPurpose, false, false),
+		WithPaymailPikeSupport(),
+	)
+	defer deferMe()
+
+	assert.True(t, isContactLoaded(client))
+	capabilities := client.GetPaymailConfig().EnrichCapabilities(testDomain)
+	assert.Equal(t,
+		"https://"+testDomain+"/v1/"+paymail.DefaultServiceName+pikeInvitePath+"paymail@"+testDomain,
+		getPikeURL(capabilities, BRFCPikeInvite, "paymail", testDomain),
+	)
+	assert.NotEmpty(t, getPikeURL(capabilities, BRFCPikeOutputs, "paymail", testDomain))
+}
@@ -0,0 +1,231 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BuxOrg/bux/notifications"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoinschema/go-bitcoin/v2"
+)
+
+// DraftApprovalStatus is the approval status of a draft that needs N-of-M approvals (see SpendingPolicyConfig)
+type DraftApprovalStatus string
+
+const (
+	// DraftApprovalPending is when the draft is waiting for the approvals
+	DraftApprovalPending DraftApprovalStatus = "pending-approval"
+
+	// DraftApprovalApproved is when the draft got the required approvals and can be recorded
+	DraftApprovalApproved DraftApprovalStatus = "approved"
+
+	// DraftApprovalRejected is when the draft can no longer get the required approvals (the draft is canceled)
+	DraftApprovalRejected DraftApprovalStatus = "rejected"
+)
+
+// DraftApprover is a named approver identity, statements are signed with the private key of PubKey
+type DraftApprover struct {
+	Name   string `json:"name" toml:"name" yaml:"name" bson:"name"`
+	PubKey string `json:"pub_key" toml:"pub_key" yaml:"pub_key" bson:"pub_key"` // Compressed public key (hex)
+}
+
+// DraftApproval is the approval of a draft: the approvers (copied from the policy) and their signed statements
+type DraftApproval struct {
+	Approvers  []*DraftApprover          `json:"approvers,omitempty" toml:"approvers" yaml:"approvers" bson:"approvers,omitempty"`
+	Required   uint32                    `json:"required,omitempty" toml:"required" yaml:"required" bson:"required,omitempty"`
+	Statements []*DraftApprovalStatement `json:"statements,omitempty" toml:"statements" yaml:"statements" bson:"statements,omitempty"`
+}
+
+// DraftApprovalStatement is the signed approval (or rejection) of a draft by an approver
+type DraftApprovalStatement struct {
+	Approved  bool      `json:"approved" toml:"approved" yaml:"approved" bson:"approved"`
+	Approver  string    `json:"approver" toml:"approver" yaml:"approver" bson:"approver"`
+	Signature string    `json:"signature" toml:"signature" yaml:"signature" bson:"signature"`
+	SignedAt  time.Time `json:"signed_at" toml:"signed_at" yaml:"signed_at" bson:"signed_at"`
+}
+
+// GetDraftApprovalMessage will get the message an approver signs (bitcoin signed message) to approve or reject the draft
+//
+// The message commits to the draft ID and the draft hex, so a statement can not be used for another transaction
+func GetDraftApprovalMessage(draft *DraftTransaction, approved bool) string {
+	action := "reject"
+	if approved {
+		action = "approve"
+	}
+	return fmt.Sprintf("%s draft %s %s", action, draft.ID, utils.Hash(draft.Hex))
+}
+
+// SignDraftApproval will sign the approval (or rejection) of the draft with the private key (hex) of an approver
+func SignDraftApproval(privateKey string, draft *DraftTransaction, approved bool) (string, error) {
+	return bitcoin.SignMessage(privateKey, GetDraftApprovalMessage(draft, approved), true)
+}
+
+// validateApprovers will check the approvers and the number of required approvals of a policy
+func validateApprovers(approvers []*DraftApprover, required uint32) error {
+	if int(required) > len(approvers) {
+		return ErrInvalidDraftApprovers
+	}
+
+	names := make(map[string]bool, len(approvers))
+	for _, approver := range approvers {
+		if approver == nil || len(strings.TrimSpace(approver.Name)) == 0 || names[approver.Name] {
+			return ErrInvalidDraftApprovers
+		}
+		names[approver.Name] = true
+
+		if pubKey, err := hex.DecodeString(approver.PubKey); err != nil || len(pubKey) != 33 {
+			return ErrInvalidDraftApprovers
+		}
+	}
+	return nil
+}
+
+// getApprover will get the approver by name
+func (a *DraftApproval) getApprover(name string) *DraftApprover {
+	for _, approver := range a.Approvers {
+		if approver.Name == name {
+			return approver
+		}
+	}
+	return nil
+}
+
+// getStatus will get the approval status from the statements
+//
+// The draft is rejected once the rejections make the required approvals impossible
+func (a *DraftApproval) getStatus() DraftApprovalStatus {
+	var approvals, rejections int
+	for _, statement := range a.Statements {
+		if statement.Approved {
+			approvals++
+		} else {
+			rejections++
+		}
+	}
+
+	if approvals >= int(a.Required) {
+		return DraftApprovalApproved
+	} else if rejections > len(a.Approvers)-int(a.Required) {
+		return DraftApprovalRejected
+	}
+	return DraftApprovalPending
+}
+
+// setApproval will set the approvers of the spending policy of the xPub on the draft (if the policy requires approvals)
+func (m *DraftTransaction) setApproval(ctx context.Context) error {
+	policy, err := getActiveSpendingPolicy(ctx, m.Client(), m.XpubID, m.GetOptions(false)...)
+	if err != nil || policy == nil || policy.Config.RequiredApprovals == 0 {
+		return err
+	}
+
+	m.Approval = DraftApproval{
+		Approvers: policy.Config.Approvers,
+		Required:  policy.Config.RequiredApprovals,
+	}
+	m.ApprovalStatus = DraftApprovalPending
+	return nil
+}
+
+// checkApproval will check that a draft that needs approvals is approved (and not expired) before it is recorded
+func (m *DraftTransaction) checkApproval() error {
+	switch m.ApprovalStatus {
+	case DraftApprovalPending:
+		return ErrDraftNotApproved
+	case DraftApprovalRejected:
+		return ErrDraftRejected
+	case DraftApprovalApproved:
+		if time.Now().UTC().After(m.ExpiresAt) {
+			return ErrDraftExpired
+		}
+	}
+	return nil
+}
+
+// addApprovalStatement will verify the signed statement of the approver and update the approval status
+//
+// A rejected draft is canceled, which releases its utxos
+func (m *DraftTransaction) addApprovalStatement(approverName, signature string, approved bool) error {
+	if len(m.ApprovalStatus) == 0 {
+		return ErrDraftApprovalNotRequired
+	} else if m.ApprovalStatus != DraftApprovalPending || m.Status != DraftStatusDraft {
+		return ErrDraftApprovalClosed
+	} else if time.Now().UTC().After(m.ExpiresAt) {
+		return ErrDraftExpired
+	}
+
+	approver := m.Approval.getApprover(approverName)
+	if approver == nil {
+		return ErrUnknownDraftApprover
+	}
+	for _, statement := range m.Approval.Statements {
+		if statement.Approver == approverName {
+			return ErrDuplicateDraftApproval
+		}
+	}
+
+	// Verify the signature of the statement
+	address, err := bitcoin.GetAddressFromPubKeyString(approver.PubKey, true)
+	if err != nil {
+		return err
+	}
+	if err = bitcoin.VerifyMessage(
+		address.AddressString, signature, GetDraftApprovalMessage(m, approved),
+	); err != nil {
+		return ErrSignatureInvalid
+	}
+
+	m.Approval.Statements = append(m.Approval.Statements, &DraftApprovalStatement{
+		Approved:  approved,
+		Approver:  approverName,
+		Signature: signature,
+		SignedAt:  time.Now().UTC(),
+	})
+	m.ApprovalStatus = m.Approval.getStatus()
+	if m.ApprovalStatus == DraftApprovalRejected {
+		m.Status = DraftStatusCanceled
+	}
+	return nil
+}
+
+// notifyApproval will notify about an approval event of the draft (approvals requested, approved or rejected)
+func (m *DraftTransaction) notifyApproval() {
+	if len(m.ApprovalStatus) > 0 {
+		notify(notifications.EventTypeApproval, m)
+	}
+}
+
+// Scan will scan the value into Struct, implements sql.Scanner interface
+func (a *DraftApproval) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	xType := fmt.Sprintf("%T", value)
+	var byteValue []byte
+	if xType == ValueTypeString {
+		byteValue = []byte(value.(string))
+	} else {
+		byteValue = value.([]byte)
+	}
+	if bytes.Equal(byteValue, []byte("")) || bytes.Equal(byteValue, []byte("\"\"")) {
+		return nil
+	}
+
+	return json.Unmarshal(byteValue, &a)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (a DraftApproval) Value() (driver.Value, error) {
+	marshal, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(marshal), nil
+}
@@ -0,0 +1,214 @@
+package bux
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitcoinschema/go-bitcoin/v2"
+	"github.com/libsv/go-bt/v2"
+	"github.com/libsv/go-bt/v2/bscript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testApprover is an approver and its private key (hex)
+type testApprover struct {
+	*DraftApprover
+	privateKey string
+}
+
+// newTestApprovers will create named approvers with new keys
+func newTestApprovers(t *testing.T, names ...string) []*testApprover {
+	approvers := make([]*testApprover, 0, len(names))
+	for _, name := range names {
+		privateKey, err := bitcoin.CreatePrivateKeyString()
+		require.NoError(t, err)
+
+		var pubKey string
+		pubKey, err = bitcoin.PubKeyFromPrivateKeyString(privateKey, true)
+		require.NoError(t, err)
+
+		approvers = append(approvers, &testApprover{
+			DraftApprover: &DraftApprover{Name: name, PubKey: pubKey},
+			privateKey:    privateKey,
+		})
+	}
+	return approvers
+}
+
+// initDraftApprovalTestCase will set a spending policy requiring 2 of the 3 approvers
+func initDraftApprovalTestCase(t *testing.T, clientOpts ...ClientOps) (context.Context, ClientInterface,
+	[]*testApprover, func()) {
+
+	ctx, client, deferMe := initSpendingPolicyTestCase(t, clientOpts...)
+
+	approvers := newTestApprovers(t, "alice", "bob", "carol")
+	config := &SpendingPolicyConfig{RequiredApprovals: 2}
+	for _, approver := range approvers {
+		config.Approvers = append(config.Approvers, approver.DraftApprover)
+	}
+	_, err := client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, config)
+	require.NoError(t, err)
+
+	return ctx, client, approvers, deferMe
+}
+
+// signStatement will sign the approval (or rejection) of the draft by the approver
+func (a *testApprover) signStatement(t *testing.T, draft *DraftTransaction, approved bool) string {
+	signature, err := SignDraftApproval(a.privateKey, draft, approved)
+	require.NoError(t, err)
+	return signature
+}
+
+func TestDraftApproval_getStatus(t *testing.T) {
+	approval := &DraftApproval{
+		Approvers: []*DraftApprover{{Name: "alice"}, {Name: "bob"}, {Name: "carol"}},
+		Required:  2,
+	}
+	assert.Equal(t, DraftApprovalPending, approval.getStatus())
+
+	approval.Statements = append(approval.Statements, &DraftApprovalStatement{Approver: "alice", Approved: false})
+	assert.Equal(t, DraftApprovalPending, approval.getStatus())
+
+	approval.Statements = append(approval.Statements, &DraftApprovalStatement{Approver: "bob", Approved: true})
+	assert.Equal(t, DraftApprovalPending, approval.getStatus())
+
+	approval.Statements = append(approval.Statements, &DraftApprovalStatement{Approver: "carol", Approved: false})
+	assert.Equal(t, DraftApprovalRejected, approval.getStatus())
+
+	approval.Statements[0].Approved = true
+	assert.Equal(t, DraftApprovalApproved, approval.getStatus())
+}
+
+func Test_validateApprovers(t *testing.T) {
+	approvers := newTestApprovers(t, "alice", "bob")
+
+	assert.NoError(t, validateApprovers(nil, 0))
+	assert.NoError(t, validateApprovers([]*DraftApprover{approvers[0].DraftApprover, approvers[1].DraftApprover}, 2))
+
+	assert.ErrorIs(t, validateApprovers([]*DraftApprover{approvers[0].DraftApprover}, 2), ErrInvalidDraftApprovers)
+	assert.ErrorIs(t, validateApprovers(
+		[]*DraftApprover{approvers[0].DraftApprover, approvers[0].DraftApprover}, 1,
+	), ErrInvalidDraftApprovers)
+	assert.ErrorIs(t, validateApprovers(
+		[]*DraftApprover{{Name: "dave", PubKey: testXPub}}, 1,
+	), ErrInvalidDraftApprovers)
+}
+
+func TestClient_ApproveDraftTransaction(t *testing.T) {
+	t.Run("no approvals needed", func(t *testing.T) {
+		ctx, client, deferMe := initSpendingPolicyTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, draft.ApprovalStatus)
+
+		_, err = client.ApproveDraftTransaction(ctx, draft.ID, "alice", "signature")
+		assert.ErrorIs(t, err, ErrDraftApprovalNotRequired)
+	})
+
+	t.Run("2 of 3 approvals", func(t *testing.T) {
+		signer, err := NewXPrivDraftSigner(testXPriv)
+		require.NoError(t, err)
+
+		ctx, client, approvers, deferMe := initDraftApprovalTestCase(t, WithDraftSigner(signer))
+		defer deferMe()
+
+		var draft *DraftTransaction
+		draft, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, DraftApprovalPending, draft.ApprovalStatus)
+		assert.Len(t, draft.Approval.Approvers, 3)
+
+		_, err = client.(*Client).signAndRecordDraft(ctx, testXPub, draft)
+		assert.ErrorIs(t, err, ErrDraftNotApproved)
+
+		_, err = client.ApproveDraftTransaction(ctx, draft.ID, "dave", approvers[0].signStatement(t, draft, true))
+		assert.ErrorIs(t, err, ErrUnknownDraftApprover)
+
+		// signed by another approver, or signed as a rejection
+		_, err = client.ApproveDraftTransaction(ctx, draft.ID, "alice", approvers[1].signStatement(t, draft, true))
+		assert.ErrorIs(t, err, ErrSignatureInvalid)
+		_, err = client.ApproveDraftTransaction(ctx, draft.ID, "alice", approvers[0].signStatement(t, draft, false))
+		assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+		draft, err = client.ApproveDraftTransaction(ctx, draft.ID, "alice", approvers[0].signStatement(t, draft, true))
+		require.NoError(t, err)
+		assert.Equal(t, DraftApprovalPending, draft.ApprovalStatus)
+
+		_, err = client.RejectDraftTransaction(ctx, draft.ID, "alice", approvers[0].signStatement(t, draft, false))
+		assert.ErrorIs(t, err, ErrDuplicateDraftApproval)
+
+		draft, err = client.ApproveDraftTransaction(ctx, draft.ID, "bob", approvers[1].signStatement(t, draft, true))
+		require.NoError(t, err)
+		assert.Equal(t, DraftApprovalApproved, draft.ApprovalStatus)
+		assert.Len(t, draft.Approval.Statements, 2)
+
+		_, err = client.ApproveDraftTransaction(ctx, draft.ID, "carol", approvers[2].signStatement(t, draft, true))
+		assert.ErrorIs(t, err, ErrDraftApprovalClosed)
+
+		// a (validly signed) transaction paying another address is not the approved draft
+		var tx *bt.Tx
+		tx, err = bt.NewTxFromString(draft.Hex)
+		require.NoError(t, err)
+		tx.Outputs[0].LockingScript, err = bscript.NewP2PKHFromAddress(testExternalAddress)
+		require.NoError(t, err)
+
+		tampered := &DraftTransaction{Configuration: draft.Configuration}
+		tampered.Hex = tx.String()
+		var tamperedHex string
+		tamperedHex, err = tampered.SignInputsWithKey(testXPriv)
+		require.NoError(t, err)
+		_, err = client.RecordTransaction(ctx, testXPub, tamperedHex, draft.ID)
+		assert.ErrorIs(t, err, ErrDraftTransactionMismatch)
+
+		var transaction *Transaction
+		transaction, err = client.(*Client).signAndRecordDraft(ctx, testXPub, draft)
+		require.NoError(t, err)
+		assert.Equal(t, draft.ID, transaction.DraftID)
+	})
+
+	t.Run("rejected drafts are canceled", func(t *testing.T) {
+		ctx, client, approvers, deferMe := initDraftApprovalTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+
+		_, err = client.RejectDraftTransaction(ctx, draft.ID, "alice", approvers[0].signStatement(t, draft, false))
+		require.NoError(t, err)
+		draft, err = client.RejectDraftTransaction(ctx, draft.ID, "carol", approvers[2].signStatement(t, draft, false))
+		require.NoError(t, err)
+		assert.Equal(t, DraftApprovalRejected, draft.ApprovalStatus)
+		assert.Equal(t, DraftStatusCanceled, draft.Status)
+
+		// the utxo was released
+		_, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("expired drafts", func(t *testing.T) {
+		ctx, client, approvers, deferMe := initDraftApprovalTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			ExpiresIn: time.Millisecond,
+			Outputs:   []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = client.ApproveDraftTransaction(ctx, draft.ID, "alice", approvers[0].signStatement(t, draft, true))
+		assert.ErrorIs(t, err, ErrDraftExpired)
+	})
+}
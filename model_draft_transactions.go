@@ -9,8 +9,6 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/bitcoinschema/go-bitcoin/v2"
-	"github.com/libsv/go-bk/bec"
 	"github.com/libsv/go-bk/bip32"
 	"github.com/libsv/go-bt/v2"
 	"github.com/libsv/go-bt/v2/bscript"
@@ -33,12 +31,15 @@ type DraftTransaction struct {
 	TransactionBase `bson:",inline"`
 
 	// Model specific fields
-	XpubID        string            `json:"xpub_id" toml:"xpub_id" yaml:"xpub_id" gorm:"<-:create;type:char(64);index;comment:This is the related xPub" bson:"xpub_id"`
-	ExpiresAt     time.Time         `json:"expires_at" toml:"expires_at" yaml:"expires_at" gorm:"<-:create;comment:Time when the draft expires" bson:"expires_at"`
-	Configuration TransactionConfig `json:"configuration" toml:"configuration" yaml:"configuration" gorm:"<-;type:text;comment:This is the configuration struct in JSON" bson:"configuration"`
-	Status        DraftStatus       `json:"status" toml:"status" yaml:"status" gorm:"<-;type:varchar(10);index;comment:This is the status of the draft" bson:"status"`
-	FinalTxID     string            `json:"final_tx_id,omitempty" toml:"final_tx_id" yaml:"final_tx_id" gorm:"<-;type:char(64);index;comment:This is the final tx ID" bson:"final_tx_id,omitempty"`
-	BUMPs         BUMPs             `json:"bumps,omitempty" toml:"bumps" yaml:"bumps" gorm:"<-;type:text;comment:Slice of BUMPs (BSV Unified Merkle Paths)" bson:"bumps,omitempty"`
+	XpubID         string              `json:"xpub_id" toml:"xpub_id" yaml:"xpub_id" gorm:"<-:create;type:char(64);index;comment:This is the related xPub" bson:"xpub_id"`
+	ExpiresAt      time.Time           `json:"expires_at" toml:"expires_at" yaml:"expires_at" gorm:"<-:create;comment:Time when the draft expires" bson:"expires_at"`
+	Configuration  TransactionConfig   `json:"configuration" toml:"configuration" yaml:"configuration" gorm:"<-;type:text;comment:This is the configuration struct in JSON" bson:"configuration"`
+	Status         DraftStatus         `json:"status" toml:"status" yaml:"status" gorm:"<-;type:varchar(10);index;comment:This is the status of the draft" bson:"status"`
+	FinalTxID      string              `json:"final_tx_id,omitempty" toml:"final_tx_id" yaml:"final_tx_id" gorm:"<-;type:char(64);index;comment:This is the final tx ID" bson:"final_tx_id,omitempty"`
+	BUMPs          BUMPs               `json:"bumps,omitempty" toml:"bumps" yaml:"bumps" gorm:"<-;type:text;comment:Slice of BUMPs (BSV Unified Merkle Paths)" bson:"bumps,omitempty"`
+	ApprovalStatus DraftApprovalStatus `json:"approval_status,omitempty" toml:"approval_status" yaml:"approval_status" gorm:"<-;type:varchar(16);index;comment:This is the approval status (if the draft needs approvals)" bson:"approval_status,omitempty"`
+	Approval       DraftApproval       `json:"approval" toml:"approval" yaml:"approval" gorm:"<-;type:text;comment:This is the approvers and their statements in JSON" bson:"approval"`
+	AccessKeyID    string              `json:"access_key_id,omitempty" toml:"access_key_id" yaml:"access_key_id" gorm:"<-;type:char(64);comment:This is the access key the spend of the draft is counted on" bson:"access_key_id,omitempty"`
 }
 
 // newDraftTransaction will start a new draft tx
@@ -173,6 +174,14 @@ func (m *DraftTransaction) processConfigOutputs(ctx context.Context) error {
 	if m.Configuration.SendAllTo != nil {
 		outputs := m.Configuration.Outputs
 
+		// No address: send all funds to a new change destination of the xPub (derived for the draft)
+		if len(m.Configuration.SendAllTo.To) == 0 {
+			if err := m.setChangeDestinations(ctx, 1); err != nil {
+				return err
+			}
+			m.Configuration.SendAllTo.To = m.Configuration.ChangeDestinations[0].Address
+		}
+
 		m.Configuration.SendAllTo.UseForChange = true
 		m.Configuration.SendAllTo.Satoshis = 0
 		m.Configuration.Outputs = []*TransactionOutput{m.Configuration.SendAllTo}
@@ -210,8 +219,16 @@ func (m *DraftTransaction) processConfigOutputs(ctx context.Context) error {
 				m.Configuration.Outputs[index].Scripts = make([]*ScriptOutput, 0)
 			}
 
+			// Payments to a confirmed contact use the outputs issued by the contact
+			processed, err := m.processPikeOutput(ctx, m.Configuration.Outputs[index], paymailFrom)
+			if err != nil {
+				return err
+			} else if processed {
+				continue
+			}
+
 			// Process the outputs
-			if err := m.Configuration.Outputs[index].processOutput(
+			if err = m.Configuration.Outputs[index].processOutput(
 				ctx, c.Cachestore(),
 				c.PaymailClient(),
 				paymailFrom,
@@ -228,8 +245,8 @@ func (m *DraftTransaction) processConfigOutputs(ctx context.Context) error {
 
 // createTransactionHex will create the transaction with the given inputs and outputs
 func (m *DraftTransaction) createTransactionHex(ctx context.Context) (err error) {
-	// Check that we have outputs
-	if len(m.Configuration.Outputs) == 0 && m.Configuration.SendAllTo == nil {
+	// Check that we have outputs (a split only has change outputs)
+	if len(m.Configuration.Outputs) == 0 && m.Configuration.SendAllTo == nil && !m.isSplit() {
 		return ErrMissingTransactionOutputs
 	}
 
@@ -295,17 +312,28 @@ func (m *DraftTransaction) createTransactionHex(ctx context.Context) (err error)
 			}
 		}
 
+		// a split needs the satoshis (and the fee) of all its change destinations
+		selectionSatoshis := satoshisNeeded
+		if m.isSplit() {
+			numberOfDestinations := uint64(m.Configuration.ChangeNumberOfDestinations)
+			selectionSatoshis = numberOfDestinations*m.Configuration.ChangeMinimumSatoshis +
+				m.estimateFee(m.Configuration.FeeUnit, numberOfDestinations*changeOutputSize)
+		}
+
 		// Reserve and Get utxos for the transaction
 		var reservedUtxos []*Utxo
-		feePerByte := float64(m.Configuration.FeeUnit.Satoshis / m.Configuration.FeeUnit.Bytes)
-
-		reserveSatoshis := satoshisNeeded + m.estimateFee(m.Configuration.FeeUnit, 0)
+		reserveSatoshis := selectionSatoshis + m.estimateFee(m.Configuration.FeeUnit, 0)
 		if reserveSatoshis <= dustLimit && !m.containsOpReturn() {
 			m.client.Logger().Error(ctx, "amount of satoshis to send less than the dust limit")
 			return ErrOutputValueTooLow
 		}
+
+		var selector UtxoSelector
+		if selector, err = m.client.GetUtxoSelector(m.Configuration.UtxoSelectionStrategy); err != nil {
+			return
+		}
 		if reservedUtxos, err = reserveUtxos(
-			ctx, m.XpubID, m.ID, reserveSatoshis, feePerByte, m.Configuration.FromUtxos, opts...,
+			ctx, m.XpubID, m.ID, m.utxoSelectionTarget(selectionSatoshis), selector, m.Configuration.FromUtxos, opts...,
 		); err != nil {
 			return
 		}
@@ -333,11 +361,19 @@ func (m *DraftTransaction) createTransactionHex(ctx context.Context) (err error)
 	if err = tx.FromUTXOs(*inputUtxos...); err != nil {
 		return
 	}
+	if err = m.setLockTime(tx); err != nil {
+		return
+	}
 
 	// Estimate the fee for the transaction
 	fee := m.estimateFee(m.Configuration.FeeUnit, 0)
 	if m.Configuration.SendAllTo != nil {
-		if m.Configuration.Outputs[0].Satoshis <= dustLimit {
+		// a child (CPFP) must also cover the fee, it can be more than the satoshis of the spent outputs
+		minimumSatoshis := dustLimit
+		if m.Configuration.ChildPaysForParent != nil {
+			minimumSatoshis += fee
+		}
+		if m.Configuration.Outputs[0].Satoshis <= minimumSatoshis {
 			return ErrOutputValueTooLow
 		}
 
@@ -357,8 +393,15 @@ func (m *DraftTransaction) createTransactionHex(ctx context.Context) (err error)
 			return ErrNotEnoughUtxos
 		}
 
-		// if we have a remainder, add that to an output to our own wallet address
+		// branch and bound avoids change, a remainder that is not worth a change output goes to the miner
 		satoshisChange := satoshisReserved - satoshisNeeded - fee
+		if m.Configuration.UtxoSelectionStrategy == UtxoSelectionBranchAndBound &&
+			satoshisChange <= m.changeCost() {
+			fee += satoshisChange
+			satoshisChange = 0
+		}
+
+		// if we have a remainder, add that to an output to our own wallet address
 		m.Configuration.Fee = fee
 		if satoshisChange > 0 {
 			var newFee uint64
@@ -500,7 +543,35 @@ func (m *DraftTransaction) estimateSize() uint64 {
 func (m *DraftTransaction) estimateFee(unit *utils.FeeUnit, addToSize uint64) uint64 {
 	size := m.estimateSize() + addToSize
 	feeEstimate := float64(size) * (float64(unit.Satoshis) / float64(unit.Bytes))
-	return uint64(math.Ceil(feeEstimate))
+	fee := uint64(math.Ceil(feeEstimate))
+
+	// A child (CPFP) also pays what is missing from the fee of the parent
+	if m.Configuration.ChildPaysForParent != nil {
+		fee += m.Configuration.ChildPaysForParent.missingFee(unit)
+	}
+	return fee
+}
+
+// utxoSelectionTarget will return the target for selecting the utxos, the fee is estimated with the selected inputs
+func (m *DraftTransaction) utxoSelectionTarget(satoshis uint64) *UtxoSelectionTarget {
+	return &UtxoSelectionTarget{
+		ChangeCost: m.changeCost(),
+		Fee: func(inputs []*Utxo) uint64 {
+			var inputsSize uint64
+			for _, utxo := range inputs {
+				inputsSize += utils.GetInputSizeForType(utxo.Type)
+			}
+			return m.estimateFee(m.Configuration.FeeUnit, inputsSize)
+		},
+		Satoshis: satoshis,
+	}
+}
+
+// changeCost will return the cost of a change output (adding it now and spending it later)
+func (m *DraftTransaction) changeCost() uint64 {
+	size := changeOutputSize + utils.GetInputSizeForType(utils.ScriptTypePubKeyHash)
+	unit := m.Configuration.FeeUnit
+	return uint64(math.Ceil(float64(size) * (float64(unit.Satoshis) / float64(unit.Bytes))))
 }
 
 // addOutputs will add the given outputs to the bt.Tx
@@ -710,6 +781,82 @@ func (m *DraftTransaction) setChangeDestinations(ctx context.Context, numberOfDe
 	return nil
 }
 
+// setLockTime will set the lock time and the input sequences of the configuration on the transaction
+func (m *DraftTransaction) setLockTime(tx *bt.Tx) error {
+	// Every configured sequence must be for an input of the transaction
+	for _, sequence := range m.Configuration.Sequences {
+		if !txSpendsUtxo(tx, &sequence.UtxoPointer) {
+			return ErrInvalidSequence
+		}
+	}
+
+	tx.LockTime = m.Configuration.LockTime
+	for _, input := range tx.Inputs {
+		input.SequenceNumber = m.Configuration.getSequence(&UtxoPointer{
+			TransactionID: input.PreviousTxIDStr(),
+			OutputIndex:   input.PreviousTxOutIndex,
+		})
+	}
+	return nil
+}
+
+// validateLockTime will check that the lock time and the input sequences of the transaction match the configuration
+func (m *DraftTransaction) validateLockTime(tx *bt.Tx) error {
+	if tx.LockTime != m.Configuration.LockTime {
+		return ErrLockTimeMismatch
+	}
+	for _, input := range tx.Inputs {
+		if input.SequenceNumber != m.Configuration.getSequence(&UtxoPointer{
+			TransactionID: input.PreviousTxIDStr(),
+			OutputIndex:   input.PreviousTxOutIndex,
+		}) {
+			return ErrSequenceMismatch
+		}
+	}
+	return nil
+}
+
+// validateDraftTx will check that the transaction is the draft transaction (same version, inputs, sequences,
+// outputs and lock time), the unlocking scripts are not checked
+func (m *DraftTransaction) validateDraftTx(tx *bt.Tx) error {
+	draftTx, err := bt.NewTxFromString(m.Hex)
+	if err != nil {
+		return err
+	}
+
+	if tx.LockTime != draftTx.LockTime {
+		return ErrLockTimeMismatch
+	} else if tx.Version != draftTx.Version ||
+		len(tx.Inputs) != len(draftTx.Inputs) || len(tx.Outputs) != len(draftTx.Outputs) {
+		return ErrDraftTransactionMismatch
+	}
+	for index, output := range draftTx.Outputs {
+		if tx.Outputs[index].Satoshis != output.Satoshis ||
+			!tx.Outputs[index].LockingScript.Equals(output.LockingScript) {
+			return ErrDraftTransactionMismatch
+		}
+	}
+	for index, input := range draftTx.Inputs {
+		if tx.Inputs[index].PreviousTxIDStr() != input.PreviousTxIDStr() ||
+			tx.Inputs[index].PreviousTxOutIndex != input.PreviousTxOutIndex {
+			return ErrDraftTransactionMismatch
+		} else if tx.Inputs[index].SequenceNumber != input.SequenceNumber {
+			return ErrSequenceMismatch
+		}
+	}
+	return nil
+}
+
+// txSpendsUtxo will return true if the transaction has an input spending the utxo
+func txSpendsUtxo(tx *bt.Tx, pointer *UtxoPointer) bool {
+	for _, input := range tx.Inputs {
+		if input.PreviousTxIDStr() == pointer.TransactionID && input.PreviousTxOutIndex == pointer.OutputIndex {
+			return true
+		}
+	}
+	return false
+}
+
 // getInputsFromUtxos this function transforms bux utxos to bt.UTXOs
 func (m *DraftTransaction) getInputsFromUtxos(reservedUtxos []*Utxo) (*[]*bt.UTXO, uint64, error) {
 	// transform to bt.utxo and check if we have enough
@@ -753,19 +900,229 @@ func (m *DraftTransaction) getTotalSatoshis() (satoshis uint64) {
 	return
 }
 
+// isSplit will return true if the draft splits the utxos into its change destinations (no outputs)
+func (m *DraftTransaction) isSplit() bool {
+	return len(m.Configuration.Outputs) == 0 && m.Configuration.SendAllTo == nil &&
+		m.Configuration.ChangeNumberOfDestinations > 0
+}
+
 // BeforeCreating will fire before the model is being inserted into the Datastore
 func (m *DraftTransaction) BeforeCreating(ctx context.Context) (err error) {
 	m.DebugLog("starting: " + m.Name() + " BeforeCreating hook...")
 
+	// Check the scope of the access key (if created with one)
+	if err = checkAccessKeyScope(ctx, AccessKeyScopeCreateTransaction); err != nil {
+		return
+	}
+
 	// Prepare the transaction
 	if err = m.createTransactionHex(ctx); err != nil {
 		return
 	}
 
+	// Check the spending policy of the xPub (if it has one)
+	if err = m.checkSpendingPolicy(ctx); err != nil {
+		return
+	}
+
+	// Require the approvals of the spending policy (if it has approvers)
+	if err = m.setApproval(ctx); err != nil {
+		return
+	}
+
+	// Check and count the spend on the access key (if created with one)
+	if err = m.addAccessKeySpend(ctx); err != nil {
+		return
+	}
+
 	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
 	return
 }
 
+// AfterCreated will fire after the model is created in the Datastore
+func (m *DraftTransaction) AfterCreated(ctx context.Context) error {
+	m.DebugLog("starting: " + m.Name() + " AfterCreated hook...")
+
+	// Notify the approvers (if the draft needs approvals)
+	m.notifyApproval()
+
+	m.DebugLog("end: " + m.Name() + " AfterCreated hook")
+	return nil
+}
+
+// getSpentSatoshis will get the satoshis leaving the xPub (outputs that are not change, plus the fee)
+func (m *DraftTransaction) getSpentSatoshis() uint64 {
+	return m.getTotalSatoshis() - m.Configuration.ChangeSatoshis + m.Configuration.Fee
+}
+
+// addAccessKeySpend will check the spent satoshis of the draft against the spend caps of the access key
+// of the context (if it belongs to the xPub) and count them on the key
+func (m *DraftTransaction) addAccessKeySpend(ctx context.Context) error {
+	contextKey := accessKeyFromContext(ctx)
+	if contextKey == nil || contextKey.XpubID != m.XpubID {
+		return nil
+	}
+
+	// The spend of a rotated key is counted on its successor (the keys share the spend cap)
+	id := contextKey.ID
+	for len(id) > 0 {
+		accessKeyID := id
+		var err error
+		if id, err = m.updateAccessKeySpend(ctx, accessKeyID, true); err != nil {
+			return err
+		} else if len(id) == 0 {
+			m.AccessKeyID = accessKeyID
+		}
+	}
+	return nil
+}
+
+// refundAccessKeySpend will take the spent satoshis of the (canceled or expired) draft off the access key
+// (or off its successor if the key was rotated since)
+func (m *DraftTransaction) refundAccessKeySpend(ctx context.Context) error {
+	for id := m.AccessKeyID; len(id) > 0; {
+		var err error
+		if id, err = m.updateAccessKeySpend(ctx, id, false); err != nil {
+			return err
+		}
+	}
+	m.AccessKeyID = ""
+	return nil
+}
+
+// updateAccessKeySpend will add (or refund) the spent satoshis of the draft on the access key, under the lock of
+// the key. If the key was rotated, nothing is updated and the ID of the successor is returned.
+func (m *DraftTransaction) updateAccessKeySpend(ctx context.Context, id string, add bool) (string, error) {
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyAccessKeySpend, id), m.Client().Cachestore())
+	defer unlock()
+	if err != nil {
+		return "", err
+	}
+
+	// Get the key fresh (under the lock), the key in the context can be stale
+	var accessKey *AccessKey
+	if accessKey, err = getAccessKey(ctx, id, m.GetOptions(false)...); err != nil {
+		return "", err
+	} else if accessKey == nil {
+		if add {
+			return "", ErrMissingAccessKey
+		}
+		return "", nil
+	} else if len(accessKey.SuccessorID) > 0 {
+		return accessKey.SuccessorID, nil
+	}
+
+	spent := m.getSpentSatoshis()
+	if add {
+		if err = accessKey.checkSpend(spent); err != nil {
+			return "", err
+		}
+		_, err = incrementField(ctx, accessKey, spentSatoshisField, int64(spent))
+		return "", err
+	}
+
+	if spent > accessKey.SpentSatoshis {
+		spent = accessKey.SpentSatoshis
+	}
+	_, err = incrementField(ctx, accessKey, spentSatoshisField, -int64(spent))
+	return "", err
+}
+
+// BeforeUpdating will fire before the model is updated in the Datastore
+func (m *DraftTransaction) BeforeUpdating(ctx context.Context) error {
+	m.DebugLog("starting: " + m.Name() + " BeforeUpdating hook...")
+
+	// Refund the spend on the access key (only once, the key is cleared on the draft)
+	if (m.Status == DraftStatusCanceled || m.Status == DraftStatusExpired) && len(m.AccessKeyID) > 0 {
+		if err := m.refundAccessKeySpend(ctx); err != nil {
+			return err
+		}
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeUpdating hook")
+	return nil
+}
+
+// getPolicySpend will get the outputs leaving the xPub and the satoshis they spend (plus the fee)
+//
+// Change outputs and outputs to destinations of the xPub are not leaving the xPub
+func (m *DraftTransaction) getPolicySpend(ctx context.Context) (*policySpend, error) {
+	changeAddresses := make(map[string]bool)
+	for _, destination := range m.Configuration.ChangeDestinations {
+		changeAddresses[destination.Address] = true
+	}
+
+	spend := &policySpend{Satoshis: m.Configuration.Fee}
+	for _, output := range m.Configuration.Outputs {
+		if output.Satoshis == 0 || changeAddresses[output.To] {
+			continue
+		}
+		if output.PaymailP4 == nil && len(output.To) > 0 {
+			destination, err := getDestinationWithCache(
+				ctx, m.Client(), "", output.To, "", m.GetOptions(false)...,
+			)
+			if err != nil && !errors.Is(err, ErrMissingDestination) {
+				return nil, err
+			} else if destination != nil && destination.XpubID == m.XpubID {
+				continue
+			}
+		}
+		spend.Outputs = append(spend.Outputs, output)
+		spend.Satoshis += output.Satoshis
+	}
+	return spend, nil
+}
+
+// checkSpendingPolicy will check the draft against the spending policy of the xPub (if it has one),
+// the decision is logged
+func (m *DraftTransaction) checkSpendingPolicy(ctx context.Context) error {
+	policy, err := getActiveSpendingPolicy(ctx, m.Client(), m.XpubID, m.GetOptions(false)...)
+	if err != nil || policy == nil {
+		return err
+	}
+
+	var spend *policySpend
+	if spend, err = m.getPolicySpend(ctx); err != nil {
+		return err
+	}
+
+	err = policy.evaluate(spend, m.Metadata, time.Now().UTC())
+	policy.logDecision(ctx, m.ID, spend, err)
+	return err
+}
+
+// addSpendingPolicySpend will check the (recorded) draft against the spending policy of the xPub (if it has one)
+// and count its spend for the caps, in one step under the lock of the policy
+func (m *DraftTransaction) addSpendingPolicySpend(ctx context.Context) error {
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeySpendingPolicy, m.XpubID), m.Client().Cachestore())
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	var policy *SpendingPolicy
+	if policy, err = getActiveSpendingPolicy(
+		ctx, m.Client(), m.XpubID, m.GetOptions(false)...,
+	); err != nil || policy == nil {
+		return err
+	}
+
+	var spend *policySpend
+	if spend, err = m.getPolicySpend(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = policy.evaluate(spend, m.Metadata, now)
+	policy.logDecision(ctx, m.ID, spend, err)
+	if err != nil {
+		return err
+	}
+
+	policy.addSpend(spend.Satoshis, now)
+	return policy.Save(ctx)
+}
+
 // AfterUpdated will fire after a successful update into the Datastore
 func (m *DraftTransaction) AfterUpdated(ctx context.Context) error {
 	m.DebugLog("starting: " + m.Name() + " AfterUpdated hook...")
@@ -848,61 +1205,35 @@ func (m *DraftTransaction) SignInputsWithKey(xPrivKey string) (signedHex string,
 }
 
 // SignInputs will sign all the inputs using the given xPriv key
+//
+// The signatures commit to the lock time and the input sequences of the draft
 func (m *DraftTransaction) SignInputs(xPriv *bip32.ExtendedKey) (signedHex string, err error) {
+	return m.SignInputsWithSigner(context.Background(), &xPrivSigner{
+		xPrivs: map[string]*bip32.ExtendedKey{m.XpubID: xPriv},
+	})
+}
+
+// SignInputsWithSigner will sign all the inputs with the signer (the keys are not needed in-process)
+//
+// The signer gets the sighash and the derivation path of each input, the returned signatures and keys
+// are verified against the sighash and the locking script of the input.
+// The signatures commit to the lock time and the input sequences of the draft
+func (m *DraftTransaction) SignInputsWithSigner(ctx context.Context, signer Signer) (signedHex string, err error) {
 	// Start a bt draft transaction
 	var txDraft *bt.Tx
 	if txDraft, err = bt.NewTxFromString(m.Hex); err != nil {
 		return
 	}
 
+	// Make sure the hex was not built with a different lock time or sequences
+	if err = m.validateLockTime(txDraft); err != nil {
+		return
+	}
+
 	// Sign the inputs
 	for index, input := range m.Configuration.Inputs {
-
-		// Get the locking script
-		var ls *bscript.Script
-		if ls, err = bscript.NewFromHexString(
-			input.Destination.LockingScript,
-		); err != nil {
-			return
-		}
-		txDraft.Inputs[index].PreviousTxScript = ls
-		txDraft.Inputs[index].PreviousTxSatoshis = input.Satoshis
-
-		// Derive the child key (chain)
-		var chainKey *bip32.ExtendedKey
-		if chainKey, err = xPriv.Child(
-			input.Destination.Chain,
-		); err != nil {
-			return
-		}
-
-		// Derive the child key (num)
-		var numKey *bip32.ExtendedKey
-		if numKey, err = chainKey.Child(
-			input.Destination.Num,
-		); err != nil {
-			return
-		}
-
-		// Get the private key
-		var privateKey *bec.PrivateKey
-		if privateKey, err = bitcoin.GetPrivateKeyFromHDKey(
-			numKey,
-		); err != nil {
-			return
-		}
-
-		// Get the unlocking script
-		var s *bscript.Script
-		if s, err = utils.GetUnlockingScript(
-			txDraft, uint32(index), privateKey,
-		); err != nil {
-			return
-		}
-
-		// Insert the locking script
-		if err = txDraft.InsertInputUnlockingScript(
-			uint32(index), s,
+		if err = signTxInput(
+			ctx, signer, txDraft, index, m.ID, m.XpubID, m.Hex, newSigningPackageInput(input),
 		); err != nil {
 			return
 		}
@@ -888,6 +888,91 @@ func TestDraftTransaction_createTransaction(t *testing.T) {
 		err = draftTransaction.createTransactionHex(ctx)
 		require.ErrorIs(t, err, ErrDuplicateUTXOs)
 	})
+
+	t.Run("lock time", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		draftTransaction := newDraftTransaction(testXPub, &TransactionConfig{
+			LockTime: 800000,
+			Outputs: []*TransactionOutput{{
+				To:       testExternalAddress,
+				Satoshis: 1000,
+			}},
+		}, append(client.DefaultModelOptions(), New())...)
+
+		err := draftTransaction.createTransactionHex(ctx)
+		require.NoError(t, err)
+
+		var btTx *bt.Tx
+		btTx, err = bt.NewTxFromString(draftTransaction.Hex)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(800000), btTx.LockTime)
+		require.Len(t, btTx.Inputs, 1)
+		assert.Equal(t, bt.DefaultSequenceNumber-1, btTx.Inputs[0].SequenceNumber)
+
+		// the hex does not match the draft anymore
+		draftTransaction.Configuration.LockTime = 800001
+		_, err = draftTransaction.SignInputsWithKey(testXPriv)
+		assert.ErrorIs(t, err, ErrLockTimeMismatch)
+	})
+
+	t.Run("input sequences", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		draftTransaction := newDraftTransaction(testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{
+				To:       testExternalAddress,
+				Satoshis: 1000,
+			}},
+			Sequences: []*InputSequence{{
+				UtxoPointer: UtxoPointer{TransactionID: testTxID, OutputIndex: 0},
+				Sequence:    5,
+			}},
+		}, append(client.DefaultModelOptions(), New())...)
+
+		err := draftTransaction.createTransactionHex(ctx)
+		require.NoError(t, err)
+
+		var btTx *bt.Tx
+		btTx, err = bt.NewTxFromString(draftTransaction.Hex)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), btTx.LockTime)
+		require.Len(t, btTx.Inputs, 1)
+		assert.Equal(t, uint32(5), btTx.Inputs[0].SequenceNumber)
+
+		var signedHex string
+		signedHex, err = draftTransaction.SignInputsWithKey(testXPriv)
+		require.NoError(t, err)
+		btTx, err = bt.NewTxFromString(signedHex)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(5), btTx.Inputs[0].SequenceNumber)
+
+		// the hex does not match the draft anymore
+		draftTransaction.Configuration.Sequences[0].Sequence = 6
+		_, err = draftTransaction.SignInputsWithKey(testXPriv)
+		assert.ErrorIs(t, err, ErrSequenceMismatch)
+	})
+
+	t.Run("sequence for a utxo that is not an input", func(t *testing.T) {
+		ctx, client, deferMe := initSimpleTestCase(t)
+		defer deferMe()
+
+		draftTransaction := newDraftTransaction(testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{
+				To:       testExternalAddress,
+				Satoshis: 1000,
+			}},
+			Sequences: []*InputSequence{{
+				UtxoPointer: UtxoPointer{TransactionID: testTxID, OutputIndex: 1},
+				Sequence:    5,
+			}},
+		}, append(client.DefaultModelOptions(), New())...)
+
+		err := draftTransaction.createTransactionHex(ctx)
+		assert.ErrorIs(t, err, ErrInvalidSequence)
+	})
 }
 
 // TestDraftTransaction_setChangeDestination setting the change destination
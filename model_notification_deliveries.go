@@ -0,0 +1,180 @@
+package bux
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/BuxOrg/bux/notifications"
+	"github.com/BuxOrg/bux/taskmanager"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+	customTypes "github.com/mrz1836/go-datastore/custom_types"
+)
+
+// NotificationDelivery is an object representing a webhook notification waiting in the outbox for another attempt
+//
+// This model is loaded automatically when notifications are enabled via WithNotifications()
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type NotificationDelivery struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID             string                  `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the unique delivery id" bson:"_id"`
+	Endpoint       string                  `json:"endpoint" toml:"endpoint" yaml:"endpoint" gorm:"<-:create;type:text;comment:This is the webhook endpoint" bson:"endpoint"`
+	EventType      notifications.EventType `json:"event_type" toml:"event_type" yaml:"event_type" gorm:"<-:create;type:varchar(20);comment:This is the event type" bson:"event_type"`
+	ModelType      string                  `json:"model_type" toml:"model_type" yaml:"model_type" gorm:"<-:create;type:varchar(64);comment:This is the model type of the event" bson:"model_type"`
+	ModelID        string                  `json:"model_id" toml:"model_id" yaml:"model_id" gorm:"<-:create;type:varchar(255);comment:This is the model id of the event" bson:"model_id"`
+	SubscriptionID string                  `json:"subscription_id" toml:"subscription_id" yaml:"subscription_id" gorm:"<-:create;type:varchar(64);comment:This is the webhook subscription id (empty for the default webhook)" bson:"subscription_id"`
+	Payload        string                  `json:"payload" toml:"payload" yaml:"payload" gorm:"<-:create;type:text;comment:This is the JSON body of the webhook" bson:"payload"`
+	Status         DeliveryStatus          `json:"status" toml:"status" yaml:"status" gorm:"<-;type:varchar(20);index;comment:This is the status of the delivery" bson:"status"`
+	Attempts       uint32                  `json:"attempts" toml:"attempts" yaml:"attempts" gorm:"<-;comment:This is the number of delivery attempts" bson:"attempts"`
+	NextAttemptAt  time.Time               `json:"next_attempt_at" toml:"next_attempt_at" yaml:"next_attempt_at" gorm:"<-;index;comment:When the next delivery attempt is due" bson:"next_attempt_at"`
+	LastAttempt    customTypes.NullTime    `json:"last_attempt" toml:"last_attempt" yaml:"last_attempt" gorm:"<-;comment:When the last delivery attempt occurred" bson:"last_attempt,omitempty"`
+	LastError      string                  `json:"last_error" toml:"last_error" yaml:"last_error" gorm:"<-;type:text;comment:This is the error of the last attempt" bson:"last_error"`
+}
+
+// newNotificationDelivery will start a new model from a failed delivery (counts as the first attempt)
+//
+// The delivery id is kept as the model id, so every attempt is sent with the same delivery id
+func newNotificationDelivery(delivery *notifications.Delivery, opts ...ModelOps) *NotificationDelivery {
+	id := delivery.DeliveryID
+	if len(id) == 0 {
+		id, _ = utils.RandomHex(32)
+	}
+	return &NotificationDelivery{
+		Attempts:       1,
+		Endpoint:       delivery.Endpoint,
+		EventType:      delivery.EventType,
+		ID:             id,
+		LastError:      delivery.LastError,
+		Model:          *NewBaseModel(ModelNotificationDelivery, opts...),
+		ModelID:        delivery.ModelID,
+		ModelType:      delivery.ModelType,
+		Payload:        string(delivery.Payload),
+		Status:         DeliveryStatusPending,
+		SubscriptionID: delivery.SubscriptionID,
+	}
+}
+
+// getNotificationDeliveriesToProcess will get the pending deliveries that are due for another attempt
+func getNotificationDeliveriesToProcess(ctx context.Context, queryParams *datastore.QueryParams,
+	opts ...ModelOps) ([]*NotificationDelivery, error) {
+
+	// Construct an empty model
+	var models []NotificationDelivery
+	conditions := map[string]interface{}{
+		statusField: DeliveryStatusPending.String(),
+		nextAttemptAtField: map[string]interface{}{
+			"$lte": time.Now().UTC(),
+		},
+	}
+
+	if queryParams == nil {
+		queryParams = &datastore.QueryParams{}
+	}
+	queryParams.OrderByField = nextAttemptAtField
+	queryParams.SortDirection = datastore.SortAsc
+
+	// Get the records
+	if err := getModels(
+		ctx, NewBaseModel(ModelNameEmpty, opts...).Client().Datastore(),
+		&models, conditions, queryParams, defaultDatabaseReadTimeout,
+	); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Loop and enrich
+	deliveries := make([]*NotificationDelivery, 0)
+	for index := range models {
+		models[index].enrich(ModelNotificationDelivery, opts...)
+		deliveries = append(deliveries, &models[index])
+	}
+
+	return deliveries, nil
+}
+
+// GetModelName will get the name of the current model
+func (m *NotificationDelivery) GetModelName() string {
+	return ModelNotificationDelivery.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *NotificationDelivery) GetModelTableName() string {
+	return tableNotificationDeliveries
+}
+
+// Save will save the model into the Datastore
+func (m *NotificationDelivery) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *NotificationDelivery) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *NotificationDelivery) BeforeCreating(_ context.Context) error {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	}
+
+	// Schedule the next attempt using the notification client's backoff
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = time.Now().UTC()
+		if n := m.Client().Notifications(); n != nil {
+			m.NextAttemptAt = m.NextAttemptAt.Add(n.RetryDelay(m.Attempts))
+		}
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// RegisterTasks will register the model specific tasks on client initialization
+func (m *NotificationDelivery) RegisterTasks() error {
+	// No task manager loaded?
+	tm := m.Client().Taskmanager()
+	if tm == nil {
+		return nil
+	}
+
+	// Register the task locally (cron task - set the defaults)
+	processTask := m.Name() + "_process"
+	ctx := context.Background()
+
+	// Register the task
+	if err := tm.RegisterTask(&taskmanager.Task{
+		Name:       processTask,
+		RetryLimit: 1,
+		Handler: func(client ClientInterface) error {
+			if taskErr := taskProcessNotificationDeliveries(ctx, client.Logger(), WithClient(client)); taskErr != nil {
+				client.Logger().Error(ctx, "error running "+processTask+" task: "+taskErr.Error())
+			}
+			return nil
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Run the task periodically
+	return tm.RunTask(ctx, &taskmanager.TaskOptions{
+		Arguments:      []interface{}{m.Client()},
+		RunEveryPeriod: m.Client().GetTaskPeriod(processTask),
+		TaskName:       processTask,
+	})
+}
+
+// Migrate model specific migration on startup
+func (m *NotificationDelivery) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tableNotificationDeliveries), metadataField)
+}
@@ -0,0 +1,182 @@
+package bux
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BuxOrg/bux/notifications"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookURL = "https://test.example.com/v1/webhook"
+
+// newTestNotificationDelivery will create and save a delivery that is due for another attempt
+func newTestNotificationDelivery(ctx context.Context, t *testing.T, client ClientInterface) *NotificationDelivery {
+	delivery := newNotificationDelivery(&notifications.Delivery{
+		Endpoint:  testWebhookURL,
+		EventType: notifications.EventTypeCreate,
+		LastError: "connection refused",
+		ModelID:   testTxID,
+		ModelType: ModelTransaction.String(),
+		Payload:   []byte(`{"id":"` + testTxID + `"}`),
+	}, client.DefaultModelOptions(New())...)
+	delivery.NextAttemptAt = time.Now().UTC().Add(-1 * time.Minute)
+	require.NoError(t, delivery.Save(ctx))
+	return delivery
+}
+
+// getTestNotificationDelivery will get the delivery by id
+func getTestNotificationDelivery(ctx context.Context, t *testing.T, client ClientInterface, id string) *NotificationDelivery {
+	delivery := &NotificationDelivery{ID: id}
+	delivery.enrich(ModelNotificationDelivery, client.DefaultModelOptions()...)
+	require.NoError(t, Get(ctx, delivery, nil, false, defaultDatabaseReadTimeout, false))
+	return delivery
+}
+
+func Test_newNotificationDelivery(t *testing.T) {
+	t.Run("valid delivery", func(t *testing.T) {
+		delivery := newNotificationDelivery(&notifications.Delivery{
+			Endpoint:  testWebhookURL,
+			EventType: notifications.EventTypeBroadcast,
+			ModelID:   testTxID,
+			ModelType: ModelSyncTransaction.String(),
+			Payload:   []byte(`{}`),
+		})
+		require.NotNil(t, delivery)
+		assert.Equal(t, 64, len(delivery.GetID()))
+		assert.Equal(t, ModelNotificationDelivery.String(), delivery.GetModelName())
+		assert.Equal(t, DeliveryStatusPending, delivery.Status)
+		assert.Equal(t, uint32(1), delivery.Attempts)
+		assert.Equal(t, `{}`, delivery.Payload)
+	})
+}
+
+func Test_processNotificationDeliveries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	t.Run("failed notify is queued in the outbox", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(http.MethodPost, testWebhookURL,
+			httpmock.NewStringResponder(http.StatusServiceUnavailable, "unavailable"),
+		)
+
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithNotifications(testWebhookURL, ""),
+		)
+		defer deferMe()
+
+		err := client.Notifications().Notify(ctx, ModelTransaction.String(), notifications.EventTypeCreate, map[string]interface{}{}, testTxID)
+		require.NoError(t, err)
+
+		var deliveries []NotificationDelivery
+		require.NoError(t, getModels(ctx, client.Datastore(), &deliveries, map[string]interface{}{}, nil, defaultDatabaseReadTimeout))
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, DeliveryStatusPending, deliveries[0].Status)
+		assert.Equal(t, testTxID, deliveries[0].ModelID)
+		assert.True(t, deliveries[0].NextAttemptAt.After(time.Now().UTC()))
+	})
+
+	t.Run("delivery succeeds on retry", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(http.MethodPost, testWebhookURL,
+			httpmock.NewStringResponder(http.StatusOK, "OK"),
+		)
+
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithNotifications(testWebhookURL, ""),
+		)
+		defer deferMe()
+
+		delivery := newTestNotificationDelivery(ctx, t, client)
+
+		require.NoError(t, processNotificationDeliveries(ctx, 10, client.DefaultModelOptions()...))
+
+		delivery = getTestNotificationDelivery(ctx, t, client, delivery.ID)
+		assert.Equal(t, DeliveryStatusComplete, delivery.Status)
+		assert.Equal(t, uint32(2), delivery.Attempts)
+		assert.Empty(t, delivery.LastError)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("every attempt is sent with the same delivery id", func(t *testing.T) {
+		httpmock.Reset()
+		deliveryIDs := make([]string, 0)
+		httpmock.RegisterResponder(http.MethodPost, testWebhookURL,
+			func(req *http.Request) (*http.Response, error) {
+				deliveryIDs = append(deliveryIDs, req.Header.Get(notifications.HeaderDeliveryID))
+				if len(deliveryIDs) == 1 {
+					return httpmock.NewStringResponse(http.StatusServiceUnavailable, "unavailable"), nil
+				}
+				return httpmock.NewStringResponse(http.StatusOK, "OK"), nil
+			},
+		)
+
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithNotifications(testWebhookURL, "test-signing-secret"),
+		)
+		defer deferMe()
+
+		err := client.Notifications().Notify(ctx, ModelTransaction.String(), notifications.EventTypeCreate, map[string]interface{}{}, testTxID)
+		require.NoError(t, err)
+
+		var deliveries []NotificationDelivery
+		require.NoError(t, getModels(ctx, client.Datastore(), &deliveries, map[string]interface{}{}, nil, defaultDatabaseReadTimeout))
+		require.Len(t, deliveries, 1)
+
+		delivery := getTestNotificationDelivery(ctx, t, client, deliveries[0].ID)
+		delivery.NextAttemptAt = time.Now().UTC().Add(-1 * time.Minute)
+		require.NoError(t, delivery.Save(ctx))
+		require.NoError(t, processNotificationDeliveries(ctx, 10, client.DefaultModelOptions()...))
+
+		delivery = getTestNotificationDelivery(ctx, t, client, delivery.ID)
+		assert.Equal(t, DeliveryStatusComplete, delivery.Status)
+		require.Len(t, deliveryIDs, 2)
+		assert.Equal(t, delivery.ID, deliveryIDs[0])
+		assert.Equal(t, deliveryIDs[0], deliveryIDs[1])
+	})
+
+	t.Run("delivery is rescheduled, then dead-lettered", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(http.MethodPost, testWebhookURL,
+			httpmock.NewStringResponder(http.StatusInternalServerError, "error"),
+		)
+
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithNotifications(testWebhookURL, ""),
+			WithNotificationsRetryPolicy(3, time.Minute, time.Hour),
+		)
+		defer deferMe()
+
+		delivery := newTestNotificationDelivery(ctx, t, client)
+
+		// Second attempt fails and is rescheduled
+		require.NoError(t, processNotificationDeliveries(ctx, 10, client.DefaultModelOptions()...))
+		delivery = getTestNotificationDelivery(ctx, t, client, delivery.ID)
+		assert.Equal(t, DeliveryStatusPending, delivery.Status)
+		assert.Equal(t, uint32(2), delivery.Attempts)
+		assert.True(t, delivery.NextAttemptAt.After(time.Now().UTC()))
+
+		// Not due yet, nothing is sent
+		require.NoError(t, processNotificationDeliveries(ctx, 10, client.DefaultModelOptions()...))
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+
+		// Third attempt fails and runs out of attempts
+		delivery.NextAttemptAt = time.Now().UTC().Add(-1 * time.Minute)
+		require.NoError(t, delivery.Save(ctx))
+		require.NoError(t, processNotificationDeliveries(ctx, 10, client.DefaultModelOptions()...))
+
+		delivery = getTestNotificationDelivery(ctx, t, client, delivery.ID)
+		assert.Equal(t, DeliveryStatusDeadLetter, delivery.Status)
+		assert.Equal(t, uint32(3), delivery.Attempts)
+		assert.Contains(t, delivery.LastError, "500")
+	})
+}
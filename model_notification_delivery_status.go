@@ -0,0 +1,52 @@
+package bux
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// DeliveryStatus notification delivery status
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending is when the delivery is waiting for another attempt
+	DeliveryStatusPending DeliveryStatus = statusPending
+
+	// DeliveryStatusComplete is when the delivery was accepted by the endpoint
+	DeliveryStatusComplete DeliveryStatus = statusComplete
+
+	// DeliveryStatusDeadLetter is when the delivery ran out of attempts and will not be retried
+	DeliveryStatusDeadLetter DeliveryStatus = statusDeadLetter
+)
+
+// Scan will scan the value into Struct, implements sql.Scanner interface
+func (t *DeliveryStatus) Scan(value interface{}) error {
+	xType := fmt.Sprintf("%T", value)
+	var stringValue string
+	if xType == ValueTypeString {
+		stringValue = value.(string)
+	} else {
+		stringValue = string(value.([]byte))
+	}
+
+	switch stringValue {
+	case statusPending:
+		*t = DeliveryStatusPending
+	case statusComplete:
+		*t = DeliveryStatusComplete
+	case statusDeadLetter:
+		*t = DeliveryStatusDeadLetter
+	}
+
+	return nil
+}
+
+// Value return json value, implement driver.Valuer interface
+func (t DeliveryStatus) Value() (driver.Value, error) {
+	return string(t), nil
+}
+
+// String is the string version of the status
+func (t DeliveryStatus) String() string {
+	return string(t)
+}
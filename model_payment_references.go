@@ -0,0 +1,246 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BuxOrg/bux/taskmanager"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/libsv/go-bt/v2"
+	"github.com/mrz1836/go-datastore"
+)
+
+// PaymentReferenceStatus is the status of a payment reference
+type PaymentReferenceStatus string
+
+const (
+	// PaymentReferenceStatusIssued is when the destinations are issued and no transaction is received yet
+	PaymentReferenceStatusIssued PaymentReferenceStatus = "issued"
+
+	// PaymentReferenceStatusPaid is when a transaction paying the destinations is received
+	PaymentReferenceStatusPaid PaymentReferenceStatus = "paid"
+
+	// PaymentReferenceStatusExpired is when no transaction was received before the reference expired
+	PaymentReferenceStatusExpired PaymentReferenceStatus = "expired"
+)
+
+// PaymentReference is the reference of a P2P payment destination response: the issued destinations and satoshis
+//
+// The P2P transaction of the reference is verified against the reference (see WithPaymailReferenceVerification)
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type PaymentReference struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID               string                  `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:varchar(64);primaryKey;comment:This is the reference ID" bson:"_id"`
+	PaymailAddressID string                  `json:"paymail_address_id" toml:"paymail_address_id" yaml:"paymail_address_id" gorm:"<-:create;type:char(64);index;comment:This is the related paymail address" bson:"paymail_address_id"`
+	XpubID           string                  `json:"xpub_id" toml:"xpub_id" yaml:"xpub_id" gorm:"<-:create;type:char(64);index;comment:This is the related xPub" bson:"xpub_id"`
+	Satoshis         uint64                  `json:"satoshis" toml:"satoshis" yaml:"satoshis" gorm:"<-:create;comment:Satoshis requested" bson:"satoshis"`
+	Outputs          PaymentReferenceOutputs `json:"outputs" toml:"outputs" yaml:"outputs" gorm:"<-:create;type:text;comment:Issued outputs in JSON" bson:"outputs"`
+	ExpiresAt        time.Time               `json:"expires_at" toml:"expires_at" yaml:"expires_at" gorm:"<-:create;comment:Time when the reference expires" bson:"expires_at"`
+	Status           PaymentReferenceStatus  `json:"status" toml:"status" yaml:"status" gorm:"<-;type:varchar(16);index;comment:This is the status of the reference" bson:"status"`
+	TransactionID    string                  `json:"transaction_id" toml:"transaction_id" yaml:"transaction_id" gorm:"<-;type:char(64);index;comment:This is the received transaction" bson:"transaction_id,omitempty"`
+	Flag             string                  `json:"flag" toml:"flag" yaml:"flag" gorm:"<-;type:text;comment:Why the last received transaction was flagged" bson:"flag,omitempty"`
+}
+
+// PaymentReferenceOutput is an issued output of the reference
+type PaymentReferenceOutput struct {
+	Address       string `json:"address"`
+	LockingScript string `json:"locking_script"`
+	Satoshis      uint64 `json:"satoshis"`
+}
+
+// PaymentReferenceOutputs are the issued outputs of the reference
+type PaymentReferenceOutputs []*PaymentReferenceOutput
+
+// newPaymentReference will start a new model
+func newPaymentReference(referenceID string, paymailAddress *PaymailAddress, satoshis uint64,
+	expiresAt time.Time, opts ...ModelOps) *PaymentReference {
+
+	reference := &PaymentReference{
+		ExpiresAt: expiresAt,
+		ID:        referenceID,
+		Model:     *NewBaseModel(ModelPaymentReference, opts...),
+		Satoshis:  satoshis,
+		Status:    PaymentReferenceStatusIssued,
+	}
+	if paymailAddress != nil {
+		reference.PaymailAddressID = paymailAddress.ID
+		reference.XpubID = paymailAddress.XpubID
+	}
+	return reference
+}
+
+// getPaymentReference will get the model for the given reference ID
+func getPaymentReference(ctx context.Context, referenceID string, opts ...ModelOps) (*PaymentReference, error) {
+
+	// Construct an empty model
+	reference := &PaymentReference{
+		ID: referenceID,
+	}
+	reference.enrich(ModelPaymentReference, opts...)
+
+	// Get the record
+	if err := Get(ctx, reference, nil, false, defaultDatabaseReadTimeout, false); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return reference, nil
+}
+
+// isPaymentReferenceLoaded will return true if the payment references are loaded (see WithPaymailSupport)
+func isPaymentReferenceLoaded(client ClientInterface) bool {
+	return utils.StringInSlice(ModelPaymentReference.String(), client.GetModelNames())
+}
+
+// verify will check that the transaction pays the issued outputs, and that the reference is not expired
+// or used by another transaction
+func (m *PaymentReference) verify(tx *bt.Tx, now time.Time) error {
+	if m.Status == PaymentReferenceStatusExpired {
+		return ErrPaymentReferenceExpired
+	} else if m.Status != PaymentReferenceStatusIssued && m.TransactionID != tx.TxID() {
+		return fmt.Errorf("%w: %s", ErrPaymentReferenceUsed, m.TransactionID)
+	} else if now.After(m.ExpiresAt) {
+		return ErrPaymentReferenceExpired
+	}
+
+	for _, output := range m.Outputs {
+		var paid uint64
+		for _, txOutput := range tx.Outputs {
+			if txOutput.LockingScriptHexString() == output.LockingScript {
+				paid += txOutput.Satoshis
+			}
+		}
+		if paid == 0 {
+			return fmt.Errorf("%w: %s", ErrPaymentReferenceScriptMismatch, output.Address)
+		} else if paid < output.Satoshis {
+			return fmt.Errorf("%w: %s (%d of %d satoshis)", ErrPaymentReferenceUnderpaid, output.Address,
+				paid, output.Satoshis)
+		}
+	}
+	return nil
+}
+
+// setTransaction will set the received transaction, or the flag if the verification failed
+//
+// A flagged transaction does not use the reference, it can still be paid by a valid transaction
+func (m *PaymentReference) setTransaction(transactionID string, flag error) {
+	if flag != nil {
+		m.Flag = flag.Error()
+		return
+	}
+	m.TransactionID = transactionID
+	m.Status = PaymentReferenceStatusPaid
+	m.Flag = ""
+}
+
+// Scan will scan the value into Struct, implements sql.Scanner interface
+func (o *PaymentReferenceOutputs) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	xType := fmt.Sprintf("%T", value)
+	var byteValue []byte
+	if xType == ValueTypeString {
+		byteValue = []byte(value.(string))
+	} else {
+		byteValue = value.([]byte)
+	}
+	if bytes.Equal(byteValue, []byte("")) || bytes.Equal(byteValue, []byte("\"\"")) {
+		return nil
+	}
+
+	return json.Unmarshal(byteValue, &o)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (o PaymentReferenceOutputs) Value() (driver.Value, error) {
+	marshal, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(marshal), nil
+}
+
+// GetModelName will get the name of the current model
+func (m *PaymentReference) GetModelName() string {
+	return ModelPaymentReference.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *PaymentReference) GetModelTableName() string {
+	return tablePaymentReferences
+}
+
+// Save will save the model into the Datastore
+func (m *PaymentReference) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *PaymentReference) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *PaymentReference) BeforeCreating(_ context.Context) error {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// RegisterTasks will register the model specific tasks on client initialization
+func (m *PaymentReference) RegisterTasks() error {
+	// No task manager loaded?
+	tm := m.Client().Taskmanager()
+	if tm == nil {
+		return nil
+	}
+
+	// Register the task locally (cron task - set the defaults)
+	cleanUpTask := m.Name() + "_clean_up"
+	ctx := context.Background()
+
+	// Register the task
+	if err := tm.RegisterTask(&taskmanager.Task{
+		Name:       cleanUpTask,
+		RetryLimit: 1,
+		Handler: func(client ClientInterface) error {
+			if taskErr := taskCleanupPaymentReferences(ctx, client.Logger(), WithClient(client)); taskErr != nil {
+				client.Logger().Error(ctx, "error running "+cleanUpTask+" task: "+taskErr.Error())
+			}
+			return nil
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Run the task periodically
+	return tm.RunTask(ctx, &taskmanager.TaskOptions{
+		Arguments:      []interface{}{m.Client()},
+		RunEveryPeriod: m.Client().GetTaskPeriod(cleanUpTask),
+		TaskName:       cleanUpTask,
+	})
+}
+
+// Migrate model specific migration on startup
+func (m *PaymentReference) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tablePaymentReferences), metadataField)
+}
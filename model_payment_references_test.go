@@ -0,0 +1,179 @@
+package bux
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitcoin-sv/go-paymail"
+	"github.com/libsv/go-bt/v2"
+	"github.com/libsv/go-bt/v2/bscript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initPaymentReferenceTestCase will create the paymail of the test xPub and issue a P2P destination (1000 satoshis)
+func initPaymentReferenceTestCase(t *testing.T, opts ...ClientOps) (context.Context, ClientInterface,
+	*PaymailDefaultServiceProvider, *paymail.PaymentDestinationPayload, func()) {
+
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, append([]ClientOps{
+		WithCustomTaskManager(&taskManagerMockBase{}),
+		WithPaymailSupport([]string{testDomain}, defaultSenderPaymail, defaultAddressResolutionPurpose, false, false),
+	}, opts...)...)
+
+	_, err := client.NewXpub(ctx, testXPub, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	_, err = client.NewPaymailAddress(testSuperAdminContext(ctx), testXPub, testPaymail, "", "", client.DefaultModelOptions()...)
+	require.NoError(t, err)
+
+	provider := &PaymailDefaultServiceProvider{client: client}
+	var payload *paymail.PaymentDestinationPayload
+	payload, err = provider.CreateP2PDestinationResponse(ctx, "paymail", "tester.com", 1000, nil)
+	require.NoError(t, err)
+
+	return ctx, client, provider, payload, deferMe
+}
+
+// newPaymentReferenceTestTx will create a transaction paying the outputs
+func newPaymentReferenceTestTx(t *testing.T, outputs ...*paymail.PaymentOutput) *bt.Tx {
+	tx := bt.NewTx()
+	for _, output := range outputs {
+		lockingScript, err := bscript.NewFromHexString(output.Script)
+		require.NoError(t, err)
+		tx.AddOutput(&bt.Output{LockingScript: lockingScript, Satoshis: output.Satoshis})
+	}
+	return tx
+}
+
+func TestPaymentReference_verify(t *testing.T) {
+	output := &paymail.PaymentOutput{Satoshis: 1000, Script: testLockingScript}
+	reference := newPaymentReference(testReferenceID, nil, 1000, time.Now().UTC().Add(time.Hour))
+	reference.Outputs = PaymentReferenceOutputs{{LockingScript: testLockingScript, Satoshis: 1000}}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, reference.verify(newPaymentReferenceTestTx(t, output), time.Now().UTC()))
+	})
+
+	t.Run("underpaid", func(t *testing.T) {
+		err := reference.verify(newPaymentReferenceTestTx(t, &paymail.PaymentOutput{
+			Satoshis: 999, Script: testLockingScript,
+		}), time.Now().UTC())
+		assert.ErrorIs(t, err, ErrPaymentReferenceUnderpaid)
+	})
+
+	t.Run("wrong script", func(t *testing.T) {
+		err := reference.verify(newPaymentReferenceTestTx(t, &paymail.PaymentOutput{
+			Satoshis: 1000, Script: "76a914000000000000000000000000000000000000000088ac",
+		}), time.Now().UTC())
+		assert.ErrorIs(t, err, ErrPaymentReferenceScriptMismatch)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		err := reference.verify(newPaymentReferenceTestTx(t, output), time.Now().UTC().Add(2*time.Hour))
+		assert.ErrorIs(t, err, ErrPaymentReferenceExpired)
+	})
+
+	t.Run("used", func(t *testing.T) {
+		tx := newPaymentReferenceTestTx(t, output)
+		used := *reference
+		used.setTransaction(testTxID, nil)
+		assert.ErrorIs(t, used.verify(tx, time.Now().UTC()), ErrPaymentReferenceUsed)
+
+		// the same transaction again
+		used.setTransaction(tx.TxID(), nil)
+		assert.NoError(t, used.verify(tx, time.Now().UTC()))
+	})
+
+	t.Run("flagged transaction does not use the reference", func(t *testing.T) {
+		flagged := *reference
+		flagged.setTransaction(testTxID, ErrPaymentReferenceUnderpaid)
+		assert.Equal(t, PaymentReferenceStatusIssued, flagged.Status)
+		assert.Equal(t, ErrPaymentReferenceUnderpaid.Error(), flagged.Flag)
+		assert.Empty(t, flagged.TransactionID)
+		assert.NoError(t, flagged.verify(newPaymentReferenceTestTx(t, output), time.Now().UTC()))
+	})
+
+	t.Run("expired status", func(t *testing.T) {
+		expired := *reference
+		expired.Status = PaymentReferenceStatusExpired
+		err := expired.verify(newPaymentReferenceTestTx(t, output), time.Now().UTC())
+		assert.ErrorIs(t, err, ErrPaymentReferenceExpired)
+	})
+}
+
+func TestPaymentReference_taskCleanupPaymentReferences(t *testing.T) {
+	ctx, client, _, payload, deferMe := initPaymentReferenceTestCase(
+		t, WithPaymailReferenceVerification(time.Millisecond, false),
+	)
+	defer deferMe()
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, taskCleanupPaymentReferences(ctx, client.Logger(), client.DefaultModelOptions()...))
+
+	reference, err := getPaymentReference(ctx, payload.Reference, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	require.NotNil(t, reference)
+	assert.Equal(t, PaymentReferenceStatusExpired, reference.Status)
+}
+
+func TestPaymailDefaultServiceProvider_verifyPaymentReference(t *testing.T) {
+	t.Run("issued reference", func(t *testing.T) {
+		ctx, client, provider, payload, deferMe := initPaymentReferenceTestCase(t)
+		defer deferMe()
+
+		reference, err := getPaymentReference(ctx, payload.Reference, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		require.NotNil(t, reference)
+		assert.Equal(t, PaymentReferenceStatusIssued, reference.Status)
+		assert.Equal(t, uint64(1000), reference.Satoshis)
+		assert.Equal(t, testXPubID, reference.XpubID)
+		require.Len(t, reference.Outputs, 1)
+		assert.Equal(t, payload.Outputs[0].Script, reference.Outputs[0].LockingScript)
+
+		var flag error
+		reference, flag, err = provider.verifyPaymentReference(ctx, &paymail.P2PTransaction{
+			Hex:       newPaymentReferenceTestTx(t, payload.Outputs...).String(),
+			Reference: payload.Reference,
+		})
+		require.NoError(t, err)
+		require.NoError(t, flag)
+		assert.NotNil(t, reference)
+	})
+
+	t.Run("flag invalid", func(t *testing.T) {
+		ctx, _, provider, payload, deferMe := initPaymentReferenceTestCase(t)
+		defer deferMe()
+
+		reference, flag, err := provider.verifyPaymentReference(ctx, &paymail.P2PTransaction{
+			Hex: newPaymentReferenceTestTx(t, &paymail.PaymentOutput{
+				Satoshis: 500, Script: payload.Outputs[0].Script,
+			}).String(),
+			Reference: payload.Reference,
+		})
+		require.NoError(t, err)
+		assert.ErrorIs(t, flag, ErrPaymentReferenceUnderpaid)
+		assert.NotNil(t, reference)
+
+		_, flag, err = provider.verifyPaymentReference(ctx, &paymail.P2PTransaction{
+			Hex:       newPaymentReferenceTestTx(t, payload.Outputs...).String(),
+			Reference: testReferenceID,
+		})
+		require.NoError(t, err)
+		assert.ErrorIs(t, flag, ErrUnknownPaymentReference)
+	})
+
+	t.Run("reject invalid", func(t *testing.T) {
+		ctx, _, provider, payload, deferMe := initPaymentReferenceTestCase(
+			t, WithPaymailReferenceVerification(time.Hour, true),
+		)
+		defer deferMe()
+
+		_, _, err := provider.verifyPaymentReference(ctx, &paymail.P2PTransaction{
+			Hex: newPaymentReferenceTestTx(t, &paymail.PaymentOutput{
+				Satoshis: 500, Script: payload.Outputs[0].Script,
+			}).String(),
+			Reference: payload.Reference,
+		})
+		assert.ErrorIs(t, err, ErrPaymentReferenceUnderpaid)
+	})
+}
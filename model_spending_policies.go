@@ -0,0 +1,308 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+	customTypes "github.com/mrz1836/go-datastore/custom_types"
+)
+
+// SpendingPolicy is the spending policy of an xPub, drafts (NewTransaction) and outgoing transactions
+// (RecordTransaction) that violate the policy are rejected
+//
+// Only the outputs leaving the xPub are checked (change and outputs to destinations of the xPub are not),
+// the recorded outgoing transactions are counted for the daily (UTC) and weekly (ISO week) caps
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type SpendingPolicy struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID          string               `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the related xPub id" bson:"_id"`
+	Config      SpendingPolicyConfig `json:"config" toml:"config" yaml:"config" gorm:"<-;type:text;comment:This is the policy config in JSON" bson:"config"`
+	DailySpent  uint64               `json:"daily_spent" toml:"daily_spent" yaml:"daily_spent" gorm:"<-;comment:Satoshis spent on the day of the last spend" bson:"daily_spent"`
+	WeeklySpent uint64               `json:"weekly_spent" toml:"weekly_spent" yaml:"weekly_spent" gorm:"<-;comment:Satoshis spent in the week of the last spend" bson:"weekly_spent"`
+	LastSpentAt customTypes.NullTime `json:"last_spent_at" toml:"last_spent_at" yaml:"last_spent_at" gorm:"<-;comment:When the last outgoing transaction was recorded" bson:"last_spent_at,omitempty"`
+}
+
+// SpendingPolicyConfig is the configuration of a spending policy, empty values are not checked
+type SpendingPolicyConfig struct {
+	AllowedAddresses      []string         `json:"allowed_addresses,omitempty" toml:"allowed_addresses" yaml:"allowed_addresses"`                   // Only send to these addresses (or the allowed paymail domains)
+	AllowedPaymailDomains []string         `json:"allowed_paymail_domains,omitempty" toml:"allowed_paymail_domains" yaml:"allowed_paymail_domains"` // Only send to paymails of these domains (or the allowed addresses)
+	Approvers             []*DraftApprover `json:"approvers,omitempty" toml:"approvers" yaml:"approvers"`                                           // Named approvers of the drafts (see RequiredApprovals)
+	BlockedAddresses      []string         `json:"blocked_addresses,omitempty" toml:"blocked_addresses" yaml:"blocked_addresses"`                   // Never send to these addresses
+	BlockedPaymailDomains []string         `json:"blocked_paymail_domains,omitempty" toml:"blocked_paymail_domains" yaml:"blocked_paymail_domains"` // Never send to paymails of these domains
+	DailyLimit            uint64           `json:"daily_limit" toml:"daily_limit" yaml:"daily_limit"`                                               // Max satoshis spent per day (UTC)
+	MaxPerTransaction     uint64           `json:"max_per_transaction" toml:"max_per_transaction" yaml:"max_per_transaction"`                       // Max satoshis spent in one transaction (including the fee)
+	RequiredApprovals     uint32           `json:"required_approvals" toml:"required_approvals" yaml:"required_approvals"`                          // Approvals (N of the approvers) a draft needs before it can be recorded
+	RequiredMetadataKeys  []string         `json:"required_metadata_keys,omitempty" toml:"required_metadata_keys" yaml:"required_metadata_keys"`    // Metadata keys every draft must have
+	WeeklyLimit           uint64           `json:"weekly_limit" toml:"weekly_limit" yaml:"weekly_limit"`                                            // Max satoshis spent per week (ISO week, UTC)
+}
+
+// policySpend is what a draft spends: the satoshis leaving the xPub (plus the fee) and the outputs leaving the xPub
+type policySpend struct {
+	Outputs  []*TransactionOutput
+	Satoshis uint64
+}
+
+// newSpendingPolicy will start a new model
+func newSpendingPolicy(xPubID string, config *SpendingPolicyConfig, opts ...ModelOps) *SpendingPolicy {
+	policy := &SpendingPolicy{
+		ID:    xPubID,
+		Model: *NewBaseModel(ModelSpendingPolicy, opts...),
+	}
+	if config != nil {
+		policy.Config = *config
+	}
+	return policy
+}
+
+// getSpendingPolicy will get the model for the given xPub ID
+func getSpendingPolicy(ctx context.Context, xPubID string, opts ...ModelOps) (*SpendingPolicy, error) {
+
+	// Construct an empty model
+	policy := &SpendingPolicy{
+		ID: xPubID,
+	}
+	policy.enrich(ModelSpendingPolicy, opts...)
+
+	// Get the record
+	if err := Get(ctx, policy, nil, false, defaultDatabaseReadTimeout, false); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+// getActiveSpendingPolicy will get the (not deleted) policy of the xPub, nil if spending policies are not loaded
+func getActiveSpendingPolicy(ctx context.Context, client ClientInterface, xPubID string,
+	opts ...ModelOps) (*SpendingPolicy, error) {
+
+	if !utils.StringInSlice(ModelSpendingPolicy.String(), client.GetModelNames()) {
+		return nil, nil
+	}
+
+	policy, err := getSpendingPolicy(ctx, xPubID, opts...)
+	if err != nil || policy == nil || policy.DeletedAt.Valid {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// validate will check the config
+func (c *SpendingPolicyConfig) validate() error {
+	for _, list := range [][]string{
+		c.AllowedAddresses, c.AllowedPaymailDomains, c.BlockedAddresses, c.BlockedPaymailDomains,
+		c.RequiredMetadataKeys,
+	} {
+		for _, value := range list {
+			if len(strings.TrimSpace(value)) == 0 {
+				return ErrInvalidSpendingPolicy
+			}
+		}
+	}
+	return validateApprovers(c.Approvers, c.RequiredApprovals)
+}
+
+// isDestinationAllowed will return true if the output can be sent to (the paymail domain, or the addresses of
+// the output and of its scripts)
+//
+// The addresses of the scripts of a paymail output are only checked against the blocked addresses, outputs
+// without any address (script only) are not allowed if the policy has allowed destinations
+func (c *SpendingPolicyConfig) isDestinationAllowed(output *TransactionOutput) bool {
+	addresses := make([]string, 0, len(output.Scripts)+1)
+	if output.PaymailP4 == nil && len(output.To) > 0 {
+		addresses = append(addresses, output.To)
+	}
+	for _, script := range output.Scripts {
+		if len(script.Address) > 0 {
+			addresses = append(addresses, script.Address)
+		}
+	}
+
+	for _, address := range addresses {
+		if utils.StringInSlice(address, c.BlockedAddresses) {
+			return false
+		}
+	}
+	if output.PaymailP4 != nil {
+		domain := strings.ToLower(output.PaymailP4.Domain)
+		if containsDomain(c.BlockedPaymailDomains, domain) {
+			return false
+		}
+		return (len(c.AllowedAddresses) == 0 && len(c.AllowedPaymailDomains) == 0) ||
+			containsDomain(c.AllowedPaymailDomains, domain)
+	}
+
+	if len(c.AllowedAddresses) == 0 && len(c.AllowedPaymailDomains) == 0 {
+		return true
+	} else if len(addresses) == 0 {
+		return false
+	}
+	for _, address := range addresses {
+		if !utils.StringInSlice(address, c.AllowedAddresses) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsDomain will return true if the (lowercase) domain is in the list (case-insensitive)
+func containsDomain(domains []string, domain string) bool {
+	for _, d := range domains {
+		if strings.ToLower(d) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// getSpent will get the satoshis spent on the day and in the week of now
+func (m *SpendingPolicy) getSpent(now time.Time) (daily, weekly uint64) {
+	if !m.LastSpentAt.Valid {
+		return 0, 0
+	}
+
+	last := m.LastSpentAt.Time.UTC()
+	now = now.UTC()
+	lastYear, lastWeek := last.ISOWeek()
+	if year, week := now.ISOWeek(); year == lastYear && week == lastWeek {
+		weekly = m.WeeklySpent
+		if last.YearDay() == now.YearDay() {
+			daily = m.DailySpent
+		}
+	}
+	return
+}
+
+// evaluate will check the spend of a draft (and its metadata) against the policy
+func (m *SpendingPolicy) evaluate(spend *policySpend, metadata Metadata, now time.Time) error {
+	for _, key := range m.Config.RequiredMetadataKeys {
+		if _, ok := metadata[key]; !ok {
+			return fmt.Errorf("%w: %s", ErrSpendingPolicyMetadata, key)
+		}
+	}
+
+	for _, output := range spend.Outputs {
+		if !m.Config.isDestinationAllowed(output) {
+			return fmt.Errorf("%w: %s", ErrSpendingPolicyDestination, output.To)
+		}
+	}
+
+	if m.Config.MaxPerTransaction > 0 && spend.Satoshis > m.Config.MaxPerTransaction {
+		return ErrSpendingPolicyMaxPerTransaction
+	}
+
+	daily, weekly := m.getSpent(now)
+	if m.Config.DailyLimit > 0 && daily+spend.Satoshis > m.Config.DailyLimit {
+		return ErrSpendingPolicyDailyLimit
+	} else if m.Config.WeeklyLimit > 0 && weekly+spend.Satoshis > m.Config.WeeklyLimit {
+		return ErrSpendingPolicyWeeklyLimit
+	}
+	return nil
+}
+
+// addSpend will count the satoshis for the day and the week of now
+func (m *SpendingPolicy) addSpend(satoshis uint64, now time.Time) {
+	daily, weekly := m.getSpent(now)
+	m.DailySpent = daily + satoshis
+	m.WeeklySpent = weekly + satoshis
+	m.LastSpentAt.Valid = true
+	m.LastSpentAt.Time = now.UTC()
+}
+
+// logDecision will log the decision of the policy for the draft
+func (m *SpendingPolicy) logDecision(ctx context.Context, draftID string, spend *policySpend, err error) {
+	if err != nil {
+		m.Client().Logger().Warn(ctx, fmt.Sprintf(
+			"spending policy of xPub %s rejected draft %s (%d satoshis): %s", m.ID, draftID, spend.Satoshis, err.Error(),
+		))
+		return
+	}
+	m.Client().Logger().Info(ctx, fmt.Sprintf(
+		"spending policy of xPub %s allowed draft %s (%d satoshis)", m.ID, draftID, spend.Satoshis,
+	))
+}
+
+// Scan will scan the value into Struct, implements sql.Scanner interface
+func (c *SpendingPolicyConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	xType := fmt.Sprintf("%T", value)
+	var byteValue []byte
+	if xType == ValueTypeString {
+		byteValue = []byte(value.(string))
+	} else {
+		byteValue = value.([]byte)
+	}
+	if bytes.Equal(byteValue, []byte("")) || bytes.Equal(byteValue, []byte("\"\"")) {
+		return nil
+	}
+
+	return json.Unmarshal(byteValue, &c)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (c SpendingPolicyConfig) Value() (driver.Value, error) {
+	marshal, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(marshal), nil
+}
+
+// GetModelName will get the name of the current model
+func (m *SpendingPolicy) GetModelName() string {
+	return ModelSpendingPolicy.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *SpendingPolicy) GetModelTableName() string {
+	return tableSpendingPolicies
+}
+
+// Save will save the model into the Datastore
+func (m *SpendingPolicy) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *SpendingPolicy) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *SpendingPolicy) BeforeCreating(_ context.Context) error {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	}
+
+	if err := m.Config.validate(); err != nil {
+		return err
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// Migrate model specific migration on startup
+func (m *SpendingPolicy) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tableSpendingPolicies), metadataField)
+}
@@ -0,0 +1,256 @@
+package bux
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicyAddress is an address outside the test xPub (testExternalAddress is the address of testLockingScript)
+const testPolicyAddress = "1A1PjKqjWMNBzTVdcBru27EV1PHcXWc63W"
+
+// initSpendingPolicyTestCase will create the xPub (with a balance of 100000) and a utxo of 100000 satoshis
+func initSpendingPolicyTestCase(t *testing.T, clientOpts ...ClientOps) (context.Context, ClientInterface, func()) {
+	ctx, client, deferMe := initConsolidationTestCase(t, []uint64{100000},
+		append(clientOpts, WithSpendingPolicies())...,
+	)
+
+	xPub, err := getXpubByID(ctx, testXPubID, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	xPub.CurrentBalance = 100000
+	require.NoError(t, xPub.Save(ctx))
+
+	return ctx, client, deferMe
+}
+
+func TestSpendingPolicyConfig_isDestinationAllowed(t *testing.T) {
+	address := &TransactionOutput{To: testExternalAddress}
+	paymailOutput := &TransactionOutput{To: testPaymail, PaymailP4: &PaymailP4{Domain: testDomain}}
+
+	t.Run("empty config", func(t *testing.T) {
+		config := &SpendingPolicyConfig{}
+		assert.True(t, config.isDestinationAllowed(address))
+		assert.True(t, config.isDestinationAllowed(paymailOutput))
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		config := &SpendingPolicyConfig{
+			BlockedAddresses:      []string{testExternalAddress},
+			BlockedPaymailDomains: []string{"Example.com"},
+		}
+		assert.False(t, config.isDestinationAllowed(address))
+		assert.True(t, config.isDestinationAllowed(paymailOutput))
+		assert.False(t, config.isDestinationAllowed(
+			&TransactionOutput{To: "user@example.com", PaymailP4: &PaymailP4{Domain: "example.com"}},
+		))
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		config := &SpendingPolicyConfig{AllowedPaymailDomains: []string{testDomain}}
+		assert.False(t, config.isDestinationAllowed(address))
+		assert.True(t, config.isDestinationAllowed(paymailOutput))
+
+		config.AllowedAddresses = []string{testExternalAddress}
+		assert.True(t, config.isDestinationAllowed(address))
+	})
+
+	t.Run("addresses of the scripts", func(t *testing.T) {
+		scriptOutput := &TransactionOutput{Scripts: []*ScriptOutput{
+			{Address: testPolicyAddress, Script: testLockingScript},
+		}}
+		scriptOnly := &TransactionOutput{Script: testLockingScript, Scripts: []*ScriptOutput{{Script: testLockingScript}}}
+
+		config := &SpendingPolicyConfig{BlockedAddresses: []string{testPolicyAddress}}
+		assert.False(t, config.isDestinationAllowed(scriptOutput))
+		assert.True(t, config.isDestinationAllowed(scriptOnly))
+		assert.False(t, config.isDestinationAllowed(&TransactionOutput{
+			To: testPaymail, PaymailP4: &PaymailP4{Domain: testDomain}, Scripts: scriptOutput.Scripts,
+		}))
+
+		config = &SpendingPolicyConfig{AllowedAddresses: []string{testExternalAddress}}
+		assert.False(t, config.isDestinationAllowed(scriptOutput))
+		assert.False(t, config.isDestinationAllowed(scriptOnly))
+		assert.False(t, config.isDestinationAllowed(&TransactionOutput{
+			To: testExternalAddress, Scripts: scriptOutput.Scripts,
+		}))
+	})
+}
+
+func TestSpendingPolicy_evaluate(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)
+	policy := newSpendingPolicy(testXPubID, &SpendingPolicyConfig{
+		DailyLimit:           1000,
+		MaxPerTransaction:    600,
+		RequiredMetadataKeys: []string{"invoice"},
+		WeeklyLimit:          1500,
+	})
+	metadata := Metadata{"invoice": "1"}
+
+	err := policy.evaluate(&policySpend{Satoshis: 500}, nil, now)
+	assert.ErrorIs(t, err, ErrSpendingPolicyMetadata)
+
+	err = policy.evaluate(&policySpend{Satoshis: 601}, metadata, now)
+	assert.ErrorIs(t, err, ErrSpendingPolicyMaxPerTransaction)
+
+	require.NoError(t, policy.evaluate(&policySpend{Satoshis: 600}, metadata, now))
+	policy.addSpend(600, now)
+
+	err = policy.evaluate(&policySpend{Satoshis: 500}, metadata, now.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrSpendingPolicyDailyLimit)
+
+	// the next day
+	require.NoError(t, policy.evaluate(&policySpend{Satoshis: 500}, metadata, now.Add(24*time.Hour)))
+	policy.addSpend(500, now.Add(24*time.Hour))
+	assert.Equal(t, uint64(500), policy.DailySpent)
+	assert.Equal(t, uint64(1100), policy.WeeklySpent)
+
+	err = policy.evaluate(&policySpend{Satoshis: 500}, metadata, now.Add(48*time.Hour))
+	assert.ErrorIs(t, err, ErrSpendingPolicyWeeklyLimit)
+
+	// the next week
+	require.NoError(t, policy.evaluate(&policySpend{Satoshis: 500}, metadata, now.Add(7*24*time.Hour)))
+}
+
+func TestClient_NewSpendingPolicy(t *testing.T) {
+	t.Run("missing xPub", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithSpendingPolicies(),
+		)
+		defer deferMe()
+
+		_, err := client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, &SpendingPolicyConfig{})
+		assert.ErrorIs(t, err, ErrMissingXpub)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, nil, WithSpendingPolicies())
+		defer deferMe()
+
+		_, err := client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, nil)
+		assert.ErrorIs(t, err, ErrInvalidSpendingPolicy)
+
+		_, err = client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, &SpendingPolicyConfig{BlockedAddresses: []string{" "}})
+		assert.ErrorIs(t, err, ErrInvalidSpendingPolicy)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, nil, WithSpendingPolicies())
+		defer deferMe()
+
+		_, err := client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, &SpendingPolicyConfig{DailyLimit: 1000})
+		require.NoError(t, err)
+
+		_, err = client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, &SpendingPolicyConfig{DailyLimit: 2000})
+		require.NoError(t, err)
+
+		var policy *SpendingPolicy
+		policy, err = client.GetSpendingPolicy(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2000), policy.Config.DailyLimit)
+
+		require.NoError(t, client.DeleteSpendingPolicy(testSuperAdminContext(ctx), testXPubID))
+		_, err = client.GetSpendingPolicy(ctx, testXPubID)
+		assert.ErrorIs(t, err, ErrMissingSpendingPolicy)
+	})
+}
+
+func TestDraftTransaction_checkSpendingPolicy(t *testing.T) {
+	t.Run("rejected drafts release the utxos", func(t *testing.T) {
+		ctx, client, deferMe := initSpendingPolicyTestCase(t)
+		defer deferMe()
+
+		_, err := client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, &SpendingPolicyConfig{
+			BlockedAddresses:     []string{testPolicyAddress},
+			MaxPerTransaction:    10000,
+			RequiredMetadataKeys: []string{"invoice"},
+		})
+		require.NoError(t, err)
+
+		_, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		}, WithMetadata("invoice", "1"))
+		assert.ErrorIs(t, err, ErrSpendingPolicyDestination)
+
+		_, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 1000}},
+		})
+		assert.ErrorIs(t, err, ErrSpendingPolicyMetadata)
+
+		_, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: "1HuoHijPa7BqQNiV953pd3taqnmyhgDXFt", Satoshis: 20000}},
+		}, WithMetadata("invoice", "1"))
+		assert.ErrorIs(t, err, ErrSpendingPolicyMaxPerTransaction)
+
+		// sending to a destination of the xPub is not spending (the utxo was released by the rejected drafts)
+		_, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 20000}},
+		}, WithMetadata("invoice", "1"))
+		require.NoError(t, err)
+	})
+
+	t.Run("recorded transactions count for the daily limit", func(t *testing.T) {
+		signer, err := NewXPrivDraftSigner(testXPriv)
+		require.NoError(t, err)
+
+		ctx, client, deferMe := initSpendingPolicyTestCase(t, WithDraftSigner(signer))
+		defer deferMe()
+
+		_, err = client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, &SpendingPolicyConfig{DailyLimit: 30000})
+		require.NoError(t, err)
+
+		var draft *DraftTransaction
+		draft, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 20000}},
+		})
+		require.NoError(t, err)
+
+		_, err = client.(*Client).signAndRecordDraft(ctx, testXPub, draft)
+		require.NoError(t, err)
+
+		var policy *SpendingPolicy
+		policy, err = client.GetSpendingPolicy(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.Equal(t, 20000+draft.Configuration.Fee, policy.DailySpent)
+
+		_, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 10000}},
+		})
+		assert.ErrorIs(t, err, ErrSpendingPolicyDailyLimit)
+	})
+
+	t.Run("drafts over the daily limit together are not both recorded", func(t *testing.T) {
+		signer, err := NewXPrivDraftSigner(testXPriv)
+		require.NoError(t, err)
+
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{30000, 30000},
+			WithSpendingPolicies(), WithDraftSigner(signer),
+		)
+		defer deferMe()
+
+		_, err = client.NewSpendingPolicy(testSuperAdminContext(ctx), testXPubID, &SpendingPolicyConfig{DailyLimit: 30000})
+		require.NoError(t, err)
+
+		drafts := make([]*DraftTransaction, 2)
+		for index := range drafts {
+			drafts[index], err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+				Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 20000}},
+			})
+			require.NoError(t, err)
+		}
+
+		_, err = client.(*Client).signAndRecordDraft(ctx, testXPub, drafts[0])
+		require.NoError(t, err)
+		_, err = client.(*Client).signAndRecordDraft(ctx, testXPub, drafts[1])
+		assert.ErrorIs(t, err, ErrSpendingPolicyDailyLimit)
+
+		// the rejected transaction is not counted
+		var policy *SpendingPolicy
+		policy, err = client.GetSpendingPolicy(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.Equal(t, 20000+drafts[0].Configuration.Fee, policy.DailySpent)
+	})
+}
@@ -5,23 +5,58 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/BuxOrg/bux/chainstate"
 )
 
 // SyncConfig is the configuration used for syncing a transaction (on-chain)
 type SyncConfig struct {
-	Broadcast        bool `json:"broadcast" toml:"broadcast" yaml:"broadcast"`                         // Transaction should be broadcasted
-	BroadcastInstant bool `json:"broadcast_instant" toml:"broadcast_instant" yaml:"broadcast_instant"` // Transaction should be broadcasted instantly (ASAP)
-	PaymailP2P       bool `json:"paymail_p2p" toml:"paymail_p2p" yaml:"paymail_p2p"`                   // Transaction will be sent to all related paymail providers if P2P is detected
-	SyncOnChain      bool `json:"sync_on_chain" toml:"sync_on_chain" yaml:"sync_on_chain"`             // Transaction should be checked that it's on-chain
+	Broadcast        bool          `json:"broadcast" toml:"broadcast" yaml:"broadcast"`                                      // Transaction should be broadcasted
+	BroadcastAt      *time.Time    `json:"broadcast_at,omitempty" toml:"broadcast_at" yaml:"broadcast_at"`                   // Transaction is held (scheduled) until this time
+	BroadcastInstant bool          `json:"broadcast_instant" toml:"broadcast_instant" yaml:"broadcast_instant"`              // Transaction should be broadcasted instantly (ASAP)
+	DelayToBroadcast time.Duration `json:"delay_to_broadcast,omitempty" toml:"delay_to_broadcast" yaml:"delay_to_broadcast"` // Transaction is held (scheduled) for this long after recording (if BroadcastAt is not set)
+	FailoverMiners   []string      `json:"failover_miners,omitempty" toml:"failover_miners" yaml:"failover_miners"`          // Miners (or ARC) to broadcast to, in order, if the preferred miner fails
+	Miner            string        `json:"miner,omitempty" toml:"miner" yaml:"miner"`                                        // Preferred miner (or ARC) to broadcast to (default: all providers)
+	PaymailP2P       bool          `json:"paymail_p2p" toml:"paymail_p2p" yaml:"paymail_p2p"`                                // Transaction will be sent to all related paymail providers if P2P is detected
+	SyncOnChain      bool          `json:"sync_on_chain" toml:"sync_on_chain" yaml:"sync_on_chain"`                          // Transaction should be checked that it's on-chain
 	// FUTURE IDEAS:
-	// DelayToBroadcast time.Duration `json:"delay_to_broadcast" toml:"delay_to_broadcast" yaml:"delay_to_broadcast"` // Delay for broadcasting
-	// Miner       string `json:"miner" toml:"miner" yaml:"miner"`  // Use a specific miner
 	// miners: []miner{name, token, feeQuote}
-	// default: miner
-	// failover: miner
 	// keep tx updated until x blocks?
 }
 
+// broadcastTime will return the time the transaction is scheduled to be broadcast (zero if not scheduled)
+func (t *SyncConfig) broadcastTime(now time.Time) time.Time {
+	if t.BroadcastAt != nil {
+		return t.BroadcastAt.UTC()
+	} else if t.DelayToBroadcast > 0 {
+		return now.Add(t.DelayToBroadcast)
+	}
+	return time.Time{}
+}
+
+// broadcastPolicy will return the broadcast policy (nil if the transaction can be broadcast to all providers)
+func (t *SyncConfig) broadcastPolicy() *chainstate.BroadcastPolicy {
+	if len(t.Miner) == 0 && len(t.FailoverMiners) == 0 {
+		return nil
+	}
+	return &chainstate.BroadcastPolicy{
+		Failover:  t.FailoverMiners,
+		Preferred: t.Miner,
+	}
+}
+
+// checkP2P will check the config of a transaction with paymail p2p outputs,
+// the paymail providers broadcast it (now, to any miner) when they are notified
+func (t *SyncConfig) checkP2P(now time.Time) error {
+	if !t.broadcastTime(now).IsZero() {
+		return ErrScheduledBroadcastP2P
+	} else if t.broadcastPolicy() != nil {
+		return ErrMinerSelectionP2P
+	}
+	return nil
+}
+
 // Scan will scan the value into Struct, implements sql.Scanner interface
 func (t *SyncConfig) Scan(value interface{}) error {
 	if value == nil {
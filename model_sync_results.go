@@ -10,8 +10,9 @@ import (
 
 // SyncResults is the results from all sync attempts (broadcast or sync)
 type SyncResults struct {
-	LastMessage string        `json:"last_message"` // Last message (success or failure)
-	Results     []*SyncResult `json:"results"`      // Each result of a sync task
+	BroadcastProvider string        `json:"broadcast_provider,omitempty"` // Provider that accepted the broadcast
+	LastMessage       string        `json:"last_message"`                 // Last message (success or failure)
+	Results           []*SyncResult `json:"results"`                      // Each result of a sync task
 }
 
 // Sync actions for syncing transactions
@@ -29,6 +29,9 @@ const (
 
 	// SyncStatusComplete is when the sync is complete
 	SyncStatusComplete SyncStatus = statusComplete
+
+	// SyncStatusScheduled is when the sync is held until its scheduled time (broadcast)
+	SyncStatusScheduled SyncStatus = statusScheduled
 )
 
 // Scan will scan the value into Struct, implements sql.Scanner interface
@@ -56,6 +59,8 @@ func (t *SyncStatus) Scan(value interface{}) error {
 		*t = SyncStatusComplete
 	case statusSkipped:
 		*t = SyncStatusSkipped
+	case statusScheduled:
+		*t = SyncStatusScheduled
 	}
 
 	return nil
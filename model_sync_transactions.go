@@ -19,11 +19,14 @@ type SyncTransaction struct {
 	// Model specific fields
 	ID              string               `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the unique transaction id" bson:"_id"`
 	Configuration   SyncConfig           `json:"configuration" toml:"configuration" yaml:"configuration" gorm:"<-;type:text;comment:This is the configuration struct in JSON" bson:"configuration"`
+	BroadcastAt     customTypes.NullTime `json:"broadcast_at" toml:"broadcast_at" yaml:"broadcast_at" gorm:"<-;comment:When the scheduled broadcast is due" bson:"broadcast_at,omitempty"`
 	LastAttempt     customTypes.NullTime `json:"last_attempt" toml:"last_attempt" yaml:"last_attempt" gorm:"<-;comment:When the last broadcast occurred" bson:"last_attempt,omitempty"`
 	Results         SyncResults          `json:"results" toml:"results" yaml:"results" gorm:"<-;type:text;comment:This is the results struct in JSON" bson:"results"`
 	BroadcastStatus SyncStatus           `json:"broadcast_status" toml:"broadcast_status" yaml:"broadcast_status" gorm:"<-;type:varchar(10);index;comment:This is the status of the broadcast" bson:"broadcast_status"`
 	P2PStatus       SyncStatus           `json:"p2p_status" toml:"p2p_status" yaml:"p2p_status" gorm:"<-;column:p2p_status;type:varchar(10);index;comment:This is the status of the p2p paymail requests" bson:"p2p_status"`
 	SyncStatus      SyncStatus           `json:"sync_status" toml:"sync_status" yaml:"sync_status" gorm:"<-;type:varchar(10);index;comment:This is the status of the on-chain sync" bson:"sync_status"`
+	ParentTxID      string               `json:"parent_tx_id,omitempty" toml:"parent_tx_id" yaml:"parent_tx_id" gorm:"<-:create;type:char(64);comment:This is the stuck transaction this transaction pays the fee for (CPFP)" bson:"parent_tx_id,omitempty"`
+	ChildTxID       string               `json:"child_tx_id,omitempty" toml:"child_tx_id" yaml:"child_tx_id" gorm:"<-;type:char(64);comment:This is the transaction that pays the fee for this transaction (CPFP)" bson:"child_tx_id,omitempty"`
 
 	// internal fields
 	transaction *Transaction
@@ -3,6 +3,7 @@ package bux
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -129,3 +130,46 @@ func TestSyncTransaction_SaveHook(t *testing.T) {
 	})
 
 }
+
+func TestSyncTransaction_broadcastPolicy(t *testing.T) {
+	t.Run("all providers", func(t *testing.T) {
+		config := &SyncConfig{Broadcast: true}
+		assert.Nil(t, config.broadcastPolicy())
+	})
+
+	t.Run("preferred miner and failover", func(t *testing.T) {
+		mock := &chainStateBroadcastPolicy{}
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, true,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithCustomChainstate(mock),
+		)
+		defer deferMe()
+
+		opts := []ModelOps{WithClient(client), New()}
+		syncTx := newSyncTransaction(testTxID, &SyncConfig{
+			Broadcast:      true,
+			FailoverMiners: []string{"GorillaPool"},
+			Miner:          "Taal",
+		}, opts...)
+		syncTx.transaction = newTransaction(testTxHex, opts...)
+
+		require.NoError(t, broadcastSyncTransaction(ctx, syncTx))
+		require.NotNil(t, mock.policy)
+		assert.Equal(t, "Taal", mock.policy.Preferred)
+		assert.Equal(t, []string{"GorillaPool"}, mock.policy.Failover)
+
+		// the provider that accepted the transaction is recorded
+		saved, err := GetSyncTransactionByID(ctx, testTxID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.Equal(t, SyncStatusComplete, saved.BroadcastStatus)
+		assert.Equal(t, "Taal", saved.Results.BroadcastProvider)
+		assert.Equal(t, "Taal", saved.Results.Results[0].Provider)
+	})
+}
+
+func TestSyncConfig_checkP2P(t *testing.T) {
+	now := time.Now().UTC()
+	assert.NoError(t, (&SyncConfig{Broadcast: true, PaymailP2P: true}).checkP2P(now))
+	assert.ErrorIs(t, (&SyncConfig{DelayToBroadcast: time.Hour}).checkP2P(now), ErrScheduledBroadcastP2P)
+	assert.ErrorIs(t, (&SyncConfig{Miner: "Taal"}).checkP2P(now), ErrMinerSelectionP2P)
+	assert.ErrorIs(t, (&SyncConfig{FailoverMiners: []string{"GorillaPool"}}).checkP2P(now), ErrMinerSelectionP2P)
+}
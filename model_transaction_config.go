@@ -7,35 +7,78 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/BuxOrg/bux/utils"
 	"github.com/bitcoin-sv/go-paymail"
 	magic "github.com/bitcoinschema/go-map"
+	"github.com/libsv/go-bt/v2"
 	"github.com/libsv/go-bt/v2/bscript"
 	"github.com/mrz1836/go-cachestore"
 )
 
 // TransactionConfig is the configuration used to start a transaction
 type TransactionConfig struct {
-	ChangeDestinations         []*Destination       `json:"change_destinations" toml:"change_destinations" yaml:"change_destinations" bson:"change_destinations"`
-	ChangeDestinationsStrategy ChangeStrategy       `json:"change_destinations_strategy" toml:"change_destinations_strategy" yaml:"change_destinations_strategy" bson:"change_destinations_strategy"`
-	ChangeMinimumSatoshis      uint64               `json:"change_minimum_satoshis" toml:"change_minimum_satoshis" yaml:"change_minimum_satoshis" bson:"change_minimum_satoshis"`
-	ChangeNumberOfDestinations int                  `json:"change_number_of_destinations" toml:"change_number_of_destinations" yaml:"change_number_of_destinations" bson:"change_number_of_destinations"`
-	ChangeSatoshis             uint64               `json:"change_satoshis" toml:"change_satoshis" yaml:"change_satoshis" bson:"change_satoshis"` // The satoshis used for change
-	ExpiresIn                  time.Duration        `json:"expires_in" toml:"expires_in" yaml:"expires_in" bson:"expires_in"`                     // The expiration time for the draft and utxos
-	Fee                        uint64               `json:"fee" toml:"fee" yaml:"fee" bson:"fee"`                                                 // The fee used for the transaction (auto generated)
-	FeeUnit                    *utils.FeeUnit       `json:"fee_unit" toml:"fee_unit" yaml:"fee_unit" bson:"fee_unit"`                             // Fee unit to use (overrides chainstate if set)
-	FromUtxos                  []*UtxoPointer       `json:"from_utxos" toml:"from_utxos" yaml:"from_utxos" bson:"from_utxos"`                     // Use these specific utxos for the transaction
-	IncludeUtxos               []*UtxoPointer       `json:"include_utxos" toml:"include_utxos" yaml:"include_utxos" bson:"include_utxos"`         // Include these utxos for the transaction, among others necessary if more is needed for fees
-	Inputs                     []*TransactionInput  `json:"inputs" toml:"inputs" yaml:"inputs" bson:"inputs"`                                     // All transaction inputs
-	Outputs                    []*TransactionOutput `json:"outputs" toml:"outputs" yaml:"outputs" bson:"outputs"`                                 // All transaction outputs
-	SendAllTo                  *TransactionOutput   `json:"send_all_to,omitempty" toml:"send_all_to" yaml:"send_all_to" bson:"send_all_to"`       // Send ALL utxos to the output
-	Sync                       *SyncConfig          `json:"sync" toml:"sync" yaml:"sync" bson:"sync"`                                             // Sync config for broadcasting and on-chain sync
+	ChangeDestinations         []*Destination        `json:"change_destinations" toml:"change_destinations" yaml:"change_destinations" bson:"change_destinations"`
+	ChangeDestinationsStrategy ChangeStrategy        `json:"change_destinations_strategy" toml:"change_destinations_strategy" yaml:"change_destinations_strategy" bson:"change_destinations_strategy"`
+	ChangeMinimumSatoshis      uint64                `json:"change_minimum_satoshis" toml:"change_minimum_satoshis" yaml:"change_minimum_satoshis" bson:"change_minimum_satoshis"`
+	ChangeNumberOfDestinations int                   `json:"change_number_of_destinations" toml:"change_number_of_destinations" yaml:"change_number_of_destinations" bson:"change_number_of_destinations"`
+	ChangeSatoshis             uint64                `json:"change_satoshis" toml:"change_satoshis" yaml:"change_satoshis" bson:"change_satoshis"`                                                     // The satoshis used for change
+	ChildPaysForParent         *ChildPaysForParent   `json:"child_pays_for_parent,omitempty" toml:"child_pays_for_parent" yaml:"child_pays_for_parent" bson:"child_pays_for_parent,omitempty"`         // Pay the missing fee of a stuck (parent) transaction
+	ExpiresIn                  time.Duration         `json:"expires_in" toml:"expires_in" yaml:"expires_in" bson:"expires_in"`                                                                         // The expiration time for the draft and utxos
+	Fee                        uint64                `json:"fee" toml:"fee" yaml:"fee" bson:"fee"`                                                                                                     // The fee used for the transaction (auto generated)
+	FeeUnit                    *utils.FeeUnit        `json:"fee_unit" toml:"fee_unit" yaml:"fee_unit" bson:"fee_unit"`                                                                                 // Fee unit to use (overrides chainstate if set)
+	FromUtxos                  []*UtxoPointer        `json:"from_utxos" toml:"from_utxos" yaml:"from_utxos" bson:"from_utxos"`                                                                         // Use these specific utxos for the transaction
+	IncludeUtxos               []*UtxoPointer        `json:"include_utxos" toml:"include_utxos" yaml:"include_utxos" bson:"include_utxos"`                                                             // Include these utxos for the transaction, among others necessary if more is needed for fees
+	Inputs                     []*TransactionInput   `json:"inputs" toml:"inputs" yaml:"inputs" bson:"inputs"`                                                                                         // All transaction inputs
+	LockTime                   uint32                `json:"lock_time,omitempty" toml:"lock_time" yaml:"lock_time" bson:"lock_time,omitempty"`                                                         // nLockTime of the transaction (block height or unix timestamp)
+	Outputs                    []*TransactionOutput  `json:"outputs" toml:"outputs" yaml:"outputs" bson:"outputs"`                                                                                     // All transaction outputs
+	SendAllTo                  *TransactionOutput    `json:"send_all_to,omitempty" toml:"send_all_to" yaml:"send_all_to" bson:"send_all_to"`                                                           // Send ALL utxos to the output (a new change destination if To is not set)
+	Sequences                  []*InputSequence      `json:"sequences,omitempty" toml:"sequences" yaml:"sequences" bson:"sequences,omitempty"`                                                         // nSequence of specific inputs (default: final, or final-1 when a lock time is set)
+	Sync                       *SyncConfig           `json:"sync" toml:"sync" yaml:"sync" bson:"sync"`                                                                                                 // Sync config for broadcasting and on-chain sync
+	UtxoSelectionStrategy      UtxoSelectionStrategy `json:"utxo_selection_strategy,omitempty" toml:"utxo_selection_strategy" yaml:"utxo_selection_strategy" bson:"utxo_selection_strategy,omitempty"` // Strategy for selecting the utxos (default: datastore order)
 	// Future ideas:
-	// Conditions (utxo strategy, chain limit, split utxos)
-	// NlockTime uint32
+	// Conditions (chain limit, split utxos)
+}
+
+// InputSequence is the nSequence of an input (utxo) of the transaction
+type InputSequence struct {
+	UtxoPointer `bson:",inline"`
+	Sequence    uint32 `json:"sequence" toml:"sequence" yaml:"sequence" bson:"sequence"`
+}
+
+// ChildPaysForParent is the stuck (parent) transaction that a child transaction pays the fee for (CPFP)
+type ChildPaysForParent struct {
+	ParentFee  uint64 `json:"parent_fee" toml:"parent_fee" yaml:"parent_fee" bson:"parent_fee"`         // Fee already paid by the parent
+	ParentSize uint64 `json:"parent_size" toml:"parent_size" yaml:"parent_size" bson:"parent_size"`     // Size of the parent in bytes
+	ParentTxID string `json:"parent_tx_id" toml:"parent_tx_id" yaml:"parent_tx_id" bson:"parent_tx_id"` // ID of the parent transaction
+}
+
+// missingFee will return the fee the parent is missing for the given fee unit
+func (c *ChildPaysForParent) missingFee(unit *utils.FeeUnit) uint64 {
+	required := uint64(math.Ceil(float64(c.ParentSize) * (float64(unit.Satoshis) / float64(unit.Bytes))))
+	if required <= c.ParentFee {
+		return 0
+	}
+	return required - c.ParentFee
+}
+
+// getSequence will return the nSequence of the input spending the given utxo
+//
+// Inputs without a configured sequence are final, unless a lock time is set: then they are
+// final-1 (the lock time is only enforced when at least one input is not final)
+func (t *TransactionConfig) getSequence(pointer *UtxoPointer) uint32 {
+	for _, sequence := range t.Sequences {
+		if sequence.TransactionID == pointer.TransactionID && sequence.OutputIndex == pointer.OutputIndex {
+			return sequence.Sequence
+		}
+	}
+	if t.LockTime > 0 {
+		return bt.DefaultSequenceNumber - 1
+	}
+	return bt.DefaultSequenceNumber
 }
 
 // TransactionInput is an input on the transaction config
@@ -112,8 +155,16 @@ const (
 
 	// ResolutionTypeP2P is the current way to resolve a Paymail (prior to P4)
 	ResolutionTypeP2P = "p2p"
+
+	// ResolutionTypePike is for a confirmed contact: P2P with the outputs issued by the contact (PIKE)
+	ResolutionTypePike = "pike"
 )
 
+// notifiesP2P will return true if the transaction is sent to the paymail provider (P2P or PIKE)
+func (p *PaymailP4) notifiesP2P() bool {
+	return p != nil && (p.ResolutionType == ResolutionTypeP2P || p.ResolutionType == ResolutionTypePike)
+}
+
 // ChangeStrategy strategy to use for change
 type ChangeStrategy string
 
@@ -313,10 +364,13 @@ func (t *TransactionOutput) processPaymailViaP2P(client paymail.ClientInterface,
 		return err
 	}
 
-	// split the total output satoshis across all the paymail outputs given
-	outputValues, err := utils.SplitOutputValues(satoshis, len(destinationInfo.Outputs))
-	if err != nil {
-		return err
+	// use the satoshis of the outputs if they add up to the total (the receiver split the payment),
+	// else split the total output satoshis across all the paymail outputs given
+	outputValues := getP2POutputValues(destinationInfo.Outputs, satoshis)
+	if outputValues == nil {
+		if outputValues, err = utils.SplitOutputValues(satoshis, len(destinationInfo.Outputs)); err != nil {
+			return err
+		}
 	}
 
 	// Loop all received P2P outputs and build scripts
@@ -342,6 +396,23 @@ func (t *TransactionOutput) processPaymailViaP2P(client paymail.ClientInterface,
 	return nil
 }
 
+// getP2POutputValues will get the satoshis of the P2P outputs, nil if they do not add up to the satoshis
+func getP2POutputValues(outputs []*paymail.PaymentOutput, satoshis uint64) []uint64 {
+	values := make([]uint64, 0, len(outputs))
+	var total uint64
+	for _, output := range outputs {
+		if output.Satoshis == 0 {
+			return nil
+		}
+		values = append(values, output.Satoshis)
+		total += output.Satoshis
+	}
+	if total != satoshis {
+		return nil
+	}
+	return values
+}
+
 // processAddressOutput will process an output for a standard Bitcoin Address Transaction
 func (t *TransactionOutput) processAddressOutput() (err error) {
 
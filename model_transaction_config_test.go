@@ -8,6 +8,7 @@ import (
 
 	"github.com/BuxOrg/bux/chainstate"
 	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoin-sv/go-paymail"
 	magic "github.com/bitcoinschema/go-map"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -488,3 +489,17 @@ func TestTransactionConfig_processScriptOutput(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+// TestTransactionConfig_getP2POutputValues will test the method getP2POutputValues()
+func TestTransactionConfig_getP2POutputValues(t *testing.T) {
+	outputs := []*paymail.PaymentOutput{{Satoshis: 600}, {Satoshis: 400}}
+
+	t.Run("outputs add up", func(t *testing.T) {
+		assert.Equal(t, []uint64{600, 400}, getP2POutputValues(outputs, 1000))
+	})
+
+	t.Run("outputs do not add up", func(t *testing.T) {
+		assert.Nil(t, getP2POutputValues(outputs, 1200))
+		assert.Nil(t, getP2POutputValues([]*paymail.PaymentOutput{{}, {}}, 0))
+	})
+}
@@ -0,0 +1,249 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BuxOrg/bux/taskmanager"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+	customTypes "github.com/mrz1836/go-datastore/custom_types"
+)
+
+// UtxoConsolidation is the automatic utxo consolidation of an xPub
+//
+// The consolidation task sends the smallest spendable utxos of the xPub back to a new internal (change) destination
+// of the xPub, when the number of spendable utxos reaches the threshold (and the fee and time window allow it)
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type UtxoConsolidation struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID                string                  `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the related xPub id" bson:"_id"`
+	XpubKey           string                  `json:"-" toml:"-" yaml:"-" gorm:"<-;type:varchar(512);comment:This is the raw xPub, encryption optional" bson:"xpub_key"`
+	Config            UtxoConsolidationConfig `json:"config" toml:"config" yaml:"config" gorm:"<-;type:text;comment:This is the consolidation config in JSON" bson:"config"`
+	LastRunAt         customTypes.NullTime    `json:"last_run_at" toml:"last_run_at" yaml:"last_run_at" gorm:"<-;comment:When the utxos were last consolidated" bson:"last_run_at,omitempty"`
+	LastTransactionID string                  `json:"last_transaction_id" toml:"last_transaction_id" yaml:"last_transaction_id" gorm:"<-;type:char(64);comment:This is the last consolidation transaction" bson:"last_transaction_id,omitempty"`
+
+	// Private fields
+	rawXpubKey string // Decrypted raw xPub
+}
+
+// UtxoConsolidationConfig is the configuration for consolidating the utxos of an xPub
+type UtxoConsolidationConfig struct {
+	DryRun          bool           `json:"dry_run" toml:"dry_run" yaml:"dry_run"`                               // Only log the transaction config (nothing is reserved, signed or recorded)
+	MaxFeeUnit      *utils.FeeUnit `json:"max_fee_unit,omitempty" toml:"max_fee_unit" yaml:"max_fee_unit"`      // Only consolidate when the current fee unit is at or below (low-fee window)
+	MaxInputs       uint32         `json:"max_inputs" toml:"max_inputs" yaml:"max_inputs"`                      // Max number of utxos in one consolidation transaction
+	UtxoThreshold   uint32         `json:"utxo_threshold" toml:"utxo_threshold" yaml:"utxo_threshold"`          // Consolidate when the xPub has at least this many spendable utxos
+	WindowEndHour   uint8          `json:"window_end_hour" toml:"window_end_hour" yaml:"window_end_hour"`       // End of the daily window (hour, UTC, exclusive)
+	WindowStartHour uint8          `json:"window_start_hour" toml:"window_start_hour" yaml:"window_start_hour"` // Start of the daily window (hour, UTC), same as the end for all day
+}
+
+// newUtxoConsolidation will start a new model
+func newUtxoConsolidation(rawXpubKey string, config *UtxoConsolidationConfig, opts ...ModelOps) *UtxoConsolidation {
+	consolidation := &UtxoConsolidation{
+		ID:         utils.Hash(rawXpubKey),
+		Model:      *NewBaseModel(ModelUtxoConsolidation, opts...),
+		rawXpubKey: rawXpubKey,
+	}
+	if config != nil {
+		consolidation.Config = *config
+	}
+	return consolidation
+}
+
+// getUtxoConsolidation will get the model for the given xPub ID
+func getUtxoConsolidation(ctx context.Context, xPubID string, opts ...ModelOps) (*UtxoConsolidation, error) {
+
+	// Construct an empty model
+	consolidation := &UtxoConsolidation{
+		ID: xPubID,
+	}
+	consolidation.enrich(ModelUtxoConsolidation, opts...)
+
+	// Get the record
+	if err := Get(ctx, consolidation, nil, false, defaultDatabaseReadTimeout, false); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return consolidation, nil
+}
+
+// getUtxoConsolidations will get all the active consolidations
+func getUtxoConsolidations(ctx context.Context, queryParams *datastore.QueryParams,
+	opts ...ModelOps) ([]*UtxoConsolidation, error) {
+
+	modelItems := make([]*UtxoConsolidation, 0)
+	if err := getModelsByConditions(
+		ctx, ModelUtxoConsolidation, &modelItems, nil, &map[string]interface{}{
+			deletedAtField: nil,
+		}, queryParams, opts...,
+	); err != nil {
+		return nil, err
+	}
+
+	return modelItems, nil
+}
+
+// getRawXpubKey will get the raw xPub (decrypted if an encryption key is set)
+func (m *UtxoConsolidation) getRawXpubKey() (rawXpubKey string, err error) {
+	if len(m.rawXpubKey) == 0 {
+		m.rawXpubKey, err = m.decryptXpubKey(m.XpubKey)
+	}
+	return m.rawXpubKey, err
+}
+
+// validate will check the threshold and the window of the config
+func (c *UtxoConsolidationConfig) validate() error {
+	if c.UtxoThreshold < 2 {
+		return ErrInvalidUtxoThreshold
+	} else if c.WindowStartHour > 23 || c.WindowEndHour > 23 {
+		return ErrInvalidConsolidationWindow
+	}
+	return nil
+}
+
+// isInWindow will return true if the time is in the daily window of the config
+func (c *UtxoConsolidationConfig) isInWindow(now time.Time) bool {
+	hour := uint8(now.UTC().Hour())
+	if c.WindowStartHour == c.WindowEndHour {
+		return true
+	} else if c.WindowStartHour < c.WindowEndHour {
+		return hour >= c.WindowStartHour && hour < c.WindowEndHour
+	}
+	return hour >= c.WindowStartHour || hour < c.WindowEndHour // window over midnight
+}
+
+// isFeeAllowed will return true if the fee unit is at or below the max fee unit of the config
+func (c *UtxoConsolidationConfig) isFeeAllowed(unit *utils.FeeUnit) bool {
+	if c.MaxFeeUnit == nil || c.MaxFeeUnit.Bytes <= 0 {
+		return true
+	} else if unit == nil || unit.Bytes <= 0 {
+		return false
+	}
+	return float64(unit.Satoshis)/float64(unit.Bytes) <=
+		float64(c.MaxFeeUnit.Satoshis)/float64(c.MaxFeeUnit.Bytes)
+}
+
+// Scan will scan the value into Struct, implements sql.Scanner interface
+func (c *UtxoConsolidationConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	xType := fmt.Sprintf("%T", value)
+	var byteValue []byte
+	if xType == ValueTypeString {
+		byteValue = []byte(value.(string))
+	} else {
+		byteValue = value.([]byte)
+	}
+	if bytes.Equal(byteValue, []byte("")) || bytes.Equal(byteValue, []byte("\"\"")) {
+		return nil
+	}
+
+	return json.Unmarshal(byteValue, &c)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (c UtxoConsolidationConfig) Value() (driver.Value, error) {
+	marshal, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(marshal), nil
+}
+
+// GetModelName will get the name of the current model
+func (m *UtxoConsolidation) GetModelName() string {
+	return ModelUtxoConsolidation.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *UtxoConsolidation) GetModelTableName() string {
+	return tableUtxoConsolidations
+}
+
+// Save will save the model into the Datastore
+func (m *UtxoConsolidation) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *UtxoConsolidation) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *UtxoConsolidation) BeforeCreating(_ context.Context) (err error) {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	}
+
+	// The task needs the raw xPub for deriving the destinations of the consolidation
+	if len(m.rawXpubKey) != utils.XpubKeyLength {
+		return utils.ErrXpubInvalidLength
+	}
+	if m.XpubKey, err = m.encryptXpubKey(m.rawXpubKey); err != nil {
+		return err
+	}
+
+	if err = m.Config.validate(); err != nil {
+		return err
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// RegisterTasks will register the model specific tasks on client initialization
+func (m *UtxoConsolidation) RegisterTasks() error {
+	// No task manager loaded?
+	tm := m.Client().Taskmanager()
+	if tm == nil {
+		return nil
+	}
+
+	// Register the task locally (cron task - set the defaults)
+	processTask := m.Name() + "_process"
+	ctx := context.Background()
+
+	// Register the task
+	if err := tm.RegisterTask(&taskmanager.Task{
+		Name:       processTask,
+		RetryLimit: 1,
+		Handler: func(client ClientInterface) error {
+			if taskErr := taskConsolidateUtxos(ctx, client); taskErr != nil {
+				client.Logger().Error(ctx, "error running "+processTask+" task: "+taskErr.Error())
+			}
+			return nil
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Run the task periodically
+	return tm.RunTask(ctx, &taskmanager.TaskOptions{
+		Arguments:      []interface{}{m.Client()},
+		RunEveryPeriod: m.Client().GetTaskPeriod(processTask),
+		TaskName:       processTask,
+	})
+}
+
+// Migrate model specific migration on startup
+func (m *UtxoConsolidation) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tableUtxoConsolidations), metadataField)
+}
@@ -0,0 +1,252 @@
+package bux
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initConsolidationTestCase will create the xPub, destination and utxos (of testTxID) for consolidating
+func initConsolidationTestCase(t *testing.T, satoshis []uint64, clientOpts ...ClientOps) (context.Context, ClientInterface, func()) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, true,
+		append(clientOpts, WithCustomTaskManager(&taskManagerMockBase{}))...,
+	)
+
+	opts := append(client.DefaultModelOptions(), New())
+	require.NoError(t, newXpub(testXPub, opts...).Save(ctx))
+	require.NoError(t, newDestination(testXPubID, testLockingScript, opts...).Save(ctx))
+	for index, value := range satoshis {
+		require.NoError(t, newUtxo(testXPubID, testTxID, testLockingScript, uint32(index), value, opts...).Save(ctx))
+	}
+	require.NoError(t, newTransaction(testTxHex, opts...).Save(ctx))
+
+	return ctx, client, deferMe
+}
+
+func TestUtxoConsolidationConfig_isInWindow(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2023, 1, 1, hour, 30, 0, 0, time.UTC)
+	}
+
+	t.Run("all day", func(t *testing.T) {
+		config := &UtxoConsolidationConfig{}
+		assert.True(t, config.isInWindow(at(0)))
+		assert.True(t, config.isInWindow(at(23)))
+	})
+
+	t.Run("window", func(t *testing.T) {
+		config := &UtxoConsolidationConfig{WindowStartHour: 2, WindowEndHour: 5}
+		assert.False(t, config.isInWindow(at(1)))
+		assert.True(t, config.isInWindow(at(2)))
+		assert.True(t, config.isInWindow(at(4)))
+		assert.False(t, config.isInWindow(at(5)))
+	})
+
+	t.Run("window over midnight", func(t *testing.T) {
+		config := &UtxoConsolidationConfig{WindowStartHour: 22, WindowEndHour: 3}
+		assert.True(t, config.isInWindow(at(23)))
+		assert.True(t, config.isInWindow(at(1)))
+		assert.False(t, config.isInWindow(at(3)))
+		assert.False(t, config.isInWindow(at(12)))
+	})
+}
+
+func TestUtxoConsolidationConfig_isFeeAllowed(t *testing.T) {
+	t.Run("no max fee", func(t *testing.T) {
+		config := &UtxoConsolidationConfig{}
+		assert.True(t, config.isFeeAllowed(&utils.FeeUnit{Satoshis: 500, Bytes: 1000}))
+	})
+
+	t.Run("max fee", func(t *testing.T) {
+		config := &UtxoConsolidationConfig{MaxFeeUnit: &utils.FeeUnit{Satoshis: 1, Bytes: 20}}
+		assert.True(t, config.isFeeAllowed(&utils.FeeUnit{Satoshis: 1, Bytes: 20}))
+		assert.True(t, config.isFeeAllowed(&utils.FeeUnit{Satoshis: 25, Bytes: 1000}))
+		assert.False(t, config.isFeeAllowed(&utils.FeeUnit{Satoshis: 1, Bytes: 10}))
+		assert.False(t, config.isFeeAllowed(nil))
+	})
+}
+
+func TestClient_NewUtxoConsolidation(t *testing.T) {
+	t.Run("missing xPub", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithUtxoConsolidation(),
+		)
+		defer deferMe()
+
+		_, err := client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 10})
+		assert.ErrorIs(t, err, ErrMissingXpub)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, nil, WithUtxoConsolidation())
+		defer deferMe()
+
+		_, err := client.NewUtxoConsolidation(ctx, testXPub, nil)
+		assert.ErrorIs(t, err, ErrInvalidUtxoConsolidation)
+
+		_, err = client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 1})
+		assert.ErrorIs(t, err, ErrInvalidUtxoThreshold)
+
+		_, err = client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 10, WindowEndHour: 24})
+		assert.ErrorIs(t, err, ErrInvalidConsolidationWindow)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, nil,
+			WithUtxoConsolidation(), WithEncryption(testEncryption),
+		)
+		defer deferMe()
+
+		consolidation, err := client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 10})
+		require.NoError(t, err)
+		assert.Equal(t, testXPubID, consolidation.ID)
+		assert.NotEqual(t, testXPub, consolidation.XpubKey)
+
+		_, err = client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 20})
+		require.NoError(t, err)
+
+		consolidation, err = client.GetUtxoConsolidation(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(20), consolidation.Config.UtxoThreshold)
+
+		var rawXpubKey string
+		rawXpubKey, err = consolidation.getRawXpubKey()
+		require.NoError(t, err)
+		assert.Equal(t, testXPub, rawXpubKey)
+
+		require.NoError(t, client.DeleteUtxoConsolidation(ctx, testXPubID))
+		_, err = client.GetUtxoConsolidation(ctx, testXPubID)
+		assert.ErrorIs(t, err, ErrMissingUtxoConsolidation)
+	})
+}
+
+func TestClient_ConsolidateUtxos(t *testing.T) {
+	t.Run("dry run", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{5000, 1000, 3000, 2000}, WithUtxoConsolidation())
+		defer deferMe()
+
+		_, err := client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{MaxInputs: 3, UtxoThreshold: 2})
+		require.NoError(t, err)
+
+		var config *TransactionConfig
+		config, err = client.ConsolidateUtxos(ctx, testXPubID, true)
+		require.NoError(t, err)
+		require.Len(t, config.FromUtxos, 3)
+		assert.Equal(t, uint32(1), config.FromUtxos[0].OutputIndex)
+		assert.Equal(t, uint32(3), config.FromUtxos[1].OutputIndex)
+		assert.Equal(t, uint32(2), config.FromUtxos[2].OutputIndex)
+		assert.Greater(t, config.Fee, uint64(0))
+		assert.Equal(t, 6000-config.Fee, config.SendAllTo.Satoshis)
+
+		// nothing was reserved
+		var count int64
+		count, err = client.GetUtxosCount(ctx, nil, &map[string]interface{}{draftIDField: nil})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), count)
+
+		_, err = client.ConsolidateUtxos(ctx, testXPubID, false)
+		assert.ErrorIs(t, err, ErrMissingDraftSigner)
+	})
+
+	t.Run("nothing to consolidate", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{5000}, WithUtxoConsolidation())
+		defer deferMe()
+
+		_, err := client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 2})
+		require.NoError(t, err)
+
+		_, err = client.ConsolidateUtxos(ctx, testXPubID, true)
+		assert.ErrorIs(t, err, ErrNothingToConsolidate)
+	})
+
+	t.Run("signer fails", func(t *testing.T) {
+		signErr := errors.New("signer is offline")
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{5000, 1000},
+			WithUtxoConsolidation(), WithDraftSigner(DraftSignerFunc(func(context.Context, *DraftTransaction) (string, error) {
+				return "", signErr
+			})),
+		)
+		defer deferMe()
+
+		_, err := client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 2})
+		require.NoError(t, err)
+
+		_, err = client.ConsolidateUtxos(ctx, testXPubID, false)
+		assert.ErrorIs(t, err, signErr)
+
+		// the utxos were released
+		var count int64
+		count, err = client.GetUtxosCount(ctx, nil, &map[string]interface{}{draftIDField: nil})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("server-held key", func(t *testing.T) {
+		signer, err := NewXPrivDraftSigner(testXPriv)
+		require.NoError(t, err)
+
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{5000, 1000, 3000}, WithUtxoConsolidation(), WithDraftSigner(signer))
+		defer deferMe()
+
+		_, err = client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 2})
+		require.NoError(t, err)
+
+		var config *TransactionConfig
+		config, err = client.ConsolidateUtxos(ctx, testXPubID, false)
+		require.NoError(t, err)
+		require.Len(t, config.Inputs, 3)
+		require.Len(t, config.Outputs, 1)
+		assert.Equal(t, 9000-config.Fee, config.Outputs[0].Satoshis)
+
+		var consolidation *UtxoConsolidation
+		consolidation, err = client.GetUtxoConsolidation(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.True(t, consolidation.LastRunAt.Valid)
+		assert.Len(t, consolidation.LastTransactionID, 64)
+	})
+}
+
+func Test_taskConsolidateUtxos(t *testing.T) {
+	t.Run("threshold and window", func(t *testing.T) {
+		var signed int
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{5000, 1000, 3000},
+			WithUtxoConsolidation(), WithDraftSigner(DraftSignerFunc(func(context.Context, *DraftTransaction) (string, error) {
+				signed++
+				return "", errors.New("not signing")
+			})),
+		)
+		defer deferMe()
+
+		// threshold not reached
+		_, err := client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 4})
+		require.NoError(t, err)
+		require.NoError(t, taskConsolidateUtxos(ctx, client))
+		assert.Equal(t, 0, signed)
+
+		// out of the window
+		hour := uint8(time.Now().UTC().Add(2 * time.Hour).Hour())
+		_, err = client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{
+			UtxoThreshold: 3, WindowStartHour: hour, WindowEndHour: (hour + 1) % 24,
+		})
+		require.NoError(t, err)
+		require.NoError(t, taskConsolidateUtxos(ctx, client))
+		assert.Equal(t, 0, signed)
+
+		// dry run
+		_, err = client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 3, DryRun: true})
+		require.NoError(t, err)
+		require.NoError(t, taskConsolidateUtxos(ctx, client))
+		assert.Equal(t, 0, signed)
+
+		// consolidate (fails to sign, does not fail the task)
+		_, err = client.NewUtxoConsolidation(ctx, testXPub, &UtxoConsolidationConfig{UtxoThreshold: 3})
+		require.NoError(t, err)
+		require.NoError(t, taskConsolidateUtxos(ctx, client))
+		assert.Equal(t, 1, signed)
+	})
+}
@@ -0,0 +1,228 @@
+package bux
+
+import (
+	"context"
+	"errors"
+
+	"github.com/BuxOrg/bux/taskmanager"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+	customTypes "github.com/mrz1836/go-datastore/custom_types"
+)
+
+// UtxoPool is a pool of spendable utxos of an xPub that is kept topped up
+//
+// The pool utxos are the spendable utxos of the xPub of at least the pool satoshis and less than twice
+// the pool satoshis. The pool task splits the other utxos of the xPub (see NewSplitTransaction) when the
+// xPub has less than the target number of pool utxos.
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type UtxoPool struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID                string               `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the related xPub id" bson:"_id"`
+	XpubKey           string               `json:"-" toml:"-" yaml:"-" gorm:"<-;type:varchar(512);comment:This is the raw xPub, encryption optional" bson:"xpub_key"`
+	Satoshis          uint64               `json:"satoshis" toml:"satoshis" yaml:"satoshis" gorm:"<-;comment:This is the satoshis of each utxo in the pool" bson:"satoshis"`
+	TargetSize        uint32               `json:"target_size" toml:"target_size" yaml:"target_size" gorm:"<-;comment:This is the number of utxos to keep in the pool" bson:"target_size"`
+	LastRunAt         customTypes.NullTime `json:"last_run_at" toml:"last_run_at" yaml:"last_run_at" gorm:"<-;comment:When the pool was last topped up" bson:"last_run_at,omitempty"`
+	LastTransactionID string               `json:"last_transaction_id" toml:"last_transaction_id" yaml:"last_transaction_id" gorm:"<-;type:char(64);comment:This is the last split transaction" bson:"last_transaction_id,omitempty"`
+
+	// Private fields
+	rawXpubKey string // Decrypted raw xPub
+}
+
+// newUtxoPool will start a new model
+func newUtxoPool(rawXpubKey string, targetSize uint32, satoshis uint64, opts ...ModelOps) *UtxoPool {
+	return &UtxoPool{
+		ID:         utils.Hash(rawXpubKey),
+		Model:      *NewBaseModel(ModelUtxoPool, opts...),
+		Satoshis:   satoshis,
+		TargetSize: targetSize,
+		rawXpubKey: rawXpubKey,
+	}
+}
+
+// getUtxoPool will get the model for the given xPub ID
+func getUtxoPool(ctx context.Context, xPubID string, opts ...ModelOps) (*UtxoPool, error) {
+
+	// Construct an empty model
+	pool := &UtxoPool{
+		ID: xPubID,
+	}
+	pool.enrich(ModelUtxoPool, opts...)
+
+	// Get the record
+	if err := Get(ctx, pool, nil, false, defaultDatabaseReadTimeout, false); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return pool, nil
+}
+
+// getUtxoPools will get all the active pools
+func getUtxoPools(ctx context.Context, queryParams *datastore.QueryParams,
+	opts ...ModelOps) ([]*UtxoPool, error) {
+
+	modelItems := make([]*UtxoPool, 0)
+	if err := getModelsByConditions(
+		ctx, ModelUtxoPool, &modelItems, nil, &map[string]interface{}{
+			deletedAtField: nil,
+		}, queryParams, opts...,
+	); err != nil {
+		return nil, err
+	}
+
+	return modelItems, nil
+}
+
+// getRawXpubKey will get the raw xPub (decrypted if an encryption key is set)
+func (m *UtxoPool) getRawXpubKey() (rawXpubKey string, err error) {
+	if len(m.rawXpubKey) == 0 {
+		m.rawXpubKey, err = m.decryptXpubKey(m.XpubKey)
+	}
+	return m.rawXpubKey, err
+}
+
+// validate will check the target size and the satoshis of the pool
+func (m *UtxoPool) validate() error {
+	if m.TargetSize == 0 || m.TargetSize > maxSplitOutputs {
+		return ErrInvalidSplitCount
+	} else if m.Satoshis <= dustLimit {
+		return ErrOutputValueTooLow
+	}
+	return nil
+}
+
+// getSize will get the number of spendable utxos of the xPub that are in the pool
+func (m *UtxoPool) getSize(ctx context.Context) (int64, error) {
+	return getUtxosCount(ctx, nil, &map[string]interface{}{
+		draftIDField: nil,
+		satoshisField: map[string]interface{}{
+			"$gte": m.Satoshis,
+			"$lt":  2 * m.Satoshis,
+		},
+		spendingTxIDField: nil,
+		typeField:         utils.ScriptTypePubKeyHash,
+		xPubIDField:       m.ID,
+	}, m.GetOptions(false)...)
+}
+
+// getFundingUtxos will get the spendable utxos of the xPub that are not in the pool (smallest first),
+// enough to fund the missing pool utxos (with one more as headroom for the fee) if the xPub has them
+func (m *UtxoPool) getFundingUtxos(ctx context.Context, missing int) ([]*UtxoPointer, uint64, error) {
+	utxos, err := getUtxosByConditions(ctx, map[string]interface{}{
+		"$or": []map[string]interface{}{
+			{satoshisField: map[string]interface{}{"$lt": m.Satoshis}},
+			{satoshisField: map[string]interface{}{"$gte": 2 * m.Satoshis}},
+		},
+		draftIDField:      nil,
+		spendingTxIDField: nil,
+		typeField:         utils.ScriptTypePubKeyHash,
+		xPubIDField:       m.ID,
+	}, &datastore.QueryParams{
+		OrderByField:  satoshisField,
+		SortDirection: datastore.SortAsc,
+	}, m.GetOptions(false)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fromUtxos := make([]*UtxoPointer, 0)
+	var satoshis uint64
+	for _, utxo := range utxos {
+		fromUtxos = append(fromUtxos, &utxo.UtxoPointer)
+		satoshis += utxo.Satoshis
+		if satoshis >= uint64(missing+1)*m.Satoshis {
+			break
+		}
+	}
+	return fromUtxos, satoshis, nil
+}
+
+// GetModelName will get the name of the current model
+func (m *UtxoPool) GetModelName() string {
+	return ModelUtxoPool.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *UtxoPool) GetModelTableName() string {
+	return tableUtxoPools
+}
+
+// Save will save the model into the Datastore
+func (m *UtxoPool) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *UtxoPool) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *UtxoPool) BeforeCreating(_ context.Context) (err error) {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	}
+
+	// The task needs the raw xPub for deriving the destinations of the split
+	if len(m.rawXpubKey) != utils.XpubKeyLength {
+		return utils.ErrXpubInvalidLength
+	}
+	if m.XpubKey, err = m.encryptXpubKey(m.rawXpubKey); err != nil {
+		return err
+	}
+
+	if err = m.validate(); err != nil {
+		return err
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// RegisterTasks will register the model specific tasks on client initialization
+func (m *UtxoPool) RegisterTasks() error {
+	// No task manager loaded?
+	tm := m.Client().Taskmanager()
+	if tm == nil {
+		return nil
+	}
+
+	// Register the task locally (cron task - set the defaults)
+	processTask := m.Name() + "_process"
+	ctx := context.Background()
+
+	// Register the task
+	if err := tm.RegisterTask(&taskmanager.Task{
+		Name:       processTask,
+		RetryLimit: 1,
+		Handler: func(client ClientInterface) error {
+			if taskErr := taskTopUpUtxoPools(ctx, client); taskErr != nil {
+				client.Logger().Error(ctx, "error running "+processTask+" task: "+taskErr.Error())
+			}
+			return nil
+		},
+	}); err != nil {
+		return err
+	}
+
+	// Run the task periodically
+	return tm.RunTask(ctx, &taskmanager.TaskOptions{
+		Arguments:      []interface{}{m.Client()},
+		RunEveryPeriod: m.Client().GetTaskPeriod(processTask),
+		TaskName:       processTask,
+	})
+}
+
+// Migrate model specific migration on startup
+func (m *UtxoPool) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tableUtxoPools), metadataField)
+}
@@ -0,0 +1,157 @@
+package bux
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_NewUtxoPool(t *testing.T) {
+	t.Run("missing xPub", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}), WithUtxoPools(),
+		)
+		defer deferMe()
+
+		_, err := client.NewUtxoPool(ctx, testXPub, 10, 1000)
+		assert.ErrorIs(t, err, ErrMissingXpub)
+	})
+
+	t.Run("invalid pool", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, nil, WithUtxoPools())
+		defer deferMe()
+
+		_, err := client.NewUtxoPool(ctx, testXPub, 0, 1000)
+		assert.ErrorIs(t, err, ErrInvalidSplitCount)
+
+		_, err = client.NewUtxoPool(ctx, testXPub, 10, 0)
+		assert.ErrorIs(t, err, ErrOutputValueTooLow)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, nil, WithUtxoPools())
+		defer deferMe()
+
+		pool, err := client.NewUtxoPool(ctx, testXPub, 10, 1000)
+		require.NoError(t, err)
+		assert.Equal(t, testXPubID, pool.ID)
+
+		_, err = client.NewUtxoPool(ctx, testXPub, 20, 2000)
+		require.NoError(t, err)
+
+		pool, err = client.GetUtxoPool(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(20), pool.TargetSize)
+		assert.Equal(t, uint64(2000), pool.Satoshis)
+
+		require.NoError(t, client.DeleteUtxoPool(ctx, testXPubID))
+		_, err = client.GetUtxoPool(ctx, testXPubID)
+		assert.ErrorIs(t, err, ErrMissingUtxoPool)
+	})
+}
+
+func TestClient_TopUpUtxoPool(t *testing.T) {
+	t.Run("missing signer", func(t *testing.T) {
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{100000}, WithUtxoPools())
+		defer deferMe()
+
+		_, err := client.NewUtxoPool(ctx, testXPub, 4, 1000)
+		require.NoError(t, err)
+
+		_, err = client.TopUpUtxoPool(ctx, testXPubID)
+		assert.ErrorIs(t, err, ErrMissingDraftSigner)
+	})
+
+	t.Run("top up", func(t *testing.T) {
+		signer, err := NewXPrivDraftSigner(testXPriv)
+		require.NoError(t, err)
+
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{1000, 1500, 3000, 500}, WithUtxoPools(), WithDraftSigner(signer))
+		defer deferMe()
+
+		var pool *UtxoPool
+		pool, err = client.NewUtxoPool(ctx, testXPub, 4, 1000)
+		require.NoError(t, err)
+
+		var size int64
+		size, err = pool.getSize(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), size)
+
+		var transaction *Transaction
+		transaction, err = client.TopUpUtxoPool(ctx, testXPubID)
+		require.NoError(t, err)
+		require.NotNil(t, transaction)
+		assert.Equal(t, uint32(2), transaction.NumberOfOutputs)
+
+		// the utxos of the pool are not spent
+		var utxos []*Utxo
+		utxos, err = client.GetUtxosByXpubID(ctx, testXPubID, nil, &map[string]interface{}{
+			spendingTxIDField: transaction.ID,
+		}, nil)
+		require.NoError(t, err)
+		require.Len(t, utxos, 2)
+		assert.ElementsMatch(t, []uint64{500, 3000}, []uint64{utxos[0].Satoshis, utxos[1].Satoshis})
+
+		size, err = pool.getSize(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), size)
+
+		// the pool is full
+		transaction, err = client.TopUpUtxoPool(ctx, testXPubID)
+		require.NoError(t, err)
+		assert.Nil(t, transaction)
+	})
+
+	t.Run("large utxo", func(t *testing.T) {
+		signer, err := NewXPrivDraftSigner(testXPriv)
+		require.NoError(t, err)
+
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{10000}, WithUtxoPools(), WithDraftSigner(signer))
+		defer deferMe()
+
+		var pool *UtxoPool
+		pool, err = client.NewUtxoPool(ctx, testXPub, 2, 1000)
+		require.NoError(t, err)
+
+		// every output is a pool utxo
+		var transaction *Transaction
+		transaction, err = client.TopUpUtxoPool(ctx, testXPubID)
+		require.NoError(t, err)
+		require.NotNil(t, transaction)
+		assert.Equal(t, uint32(5), transaction.NumberOfOutputs)
+
+		var size int64
+		size, err = pool.getSize(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), size)
+	})
+}
+
+func Test_taskTopUpUtxoPools(t *testing.T) {
+	t.Run("failing pool does not fail the task", func(t *testing.T) {
+		var signed int
+		ctx, client, deferMe := initConsolidationTestCase(t, []uint64{100000}, WithUtxoPools(),
+			WithDraftSigner(DraftSignerFunc(func(context.Context, *DraftTransaction) (string, error) {
+				signed++
+				return "", errors.New("not signing")
+			})),
+		)
+		defer deferMe()
+
+		_, err := client.NewUtxoPool(ctx, testXPub, 4, 1000)
+		require.NoError(t, err)
+
+		require.NoError(t, taskTopUpUtxoPools(ctx, client))
+		assert.Equal(t, 1, signed)
+
+		// the utxo of the split was released
+		var count int64
+		count, err = client.GetUtxosCount(ctx, nil, &map[string]interface{}{draftIDField: nil})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
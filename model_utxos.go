@@ -143,9 +143,12 @@ func unReserveUtxos(ctx context.Context, xPubID, draftID string, opts ...ModelOp
 	return nil
 }
 
-// reserveUtxos reserve utxos for the given draft ID and amount
-func reserveUtxos(ctx context.Context, xPubID, draftID string,
-	satoshis uint64, feePerByte float64, fromUtxos []*UtxoPointer, opts ...ModelOps) ([]*Utxo, error) {
+// reserveUtxos will reserve utxos for the given draft ID that cover the target
+//
+// Without a selector, the spendable utxos are selected in datastore order (paginated) until the target is covered,
+// otherwise the selector picks from all the spendable utxos of the xPub (or fromUtxos if set)
+func reserveUtxos(ctx context.Context, xPubID, draftID string, target *UtxoSelectionTarget,
+	selector UtxoSelector, fromUtxos []*UtxoPointer, opts ...ModelOps) ([]*Utxo, error) {
 
 	// Create base model
 	m := NewBaseModel(ModelNameEmpty, opts...)
@@ -161,11 +164,9 @@ func reserveUtxos(ctx context.Context, xPubID, draftID string,
 
 	// Get spendable utxos
 	utxos := new([]*Utxo)
-	feeNeeded := uint64(0)
-	reservedSatoshis := uint64(0)
 
 	queryParams := &datastore.QueryParams{}
-	if fromUtxos == nil {
+	if fromUtxos == nil && selector == nil {
 		// if we are not getting all utxos, paginate the retreival
 		queryParams.Page = 1
 		queryParams.PageSize = m.pageSize
@@ -187,8 +188,12 @@ reserveUtxoLoop:
 			break reserveUtxoLoop
 		}
 
-		// Set vars
-		size := utils.GetInputSizeForType(utils.ScriptTypePubKeyHash)
+		// Let the selector pick from all the spendable utxos
+		if selector != nil {
+			if freeUtxos, err = selector.SelectUtxos(freeUtxos, target); err != nil {
+				return nil, err
+			}
+		}
 
 		// Loop the returned utxos
 		for _, utxo := range freeUtxos {
@@ -199,9 +204,6 @@ reserveUtxoLoop:
 			utxo.ReservedAt.Valid = true
 			utxo.ReservedAt.Time = time.Now().UTC()
 
-			// Accumulate the reserved satoshis
-			reservedSatoshis += utxo.Satoshis
-
 			// Save the UTXO
 			// todo: should occur in 1 DB transaction
 			if err = utxo.Save(ctx); err != nil {
@@ -211,9 +213,8 @@ reserveUtxoLoop:
 			// Add the utxo to the final slice
 			*utxos = append(*utxos, utxo)
 
-			// add fee for this new input
-			feeNeeded += uint64(float64(size) * feePerByte)
-			if reservedSatoshis >= (satoshis + feeNeeded) {
+			// the fee is estimated for the inputs selected so far
+			if selector == nil && target.IsCovered(*utxos) {
 				break reserveUtxoLoop
 			}
 		}
@@ -224,7 +225,7 @@ reserveUtxoLoop:
 		}
 	}
 
-	if reservedSatoshis < satoshis {
+	if !target.IsCovered(*utxos) {
 		if err = unReserveUtxos(
 			ctx, xPubID, draftID, m.GetOptions(false)...,
 		); err != nil {
@@ -163,7 +163,7 @@ func TestUtxo_UnReserveUtxos(t *testing.T) {
 		require.NoError(t, err)
 
 		var utxos []*Utxo
-		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, 2000, 0.5, nil, client.DefaultModelOptions()...)
+		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(2000, 0.5), nil, nil, client.DefaultModelOptions()...)
 		require.NoError(t, err)
 		assert.Len(t, utxos, 2)
 		for _, utxo := range utxos {
@@ -194,7 +194,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 		require.NoError(t, err)
 
 		var utxos []*Utxo
-		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, 1000, 0.5, nil, client.DefaultModelOptions()...)
+		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(1000, 0.5), nil, nil, client.DefaultModelOptions()...)
 		require.NoError(t, err)
 		assert.Len(t, utxos, 1)
 		assert.Equal(t, testDraftID2, utxos[0].DraftID.String)
@@ -208,7 +208,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 		require.NoError(t, err)
 
 		var utxos []*Utxo
-		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, 2000, 0.5, nil, client.DefaultModelOptions()...)
+		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(2000, 0.5), nil, nil, client.DefaultModelOptions()...)
 		require.NoError(t, err)
 		assert.Len(t, utxos, 2)
 		assert.Equal(t, testDraftID2, utxos[0].DraftID.String)
@@ -223,7 +223,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 		err := createTestUtxos(ctx, client)
 		require.NoError(t, err)
 
-		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, 20000, 0.5, nil, client.DefaultModelOptions()...)
+		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(20000, 0.5), nil, nil, client.DefaultModelOptions()...)
 		require.Error(t, err, ErrNotEnoughUtxos)
 	})
 
@@ -239,7 +239,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 		}}
 
 		var utxos []*Utxo
-		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, 1000, 0.5, fromUtxos, client.DefaultModelOptions()...)
+		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(1000, 0.5), nil, fromUtxos, client.DefaultModelOptions()...)
 		require.NoError(t, err)
 		assert.Len(t, utxos, 1)
 		assert.Equal(t, testDraftID2, utxos[0].DraftID.String)
@@ -263,7 +263,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 		}}
 
 		var utxos []*Utxo
-		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, 2000, 0.5, fromUtxos, client.DefaultModelOptions()...)
+		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(2000, 0.5), nil, fromUtxos, client.DefaultModelOptions()...)
 		require.NoError(t, err)
 		assert.Len(t, utxos, 2)
 		assert.Equal(t, testDraftID2, utxos[0].DraftID.String)
@@ -286,7 +286,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 			TransactionID: testTxID,
 			OutputIndex:   16,
 		}}
-		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, 2000, 0.5, fromUtxos, client.DefaultModelOptions()...)
+		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(2000, 0.5), nil, fromUtxos, client.DefaultModelOptions()...)
 		require.Error(t, err, ErrNotEnoughUtxos)
 	})
 
@@ -297,7 +297,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 		require.NoError(t, err)
 
 		var utxos []*Utxo
-		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, 4000, 0.5, nil, client.DefaultModelOptions(WithPageSize(2))...)
+		utxos, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(4000, 0.5), nil, nil, client.DefaultModelOptions(WithPageSize(2))...)
 		require.NoError(t, err)
 		assert.Len(t, utxos, 4)
 	})
@@ -319,7 +319,7 @@ func TestUtxo_ReserveUtxos(t *testing.T) {
 			OutputIndex:   utxo.OutputIndex,
 		}}
 
-		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, 2200, 0.05, fromUtxos, client.DefaultModelOptions()...)
+		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(2200, 0.05), nil, fromUtxos, client.DefaultModelOptions()...)
 		require.ErrorIs(t, err, ErrDuplicateUTXOs)
 	})
 }
@@ -339,14 +339,14 @@ func TestUtxo_GetSpendableUtxos(t *testing.T) {
 		require.NoError(t, err)
 		assert.Len(t, utxos, 5)
 
-		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, 2000, 0.5, nil, opts...)
+		_, err = reserveUtxos(ctx, testXPubID, testDraftID2, testUtxoSelectionTarget(2000, 0.5), nil, nil, opts...)
 		require.NoError(t, err)
 
 		utxos, err = getSpendableUtxos(ctx, testXPubID, utils.ScriptTypePubKeyHash, nil, nil, opts...)
 		require.NoError(t, err)
 		assert.Len(t, utxos, 3)
 
-		_, err = reserveUtxos(ctx, testXPubID, testDraftID3, 1000, 0.5, nil, opts...)
+		_, err = reserveUtxos(ctx, testXPubID, testDraftID3, testUtxoSelectionTarget(1000, 0.5), nil, nil, opts...)
 		require.NoError(t, err)
 
 		utxos, err = getSpendableUtxos(ctx, testXPubID, utils.ScriptTypePubKeyHash, nil, nil, opts...)
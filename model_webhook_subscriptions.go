@@ -0,0 +1,239 @@
+package bux
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/BuxOrg/bux/notifications"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+)
+
+// WebhookSubscription is an object representing a webhook that is notified about the matching events
+//
+// Empty model types or event types match all of them. A subscription with an xPub ID only
+// receives the events about that xPub, a subscription without an xPub ID receives all events.
+//
+// Gorm related models & indexes: https://gorm.io/docs/models.html - https://gorm.io/docs/indexes.html
+type WebhookSubscription struct {
+	// Base model
+	Model `bson:",inline"`
+
+	// Model specific fields
+	ID            string `json:"id" toml:"id" yaml:"id" gorm:"<-:create;type:char(64);primaryKey;comment:This is the unique subscription id" bson:"_id"`
+	XpubID        string `json:"xpub_id" toml:"xpub_id" yaml:"xpub_id" gorm:"<-:create;type:char(64);index;comment:This is the related xPub id (empty for all xPubs)" bson:"xpub_id"`
+	URL           string `json:"url" toml:"url" yaml:"url" gorm:"<-:create;type:text;comment:This is the webhook endpoint" bson:"url"`
+	ModelTypes    IDs    `json:"model_types" toml:"model_types" yaml:"model_types" gorm:"<-:create;type:json;comment:This is the list of model types (empty for all)" bson:"model_types"`
+	EventTypes    IDs    `json:"event_types" toml:"event_types" yaml:"event_types" gorm:"<-:create;type:json;comment:This is the list of event types (empty for all)" bson:"event_types"`
+	SigningSecret string `json:"-" toml:"-" yaml:"-" gorm:"<-:create;type:text;comment:This is the secret for signing the payloads (encrypted)" bson:"signing_secret"`
+
+	// Private fields
+	signingSecret string // Decrypted signing secret
+}
+
+// newWebhookSubscription will start a new model
+func newWebhookSubscription(xPubID, webhookURL, signingSecret string, modelTypes []ModelName,
+	eventTypes []notifications.EventType, opts ...ModelOps) *WebhookSubscription {
+
+	id, _ := utils.RandomHex(32)
+	subscription := &WebhookSubscription{
+		EventTypes:    IDs{},
+		ID:            id,
+		Model:         *NewBaseModel(ModelWebhookSubscription, opts...),
+		ModelTypes:    IDs{},
+		URL:           webhookURL,
+		XpubID:        xPubID,
+		signingSecret: signingSecret,
+	}
+	for _, modelType := range modelTypes {
+		subscription.ModelTypes = append(subscription.ModelTypes, modelType.String())
+	}
+	for _, eventType := range eventTypes {
+		subscription.EventTypes = append(subscription.EventTypes, string(eventType))
+	}
+	return subscription
+}
+
+// getWebhookSubscription will get the model with a given ID
+func getWebhookSubscription(ctx context.Context, id string, opts ...ModelOps) (*WebhookSubscription, error) {
+
+	// Construct an empty model
+	subscription := &WebhookSubscription{
+		ID: id,
+	}
+	subscription.enrich(ModelWebhookSubscription, opts...)
+
+	// Get the record
+	if err := Get(ctx, subscription, nil, false, defaultDatabaseReadTimeout, false); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// getWebhookSubscriptions will get all the subscriptions with the given conditions
+func getWebhookSubscriptions(ctx context.Context, metadata *Metadata, conditions *map[string]interface{},
+	queryParams *datastore.QueryParams, opts ...ModelOps) ([]*WebhookSubscription, error) {
+
+	modelItems := make([]*WebhookSubscription, 0)
+	if err := getModelsByConditions(
+		ctx, ModelWebhookSubscription, &modelItems, metadata, conditions, queryParams, opts...,
+	); err != nil {
+		return nil, err
+	}
+
+	// Set the options (the encryption key of the signing secret)
+	for index := range modelItems {
+		modelItems[index].enrich(ModelWebhookSubscription, opts...)
+	}
+
+	return modelItems, nil
+}
+
+// getWebhookSubscriptionsForXPubs will get the active subscriptions for all xPubs and for the given xPubs
+func getWebhookSubscriptionsForXPubs(ctx context.Context, xPubIDs []string,
+	opts ...ModelOps) ([]*WebhookSubscription, error) {
+
+	or := []map[string]interface{}{{xPubIDField: ""}}
+	for _, xPubID := range xPubIDs {
+		or = append(or, map[string]interface{}{xPubIDField: xPubID})
+	}
+
+	return getWebhookSubscriptions(ctx, nil, &map[string]interface{}{
+		deletedAtField: nil,
+		"$or":          or,
+	}, nil, opts...)
+}
+
+// matches will return true if the subscription wants the event
+func (m *WebhookSubscription) matches(modelType string, eventType notifications.EventType) bool {
+	return (len(m.ModelTypes) == 0 || utils.StringInSlice(modelType, m.ModelTypes)) &&
+		(len(m.EventTypes) == 0 || utils.StringInSlice(string(eventType), m.EventTypes))
+}
+
+// encryptSigningSecret will encrypt the signing secret for storing (if an encryption key is set)
+func (m *WebhookSubscription) encryptSigningSecret() (err error) {
+	if len(m.signingSecret) == 0 || len(m.encryptionKey) == 0 {
+		m.SigningSecret = m.signingSecret
+		return
+	}
+	m.SigningSecret, err = utils.Encrypt(m.encryptionKey, m.signingSecret)
+	return
+}
+
+// getSigningSecret will get the decrypted signing secret
+func (m *WebhookSubscription) getSigningSecret() (string, error) {
+	if len(m.signingSecret) == 0 && len(m.SigningSecret) > 0 {
+		if len(m.encryptionKey) == 0 {
+			m.signingSecret = m.SigningSecret
+		} else {
+			var err error
+			if m.signingSecret, err = utils.Decrypt(m.encryptionKey, m.SigningSecret); err != nil {
+				return "", err
+			}
+		}
+	}
+	return m.signingSecret, nil
+}
+
+// webhook will return the notifications webhook for the subscription
+func (m *WebhookSubscription) webhook() (*notifications.Webhook, error) {
+	signingSecret, err := m.getSigningSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &notifications.Webhook{
+		Endpoint:       m.URL,
+		SigningSecret:  signingSecret,
+		SubscriptionID: m.ID,
+	}, nil
+}
+
+// GetModelName will get the name of the current model
+func (m *WebhookSubscription) GetModelName() string {
+	return ModelWebhookSubscription.String()
+}
+
+// GetModelTableName will get the db table name of the current model
+func (m *WebhookSubscription) GetModelTableName() string {
+	return tableWebhookSubscriptions
+}
+
+// Save will save the model into the Datastore
+func (m *WebhookSubscription) Save(ctx context.Context) error {
+	return Save(ctx, m)
+}
+
+// GetID will get the ID
+func (m *WebhookSubscription) GetID() string {
+	return m.ID
+}
+
+// BeforeCreating will fire before the model is being inserted into the Datastore
+func (m *WebhookSubscription) BeforeCreating(_ context.Context) error {
+	m.DebugLog("starting: [" + m.name.String() + "] BeforeCreating hook...")
+
+	// Make sure ID is valid
+	if len(m.ID) == 0 {
+		return ErrMissingFieldID
+	}
+
+	// Only absolute http(s) endpoints can be notified
+	u, err := url.Parse(m.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return ErrInvalidWebhookURL
+	}
+
+	for _, modelType := range m.ModelTypes {
+		if !isKnownModelName(modelType) {
+			return ErrUnknownModelType
+		}
+	}
+
+	for _, eventType := range m.EventTypes {
+		if !isKnownEventType(eventType) {
+			return ErrUnknownEventType
+		}
+	}
+
+	// Encrypt the signing secret
+	if err = m.encryptSigningSecret(); err != nil {
+		return err
+	}
+
+	m.DebugLog("end: " + m.Name() + " BeforeCreating hook")
+	return nil
+}
+
+// RegisterTasks will register the model specific tasks on client initialization
+func (m *WebhookSubscription) RegisterTasks() error {
+	return nil
+}
+
+// Migrate model specific migration on startup
+func (m *WebhookSubscription) Migrate(client datastore.ClientInterface) error {
+	return client.IndexMetadata(client.GetTableName(tableWebhookSubscriptions), metadataField)
+}
+
+// isKnownModelName will return true if the name is one of the bux models
+func isKnownModelName(name string) bool {
+	for _, modelName := range AllModelNames {
+		if modelName.String() == name {
+			return true
+		}
+	}
+	return name == ModelDraftTransaction.String()
+}
+
+// isKnownEventType will return true if the event type is fired by bux
+func isKnownEventType(eventType string) bool {
+	switch notifications.EventType(eventType) {
+	case notifications.EventTypeCreate, notifications.EventTypeUpdate,
+		notifications.EventTypeDelete, notifications.EventTypeBroadcast, notifications.EventTypeApproval:
+		return true
+	}
+	return false
+}
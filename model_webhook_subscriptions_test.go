@@ -0,0 +1,260 @@
+package bux
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BuxOrg/bux/notifications"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSubscriberURL = "https://subscriber.example.com/v1/webhook"
+
+func Test_newWebhookSubscription(t *testing.T) {
+	t.Run("valid subscription", func(t *testing.T) {
+		subscription := newWebhookSubscription(
+			testXPubID, testSubscriberURL, "secret",
+			[]ModelName{ModelDestination}, []notifications.EventType{notifications.EventTypeCreate},
+		)
+		require.NotNil(t, subscription)
+		assert.Equal(t, 64, len(subscription.GetID()))
+		assert.Equal(t, ModelWebhookSubscription.String(), subscription.GetModelName())
+		assert.Equal(t, IDs{ModelDestination.String()}, subscription.ModelTypes)
+		assert.Equal(t, IDs{string(notifications.EventTypeCreate)}, subscription.EventTypes)
+	})
+}
+
+func TestWebhookSubscription_matches(t *testing.T) {
+	t.Run("empty filters match all", func(t *testing.T) {
+		subscription := newWebhookSubscription("", testSubscriberURL, "", nil, nil)
+		assert.True(t, subscription.matches(ModelTransaction.String(), notifications.EventTypeCreate))
+		assert.True(t, subscription.matches(ModelDestination.String(), notifications.EventTypeDelete))
+	})
+
+	t.Run("model and event filters", func(t *testing.T) {
+		subscription := newWebhookSubscription(
+			"", testSubscriberURL, "",
+			[]ModelName{ModelSyncTransaction}, []notifications.EventType{notifications.EventTypeBroadcast},
+		)
+		assert.True(t, subscription.matches(ModelSyncTransaction.String(), notifications.EventTypeBroadcast))
+		assert.False(t, subscription.matches(ModelSyncTransaction.String(), notifications.EventTypeUpdate))
+		assert.False(t, subscription.matches(ModelTransaction.String(), notifications.EventTypeBroadcast))
+	})
+}
+
+func TestClient_NewWebhookSubscription(t *testing.T) {
+	t.Run("invalid url", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		_, err := client.NewWebhookSubscription(ctx, "", "ftp://example.com", "", nil, nil)
+		assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+	})
+
+	t.Run("unknown model type", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		_, err := client.NewWebhookSubscription(ctx, "", testSubscriberURL, "", []ModelName{"unknown"}, nil)
+		assert.ErrorIs(t, err, ErrUnknownModelType)
+	})
+
+	t.Run("unknown event type", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		_, err := client.NewWebhookSubscription(ctx, "", testSubscriberURL, "", nil, []notifications.EventType{"unknown"})
+		assert.ErrorIs(t, err, ErrUnknownEventType)
+	})
+
+	t.Run("unknown xPub", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+
+		_, err := client.NewWebhookSubscription(ctx, testXPubID, testSubscriberURL, "", nil, nil)
+		assert.ErrorIs(t, err, ErrMissingXpub)
+	})
+
+	t.Run("encrypted signing secret", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.Reset()
+
+		verifier := notifications.NewVerifier("secret", 0)
+		var verifyErr error
+		httpmock.RegisterResponder(http.MethodPost, testSubscriberURL,
+			func(req *http.Request) (*http.Response, error) {
+				_, verifyErr = verifier.VerifyRequest(req)
+				return httpmock.NewStringResponse(http.StatusOK, "OK"), nil
+			},
+		)
+
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithWebhookSubscriptions(),
+			WithEncryption(testEncryption),
+		)
+		defer deferMe()
+
+		subscription, err := client.NewWebhookSubscription(ctx, "", testSubscriberURL, "secret", nil, nil)
+		require.NoError(t, err)
+
+		subscription, err = getWebhookSubscription(ctx, subscription.ID, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		assert.NotEqual(t, "secret", subscription.SigningSecret)
+
+		// the payloads are signed with the decrypted secret
+		require.NoError(t, client.Notifications().Notify(
+			ctx, ModelTransaction.String(), notifications.EventTypeCreate, map[string]interface{}{}, testTxID,
+		))
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+		assert.NoError(t, verifyErr)
+	})
+
+	t.Run("get and delete", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithWebhookSubscriptions(),
+		)
+		defer deferMe()
+
+		_, err := client.NewXpub(ctx, testXPub, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+
+		var subscription *WebhookSubscription
+		subscription, err = client.NewWebhookSubscription(ctx, testXPubID, testSubscriberURL, "secret", nil, nil)
+		require.NoError(t, err)
+
+		_, err = client.GetWebhookSubscription(ctx, "", subscription.ID)
+		assert.ErrorIs(t, err, utils.ErrXpubNoMatch)
+
+		var subscriptions []*WebhookSubscription
+		subscriptions, err = client.GetWebhookSubscriptionsByXPubID(ctx, testXPubID, nil)
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 1)
+		assert.Equal(t, subscription.ID, subscriptions[0].ID)
+
+		require.NoError(t, client.DeleteWebhookSubscription(ctx, testXPubID, subscription.ID))
+
+		_, err = client.GetWebhookSubscription(ctx, testXPubID, subscription.ID)
+		assert.ErrorIs(t, err, ErrMissingWebhookSubscription)
+
+		subscriptions, err = client.GetWebhookSubscriptionsByXPubID(ctx, testXPubID, nil)
+		require.NoError(t, err)
+		assert.Len(t, subscriptions, 0)
+	})
+}
+
+func Test_notificationSubscriptions(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	t.Run("only matching subscriptions are notified", func(t *testing.T) {
+		httpmock.Reset()
+		allURL := testSubscriberURL + "/all"
+		xPubURL := testSubscriberURL + "/xpub"
+		txURL := testSubscriberURL + "/transactions"
+		for _, u := range []string{allURL, xPubURL, txURL} {
+			httpmock.RegisterResponder(http.MethodPost, u, httpmock.NewStringResponder(http.StatusOK, "OK"))
+		}
+
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithWebhookSubscriptions(),
+		)
+		defer deferMe()
+
+		_, err := client.NewXpub(ctx, testXPub, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+
+		_, err = client.NewWebhookSubscription(ctx, "", allURL, "", nil, nil)
+		require.NoError(t, err)
+		_, err = client.NewWebhookSubscription(ctx, testXPubID, xPubURL, "", []ModelName{ModelDestination}, nil)
+		require.NoError(t, err)
+		_, err = client.NewWebhookSubscription(ctx, "", txURL, "", []ModelName{ModelTransaction}, nil)
+		require.NoError(t, err)
+
+		// Destination of the xPub
+		destination := newDestination(testXPubID, testLockingScript, client.DefaultModelOptions()...)
+		require.NoError(t, client.Notifications().Notify(
+			ctx, destination.GetModelName(), notifications.EventTypeCreate, destination, destination.GetID(),
+		))
+
+		// Destination of another xPub
+		other := newDestination(utils.Hash("other-xpub"), testLockingScript, client.DefaultModelOptions()...)
+		require.NoError(t, client.Notifications().Notify(
+			ctx, other.GetModelName(), notifications.EventTypeCreate, other, other.GetID(),
+		))
+
+		info := httpmock.GetCallCountInfo()
+		assert.Equal(t, 2, info[http.MethodPost+" "+allURL])
+		assert.Equal(t, 1, info[http.MethodPost+" "+xPubURL])
+		assert.Equal(t, 0, info[http.MethodPost+" "+txURL])
+	})
+
+	t.Run("approval events of a draft are sent to the xPub", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(http.MethodPost, testSubscriberURL, httpmock.NewStringResponder(http.StatusOK, "OK"))
+
+		ctx, client, _, deferMe := initDraftApprovalTestCase(t,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithWebhookSubscriptions(),
+		)
+		defer deferMe()
+
+		_, err := client.NewWebhookSubscription(
+			ctx, testXPubID, testSubscriberURL, "", nil, []notifications.EventType{notifications.EventTypeApproval},
+		)
+		require.NoError(t, err)
+
+		// approvals are requested for the new draft
+		var draft *DraftTransaction
+		draft, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, DraftApprovalPending, draft.ApprovalStatus)
+
+		assert.Eventually(t, func() bool {
+			return httpmock.GetCallCountInfo()[http.MethodPost+" "+testSubscriberURL] == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("deliveries of a deleted subscription are dead-lettered", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(http.MethodPost, testSubscriberURL,
+			httpmock.NewStringResponder(http.StatusServiceUnavailable, "unavailable"),
+		)
+
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithWebhookSubscriptions(),
+		)
+		defer deferMe()
+
+		subscription, err := client.NewWebhookSubscription(ctx, "", testSubscriberURL, "secret", nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, client.Notifications().Notify(
+			ctx, ModelTransaction.String(), notifications.EventTypeCreate, map[string]interface{}{}, testTxID,
+		))
+
+		var deliveries []NotificationDelivery
+		require.NoError(t, getModels(ctx, client.Datastore(), &deliveries, map[string]interface{}{}, nil, defaultDatabaseReadTimeout))
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, subscription.ID, deliveries[0].SubscriptionID)
+
+		require.NoError(t, client.DeleteWebhookSubscription(ctx, "", subscription.ID))
+
+		deliveries[0].enrich(ModelNotificationDelivery, client.DefaultModelOptions()...)
+		require.NoError(t, retryNotificationDelivery(ctx, &deliveries[0]))
+
+		delivery := getTestNotificationDelivery(ctx, t, client, deliveries[0].ID)
+		assert.Equal(t, DeliveryStatusDeadLetter, delivery.Status)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+}
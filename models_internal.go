@@ -5,6 +5,10 @@ import (
 	"time"
 
 	"github.com/BuxOrg/bux/notifications"
+	"github.com/BuxOrg/bux/utils"
+	"github.com/mrz1836/go-datastore"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
 )
 
 // AfterDeleted will fire after a successful delete in the Datastore
@@ -150,6 +154,22 @@ func (m *Model) AfterCreated(_ context.Context) error {
 	return nil
 }
 
+// encryptXpubKey will encrypt the raw xPub for storing (if an encryption key is set)
+func (m *Model) encryptXpubKey(rawXpubKey string) (string, error) {
+	if len(m.encryptionKey) == 0 {
+		return rawXpubKey, nil
+	}
+	return utils.Encrypt(m.encryptionKey, rawXpubKey)
+}
+
+// decryptXpubKey will decrypt a stored xPub (if it was encrypted)
+func (m *Model) decryptXpubKey(xPubKey string) (string, error) {
+	if len(xPubKey) == utils.XpubKeyLength {
+		return xPubKey, nil
+	}
+	return utils.Decrypt(m.encryptionKey, xPubKey)
+}
+
 // incrementField will increment the given field atomically in the datastore
 func incrementField(ctx context.Context, model ModelInterface, fieldName string,
 	increment int64) (int64, error) {
@@ -171,6 +191,31 @@ func incrementField(ctx context.Context, model ModelInterface, fieldName string,
 	return newValue, nil
 }
 
+// updateFields will update only the given fields (columns) of the model in the datastore, the other fields
+// are not written (unlike Save) so they cannot be overwritten with stale values
+func updateFields(ctx context.Context, model ModelInterface, fields map[string]interface{}) error {
+
+	// Check for client
+	c := model.Client()
+	if c == nil {
+		return ErrMissingClient
+	}
+
+	fields[updatedAtField] = time.Now().UTC()
+	if c.Datastore().Engine() == datastore.MongoDB {
+		_, err := c.Datastore().GetMongoCollection(model.GetModelTableName()).UpdateOne(
+			ctx, bson.M{"_id": model.GetID()}, bson.M{"$set": fields},
+		)
+		return err
+	} else if !datastore.IsSQLEngine(c.Datastore().Engine()) {
+		return datastore.ErrUnsupportedEngine
+	}
+
+	return c.Datastore().Raw("").Session(&gorm.Session{NewDB: true, Context: ctx}).
+		Table(c.Datastore().GetTableName(model.GetModelTableName())).
+		Where(idField+" = ?", model.GetID()).Updates(fields).Error
+}
+
 // notify about an event on the model
 func notify(eventType notifications.EventType, model interface{}) {
 
@@ -21,17 +21,22 @@ func TestModelName_String(t *testing.T) {
 
 	t.Run("all model names", func(t *testing.T) {
 		assert.Equal(t, "block_header", ModelBlockHeader.String())
+		assert.Equal(t, "contact", ModelContact.String())
 		assert.Equal(t, "destination", ModelDestination.String())
 		assert.Equal(t, "empty", ModelNameEmpty.String())
 		assert.Equal(t, "incoming_transaction", ModelIncomingTransaction.String())
 		assert.Equal(t, "metadata", ModelMetadata.String())
+		assert.Equal(t, "notification_delivery", ModelNotificationDelivery.String())
 		assert.Equal(t, "paymail_address", ModelPaymailAddress.String())
 		assert.Equal(t, "paymail_address", ModelPaymailAddress.String())
+		assert.Equal(t, "payment_reference", ModelPaymentReference.String())
+		assert.Equal(t, "spending_policy", ModelSpendingPolicy.String())
 		assert.Equal(t, "sync_transaction", ModelSyncTransaction.String())
 		assert.Equal(t, "transaction", ModelTransaction.String())
 		assert.Equal(t, "utxo", ModelUtxo.String())
+		assert.Equal(t, "webhook_subscription", ModelWebhookSubscription.String())
 		assert.Equal(t, "xpub", ModelXPub.String())
-		assert.Len(t, AllModelNames, 11)
+		assert.Len(t, AllModelNames, 19)
 	})
 }
 
@@ -0,0 +1,203 @@
+package bux
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/BuxOrg/bux/notifications"
+	"github.com/mrz1836/go-datastore"
+	customTypes "github.com/mrz1836/go-datastore/custom_types"
+)
+
+// notificationOutbox is the datastore backed outbox for the notifications client
+type notificationOutbox struct {
+	client ClientInterface
+}
+
+// Enqueue will store a failed delivery, so it can be retried by the task manager
+func (o *notificationOutbox) Enqueue(ctx context.Context, delivery *notifications.Delivery) error {
+	return newNotificationDelivery(
+		delivery, o.client.DefaultModelOptions(New())...,
+	).Save(ctx)
+}
+
+// notificationSubscriptions is the datastore backed provider of the subscribed webhooks
+type notificationSubscriptions struct {
+	client ClientInterface
+}
+
+// GetWebhooks will get the webhooks of the active subscriptions that match the event
+func (s *notificationSubscriptions) GetWebhooks(ctx context.Context, modelType string,
+	eventType notifications.EventType, model interface{}) ([]*notifications.Webhook, error) {
+
+	subscriptions, err := getWebhookSubscriptionsForXPubs(
+		ctx, getNotificationXPubIDs(ctx, model), s.client.DefaultModelOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]*notifications.Webhook, 0)
+	for _, subscription := range subscriptions {
+		if subscription.matches(modelType, eventType) {
+			// One broken subscription should not stop the others
+			webhook, webhookErr := subscription.webhook()
+			if webhookErr != nil {
+				s.client.Logger().Error(ctx, fmt.Sprintf(
+					"webhook subscription %s is skipped: %s", subscription.ID, webhookErr.Error(),
+				))
+				continue
+			}
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+// getNotificationXPubIDs will get the ids of the xPubs that are related to the model of the event
+func getNotificationXPubIDs(ctx context.Context, model interface{}) []string {
+	switch m := model.(type) {
+	case *Destination:
+		return []string{m.XpubID}
+	case *DraftTransaction:
+		return []string{m.XpubID}
+	case *PaymailAddress:
+		return []string{m.XpubID}
+	case *Utxo:
+		return []string{m.XpubID}
+	case *Transaction:
+		return append(append([]string{}, m.XpubInIDs...), m.XpubOutIDs...)
+	case *SyncTransaction:
+		transaction := m.transaction
+		if transaction == nil {
+			transaction, _ = getTransactionByID(ctx, "", m.ID, m.GetOptions(false)...)
+		}
+		if transaction != nil {
+			return getNotificationXPubIDs(ctx, transaction)
+		}
+	}
+	return nil
+}
+
+// processNotificationDeliveries will retry the pending deliveries that are due
+func processNotificationDeliveries(ctx context.Context, maxDeliveries int, opts ...ModelOps) error {
+	queryParams := &datastore.QueryParams{
+		Page:     1,
+		PageSize: maxDeliveries,
+	}
+
+	// Get x records
+	records, err := getNotificationDeliveriesToProcess(
+		ctx, queryParams, opts...,
+	)
+	if err != nil {
+		return err
+	} else if len(records) == 0 {
+		return nil
+	}
+
+	// Process the deliveries (a failed delivery does not stop the others)
+	for index := range records {
+		if err = retryNotificationDelivery(
+			ctx, records[index],
+		); err != nil {
+			records[index].Client().Logger().Error(ctx, fmt.Sprintf(
+				"processing notification delivery %s failed: %s", records[index].ID, err.Error(),
+			))
+		}
+	}
+
+	return nil
+}
+
+// retryNotificationDelivery will attempt the delivery again, reschedule it or move it to the dead-letter state
+func retryNotificationDelivery(ctx context.Context, delivery *NotificationDelivery) error {
+	// Successfully capture any panics, convert to readable string and log the error
+	defer recoverAndLog(ctx, delivery.Client().Logger())
+
+	// Create the lock and set the release for after the function completes
+	unlock, err := newWriteLock(
+		ctx, fmt.Sprintf(lockKeyProcessNotification, delivery.GetID()), delivery.Client().Cachestore(),
+	)
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	// No notifications client, nothing can be sent
+	n := delivery.Client().Notifications()
+	if n == nil {
+		return nil
+	}
+
+	delivery.Attempts++
+	delivery.LastAttempt = customTypes.NullTime{
+		NullTime: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	}
+
+	var webhook *notifications.Webhook
+	if webhook, err = getDeliveryWebhook(ctx, delivery); err != nil {
+		// Save the attempt, so a broken webhook ends in the dead-letter state
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= n.MaxAttempts() {
+			delivery.Status = DeliveryStatusDeadLetter
+		} else {
+			delivery.NextAttemptAt = time.Now().UTC().Add(n.RetryDelay(delivery.Attempts))
+		}
+		if saveErr := delivery.Save(ctx); saveErr != nil {
+			return saveErr
+		}
+		return err
+	} else if webhook == nil {
+		// The subscription was removed, there is no one left to notify
+		delivery.Status = DeliveryStatusDeadLetter
+		delivery.LastError = ErrMissingWebhookSubscription.Error()
+		return delivery.Save(ctx)
+	}
+
+	if err = n.Deliver(ctx, webhook, delivery.ID, []byte(delivery.Payload)); err == nil {
+		delivery.Status = DeliveryStatusComplete
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= n.MaxAttempts() {
+			delivery.Status = DeliveryStatusDeadLetter
+			delivery.Client().Logger().Error(ctx, fmt.Sprintf(
+				"notification delivery %s failed after %d attempts, moved to dead-letter: %s",
+				delivery.ID, delivery.Attempts, err.Error(),
+			))
+		} else {
+			delivery.NextAttemptAt = time.Now().UTC().Add(n.RetryDelay(delivery.Attempts))
+		}
+	}
+
+	return delivery.Save(ctx)
+}
+
+// getDeliveryWebhook will get the webhook for the delivery (nil if the subscription was removed)
+func getDeliveryWebhook(ctx context.Context, delivery *NotificationDelivery) (*notifications.Webhook, error) {
+
+	// Default webhook, signed with the current secret
+	if len(delivery.SubscriptionID) == 0 {
+		if webhook := delivery.Client().Notifications().DefaultWebhook(); webhook != nil &&
+			webhook.Endpoint == delivery.Endpoint {
+			return webhook, nil
+		}
+		return &notifications.Webhook{Endpoint: delivery.Endpoint}, nil
+	}
+
+	subscription, err := getWebhookSubscription(
+		ctx, delivery.SubscriptionID, delivery.Client().DefaultModelOptions()...,
+	)
+	if err != nil {
+		return nil, err
+	} else if subscription == nil || subscription.DeletedAt.Valid {
+		return nil, nil
+	}
+	return subscription.webhook()
+}
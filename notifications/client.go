@@ -1,6 +1,8 @@
 package notifications
 
 import (
+	"time"
+
 	zLogger "github.com/mrz1836/go-logger"
 )
 
@@ -19,6 +21,9 @@ const (
 
 	// EventTypeBroadcast when a transaction is broadcasted (sync tx)
 	EventTypeBroadcast EventType = "broadcast"
+
+	// EventTypeApproval when a draft needs approvals, or an approver approved or rejected it (draft transaction)
+	EventTypeApproval EventType = "approval"
 )
 
 type (
@@ -30,15 +35,21 @@ type (
 
 	// clientOptions holds all the configuration for the client
 	clientOptions struct {
-		config     *notificationsConfig        // Configuration for broadcasting and other chain-state actions
-		debug      bool                        // Debugging mode
-		httpClient HTTPInterface               // Custom HTTP client
-		logger     zLogger.GormLoggerInterface // Custom logger interface
+		config        *notificationsConfig          // Configuration for broadcasting and other chain-state actions
+		debug         bool                          // Debugging mode
+		httpClient    HTTPInterface                 // Custom HTTP client
+		logger        zLogger.GormLoggerInterface   // Custom logger interface
+		outbox        OutboxInterface               // Persistent outbox for failed deliveries
+		subscriptions SubscriptionProviderInterface // Provider of the subscribed webhooks
 	}
 
 	// syncConfig holds all the configuration about the different notifications
 	notificationsConfig struct {
-		webhookEndpoint string // Webhook URL for basic notifications
+		maxAttempts     uint32        // Max delivery attempts before a notification is dead-lettered
+		maxRetryDelay   time.Duration // Upper bound for the exponential backoff
+		retryDelay      time.Duration // Initial delay before the first retry
+		signingSecret   string        // Secret for signing the webhook payloads (HMAC-SHA256)
+		webhookEndpoint string        // Webhook URL for basic notifications
 	}
 )
 
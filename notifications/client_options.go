@@ -8,7 +8,10 @@ import (
 )
 
 const (
-	defaultHTTPTimeout = 20 * time.Second
+	defaultHTTPTimeout   = 20 * time.Second
+	defaultMaxAttempts   = uint32(10)
+	defaultMaxRetryDelay = 1 * time.Hour
+	defaultRetryDelay    = 30 * time.Second
 )
 
 // ClientOps allow functional options to be supplied
@@ -23,6 +26,9 @@ func defaultClientOptions() *clientOptions {
 	// Set the default options
 	return &clientOptions{
 		config: &notificationsConfig{
+			maxAttempts:     defaultMaxAttempts,
+			maxRetryDelay:   defaultMaxRetryDelay,
+			retryDelay:      defaultRetryDelay,
 			webhookEndpoint: "",
 		},
 		logger: nil,
@@ -32,10 +38,46 @@ func defaultClientOptions() *clientOptions {
 	}
 }
 
-// WithNotifications will set the webhook endpoint
-func WithNotifications(webhookEndpoint string) ClientOps {
+// WithNotifications will set the webhook endpoint and the secret for signing the payloads
+//
+// If the signing secret is empty, the payloads are not signed
+func WithNotifications(webhookEndpoint, signingSecret string) ClientOps {
 	return func(c *clientOptions) {
 		c.config.webhookEndpoint = webhookEndpoint
+		c.config.signingSecret = signingSecret
+	}
+}
+
+// WithOutbox will set the persistent outbox used to retry failed deliveries
+func WithOutbox(outbox OutboxInterface) ClientOps {
+	return func(c *clientOptions) {
+		if outbox != nil {
+			c.outbox = outbox
+		}
+	}
+}
+
+// WithSubscriptions will set the provider of the subscribed webhooks (notified next to the default endpoint)
+func WithSubscriptions(provider SubscriptionProviderInterface) ClientOps {
+	return func(c *clientOptions) {
+		if provider != nil {
+			c.subscriptions = provider
+		}
+	}
+}
+
+// WithRetryPolicy will set the max delivery attempts and the exponential backoff bounds
+func WithRetryPolicy(maxAttempts uint32, retryDelay, maxRetryDelay time.Duration) ClientOps {
+	return func(c *clientOptions) {
+		if maxAttempts > 0 {
+			c.config.maxAttempts = maxAttempts
+		}
+		if retryDelay > 0 {
+			c.config.retryDelay = retryDelay
+		}
+		if maxRetryDelay > 0 {
+			c.config.maxRetryDelay = maxRetryDelay
+		}
 	}
 }
 
@@ -3,6 +3,7 @@ package notifications
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -29,7 +30,7 @@ func TestWithNotifications(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			opts := []ClientOps{WithNotifications(tt.args.webhookEndpoint)}
+			opts := []ClientOps{WithNotifications(tt.args.webhookEndpoint, "")}
 			client, err := NewClient(opts...)
 			assert.NoError(t, err)
 			assert.Equal(t, tt.want, client.GetWebhookEndpoint())
@@ -37,6 +38,22 @@ func TestWithNotifications(t *testing.T) {
 	}
 }
 
+func TestWithRetryPolicy(t *testing.T) {
+	t.Run("custom policy", func(t *testing.T) {
+		client, err := NewClient(WithRetryPolicy(3, time.Second, time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(3), client.MaxAttempts())
+		assert.Equal(t, time.Second, client.RetryDelay(1))
+	})
+
+	t.Run("zero values keep the defaults", func(t *testing.T) {
+		client, err := NewClient(WithRetryPolicy(0, 0, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, defaultMaxAttempts, client.MaxAttempts())
+		assert.Equal(t, defaultRetryDelay, client.RetryDelay(1))
+	})
+}
+
 func Test_defaultClientOptions(t *testing.T) {
 	tests := []struct {
 		name string
@@ -46,6 +63,9 @@ func Test_defaultClientOptions(t *testing.T) {
 			name: "options",
 			want: &clientOptions{
 				config: &notificationsConfig{
+					maxAttempts:     defaultMaxAttempts,
+					maxRetryDelay:   defaultMaxRetryDelay,
+					retryDelay:      defaultRetryDelay,
 					webhookEndpoint: "",
 				},
 				httpClient: &http.Client{
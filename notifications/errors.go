@@ -0,0 +1,21 @@
+package notifications
+
+import "errors"
+
+// ErrUnexpectedStatusCode is when the webhook endpoint did not respond with a success status code
+var ErrUnexpectedStatusCode = errors.New("received invalid response from notification endpoint")
+
+// ErrMissingSignature is when the delivery is missing the signature headers
+var ErrMissingSignature = errors.New("missing webhook signature")
+
+// ErrInvalidTimestamp is when the delivery timestamp header cannot be parsed
+var ErrInvalidTimestamp = errors.New("invalid webhook timestamp")
+
+// ErrSignatureInvalid is when the signature does not match the payload
+var ErrSignatureInvalid = errors.New("webhook signature invalid")
+
+// ErrSignatureExpired is when the delivery timestamp is outside the tolerance window
+var ErrSignatureExpired = errors.New("webhook signature has expired")
+
+// ErrReplayedDelivery is when the same delivery id was already received
+var ErrReplayedDelivery = errors.New("webhook delivery has already been received")
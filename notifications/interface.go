@@ -3,6 +3,7 @@ package notifications
 import (
 	"context"
 	"net/http"
+	"time"
 
 	zLogger "github.com/mrz1836/go-logger"
 )
@@ -12,11 +13,25 @@ type HTTPInterface interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// OutboxInterface is the persistent storage for deliveries that failed and need another attempt
+type OutboxInterface interface {
+	Enqueue(ctx context.Context, delivery *Delivery) error
+}
+
+// SubscriptionProviderInterface will find the subscribed webhooks that match an event
+type SubscriptionProviderInterface interface {
+	GetWebhooks(ctx context.Context, modelType string, eventType EventType, model interface{}) ([]*Webhook, error)
+}
+
 // ClientInterface is the notification client interface
 type ClientInterface interface {
 	Debug(on bool)
+	DefaultWebhook() *Webhook
+	Deliver(ctx context.Context, webhook *Webhook, deliveryID string, payload []byte) error
 	GetWebhookEndpoint() string
 	IsDebug() bool
 	Logger() zLogger.GormLoggerInterface
+	MaxAttempts() uint32
 	Notify(ctx context.Context, modelType string, eventType EventType, model interface{}, id string) error
+	RetryDelay(attempts uint32) time.Duration
 }
@@ -7,56 +7,178 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
+// Delivery is a notification that could not be delivered and is handed to the outbox
+type Delivery struct {
+	DeliveryID     string    // Unique id of the delivery, sent with every attempt
+	Endpoint       string    // Webhook URL the payload is sent to
+	EventType      EventType // Event type of the notification
+	LastError      string    // Reason the last attempt failed
+	ModelID        string    // ID of the model the event is about
+	ModelType      string    // Type of the model the event is about
+	Payload        []byte    // JSON body of the webhook request
+	SubscriptionID string    // ID of the subscription (empty for the default webhook)
+}
+
+// Webhook is a destination for the notifications
+type Webhook struct {
+	Endpoint       string // Webhook URL
+	SigningSecret  string // Secret for signing the payloads (unsigned if empty)
+	SubscriptionID string // ID of the subscription (empty for the default webhook)
+}
+
+// DefaultWebhook will get the configured webhook (nil if no endpoint is set)
+func (c *Client) DefaultWebhook() *Webhook {
+	if len(c.options.config.webhookEndpoint) == 0 {
+		return nil
+	}
+	return &Webhook{
+		Endpoint:      c.options.config.webhookEndpoint,
+		SigningSecret: c.options.config.signingSecret,
+	}
+}
+
 // GetWebhookEndpoint will get the configured webhook endpoint
 func (c *Client) GetWebhookEndpoint() string {
 	return c.options.config.webhookEndpoint
 }
 
-// Notify will create a new notification event
+// MaxAttempts will return the number of delivery attempts before a notification is dead-lettered
+func (c *Client) MaxAttempts() uint32 {
+	return c.options.config.maxAttempts
+}
+
+// RetryDelay will return the backoff before the next attempt, given the number of attempts made so far
+func (c *Client) RetryDelay(attempts uint32) time.Duration {
+	delay := c.options.config.retryDelay
+	for i := uint32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= c.options.config.maxRetryDelay {
+			return c.options.config.maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// Notify will create a new notification event and send it to the default webhook and all matching subscriptions
+//
+// If a delivery fails and an outbox is set, the notification is queued for another try
 func (c *Client) Notify(ctx context.Context, modelType string, eventType EventType,
 	model interface{}, id string) error {
 
-	if len(c.options.config.webhookEndpoint) == 0 {
+	webhooks, err := c.getWebhooks(ctx, modelType, eventType, model)
+	if err != nil {
+		return err
+	} else if len(webhooks) == 0 {
 		if c.IsDebug() {
 			c.Logger().Info(ctx, fmt.Sprintf("NOTIFY %s: %s - %v", eventType, id, model))
 		}
-	} else {
-		jsonData, err := json.Marshal(map[string]interface{}{
-			"event_type": eventType,
-			"id":         id,
-			"model":      model,
-			"model_type": modelType,
-		})
-		if err != nil {
-			return err
-		}
+		return nil
+	}
 
-		var req *http.Request
-		if req, err = http.NewRequestWithContext(ctx,
-			http.MethodPost,
-			c.options.config.webhookEndpoint,
-			bytes.NewBuffer(jsonData),
-		); err != nil {
-			return err
-		}
+	var jsonData []byte
+	if jsonData, err = json.Marshal(map[string]interface{}{
+		"event_type": eventType,
+		"id":         id,
+		"model":      model,
+		"model_type": modelType,
+	}); err != nil {
+		return err
+	}
 
-		var response *http.Response
-		if response, err = c.options.httpClient.Do(req); err != nil {
-			return err
+	// One failing webhook should not stop the others
+	var lastErr error
+	for _, webhook := range webhooks {
+		if err = c.notifyWebhook(ctx, webhook, modelType, eventType, id, jsonData); err != nil {
+			lastErr = err
 		}
-		defer func() {
-			_ = response.Body.Close()
-		}()
-
-		if response.StatusCode != http.StatusOK {
-			// todo queue notification for another try ...
-			c.Logger().Error(ctx, fmt.Sprintf(
-				"%s: %d",
-				"received invalid response from notification endpoint: ",
-				response.StatusCode))
+	}
+
+	return lastErr
+}
+
+// getWebhooks will get the default webhook and the subscribed webhooks for the event
+func (c *Client) getWebhooks(ctx context.Context, modelType string, eventType EventType,
+	model interface{}) ([]*Webhook, error) {
+
+	webhooks := make([]*Webhook, 0)
+	if webhook := c.DefaultWebhook(); webhook != nil {
+		webhooks = append(webhooks, webhook)
+	}
+
+	if c.options.subscriptions != nil {
+		subscribed, err := c.options.subscriptions.GetWebhooks(ctx, modelType, eventType, model)
+		if err != nil {
+			return nil, err
 		}
+		webhooks = append(webhooks, subscribed...)
+	}
+
+	return webhooks, nil
+}
+
+// notifyWebhook will deliver the payload to the webhook, or hand it to the outbox if the delivery fails
+func (c *Client) notifyWebhook(ctx context.Context, webhook *Webhook, modelType string,
+	eventType EventType, id string, payload []byte) error {
+
+	deliveryID, err := NewDeliveryID()
+	if err != nil {
+		return err
+	}
+
+	if err = c.Deliver(ctx, webhook, deliveryID, payload); err == nil {
+		return nil
+	} else if c.options.outbox == nil {
+		return err
+	}
+
+	c.Logger().Warn(ctx, fmt.Sprintf("notification %s for %s to %s failed, queueing for another try: %s",
+		eventType, id, webhook.Endpoint, err.Error()))
+
+	return c.options.outbox.Enqueue(ctx, &Delivery{
+		DeliveryID:     deliveryID,
+		Endpoint:       webhook.Endpoint,
+		EventType:      eventType,
+		LastError:      err.Error(),
+		ModelID:        id,
+		ModelType:      modelType,
+		Payload:        payload,
+		SubscriptionID: webhook.SubscriptionID,
+	})
+}
+
+// Deliver will POST the payload to the webhook, any non-2xx response is returned as an error
+//
+// Every attempt of a delivery uses the same delivery id (receivers can deduplicate), and is signed with a new timestamp
+func (c *Client) Deliver(ctx context.Context, webhook *Webhook, deliveryID string, payload []byte) error {
+
+	req, err := http.NewRequestWithContext(ctx,
+		http.MethodPost,
+		webhook.Endpoint,
+		bytes.NewBuffer(payload),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Sign the payload (if a secret is set)
+	if len(webhook.SigningSecret) > 0 {
+		signRequest(req, webhook.SigningSecret, deliveryID, payload)
+	}
+
+	var response *http.Response
+	if response, err = c.options.httpClient.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, response.StatusCode)
 	}
 
 	return nil
@@ -6,12 +6,34 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// outboxMock is an in-memory outbox for testing
+type outboxMock struct {
+	deliveries []*Delivery
+}
+
+// Enqueue will store the delivery in memory
+func (o *outboxMock) Enqueue(_ context.Context, delivery *Delivery) error {
+	o.deliveries = append(o.deliveries, delivery)
+	return nil
+}
+
+// subscriptionsMock will return a fixed list of webhooks
+type subscriptionsMock struct {
+	webhooks []*Webhook
+}
+
+// GetWebhooks will return the webhooks of the mock
+func (s *subscriptionsMock) GetWebhooks(_ context.Context, _ string, _ EventType, _ interface{}) ([]*Webhook, error) {
+	return s.webhooks, nil
+}
+
 func TestClient_Notify(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -52,7 +74,7 @@ func TestClient_Notify(t *testing.T) {
 		{
 			name: "http call done",
 			options: []ClientOps{
-				WithNotifications(webhookURL),
+				WithNotifications(webhookURL, ""),
 			},
 			args:      useArgs,
 			wantErr:   assert.NoError,
@@ -69,11 +91,43 @@ func TestClient_Notify(t *testing.T) {
 		{
 			name: "http error",
 			options: []ClientOps{
-				WithNotifications(webhookURL),
+				WithNotifications(webhookURL, ""),
+			},
+			args:      useArgs,
+			wantErr:   assert.Error,
+			httpCalls: 1,
+			httpMock: func() {
+				httpmock.RegisterResponder(http.MethodPost, webhookURL,
+					httpmock.NewErrorResponder(errors.New("error")),
+				)
+			},
+		},
+		{
+			name: "invalid status code",
+			options: []ClientOps{
+				WithNotifications(webhookURL, ""),
 			},
 			args:      useArgs,
 			wantErr:   assert.Error,
 			httpCalls: 1,
+			httpMock: func() {
+				httpmock.RegisterResponder(http.MethodPost, webhookURL,
+					httpmock.NewStringResponder(
+						http.StatusServiceUnavailable,
+						`unavailable`,
+					),
+				)
+			},
+		},
+		{
+			name: "http error queued in outbox",
+			options: []ClientOps{
+				WithNotifications(webhookURL, ""),
+				WithOutbox(&outboxMock{}),
+			},
+			args:      useArgs,
+			wantErr:   assert.NoError,
+			httpCalls: 1,
 			httpMock: func() {
 				httpmock.RegisterResponder(http.MethodPost, webhookURL,
 					httpmock.NewErrorResponder(errors.New("error")),
@@ -92,3 +146,93 @@ func TestClient_Notify(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_Notify_Outbox(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	webhookURL := "https://test.example.com/v1/api-endpoint"
+
+	httpmock.RegisterResponder(http.MethodPost, webhookURL,
+		httpmock.NewStringResponder(http.StatusInternalServerError, `error`),
+	)
+
+	outbox := &outboxMock{}
+	c, err := NewClient(WithNotifications(webhookURL, ""), WithOutbox(outbox))
+	require.NoError(t, err)
+
+	err = c.Notify(ctx, "transaction", EventTypeBroadcast, map[string]interface{}{}, "test-id")
+	require.NoError(t, err)
+	require.Len(t, outbox.deliveries, 1)
+
+	delivery := outbox.deliveries[0]
+	assert.Equal(t, webhookURL, delivery.Endpoint)
+	assert.Equal(t, EventTypeBroadcast, delivery.EventType)
+	assert.Equal(t, "transaction", delivery.ModelType)
+	assert.Equal(t, "test-id", delivery.ModelID)
+	assert.Contains(t, delivery.LastError, "500")
+	assert.NotEmpty(t, delivery.Payload)
+}
+
+func TestClient_Notify_Subscriptions(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	ctx := context.Background()
+	webhookURL := "https://test.example.com/v1/api-endpoint"
+	subscriberURL := "https://subscriber.example.com/webhook"
+	failingURL := "https://failing.example.com/webhook"
+
+	verifier := NewVerifier("subscriber-secret", 0)
+	var verifyErr error
+	httpmock.RegisterResponder(http.MethodPost, webhookURL,
+		httpmock.NewStringResponder(http.StatusOK, "OK"),
+	)
+	httpmock.RegisterResponder(http.MethodPost, subscriberURL,
+		func(req *http.Request) (*http.Response, error) {
+			_, verifyErr = verifier.VerifyRequest(req)
+			return httpmock.NewStringResponse(http.StatusOK, "OK"), nil
+		},
+	)
+	httpmock.RegisterResponder(http.MethodPost, failingURL,
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, "unavailable"),
+	)
+
+	outbox := &outboxMock{}
+	c, err := NewClient(
+		WithNotifications(webhookURL, ""),
+		WithOutbox(outbox),
+		WithSubscriptions(&subscriptionsMock{webhooks: []*Webhook{
+			{Endpoint: subscriberURL, SigningSecret: "subscriber-secret", SubscriptionID: "sub-1"},
+			{Endpoint: failingURL, SubscriptionID: "sub-2"},
+		}}),
+	)
+	require.NoError(t, err)
+
+	err = c.Notify(ctx, "transaction", EventTypeCreate, map[string]interface{}{}, "test-id")
+	require.NoError(t, err)
+
+	info := httpmock.GetCallCountInfo()
+	assert.Equal(t, 1, info[http.MethodPost+" "+webhookURL])
+	assert.Equal(t, 1, info[http.MethodPost+" "+subscriberURL])
+	assert.Equal(t, 1, info[http.MethodPost+" "+failingURL])
+	assert.NoError(t, verifyErr)
+
+	// Only the failed subscription is queued
+	require.Len(t, outbox.deliveries, 1)
+	assert.Equal(t, failingURL, outbox.deliveries[0].Endpoint)
+	assert.Equal(t, "sub-2", outbox.deliveries[0].SubscriptionID)
+}
+
+func TestClient_RetryDelay(t *testing.T) {
+	c, err := NewClient(WithRetryPolicy(5, 10*time.Second, time.Minute))
+	require.NoError(t, err)
+
+	assert.Equal(t, 10*time.Second, c.RetryDelay(0))
+	assert.Equal(t, 10*time.Second, c.RetryDelay(1))
+	assert.Equal(t, 20*time.Second, c.RetryDelay(2))
+	assert.Equal(t, 40*time.Second, c.RetryDelay(3))
+	assert.Equal(t, time.Minute, c.RetryDelay(4))
+	assert.Equal(t, time.Minute, c.RetryDelay(30))
+}
@@ -0,0 +1,160 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderDeliveryID is the header with the unique id of the delivery (the same for every attempt)
+	HeaderDeliveryID = "bux-webhook-delivery-id"
+
+	// HeaderSignature is the header with the HMAC-SHA256 signature (hex) of the delivery
+	HeaderSignature = "bux-webhook-signature"
+
+	// HeaderTimestamp is the header with the time of the delivery attempt (unix seconds)
+	HeaderTimestamp = "bux-webhook-timestamp"
+
+	// DefaultSignatureTolerance is the max age of a delivery before it is considered a replay
+	DefaultSignatureTolerance = 5 * time.Minute
+)
+
+// SignPayload will create the HMAC-SHA256 signature for the delivery
+//
+// The signed message is: timestamp + "." + deliveryID + "." + payload
+func SignPayload(secret string, timestamp int64, deliveryID string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "." + deliveryID + "."))
+	_, _ = mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature will check the signature headers against the payload, and that the delivery is not too old
+//
+// Returns the delivery id if the signature is valid
+func VerifySignature(secret string, header http.Header, payload []byte, tolerance time.Duration) (string, error) {
+
+	deliveryID := header.Get(HeaderDeliveryID)
+	signature := header.Get(HeaderSignature)
+	if len(deliveryID) == 0 || len(signature) == 0 {
+		return "", ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return "", ErrInvalidTimestamp
+	}
+
+	// Check the signature before trusting the timestamp
+	expected := SignPayload(secret, timestamp, deliveryID, payload)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", ErrSignatureInvalid
+	}
+
+	if tolerance <= 0 {
+		tolerance = DefaultSignatureTolerance
+	}
+	age := time.Since(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return "", ErrSignatureExpired
+	}
+
+	return deliveryID, nil
+}
+
+// Verifier will verify signed deliveries on the receiving side and reject replayed attempts
+//
+// A retried delivery keeps its delivery id (for deduplication) but is signed with a new timestamp,
+// so only an attempt that is sent again as-is is a replay
+type Verifier struct {
+	mu        sync.Mutex
+	secret    string
+	seen      map[string]time.Time
+	tolerance time.Duration
+}
+
+// NewVerifier will create a new verifier for the given secret (tolerance of 0 uses the default)
+func NewVerifier(secret string, tolerance time.Duration) *Verifier {
+	if tolerance <= 0 {
+		tolerance = DefaultSignatureTolerance
+	}
+	return &Verifier{
+		secret:    secret,
+		seen:      make(map[string]time.Time),
+		tolerance: tolerance,
+	}
+}
+
+// Verify will check the signature and that the attempt (delivery id and timestamp) was not seen within the tolerance window
+func (v *Verifier) Verify(header http.Header, payload []byte) error {
+	deliveryID, err := VerifySignature(v.secret, header, payload, v.tolerance)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Forget the attempts that are older than the window, those are rejected by the timestamp check
+	now := time.Now()
+	for attempt, seenAt := range v.seen {
+		if now.Sub(seenAt) > 2*v.tolerance {
+			delete(v.seen, attempt)
+		}
+	}
+
+	attempt := deliveryID + "." + header.Get(HeaderTimestamp)
+	if _, ok := v.seen[attempt]; ok {
+		return ErrReplayedDelivery
+	}
+	v.seen[attempt] = now
+
+	return nil
+}
+
+// VerifyRequest will read the body of the request and verify it (the body can be read again afterwards)
+func (v *Verifier) VerifyRequest(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, ErrMissingSignature
+	}
+	defer func() {
+		_ = req.Body.Close()
+	}()
+
+	payload, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+
+	if err = v.Verify(req.Header, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// NewDeliveryID will create a new unique delivery id (32 random bytes, hex)
+func NewDeliveryID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// signRequest will add the delivery id, timestamp and signature headers to the request
+func signRequest(req *http.Request, secret, deliveryID string, payload []byte) {
+	timestamp := time.Now().Unix()
+
+	req.Header.Set(HeaderDeliveryID, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, SignPayload(secret, timestamp, deliveryID, payload))
+}
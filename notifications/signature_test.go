@@ -0,0 +1,136 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningSecret = "test-signing-secret"
+
+// newSignedHeader will create the headers of a signed delivery
+func newSignedHeader(secret string, timestamp int64, deliveryID string, payload []byte) http.Header {
+	header := http.Header{}
+	header.Set(HeaderDeliveryID, deliveryID)
+	header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderSignature, SignPayload(secret, timestamp, deliveryID, payload))
+	return header
+}
+
+func TestSignPayload(t *testing.T) {
+	t.Run("deterministic signature", func(t *testing.T) {
+		payload := []byte(`{"id":"test"}`)
+		sig := SignPayload(testSigningSecret, 1700000000, "delivery-id", payload)
+		assert.Len(t, sig, 64)
+		assert.Equal(t, sig, SignPayload(testSigningSecret, 1700000000, "delivery-id", payload))
+	})
+
+	t.Run("every part changes the signature", func(t *testing.T) {
+		payload := []byte(`{"id":"test"}`)
+		sig := SignPayload(testSigningSecret, 1700000000, "delivery-id", payload)
+		assert.NotEqual(t, sig, SignPayload("other-secret", 1700000000, "delivery-id", payload))
+		assert.NotEqual(t, sig, SignPayload(testSigningSecret, 1700000001, "delivery-id", payload))
+		assert.NotEqual(t, sig, SignPayload(testSigningSecret, 1700000000, "other-id", payload))
+		assert.NotEqual(t, sig, SignPayload(testSigningSecret, 1700000000, "delivery-id", []byte(`{"id":"other"}`)))
+	})
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"event_type":"broadcast"}`)
+
+	t.Run("valid signature", func(t *testing.T) {
+		header := newSignedHeader(testSigningSecret, time.Now().Unix(), "delivery-id", payload)
+		deliveryID, err := VerifySignature(testSigningSecret, header, payload, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "delivery-id", deliveryID)
+	})
+
+	t.Run("missing headers", func(t *testing.T) {
+		_, err := VerifySignature(testSigningSecret, http.Header{}, payload, 0)
+		assert.ErrorIs(t, err, ErrMissingSignature)
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		header := newSignedHeader(testSigningSecret, time.Now().Unix(), "delivery-id", payload)
+		header.Set(HeaderTimestamp, "not-a-number")
+		_, err := VerifySignature(testSigningSecret, header, payload, 0)
+		assert.ErrorIs(t, err, ErrInvalidTimestamp)
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		header := newSignedHeader(testSigningSecret, time.Now().Unix(), "delivery-id", payload)
+		_, err := VerifySignature(testSigningSecret, header, []byte(`{"event_type":"delete"}`), 0)
+		assert.ErrorIs(t, err, ErrSignatureInvalid)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		header := newSignedHeader("other-secret", time.Now().Unix(), "delivery-id", payload)
+		_, err := VerifySignature(testSigningSecret, header, payload, 0)
+		assert.ErrorIs(t, err, ErrSignatureInvalid)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		header := newSignedHeader(testSigningSecret, time.Now().Add(-10*time.Minute).Unix(), "delivery-id", payload)
+		_, err := VerifySignature(testSigningSecret, header, payload, time.Minute)
+		assert.ErrorIs(t, err, ErrSignatureExpired)
+	})
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	payload := []byte(`{"event_type":"create"}`)
+
+	t.Run("replayed delivery", func(t *testing.T) {
+		v := NewVerifier(testSigningSecret, 0)
+		header := newSignedHeader(testSigningSecret, time.Now().Unix(), "delivery-id", payload)
+		require.NoError(t, v.Verify(header, payload))
+		assert.ErrorIs(t, v.Verify(header, payload), ErrReplayedDelivery)
+	})
+
+	t.Run("retried delivery", func(t *testing.T) {
+		v := NewVerifier(testSigningSecret, 0)
+		require.NoError(t, v.Verify(newSignedHeader(testSigningSecret, time.Now().Unix()-60, "delivery-id", payload), payload))
+		require.NoError(t, v.Verify(newSignedHeader(testSigningSecret, time.Now().Unix(), "delivery-id", payload), payload))
+	})
+
+	t.Run("different deliveries", func(t *testing.T) {
+		v := NewVerifier(testSigningSecret, 0)
+		require.NoError(t, v.Verify(newSignedHeader(testSigningSecret, time.Now().Unix(), "delivery-1", payload), payload))
+		require.NoError(t, v.Verify(newSignedHeader(testSigningSecret, time.Now().Unix(), "delivery-2", payload), payload))
+	})
+}
+
+func TestClient_Deliver_Signed(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	webhookURL := "https://test.example.com/v1/api-endpoint"
+	verifier := NewVerifier(testSigningSecret, 0)
+
+	var verifyErr error
+	httpmock.RegisterResponder(http.MethodPost, webhookURL,
+		func(req *http.Request) (*http.Response, error) {
+			_, verifyErr = verifier.VerifyRequest(req)
+			return httpmock.NewStringResponse(http.StatusOK, "OK"), nil
+		},
+	)
+
+	t.Run("signed and verified", func(t *testing.T) {
+		c, err := NewClient(WithNotifications(webhookURL, testSigningSecret))
+		require.NoError(t, err)
+		require.NoError(t, c.Notify(context.Background(), "transaction", EventTypeBroadcast, map[string]interface{}{}, "test-id"))
+		assert.NoError(t, verifyErr)
+	})
+
+	t.Run("unsigned delivery is rejected", func(t *testing.T) {
+		c, err := NewClient(WithNotifications(webhookURL, ""))
+		require.NoError(t, err)
+		require.NoError(t, c.Notify(context.Background(), "transaction", EventTypeBroadcast, map[string]interface{}{}, "test-id"))
+		assert.ErrorIs(t, verifyErr, ErrMissingSignature)
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/bitcoin-sv/go-paymail"
+	"github.com/bitcoinschema/go-bitcoin/v2"
+	"github.com/libsv/go-bt/v2/bscript"
 	"github.com/mrz1836/go-cachestore"
 )
 
@@ -208,3 +210,34 @@ func buildP2pTx(ctx context.Context, p4 *PaymailP4, transaction *Transaction) (*
 
 	return p2pTransaction, nil
 }
+
+// verifySenderSignature will verify the signature of the txid with the key of the sender paymail (PKI)
+func verifySenderSignature(ctx context.Context, client ClientInterface, metaData *paymail.P2PMetaData,
+	txID string) error {
+
+	if metaData == nil || len(metaData.Sender) == 0 || len(metaData.Signature) == 0 {
+		return ErrMissingSenderSignature
+	}
+
+	alias, domain, address := paymail.SanitizePaymail(metaData.Sender)
+	if len(address) == 0 {
+		return ErrPaymailAddressIsInvalid
+	}
+
+	pubKey, err := getPaymailPubKey(ctx, client, alias, domain)
+	if err != nil {
+		return err
+	} else if len(metaData.PubKey) > 0 && metaData.PubKey != pubKey {
+		return ErrSenderPubKeyMismatch
+	}
+
+	var rawAddress *bscript.Address
+	if rawAddress, err = bitcoin.GetAddressFromPubKeyString(pubKey, true); err != nil {
+		return err
+	}
+
+	if err = bitcoin.VerifyMessage(rawAddress.AddressString, metaData.Signature, txID); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSenderSignature, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package bux
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sort"
+)
+
+// P2POutputSplitStrategy is the strategy for splitting a P2P payment into several outputs
+type P2POutputSplitStrategy string
+
+const (
+	// P2POutputSplitFixedCount splits the payment into Count (near) equal outputs
+	P2POutputSplitFixedCount P2POutputSplitStrategy = "fixed_count"
+
+	// P2POutputSplitDenominations splits the payment into outputs of the Denominations (and an output for the remainder)
+	P2POutputSplitDenominations P2POutputSplitStrategy = "denominations"
+
+	// P2POutputSplitRandom splits payments above the Threshold into 2 to Count outputs of random values
+	P2POutputSplitRandom P2POutputSplitStrategy = "random"
+)
+
+// defaultP2PMaxOutputs is the max outputs of a split payment (if MaxOutputs is not set)
+const defaultP2PMaxOutputs = 100
+
+// P2POutputSplit is the configuration for splitting the P2P payment destinations (see WithPaymailOutputSplit)
+//
+// Every output gets its own destination derived from the xPub of the paymail, all tied to the reference ID
+type P2POutputSplit struct {
+	Count         uint32                 `json:"count"`         // Outputs (fixed count) or max outputs (random)
+	Denominations []uint64               `json:"denominations"` // Values of the outputs (denominations)
+	MaxOutputs    uint32                 `json:"max_outputs"`   // Max outputs for all strategies, the last denomination gets the rest
+	MinSatoshis   uint64                 `json:"min_satoshis"`  // Min satoshis of an output (fixed count, random)
+	Strategy      P2POutputSplitStrategy `json:"strategy"`
+	Threshold     uint64                 `json:"threshold"` // Only payments above the threshold are split (random)
+}
+
+// validate will check the strategy and its settings
+func (s *P2POutputSplit) validate() error {
+	switch s.Strategy {
+	case P2POutputSplitFixedCount, P2POutputSplitRandom:
+		if s.Count < 1 {
+			return ErrInvalidOutputSplit
+		}
+	case P2POutputSplitDenominations:
+		if len(s.Denominations) == 0 {
+			return ErrInvalidOutputSplit
+		}
+		for _, denomination := range s.Denominations {
+			if denomination == 0 {
+				return ErrInvalidOutputSplit
+			}
+		}
+	default:
+		return ErrInvalidOutputSplit
+	}
+	return nil
+}
+
+// split will split the satoshis into the values of the outputs, the values add up to the satoshis
+//
+// The split is validated once when it is set (see WithPaymailOutputSplit)
+func (s *P2POutputSplit) split(satoshis uint64) ([]uint64, error) {
+	if s == nil || satoshis == 0 {
+		return []uint64{satoshis}, nil
+	}
+
+	switch s.Strategy {
+	case P2POutputSplitFixedCount:
+		return splitEqual(satoshis, s.getCount(satoshis, s.Count)), nil
+	case P2POutputSplitDenominations:
+		return splitDenominations(satoshis, s.Denominations, s.getMaxOutputs()), nil
+	case P2POutputSplitRandom:
+		if satoshis <= s.Threshold || s.Count < 2 {
+			return []uint64{satoshis}, nil
+		}
+		count, err := randomUint64(uint64(s.Count) - 1)
+		if err != nil {
+			return nil, err
+		}
+		return s.splitRandom(satoshis, s.getCount(satoshis, uint32(count)+2))
+	}
+	return []uint64{satoshis}, nil
+}
+
+// getMaxOutputs will get the max outputs of the split (the default if MaxOutputs is not set)
+func (s *P2POutputSplit) getMaxOutputs() uint32 {
+	if s.MaxOutputs == 0 {
+		return defaultP2PMaxOutputs
+	}
+	return s.MaxOutputs
+}
+
+// getCount will get the number of outputs that keeps every output at the min satoshis (and within the max outputs)
+func (s *P2POutputSplit) getCount(satoshis uint64, count uint32) uint32 {
+	if maxOutputs := s.getMaxOutputs(); count > maxOutputs {
+		count = maxOutputs
+	}
+	minSatoshis := s.MinSatoshis
+	if minSatoshis == 0 {
+		minSatoshis = 1
+	}
+	if maxCount := satoshis / minSatoshis; maxCount < uint64(count) {
+		count = uint32(maxCount)
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// splitRandom will split the satoshis into count outputs of random values (at least the min satoshis each)
+func (s *P2POutputSplit) splitRandom(satoshis uint64, count uint32) ([]uint64, error) {
+	minSatoshis := s.MinSatoshis
+	if minSatoshis == 0 {
+		minSatoshis = 1
+	}
+
+	// Cut the satoshis above the min satoshis of every output at random points
+	rest := satoshis - minSatoshis*uint64(count)
+	cuts := make([]uint64, 0, count+1)
+	cuts = append(cuts, 0, rest)
+	for i := uint32(1); i < count; i++ {
+		cut, err := randomUint64(rest + 1)
+		if err != nil {
+			return nil, err
+		}
+		cuts = append(cuts, cut)
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+
+	values := make([]uint64, 0, count)
+	for i := 1; i < len(cuts); i++ {
+		values = append(values, minSatoshis+cuts[i]-cuts[i-1])
+	}
+	return values, nil
+}
+
+// splitEqual will split the satoshis into count (near) equal outputs
+func splitEqual(satoshis uint64, count uint32) []uint64 {
+	values := make([]uint64, count)
+	for i := range values {
+		values[i] = satoshis / uint64(count)
+		if uint64(i) < satoshis%uint64(count) {
+			values[i]++
+		}
+	}
+	return values
+}
+
+// splitDenominations will split the satoshis into the denominations (largest first), the remainder is its own output
+//
+// Once the max outputs are reached, the last output gets the rest of the satoshis
+func splitDenominations(satoshis uint64, denominations []uint64, maxOutputs uint32) []uint64 {
+	sorted := make([]uint64, len(denominations))
+	copy(sorted, denominations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+
+	values := make([]uint64, 0, maxOutputs)
+	for _, denomination := range sorted {
+		for satoshis >= denomination && uint32(len(values)) < maxOutputs-1 {
+			values = append(values, denomination)
+			satoshis -= denomination
+		}
+	}
+	if satoshis > 0 {
+		values = append(values, satoshis)
+	}
+	return values
+}
+
+// randomUint64 will return a random number in [0, n)
+func randomUint64(n uint64) (uint64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	value, err := rand.Int(rand.Reader, new(big.Int).SetUint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return value.Uint64(), nil
+}
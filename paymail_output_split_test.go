@@ -0,0 +1,133 @@
+package bux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sumValues will add up the values of the outputs
+func sumValues(values []uint64) (sum uint64) {
+	for _, value := range values {
+		sum += value
+	}
+	return
+}
+
+func TestP2POutputSplit_split(t *testing.T) {
+	t.Run("no split", func(t *testing.T) {
+		var split *P2POutputSplit
+		values, err := split.split(1000)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{1000}, values)
+	})
+
+	t.Run("invalid split", func(t *testing.T) {
+		err := (&P2POutputSplit{Strategy: "unknown"}).validate()
+		assert.ErrorIs(t, err, ErrInvalidOutputSplit)
+
+		err = (&P2POutputSplit{Strategy: P2POutputSplitFixedCount}).validate()
+		assert.ErrorIs(t, err, ErrInvalidOutputSplit)
+
+		err = (&P2POutputSplit{Strategy: P2POutputSplitDenominations, Denominations: []uint64{100, 0}}).validate()
+		assert.ErrorIs(t, err, ErrInvalidOutputSplit)
+
+		// the split is validated when it is set
+		assert.Panics(t, func() {
+			WithPaymailOutputSplit(&P2POutputSplit{Strategy: P2POutputSplitFixedCount})(defaultClientOptions())
+		})
+	})
+
+	t.Run("fixed count", func(t *testing.T) {
+		values, err := (&P2POutputSplit{Strategy: P2POutputSplitFixedCount, Count: 3}).split(1000)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{334, 333, 333}, values)
+
+		// keep every output at the min satoshis
+		values, err = (&P2POutputSplit{Strategy: P2POutputSplitFixedCount, Count: 10, MinSatoshis: 300}).split(1000)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{334, 333, 333}, values)
+	})
+
+	t.Run("denominations", func(t *testing.T) {
+		values, err := (&P2POutputSplit{
+			Strategy: P2POutputSplitDenominations, Denominations: []uint64{100, 500},
+		}).split(1250)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{500, 500, 100, 100, 50}, values)
+	})
+
+	t.Run("max outputs", func(t *testing.T) {
+		values, err := (&P2POutputSplit{
+			Strategy: P2POutputSplitDenominations, Denominations: []uint64{100}, MaxOutputs: 3,
+		}).split(1000)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{100, 100, 800}, values)
+
+		// stops splitting at the max outputs
+		values, err = (&P2POutputSplit{
+			Strategy: P2POutputSplitDenominations, Denominations: []uint64{1}, MaxOutputs: 2,
+		}).split(100000000)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{1, 99999999}, values)
+
+		values, err = (&P2POutputSplit{Strategy: P2POutputSplitFixedCount, Count: 10, MaxOutputs: 4}).split(1000)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{250, 250, 250, 250}, values)
+	})
+
+	t.Run("random", func(t *testing.T) {
+		split := &P2POutputSplit{Strategy: P2POutputSplitRandom, Count: 5, MinSatoshis: 100, Threshold: 1000}
+
+		// below the threshold
+		values, err := split.split(1000)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{1000}, values)
+
+		for i := 0; i < 20; i++ {
+			values, err = split.split(100000)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, len(values), 2)
+			assert.LessOrEqual(t, len(values), 5)
+			assert.Equal(t, uint64(100000), sumValues(values))
+			for _, value := range values {
+				assert.GreaterOrEqual(t, value, uint64(100))
+			}
+		}
+	})
+}
+
+func TestPaymailDefaultServiceProvider_CreateP2PDestinationResponse(t *testing.T) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}), WithAutoMigrate(newPaymail(testPaymail)),
+		WithPaymailOutputSplit(&P2POutputSplit{Strategy: P2POutputSplitFixedCount, Count: 3}),
+	)
+	defer deferMe()
+
+	_, err := client.NewXpub(ctx, testXPub, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	_, err = client.NewPaymailAddress(testSuperAdminContext(ctx), testXPub, testPaymail, "", "", client.DefaultModelOptions()...)
+	require.NoError(t, err)
+
+	provider := &PaymailDefaultServiceProvider{client: client}
+	payload, err := provider.CreateP2PDestinationResponse(ctx, "paymail", "tester.com", 1000, nil)
+	require.NoError(t, err)
+	require.Len(t, payload.Outputs, 3)
+
+	addresses := make(map[string]bool)
+	var values []uint64
+	for _, output := range payload.Outputs {
+		addresses[output.Address] = true
+		values = append(values, output.Satoshis)
+
+		// every destination is tied to the reference
+		var destination *Destination
+		destination, err = getDestinationByAddress(ctx, output.Address, client.DefaultModelOptions()...)
+		require.NoError(t, err)
+		require.NotNil(t, destination)
+		assert.Equal(t, payload.Reference, destination.Metadata[ReferenceIDField])
+	}
+	assert.Len(t, addresses, 3)
+	assert.Equal(t, uint64(1000), sumValues(values))
+}
@@ -0,0 +1,414 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoin-sv/go-paymail"
+	"github.com/bitcoin-sv/go-paymail/server"
+)
+
+// PIKE (Paymail Invitations For Key Exchange) capability, the value is an object with the URL of each endpoint:
+// {"8c4ed5ef8ace": {"invite": url, "outputs": url}}
+const (
+	BRFCPike        = "8c4ed5ef8ace" // BRFC id of PIKE
+	BRFCPikeInvite  = "invite"       // POST PikeContactRequest: invite the paymail as a contact
+	BRFCPikeOutputs = "outputs"      // POST PikeOutputsRequest: get the outputs issued to the (confirmed) contact
+)
+
+// PIKE endpoints (relative to the service URL of the paymail server)
+const (
+	pikeInvitePath  = "/contact/invite/"
+	pikeOutputsPath = "/pike/outputs/"
+)
+
+// PikeContactRequest is the invitation of a contact (PIKE invite payload)
+type PikeContactRequest struct {
+	FullName string `json:"fullName"` // Name of the requester
+	Paymail  string `json:"paymail"`  // Paymail of the requester
+}
+
+// PikeOutputsRequest is the request of a contact for the outputs of a payment (PIKE outputs payload)
+//
+// The request is not signed (as per PIKE), SenderPaymail is not authenticated
+type PikeOutputsRequest struct {
+	Amount        uint64 `json:"amount"`        // Satoshis of the payment
+	SenderPaymail string `json:"senderPaymail"` // Paymail of the contact
+}
+
+// PikeOutputsResponse is the outputs issued to the contact, the P2P transaction is sent with the reference
+type PikeOutputsResponse struct {
+	Outputs   []*paymail.PaymentOutput `json:"outputs"`
+	Reference string                   `json:"reference"`
+}
+
+// pikeCapabilities will get the PIKE capability of the paymail server
+//
+// The paymail server only prefixes string capabilities with the service URL, the URLs of the endpoints
+// are advertised with the service URL of the paymail domain ({domain.tld} is the domain of the paymail)
+func pikeCapabilities(config *server.Configuration) map[string]interface{} {
+	serviceURL := server.GenerateServiceURL(config.Prefix, "{domain.tld}", config.APIVersion, config.ServiceName)
+	return map[string]interface{}{
+		BRFCPike: map[string]interface{}{
+			BRFCPikeInvite:  serviceURL + pikeInvitePath + "{alias}@{domain.tld}",
+			BRFCPikeOutputs: serviceURL + pikeOutputsPath + "{alias}@{domain.tld}",
+		},
+	}
+}
+
+// getPikeURL will get the URL of the PIKE endpoint (invite or outputs) for the paymail, empty if not supported
+func getPikeURL(capabilities *paymail.CapabilitiesPayload, endpoint, alias, domain string) string {
+	pike, ok := capabilities.Capabilities[BRFCPike].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	url, _ := pike[endpoint].(string)
+	if len(url) == 0 {
+		return ""
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(url, "{alias}", alias), "{domain.tld}", domain)
+}
+
+// postPikeRequest will POST the PIKE request (JSON) and decode the response (if given)
+func postPikeRequest(ctx context.Context, httpClient HTTPInterface, url string,
+	payload, response interface{}) error {
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var res *http.Response
+	if res, err = httpClient.Do(req); err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", ErrPikeRequestFailed, res.StatusCode)
+	} else if response == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(response)
+}
+
+// getPaymailPubKey will get the PKI key of the paymail
+func getPaymailPubKey(ctx context.Context, client ClientInterface, alias, domain string) (string, error) {
+	capabilities, err := getCapabilities(ctx, client.Cachestore(), client.PaymailClient(), domain)
+	if err != nil {
+		return "", err
+	}
+
+	pkiURL := capabilities.GetString(paymail.BRFCPki, paymail.BRFCPkiAlternate)
+	if len(pkiURL) == 0 {
+		return "", ErrMissingPaymailPKI
+	}
+
+	var pki *paymail.PKIResponse
+	if pki, err = client.PaymailClient().GetPKI(pkiURL, alias, domain); err != nil {
+		return "", err
+	}
+	return pki.PubKey, nil
+}
+
+// getXpubPaymail will get the (first) paymail of the xPub, empty if the xPub has no paymail
+func getXpubPaymail(ctx context.Context, client ClientInterface, xPubID string) (string, error) {
+	paymails, err := client.GetPaymailAddressesByXPubID(ctx, xPubID, nil, &map[string]interface{}{
+		xPubIDField: xPubID,
+	}, nil)
+	if err != nil || len(paymails) == 0 {
+		return "", err
+	}
+	return paymails[0].Alias + "@" + paymails[0].Domain, nil
+}
+
+// sendPikeInvite will invite the paymail as a contact of the requester, false if PIKE is not supported
+func sendPikeInvite(ctx context.Context, client ClientInterface, requester *PikeContactRequest,
+	alias, domain string) (bool, error) {
+
+	capabilities, err := getCapabilities(ctx, client.Cachestore(), client.PaymailClient(), domain)
+	if err != nil {
+		return false, err
+	}
+
+	inviteURL := getPikeURL(capabilities, BRFCPikeInvite, alias, domain)
+	if len(inviteURL) == 0 {
+		return false, nil
+	}
+	return true, postPikeRequest(ctx, client.HTTPClient(), inviteURL, requester, nil)
+}
+
+// AddContactInvitation will add (or update) the requester as a contact awaiting the acceptance (PIKE invite)
+//
+// The key of the requester is resolved with PKI, rejected and confirmed contacts are not changed by an invitation
+func (p *PaymailDefaultServiceProvider) AddContactInvitation(ctx context.Context, alias, domain string,
+	request *PikeContactRequest) (*Contact, error) {
+
+	requesterAlias, requesterDomain, requesterPaymail := paymail.SanitizePaymail(request.Paymail)
+	if len(requesterPaymail) == 0 {
+		return nil, ErrPaymailAddressIsInvalid
+	}
+
+	paymailAddress, err := getPaymailAddress(ctx, alias+"@"+domain, p.client.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if paymailAddress == nil {
+		return nil, ErrMissingPaymail
+	}
+
+	var pubKey string
+	if pubKey, err = getPaymailPubKey(ctx, p.client, requesterAlias, requesterDomain); err != nil {
+		return nil, err
+	}
+
+	contactID := getContactID(paymailAddress.XpubID, requesterPaymail)
+	unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyContact, contactID), p.client.Cachestore())
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var contact *Contact
+	if contact, err = getContact(
+		ctx, paymailAddress.XpubID, requesterPaymail, p.client.DefaultModelOptions()...,
+	); err != nil {
+		return nil, err
+	} else if contact == nil {
+		contact = newContact(
+			paymailAddress.XpubID, requesterPaymail, request.FullName, pubKey, ContactStatusAwaiting,
+			p.client.DefaultModelOptions(New())...,
+		)
+	} else if contact.Status == ContactStatusRejected || contact.Status == ContactStatusConfirmed {
+		return contact, nil
+	} else {
+		contact.FullName = request.FullName
+		contact.PubKey = pubKey
+	}
+
+	if err = contact.Save(ctx); err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// CreatePikeOutputsResponse will create the outputs for a payment of a confirmed contact (PIKE outputs)
+//
+// The outputs are derived from the chain of the contact, and the P2P transaction is sent with the reference.
+// The sender paymail is NOT authenticated (PIKE outputs requests are not signed): anyone can get the outputs
+// of a confirmed contact by naming it, so the outputs (and the reference) do not prove who paid. The receiving
+// policy of the paymail is checked against the claimed sender only.
+func (p *PaymailDefaultServiceProvider) CreatePikeOutputsResponse(ctx context.Context, alias, domain string,
+	request *PikeOutputsRequest, requestMetadata *server.RequestMetadata) (*PikeOutputsResponse, error) {
+
+	_, _, senderPaymail := paymail.SanitizePaymail(request.SenderPaymail)
+	if len(senderPaymail) == 0 {
+		return nil, ErrPaymailAddressIsInvalid
+	}
+
+	paymailAddress, err := getPaymailAddress(ctx, alias+"@"+domain, p.client.DefaultModelOptions()...)
+	if err != nil {
+		return nil, err
+	} else if paymailAddress == nil {
+		return nil, ErrMissingPaymail
+	}
+
+	// Check the receiving policy of the paymail
+	if err = paymailAddress.ReceivingPolicy.check(request.Amount, senderPaymail); err != nil {
+		return nil, err
+	}
+
+	var contact *Contact
+	if contact, err = getConfirmedContact(
+		ctx, p.client, paymailAddress.XpubID, senderPaymail, p.client.DefaultModelOptions()...,
+	); err != nil {
+		return nil, err
+	} else if contact == nil {
+		return nil, ErrContactNotConfirmed
+	}
+
+	var referenceID string
+	if referenceID, err = utils.RandomHex(16); err != nil {
+		return nil, err
+	}
+
+	metadata := p.createMetadata(requestMetadata, "CreatePikeOutputsResponse")
+	metadata[ReferenceIDField] = referenceID
+	metadata[satoshisField] = request.Amount
+
+	var outputs []*paymail.PaymentOutput
+	if outputs, err = p.createP2POutputs(
+		ctx, referenceID, request.Amount, metadata,
+		func() (*PaymailAddress, *derivedPubKey, error) {
+			pubKey, deriveErr := contact.deriveKey(ctx)
+			return paymailAddress, pubKey, deriveErr
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	return &PikeOutputsResponse{
+		Outputs:   outputs,
+		Reference: referenceID,
+	}, nil
+}
+
+// NewPikeHandler will return the HTTP handler of the PIKE endpoints (see BRFCPike), mount it on the service URL
+// of the paymail server. The sender of an outputs request is not authenticated (see CreatePikeOutputsResponse)
+func NewPikeHandler(client ClientInterface) http.Handler {
+	provider := &PaymailDefaultServiceProvider{client: client}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			server.ErrorResponse(w, server.ErrorMethodNotFound, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Get the endpoint and the paymail (last part of the path)
+		var endpoint, paymailAddress string
+		for _, path := range []string{pikeInvitePath, pikeOutputsPath} {
+			if index := strings.LastIndex(req.URL.Path, path); index >= 0 {
+				endpoint, paymailAddress = path, req.URL.Path[index+len(path):]
+				break
+			}
+		}
+		alias, domain, address := paymail.SanitizePaymail(paymailAddress)
+		if len(endpoint) == 0 {
+			server.ErrorResponse(w, server.ErrorRequestNotFound, "request not found", http.StatusNotFound)
+			return
+		} else if len(address) == 0 {
+			server.ErrorResponse(w, server.ErrorInvalidParameter, "invalid paymail: "+paymailAddress, http.StatusBadRequest)
+			return
+		}
+
+		var response interface{}
+		var err error
+		if endpoint == pikeInvitePath {
+			request := new(PikeContactRequest)
+			if err = json.NewDecoder(req.Body).Decode(request); err != nil {
+				server.ErrorResponse(w, server.ErrorInvalidParameter, err.Error(), http.StatusBadRequest)
+				return
+			}
+			_, err = provider.AddContactInvitation(req.Context(), alias, domain, request)
+		} else {
+			request := new(PikeOutputsRequest)
+			if err = json.NewDecoder(req.Body).Decode(request); err != nil {
+				server.ErrorResponse(w, server.ErrorInvalidParameter, err.Error(), http.StatusBadRequest)
+				return
+			}
+			response, err = provider.CreatePikeOutputsResponse(req.Context(), alias, domain, request, &server.RequestMetadata{
+				IPAddress: req.RemoteAddr,
+				UserAgent: req.UserAgent(),
+			})
+		}
+
+		switch {
+		case errors.Is(err, ErrMissingPaymail), errors.Is(err, ErrContactNotConfirmed):
+			server.ErrorResponse(w, server.ErrorPaymailNotFound, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrPaymailAddressIsInvalid),
+			errors.Is(err, ErrPaymailAmountBelowMinimum), errors.Is(err, ErrPaymailAmountAboveMaximum):
+			server.ErrorResponse(w, server.ErrorInvalidParameter, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrPaymailSenderNotAllowed), errors.Is(err, ErrPaymailReceivingPaused):
+			server.ErrorResponse(w, server.ErrorInvalidSenderHandle, err.Error(), http.StatusForbidden)
+		case err != nil:
+			server.ErrorResponse(w, server.ErrorFindingPaymail, err.Error(), http.StatusExpectationFailed)
+		case response == nil:
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(response)
+		}
+	})
+}
+
+// processPikeOutput will process the output with the outputs issued by the contact, if the paymail of the output
+// is a confirmed contact of the xPub (false if not processed)
+func (m *DraftTransaction) processPikeOutput(ctx context.Context, output *TransactionOutput,
+	fromPaymail string) (bool, error) {
+
+	if output.Satoshis == 0 || !strings.Contains(output.To, "@") {
+		return false, nil
+	}
+
+	_, _, paymailAddress := paymail.SanitizePaymail(output.To)
+	contact, err := getConfirmedContact(ctx, m.Client(), m.XpubID, paymailAddress, m.GetOptions(false)...)
+	if err != nil || contact == nil {
+		return false, err
+	}
+	return output.processPaymailViaPike(ctx, m.Client(), fromPaymail)
+}
+
+// processPaymailViaPike will process the output for a confirmed contact with the outputs issued by the contact
+//
+// Returns false (not processed) if the paymail server of the contact does not support PIKE outputs and P2P,
+// or does not issue the outputs (the output is then processed like any paymail output)
+func (t *TransactionOutput) processPaymailViaPike(ctx context.Context, client ClientInterface,
+	fromPaymail string) (bool, error) {
+
+	alias, domain, paymailAddress := paymail.SanitizePaymail(t.To)
+	if len(paymailAddress) == 0 {
+		return false, ErrPaymailAddressIsInvalid
+	}
+
+	capabilities, err := getCapabilities(ctx, client.Cachestore(), client.PaymailClient(), domain)
+	if err != nil {
+		return false, err
+	}
+
+	outputsURL := getPikeURL(capabilities, BRFCPikeOutputs, alias, domain)
+	success, _, p2pSubmitTxURL, format := hasP2P(capabilities)
+	if len(outputsURL) == 0 || !success {
+		return false, nil
+	}
+
+	// Get the outputs issued to the contact (us)
+	response := new(PikeOutputsResponse)
+	if err = postPikeRequest(ctx, client.HTTPClient(), outputsURL, &PikeOutputsRequest{
+		Amount:        t.Satoshis,
+		SenderPaymail: fromPaymail,
+	}, response); errors.Is(err, ErrPikeRequestFailed) {
+		client.Logger().Warn(ctx, fmt.Sprintf("pike outputs of %s not issued: %s", paymailAddress, err.Error()))
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	outputValues := getP2POutputValues(response.Outputs, t.Satoshis)
+	if outputValues == nil {
+		return false, ErrInvalidPikeOutputs
+	}
+	for index, out := range response.Outputs {
+		t.Scripts = append(t.Scripts, &ScriptOutput{
+			Address:    out.Address,
+			Satoshis:   outputValues[index],
+			Script:     out.Script,
+			ScriptType: utils.ScriptTypePubKeyHash,
+		})
+	}
+
+	// Send the transaction like a P2P transaction (with the reference of the outputs)
+	t.To = paymailAddress
+	if t.PaymailP4 == nil {
+		t.PaymailP4 = &PaymailP4{}
+	}
+	t.PaymailP4.Alias = alias
+	t.PaymailP4.Domain = domain
+	t.PaymailP4.Format = format
+	t.PaymailP4.FromPaymail = fromPaymail
+	t.PaymailP4.ReceiveEndpoint = p2pSubmitTxURL
+	t.PaymailP4.ReferenceID = response.Reference
+	t.PaymailP4.ResolutionType = ResolutionTypePike
+	return true, nil
+}
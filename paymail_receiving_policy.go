@@ -0,0 +1,110 @@
+package bux
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bitcoin-sv/go-paymail"
+)
+
+// PaymailReceivingPolicy is the receiving policy of a paymail address, empty values are not checked
+//
+// The amount is only checked when the sender gives it (P2P destinations, address resolutions with an amount).
+// The sender of a P2P payment is only known when the transaction is received, so it is checked when recorded,
+// and a sender that is not given is not allowed if there are allowed sender domains
+type PaymailReceivingPolicy struct {
+	AllowedSenderDomains []string `json:"allowed_sender_domains,omitempty" toml:"allowed_sender_domains" yaml:"allowed_sender_domains"` // Only receive from paymails of these domains
+	BlockedSenderDomains []string `json:"blocked_sender_domains,omitempty" toml:"blocked_sender_domains" yaml:"blocked_sender_domains"` // Never receive from paymails of these domains
+	MaxSatoshis          uint64   `json:"max_satoshis" toml:"max_satoshis" yaml:"max_satoshis"`                                         // Max satoshis of a payment
+	MinSatoshis          uint64   `json:"min_satoshis" toml:"min_satoshis" yaml:"min_satoshis"`                                         // Min satoshis of a payment
+	Paused               bool     `json:"paused" toml:"paused" yaml:"paused"`                                                           // Receiving is paused (disabled)
+}
+
+// validate will check the policy
+func (p *PaymailReceivingPolicy) validate() error {
+	if p.MaxSatoshis > 0 && p.MinSatoshis > p.MaxSatoshis {
+		return ErrInvalidReceivingPolicy
+	}
+	for _, list := range [][]string{p.AllowedSenderDomains, p.BlockedSenderDomains} {
+		for _, domain := range list {
+			if len(strings.TrimSpace(domain)) == 0 {
+				return ErrInvalidReceivingPolicy
+			}
+		}
+	}
+	return nil
+}
+
+// check will check the payment (satoshis, zero if not given) of the sender
+func (p *PaymailReceivingPolicy) check(satoshis uint64, sender string) error {
+	if err := p.checkAmount(satoshis); err != nil {
+		return err
+	}
+	return p.checkSender(sender)
+}
+
+// checkAmount will check the payment (satoshis, zero if not given) when the sender is not known yet
+func (p *PaymailReceivingPolicy) checkAmount(satoshis uint64) error {
+	if p.Paused {
+		return ErrPaymailReceivingPaused
+	}
+
+	if satoshis > 0 && p.MinSatoshis > 0 && satoshis < p.MinSatoshis {
+		return fmt.Errorf("%w: %d satoshis, the minimum is %d", ErrPaymailAmountBelowMinimum, satoshis, p.MinSatoshis)
+	} else if p.MaxSatoshis > 0 && satoshis > p.MaxSatoshis {
+		return fmt.Errorf("%w: %d satoshis, the maximum is %d", ErrPaymailAmountAboveMaximum, satoshis, p.MaxSatoshis)
+	}
+	return nil
+}
+
+// checkSender will check the sender (paymail) against the sender domains
+func (p *PaymailReceivingPolicy) checkSender(sender string) error {
+	if p.Paused {
+		return ErrPaymailReceivingPaused
+	}
+
+	_, domain, _ := paymail.SanitizePaymail(sender)
+	if len(domain) > 0 && containsDomain(p.BlockedSenderDomains, domain) {
+		return fmt.Errorf("%w: the domain %s is blocked", ErrPaymailSenderNotAllowed, domain)
+	} else if len(p.AllowedSenderDomains) == 0 {
+		return nil
+	} else if len(domain) == 0 {
+		return fmt.Errorf("%w: the sender paymail is required", ErrPaymailSenderNotAllowed)
+	} else if !containsDomain(p.AllowedSenderDomains, domain) {
+		return fmt.Errorf("%w: the domain %s is not allowed", ErrPaymailSenderNotAllowed, domain)
+	}
+	return nil
+}
+
+// Scan will scan the value into Struct, implements sql.Scanner interface
+func (p *PaymailReceivingPolicy) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	xType := fmt.Sprintf("%T", value)
+	var byteValue []byte
+	if xType == ValueTypeString {
+		byteValue = []byte(value.(string))
+	} else {
+		byteValue = value.([]byte)
+	}
+	if bytes.Equal(byteValue, []byte("")) || bytes.Equal(byteValue, []byte("\"\"")) {
+		return nil
+	}
+
+	return json.Unmarshal(byteValue, &p)
+}
+
+// Value return json value, implement driver.Valuer interface
+func (p PaymailReceivingPolicy) Value() (driver.Value, error) {
+	marshal, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(marshal), nil
+}
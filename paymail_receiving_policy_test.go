@@ -0,0 +1,146 @@
+package bux
+
+import (
+	"testing"
+
+	"github.com/bitcoin-sv/go-paymail"
+	"github.com/bitcoin-sv/go-paymail/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymailReceivingPolicy_check(t *testing.T) {
+	t.Run("no policy", func(t *testing.T) {
+		assert.NoError(t, (&PaymailReceivingPolicy{}).check(1, ""))
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		assert.ErrorIs(t, (&PaymailReceivingPolicy{Paused: true}).check(1000, ""), ErrPaymailReceivingPaused)
+	})
+
+	t.Run("min and max", func(t *testing.T) {
+		policy := &PaymailReceivingPolicy{MinSatoshis: 1000, MaxSatoshis: 5000}
+		assert.ErrorIs(t, policy.check(999, ""), ErrPaymailAmountBelowMinimum)
+		assert.ErrorIs(t, policy.check(5001, ""), ErrPaymailAmountAboveMaximum)
+		assert.NoError(t, policy.check(1000, ""))
+		assert.NoError(t, policy.check(5000, ""))
+
+		// the amount is not given
+		assert.NoError(t, policy.check(0, ""))
+	})
+
+	t.Run("sender domains", func(t *testing.T) {
+		policy := &PaymailReceivingPolicy{BlockedSenderDomains: []string{"Blocked.com"}}
+		assert.ErrorIs(t, policy.check(1000, "sender@blocked.com"), ErrPaymailSenderNotAllowed)
+		assert.NoError(t, policy.check(1000, "sender@"+testDomain))
+		assert.NoError(t, policy.check(1000, ""))
+
+		policy = &PaymailReceivingPolicy{AllowedSenderDomains: []string{testDomain}}
+		assert.NoError(t, policy.check(1000, "sender@"+testDomain))
+		assert.ErrorIs(t, policy.check(1000, "sender@other.com"), ErrPaymailSenderNotAllowed)
+		assert.ErrorIs(t, policy.check(1000, ""), ErrPaymailSenderNotAllowed)
+
+		// the sender is not known yet (P2P destinations)
+		assert.NoError(t, policy.checkAmount(1000))
+		assert.ErrorIs(t, policy.checkSender(""), ErrPaymailSenderNotAllowed)
+		assert.EqualError(t, policy.checkSender("sender@other.com"),
+			ErrPaymailSenderNotAllowed.Error()+": the domain other.com is not allowed")
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		assert.ErrorIs(t, (&PaymailReceivingPolicy{MinSatoshis: 2, MaxSatoshis: 1}).validate(), ErrInvalidReceivingPolicy)
+		assert.ErrorIs(t, (&PaymailReceivingPolicy{AllowedSenderDomains: []string{" "}}).validate(), ErrInvalidReceivingPolicy)
+		assert.NoError(t, (&PaymailReceivingPolicy{MinSatoshis: 1, MaxSatoshis: 1}).validate())
+	})
+}
+
+func TestPaymailDefaultServiceProvider_getReceivingPolicy(t *testing.T) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}), WithAutoMigrate(newPaymail(testPaymail)),
+	)
+	defer deferMe()
+
+	_, err := client.NewXpub(ctx, testXPub, client.DefaultModelOptions()...)
+	require.NoError(t, err)
+	_, err = client.NewPaymailAddress(testSuperAdminContext(ctx), testXPub, testPaymail, "", "", client.DefaultModelOptions()...)
+	require.NoError(t, err)
+
+	_, err = client.UpdatePaymailAddressReceivingPolicy(testSuperAdminContext(ctx), testPaymail, &PaymailReceivingPolicy{
+		MinSatoshis: 2, MaxSatoshis: 1,
+	})
+	require.ErrorIs(t, err, ErrInvalidReceivingPolicy)
+
+	var paymailAddress *PaymailAddress
+	paymailAddress, err = client.UpdatePaymailAddressReceivingPolicy(testSuperAdminContext(ctx), testPaymail, &PaymailReceivingPolicy{
+		BlockedSenderDomains: []string{"blocked.com"}, MinSatoshis: 1000,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), paymailAddress.ReceivingPolicy.MinSatoshis)
+
+	// the policy is stored
+	paymailAddress, err = client.GetPaymailAddress(ctx, testPaymail)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blocked.com"}, paymailAddress.ReceivingPolicy.BlockedSenderDomains)
+
+	provider := &PaymailDefaultServiceProvider{client: client}
+
+	t.Run("p2p destination", func(t *testing.T) {
+		_, err = provider.CreateP2PDestinationResponse(ctx, "paymail", "tester.com", 999, nil)
+		assert.ErrorIs(t, err, ErrPaymailAmountBelowMinimum)
+
+		var payload *paymail.PaymentDestinationPayload
+		payload, err = provider.CreateP2PDestinationResponse(ctx, "paymail", "tester.com", 1000, nil)
+		require.NoError(t, err)
+		assert.Len(t, payload.Outputs, 1)
+	})
+
+	t.Run("address resolution", func(t *testing.T) {
+		_, err = provider.CreateAddressResolutionResponse(ctx, "paymail", "tester.com", false, &server.RequestMetadata{
+			ResolveAddress: &paymail.SenderRequest{SenderHandle: "sender@blocked.com"},
+		})
+		assert.ErrorIs(t, err, ErrPaymailSenderNotAllowed)
+
+		var payload *paymail.ResolutionPayload
+		payload, err = provider.CreateAddressResolutionResponse(ctx, "paymail", "tester.com", false, &server.RequestMetadata{
+			ResolveAddress: &paymail.SenderRequest{SenderHandle: "sender@" + testDomain},
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, payload.Address)
+	})
+
+	t.Run("sender of the p2p transaction", func(t *testing.T) {
+		_, err = client.UpdatePaymailAddressReceivingPolicy(testSuperAdminContext(ctx), testPaymail, &PaymailReceivingPolicy{
+			AllowedSenderDomains: []string{testDomain},
+		})
+		require.NoError(t, err)
+
+		// the sender is not known when the destination is issued
+		_, err = provider.CreateP2PDestinationResponse(ctx, "paymail", "tester.com", 1000, nil)
+		require.NoError(t, err)
+
+		requestMetadata := &server.RequestMetadata{Alias: "paymail", Domain: "tester.com"}
+		_, err = provider.RecordTransaction(ctx, &paymail.P2PTransaction{
+			Hex: testTxHex, MetaData: &paymail.P2PMetaData{Sender: "sender@other.com"},
+		}, requestMetadata)
+		assert.ErrorIs(t, err, ErrPaymailSenderNotAllowed)
+
+		_, err = provider.RecordTransaction(ctx, &paymail.P2PTransaction{
+			Hex: testTxHex, MetaData: &paymail.P2PMetaData{},
+		}, requestMetadata)
+		assert.ErrorIs(t, err, ErrPaymailSenderNotAllowed)
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		_, err = client.UpdatePaymailAddressReceivingPolicy(testSuperAdminContext(ctx), testPaymail, &PaymailReceivingPolicy{Paused: true})
+		require.NoError(t, err)
+
+		_, err = provider.CreateP2PDestinationResponse(ctx, "paymail", "tester.com", 1000, nil)
+		assert.ErrorIs(t, err, ErrPaymailReceivingPaused)
+		_, err = provider.CreateAddressResolutionResponse(ctx, "paymail", "tester.com", false, nil)
+		assert.ErrorIs(t, err, ErrPaymailReceivingPaused)
+		_, err = provider.RecordTransaction(ctx, &paymail.P2PTransaction{
+			Hex: testTxHex, MetaData: &paymail.P2PMetaData{Sender: "sender@" + testDomain},
+		}, &server.RequestMetadata{Alias: "paymail", Domain: "tester.com"})
+		assert.ErrorIs(t, err, ErrPaymailReceivingPaused)
+	})
+}
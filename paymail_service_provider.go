@@ -11,6 +11,7 @@ import (
 	"github.com/bitcoin-sv/go-paymail/server"
 	"github.com/bitcoinschema/go-bitcoin/v2"
 	"github.com/libsv/go-bk/bec"
+	"github.com/libsv/go-bt/v2"
 	customTypes "github.com/mrz1836/go-datastore/custom_types"
 
 	"github.com/BuxOrg/bux/chainstate"
@@ -78,6 +79,19 @@ func (p *PaymailDefaultServiceProvider) CreateAddressResolutionResponse(
 	_ bool,
 	requestMetadata *server.RequestMetadata,
 ) (*paymail.ResolutionPayload, error) {
+	// Check the receiving policy of the paymail (amount and sender are optional)
+	var satoshis uint64
+	var sender string
+	if requestMetadata != nil && requestMetadata.ResolveAddress != nil {
+		satoshis, sender = requestMetadata.ResolveAddress.Amount, requestMetadata.ResolveAddress.SenderHandle
+	}
+	policy, err := p.getReceivingPolicy(ctx, alias, domain)
+	if err != nil {
+		return nil, err
+	} else if err = policy.check(satoshis, sender); err != nil {
+		return nil, err
+	}
+
 	metadata := p.createMetadata(requestMetadata, "CreateAddressResolutionResponse")
 
 	paymailAddress, pubKey, err := p.createPaymailInformation(
@@ -107,42 +121,171 @@ func (p *PaymailDefaultServiceProvider) CreateP2PDestinationResponse(
 	satoshis uint64,
 	requestMetadata *server.RequestMetadata,
 ) (*paymail.PaymentDestinationPayload, error) {
-	referenceID, err := utils.RandomHex(16)
+	// Check the receiving policy of the paymail (the sender is checked when the transaction is recorded)
+	policy, err := p.getReceivingPolicy(ctx, alias, domain)
 	if err != nil {
 		return nil, err
+	} else if err = policy.checkAmount(satoshis); err != nil {
+		return nil, err
+	}
+
+	var referenceID string
+	if referenceID, err = utils.RandomHex(16); err != nil {
+		return nil, err
 	}
 
 	metadata := p.createMetadata(requestMetadata, "CreateP2PDestinationResponse")
 	metadata[ReferenceIDField] = referenceID
 	metadata[satoshisField] = satoshis
 
-	// todo: strategy to break apart outputs based on satoshis (return x Outputs)
-	var destination *Destination
-	paymailAddress, pubKey, err := p.createPaymailInformation(
-		ctx, alias, domain, append(p.client.DefaultModelOptions(), WithMetadatas(metadata))...,
-	)
+	var outputs []*paymail.PaymentOutput
+	if outputs, err = p.createP2POutputs(
+		ctx, referenceID, satoshis, metadata,
+		func() (*PaymailAddress, *derivedPubKey, error) {
+			return p.createPaymailInformation(
+				ctx, alias, domain, append(p.client.DefaultModelOptions(), WithMetadatas(metadata))...,
+			)
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	return &paymail.PaymentDestinationPayload{
+		Outputs:   outputs,
+		Reference: referenceID,
+	}, nil
+}
+
+// getReceivingPolicy will get the receiving policy of the paymail address
+func (p *PaymailDefaultServiceProvider) getReceivingPolicy(ctx context.Context,
+	alias, domain string) (*PaymailReceivingPolicy, error) {
+
+	paymailAddress, err := getPaymailAddress(ctx, alias+"@"+domain, p.client.DefaultModelOptions()...)
 	if err != nil {
 		return nil, err
+	} else if paymailAddress == nil {
+		return nil, ErrMissingPaymail
 	}
-	destination, err = createDestination(
-		ctx, paymailAddress, pubKey, false, append(p.client.DefaultModelOptions(), WithMetadatas(metadata))...,
-	)
+	return &paymailAddress.ReceivingPolicy, nil
+}
+
+// createP2POutputs will create the destinations of the (split) payment with the derived keys, and persist
+// the issued outputs of the reference (if the payment references are loaded)
+func (p *PaymailDefaultServiceProvider) createP2POutputs(ctx context.Context, referenceID string, satoshis uint64,
+	metadata Metadata, deriveOutputKey func() (*PaymailAddress, *derivedPubKey, error),
+) ([]*paymail.PaymentOutput, error) {
+
+	// Split the payment into outputs (one output if no split is set)
+	values, err := p.getOutputSplit().split(satoshis)
 	if err != nil {
 		return nil, err
 	}
 
-	// Append the output(s)
-	var outputs []*paymail.PaymentOutput
-	outputs = append(outputs, &paymail.PaymentOutput{
-		Address:  destination.Address,
-		Satoshis: satoshis,
-		Script:   destination.LockingScript,
-	})
+	// Derive a destination for each output
+	var paymailAddress *PaymailAddress
+	outputs := make([]*paymail.PaymentOutput, 0, len(values))
+	referenceOutputs := make(PaymentReferenceOutputs, 0, len(values))
+	for _, value := range values {
+		var pubKey *derivedPubKey
+		if paymailAddress, pubKey, err = deriveOutputKey(); err != nil {
+			return nil, err
+		}
 
-	return &paymail.PaymentDestinationPayload{
-		Outputs:   outputs,
-		Reference: referenceID,
-	}, nil
+		var destination *Destination
+		if destination, err = createDestination(
+			ctx, paymailAddress, pubKey, false, append(p.client.DefaultModelOptions(), WithMetadatas(metadata))...,
+		); err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, &paymail.PaymentOutput{
+			Address:  destination.Address,
+			Satoshis: value,
+			Script:   destination.LockingScript,
+		})
+		referenceOutputs = append(referenceOutputs, &PaymentReferenceOutput{
+			Address:       destination.Address,
+			LockingScript: destination.LockingScript,
+			Satoshis:      value,
+		})
+	}
+
+	// Persist the issued outputs for verifying the P2P transaction of the reference
+	if isPaymentReferenceLoaded(p.client) {
+		reference := newPaymentReference(
+			referenceID, paymailAddress, satoshis,
+			time.Now().UTC().Add(p.client.GetPaymailConfig().ReferenceExpiresIn),
+			append(p.client.DefaultModelOptions(), New(), WithMetadatas(metadata))...,
+		)
+		reference.Outputs = referenceOutputs
+		if err = reference.Save(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return outputs, nil
+}
+
+// verifyPaymentReference will verify the P2P transaction against its payment reference (see PaymentReference.verify)
+//
+// A failed verification is returned as the error if invalid references are rejected, else as the flag
+func (p *PaymailDefaultServiceProvider) verifyPaymentReference(ctx context.Context,
+	p2pTx *paymail.P2PTransaction) (reference *PaymentReference, flag, err error) {
+
+	tx, err := bt.NewTxFromString(p2pTx.Hex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if reference, err = getPaymentReference(ctx, p2pTx.Reference, p.client.DefaultModelOptions()...); err != nil {
+		return nil, nil, err
+	} else if reference == nil {
+		flag = fmt.Errorf("%w: %s", ErrUnknownPaymentReference, p2pTx.Reference)
+	} else {
+		flag = reference.verify(tx, time.Now().UTC())
+	}
+
+	if flag == nil {
+		return reference, nil, nil
+	} else if p.client.GetPaymailConfig().RejectInvalidReferences {
+		return nil, nil, flag
+	}
+	p.client.Logger().Warn(ctx, fmt.Sprintf(
+		"p2p transaction %s flagged for reference %s: %s", tx.TxID(), p2pTx.Reference, flag.Error(),
+	))
+	return reference, flag, nil
+}
+
+// verifySender will verify the signature of the txid with the key of the sender paymail (PKI)
+//
+// A failed verification is returned as the error if the receiving paymail rejects unverified senders
+func (p *PaymailDefaultServiceProvider) verifySender(ctx context.Context, p2pTx *paymail.P2PTransaction,
+	requestMetadata *server.RequestMetadata) (bool, error) {
+
+	tx, err := bt.NewTxFromString(p2pTx.Hex)
+	if err != nil {
+		return false, err
+	}
+
+	if err = verifySenderSignature(ctx, p.client, p2pTx.MetaData, tx.TxID()); err == nil {
+		return true, nil
+	}
+
+	if requestMetadata != nil && utils.StringInSlice(
+		requestMetadata.Alias+"@"+requestMetadata.Domain, p.client.GetPaymailConfig().RejectUnverifiedSenders,
+	) {
+		return false, err
+	}
+	p.client.Logger().Warn(ctx, fmt.Sprintf("p2p transaction %s from an unverified sender: %s", tx.TxID(), err.Error()))
+	return false, nil
+}
+
+// getOutputSplit will get the output split of the paymail server config (nil if not set)
+func (p *PaymailDefaultServiceProvider) getOutputSplit() *P2POutputSplit {
+	if config := p.client.GetPaymailConfig(); config != nil {
+		return config.OutputSplit
+	}
+	return nil
 }
 
 // RecordTransaction will record the transaction
@@ -155,6 +298,42 @@ func (p *PaymailDefaultServiceProvider) RecordTransaction(ctx context.Context,
 	metadata[p2pMetadataField] = p2pTx.MetaData
 	metadata[ReferenceIDField] = p2pTx.Reference
 
+	// Verify the transaction against the payment reference (lock the reference until it is marked)
+	var reference *PaymentReference
+	var flag error
+	if isPaymentReferenceLoaded(p.client) {
+		unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyPaymentReference, p2pTx.Reference), p.client.Cachestore())
+		defer unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		if reference, flag, err = p.verifyPaymentReference(ctx, p2pTx); err != nil {
+			return nil, err
+		} else if flag != nil {
+			metadata[p2pReferenceFlagField] = flag.Error()
+		}
+	}
+
+	// Verify the sender (signature of the txid with the key of the sender paymail)
+	if p.client.GetPaymailConfig().VerifySenders {
+		verified, err := p.verifySender(ctx, p2pTx, requestMetadata)
+		if err != nil {
+			return nil, err
+		}
+		metadata[senderVerifiedField] = verified
+	}
+
+	// Check the sender against the receiving policy of the paymail (unknown when the destination was issued)
+	if requestMetadata != nil {
+		policy, err := p.getReceivingPolicy(ctx, requestMetadata.Alias, requestMetadata.Domain)
+		if err != nil {
+			return nil, err
+		} else if err = policy.checkSender(p2pTx.MetaData.Sender); err != nil {
+			return nil, err
+		}
+	}
+
 	// Record the transaction
 	rts, err := getIncomingTxRecordStrategy(ctx, p.client, p2pTx.Hex)
 	if err != nil {
@@ -172,6 +351,14 @@ func (p *PaymailDefaultServiceProvider) RecordTransaction(ctx context.Context,
 		return nil, err
 	}
 
+	// Mark the reference as used by the transaction
+	if reference != nil && reference.Status == PaymentReferenceStatusIssued {
+		reference.setTransaction(transaction.ID, flag)
+		if err = reference.Save(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	// Return the response from the p2p request
 	return &paymail.P2PTransactionPayload{
 		Note: p2pTx.MetaData.Note,
@@ -245,7 +432,7 @@ func createDestination(ctx context.Context, paymailAddress *PaymailAddress, pubK
 	// create a new destination, based on the External xPub child
 	// this is not yet possible using the xpub struct. That needs the full xPub, which we don't have.
 	destination = newDestination(paymailAddress.XpubID, lockingScript, append(opts, New())...)
-	destination.Chain = utils.ChainExternal
+	destination.Chain = pubKey.chain
 	destination.Num = pubKey.chainNum
 
 	// Only on for basic address resolution, not enabled for p2p
@@ -280,12 +467,13 @@ func createLockingScript(ecPubKey *bec.PublicKey) (lockingScript string, err err
 
 type derivedPubKey struct {
 	ecPubKey *bec.PublicKey
+	chain    uint32
 	chainNum uint32
 	pubKey   string
 }
 
 func deriveKey(rawXPubKey string, num uint32) (k *derivedPubKey, err error) {
-	k = &derivedPubKey{chainNum: num}
+	k = &derivedPubKey{chain: utils.ChainExternal, chainNum: num}
 
 	hdKey, err := utils.ValidateXPub(rawXPubKey)
 	if err != nil {
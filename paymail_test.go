@@ -11,6 +11,7 @@ import (
 	xtester "github.com/BuxOrg/bux/tester"
 	"github.com/bitcoin-sv/go-paymail"
 	"github.com/bitcoin-sv/go-paymail/server"
+	"github.com/bitcoinschema/go-bitcoin/v2"
 	"github.com/jarcoal/httpmock"
 	"github.com/mrz1836/go-cache"
 	"github.com/mrz1836/go-datastore"
@@ -490,3 +491,90 @@ func Test_resolvePaymailAddress(t *testing.T) {
 		assert.Equal(t, "1Cat862cjhp8SgLLMvin5gyk5UScasg1P9", resolvePayload.Address)
 	})
 }
+
+// mockSenderPKI will mock the capabilities and the PKI of the sender paymail (tester@test.com)
+func mockSenderPKI(t *testing.T) (privateKey, pubKey string) {
+	var err error
+	privateKey, err = bitcoin.CreatePrivateKeyString()
+	require.NoError(t, err)
+	pubKey, err = bitcoin.PubKeyFromPrivateKeyString(privateKey, true)
+	require.NoError(t, err)
+
+	mockValidResponse(http.StatusOK, false, testDomain)
+	httpmock.RegisterResponder(http.MethodGet,
+		"https://"+testDomain+"/api/v1/"+paymail.DefaultServiceName+"/id/"+testAlias+"@"+testDomain,
+		httpmock.NewStringResponder(
+			http.StatusOK,
+			`{"`+paymail.DefaultServiceName+`": "`+paymail.DefaultBsvAliasVersion+`","handle": "`+testAlias+"@"+testDomain+`","pubkey": "`+pubKey+`"}`,
+		),
+	)
+	return
+}
+
+// Test_verifySenderSignature will test the method verifySenderSignature()
+func Test_verifySenderSignature(t *testing.T) {
+	privateKey, pubKey := mockSenderPKI(t)
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}),
+		WithPaymailClient(newTestPaymailClient(t, []string{testDomain})),
+	)
+	defer deferMe()
+
+	signature, err := bitcoin.SignMessage(privateKey, testTxID, true)
+	require.NoError(t, err)
+
+	t.Run("valid signature", func(t *testing.T) {
+		assert.NoError(t, verifySenderSignature(ctx, client, &paymail.P2PMetaData{
+			PubKey: pubKey, Sender: testAlias + "@" + testDomain, Signature: signature,
+		}, testTxID))
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		err = verifySenderSignature(ctx, client, &paymail.P2PMetaData{Sender: testAlias + "@" + testDomain}, testTxID)
+		assert.ErrorIs(t, err, ErrMissingSenderSignature)
+		assert.ErrorIs(t, verifySenderSignature(ctx, client, nil, testTxID), ErrMissingSenderSignature)
+	})
+
+	t.Run("pubkey of another key", func(t *testing.T) {
+		err = verifySenderSignature(ctx, client, &paymail.P2PMetaData{
+			PubKey:    "02ead23149a1e33df17325ec7a7ba9e0b20c674c57c630f527d69b866aa9b65b10",
+			Sender:    testAlias + "@" + testDomain,
+			Signature: signature,
+		}, testTxID)
+		assert.ErrorIs(t, err, ErrSenderPubKeyMismatch)
+	})
+
+	t.Run("signature of another txid", func(t *testing.T) {
+		err = verifySenderSignature(ctx, client, &paymail.P2PMetaData{
+			Sender: testAlias + "@" + testDomain, Signature: signature,
+		}, testTxID2)
+		assert.ErrorIs(t, err, ErrInvalidSenderSignature)
+	})
+}
+
+// TestPaymailDefaultServiceProvider_verifySender will test the method verifySender()
+func TestPaymailDefaultServiceProvider_verifySender(t *testing.T) {
+	mockSenderPKI(t)
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}),
+		WithPaymailClient(newTestPaymailClient(t, []string{testDomain})),
+		WithPaymailSenderVerification(testPaymail),
+	)
+	defer deferMe()
+
+	provider := &PaymailDefaultServiceProvider{client: client}
+	p2pTx := &paymail.P2PTransaction{
+		Hex:      testTxHex,
+		MetaData: &paymail.P2PMetaData{Sender: testAlias + "@" + testDomain},
+	}
+
+	// rejected by the paymail address
+	_, err := provider.verifySender(ctx, p2pTx, &server.RequestMetadata{Alias: "paymail", Domain: "tester.com"})
+	assert.ErrorIs(t, err, ErrMissingSenderSignature)
+
+	// recorded as unverified by another paymail address
+	var verified bool
+	verified, err = provider.verifySender(ctx, p2pTx, &server.RequestMetadata{Alias: "other", Domain: "tester.com"})
+	require.NoError(t, err)
+	assert.False(t, verified)
+}
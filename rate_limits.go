@@ -0,0 +1,138 @@
+package bux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mrz1836/go-cachestore"
+)
+
+// RateLimitAction is an engine action that can be rate limited
+type RateLimitAction string
+
+const (
+	// RateLimitNewDestination is for NewDestination and NewDestinationForLockingScript
+	RateLimitNewDestination RateLimitAction = "new_destination"
+
+	// RateLimitNewTransaction is for NewTransaction
+	RateLimitNewTransaction RateLimitAction = "new_transaction"
+)
+
+// RateLimit is the token bucket of an action, every xPub (or access key) has its own bucket
+//
+// The bucket holds up to Burst requests and gets one request back every Every
+type RateLimit struct {
+	Burst uint32        `json:"burst"`
+	Every time.Duration `json:"every"`
+}
+
+// RateLimitError is returned when the bucket of the xPub or of the access key is empty
+//
+// errors.Is(err, ErrRateLimited) is true for this error
+type RateLimitError struct {
+	Action     RateLimitAction `json:"action"`
+	RetryAfter time.Duration   `json:"retry_after"`
+}
+
+// Error will return the error message
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s for %s, retry after %s", ErrRateLimited.Error(), e.Action, e.RetryAfter)
+}
+
+// Is will match ErrRateLimited
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// tokenBucket is the state of a bucket, stored in the cachestore (shared by all nodes of the cluster)
+type tokenBucket struct {
+	Tokens    float64 `json:"tokens"`
+	UpdatedAt int64   `json:"updated_at"` // Unix nano
+}
+
+// take will refill the bucket up to now and take one token, returns the wait for the next token if empty
+func (b *tokenBucket) take(limit *RateLimit, now time.Time) (retryAfter time.Duration, ok bool) {
+	elapsed := now.Sub(time.Unix(0, b.UpdatedAt))
+	if elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+float64(elapsed)/float64(limit.Every))
+		b.UpdatedAt = now.UnixNano()
+	}
+
+	if b.Tokens < 1 {
+		return time.Duration((1 - b.Tokens) * float64(limit.Every)), false
+	}
+	b.Tokens--
+	return 0, true
+}
+
+// rateLimitBucket is the bucket of an action for an xPub or an access key
+type rateLimitBucket struct {
+	cacheKey string
+	limit    *RateLimit
+	state    *tokenBucket
+}
+
+// checkRateLimit will take a token from the bucket of the access key (if the request was made with one) and of the xPub
+//
+// No token is taken if one of the buckets is empty, returns nil if no limit is set for the action
+func (c *Client) checkRateLimit(ctx context.Context, action RateLimitAction, xPubID string) error {
+	buckets := make([]*rateLimitBucket, 0, 2)
+	if limit := c.options.accessKeyRateLimits[action]; limit != nil {
+		if key := accessKeyFromContext(ctx); key != nil {
+			buckets = append(buckets, &rateLimitBucket{
+				cacheKey: fmt.Sprintf(cacheKeyRateLimitAccessKey, action, key.ID),
+				limit:    limit,
+			})
+		}
+	}
+	if limit := c.options.rateLimits[action]; limit != nil {
+		buckets = append(buckets, &rateLimitBucket{
+			cacheKey: fmt.Sprintf(cacheKeyRateLimitXpub, action, xPubID),
+			limit:    limit,
+		})
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+	return c.takeRateLimitTokens(ctx, action, buckets)
+}
+
+// takeRateLimitTokens will take a token from every bucket (new buckets are full), the buckets are only saved
+// if all of them have a token
+func (c *Client) takeRateLimitTokens(ctx context.Context, action RateLimitAction, buckets []*rateLimitBucket) error {
+
+	// Lock the buckets (always in the same order), the nodes of the cluster share them
+	now := time.Now()
+	for _, bucket := range buckets {
+		unlock, err := newWaitWriteLock(ctx, fmt.Sprintf(lockKeyRateLimit, bucket.cacheKey), c.Cachestore())
+		defer unlock()
+		if err != nil {
+			return err
+		}
+
+		bucket.state = &tokenBucket{Tokens: float64(bucket.limit.Burst), UpdatedAt: now.UnixNano()}
+		if err = c.Cachestore().GetModel(
+			ctx, bucket.cacheKey, bucket.state,
+		); err != nil && !errors.Is(err, cachestore.ErrKeyNotFound) {
+			return err
+		}
+
+		if retryAfter, ok := bucket.state.take(bucket.limit, now); !ok {
+			return &RateLimitError{Action: action, RetryAfter: retryAfter}
+		}
+	}
+
+	// A bucket that is not saved is full, so it expires once it would be refilled
+	for _, bucket := range buckets {
+		ttl := time.Duration((float64(bucket.limit.Burst) - bucket.state.Tokens) * float64(bucket.limit.Every))
+		if err := c.Cachestore().SetModel(
+			ctx, bucket.cacheKey, bucket.state, ttl.Round(time.Second)+time.Second,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
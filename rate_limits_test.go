@@ -0,0 +1,136 @@
+package bux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initRateLimitTestCase will create the xPub for deriving destinations
+func initRateLimitTestCase(t *testing.T, clientOpts ...ClientOps) (context.Context, ClientInterface, func()) {
+	ctx, client, deferMe := CreateTestSQLiteClient(t, false, true,
+		append(clientOpts, WithCustomTaskManager(&taskManagerMockBase{}))...,
+	)
+	require.NoError(t, newXpub(testXPub, append(client.DefaultModelOptions(), New())...).Save(ctx))
+	return ctx, client, deferMe
+}
+
+func TestTokenBucket_take(t *testing.T) {
+	limit := &RateLimit{Burst: 2, Every: time.Second}
+	now := time.Now()
+	bucket := &tokenBucket{Tokens: 2, UpdatedAt: now.UnixNano()}
+
+	_, ok := bucket.take(limit, now)
+	assert.True(t, ok)
+	_, ok = bucket.take(limit, now)
+	assert.True(t, ok)
+
+	var retryAfter time.Duration
+	retryAfter, ok = bucket.take(limit, now.Add(250*time.Millisecond))
+	assert.False(t, ok)
+	assert.Equal(t, 750*time.Millisecond, retryAfter)
+
+	_, ok = bucket.take(limit, now.Add(time.Second))
+	assert.True(t, ok)
+
+	// never more than the burst
+	_, ok = bucket.take(limit, now.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, float64(1), bucket.Tokens)
+}
+
+func TestClient_checkRateLimit(t *testing.T) {
+	t.Run("no limit", func(t *testing.T) {
+		ctx, client, deferMe := initRateLimitTestCase(t)
+		defer deferMe()
+
+		for i := 0; i < 5; i++ {
+			_, err := client.NewDestination(ctx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("per xPub", func(t *testing.T) {
+		ctx, client, deferMe := initRateLimitTestCase(t,
+			WithRateLimit(RateLimitNewDestination, 2, time.Hour),
+		)
+		defer deferMe()
+
+		for i := 0; i < 2; i++ {
+			_, err := client.NewDestination(ctx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+			require.NoError(t, err)
+		}
+
+		_, err := client.NewDestination(ctx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+		require.ErrorIs(t, err, ErrRateLimited)
+
+		var rateLimitErr *RateLimitError
+		require.True(t, errors.As(err, &rateLimitErr))
+		assert.Equal(t, RateLimitNewDestination, rateLimitErr.Action)
+		assert.Greater(t, rateLimitErr.RetryAfter, 59*time.Minute)
+
+		// the other actions have their own buckets
+		_, err = client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			SendAllTo: &TransactionOutput{To: testExternalAddress},
+		})
+		assert.NotErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("per access key", func(t *testing.T) {
+		ctx, client, deferMe := initRateLimitTestCase(t,
+			WithRateLimit(RateLimitNewDestination, 2, time.Hour),
+			WithAccessKeyRateLimit(RateLimitNewDestination, 1, time.Hour),
+		)
+		defer deferMe()
+
+		key, err := client.NewAccessKey(ctx, testXPub)
+		require.NoError(t, err)
+
+		// the context of an authenticated request (see AuthenticateRequest)
+		keyCtx := context.WithValue(ctx, ParamAccessKey, key)
+
+		_, err = client.NewDestination(keyCtx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+		require.NoError(t, err)
+
+		// the bucket of the key is empty, the xPub is not limited yet
+		_, err = client.NewDestination(keyCtx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		_, err = client.NewDestination(ctx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+		require.NoError(t, err)
+
+		// the requests of the key counted for the xPub
+		_, err = client.NewDestinationForLockingScript(ctx, testXPubID, testLockingScript, false)
+		assert.ErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("no token is taken if one bucket is empty", func(t *testing.T) {
+		ctx, client, deferMe := initRateLimitTestCase(t,
+			WithRateLimit(RateLimitNewDestination, 1, time.Hour),
+			WithAccessKeyRateLimit(RateLimitNewDestination, 2, time.Hour),
+		)
+		defer deferMe()
+
+		key, err := client.NewAccessKey(ctx, testXPub)
+		require.NoError(t, err)
+		keyCtx := context.WithValue(ctx, ParamAccessKey, key)
+
+		_, err = client.NewDestination(keyCtx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+		require.NoError(t, err)
+
+		// the bucket of the xPub is empty, the bucket of the key keeps its token
+		_, err = client.NewDestination(keyCtx, testXPub, utils.ChainExternal, utils.ScriptTypePubKeyHash, false)
+		assert.ErrorIs(t, err, ErrRateLimited)
+
+		bucket := &tokenBucket{}
+		require.NoError(t, client.Cachestore().GetModel(
+			ctx, fmt.Sprintf(cacheKeyRateLimitAccessKey, RateLimitNewDestination, key.ID), bucket,
+		))
+		assert.InDelta(t, 1, bucket.Tokens, 0.01)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/libsv/go-bt/v2"
 	zLogger "github.com/mrz1836/go-logger"
@@ -25,10 +26,21 @@ func (strategy *outgoingTx) Execute(ctx context.Context, c ClientInterface, opts
 		return nil, fmt.Errorf("OutgoingTx.Execute(): creation of outgoing tx failed. Reason: %w", err)
 	}
 
+	// check and count the spend for the caps of the spending policy, the policy could have changed
+	// (or the caps been reached) since the draft was created
+	if err = transaction.draftTransaction.addSpendingPolicySpend(ctx); err != nil {
+		return nil, fmt.Errorf("OutgoingTx.Execute(): spending policy check failed. Reason: %w", err)
+	}
+
 	if err = transaction.Save(ctx); err != nil {
 		return nil, fmt.Errorf("OutgoingTx.Execute(): saving of Transaction failed. Reason: %w", err)
 	}
 
+	// link the stuck parent to the child (CPFP)
+	if len(transaction.syncTransaction.ParentTxID) > 0 {
+		_linkParentSyncTransaction(ctx, logger, transaction) // ignore error
+	}
+
 	// process
 	if transaction.syncTransaction.P2PStatus == SyncStatusReady {
 		if err = _outgoingNotifyP2p(ctx, logger, transaction); err != nil {
@@ -94,8 +106,28 @@ func _createOutgoingTxToRecord(ctx context.Context, oTx *outgoingTx, c ClientInt
 		return nil, err
 	}
 
+	// a draft that needs approvals can only be recorded once approved
+	if err := tx.draftTransaction.checkApproval(); err != nil {
+		return nil, err
+	}
+
+	// the recorded hex must be the (approved) draft: same inputs, sequences, outputs and lock time
+	if tx.TransactionBase.parsedTx == nil {
+		return nil, ErrTransactionNotParsed
+	}
+	if err := tx.draftTransaction.validateDraftTx(tx.TransactionBase.parsedTx); err != nil {
+		return nil, err
+	}
+
 	_hydrateOutgoingWithSync(tx)
 
+	// a paymail provider could broadcast the transaction before its scheduled time, or to any miner
+	if tx.syncTransaction.P2PStatus == SyncStatusReady {
+		if err := tx.draftTransaction.Configuration.Sync.checkP2P(time.Now().UTC()); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := tx.processUtxos(ctx); err != nil {
 		return nil, err
 	}
@@ -138,11 +170,17 @@ func _hydrateOutgoingWithSync(tx *Transaction) {
 
 	// setup synchronization
 	sync.BroadcastStatus = _getBroadcastSyncStatus(tx)
+	_scheduleBroadcast(sync)
 	sync.P2PStatus = _getP2pSyncStatus(tx)
 	sync.SyncStatus = SyncStatusPending // wait until transaction is broadcasted or P2P provider is notified
 
 	sync.Metadata = tx.Metadata
 
+	// link the child to the stuck parent (CPFP)
+	if cpfp := tx.draftTransaction.Configuration.ChildPaysForParent; cpfp != nil {
+		sync.ParentTxID = cpfp.ParentTxID
+	}
+
 	sync.transaction = tx
 	tx.syncTransaction = sync
 }
@@ -166,12 +204,22 @@ func _getBroadcastSyncStatus(tx *Transaction) SyncStatus {
 	return broadcast
 }
 
+// _scheduleBroadcast will hold the broadcast until the scheduled time of the sync config (BroadcastAt, DelayToBroadcast)
+func _scheduleBroadcast(sync *SyncTransaction) {
+	now := time.Now().UTC()
+	if at := sync.Configuration.broadcastTime(now); at.After(now) && sync.BroadcastStatus == SyncStatusReady {
+		sync.BroadcastAt.Valid = true
+		sync.BroadcastAt.Time = at
+		sync.BroadcastStatus = SyncStatusScheduled
+	}
+}
+
 func _getP2pSyncStatus(tx *Transaction) SyncStatus {
 	p2pStatus := SyncStatusSkipped
 
 	outputs := tx.draftTransaction.Configuration.Outputs
 	for _, o := range outputs {
-		if o.PaymailP4 != nil && o.PaymailP4.ResolutionType == ResolutionTypeP2P {
+		if o.PaymailP4.notifiesP2P() {
 			p2pStatus = SyncStatusReady // notify p2p immediately
 
 			break
@@ -181,6 +229,19 @@ func _getP2pSyncStatus(tx *Transaction) SyncStatus {
 	return p2pStatus
 }
 
+func _linkParentSyncTransaction(ctx context.Context, logger zLogger.GormLoggerInterface, tx *Transaction) {
+	parentTxID := tx.syncTransaction.ParentTxID
+
+	parent, err := GetSyncTransactionByID(ctx, parentTxID, tx.GetOptions(false)...)
+	if err == nil && parent != nil {
+		parent.ChildTxID = tx.ID
+		err = parent.Save(ctx)
+	}
+	if err != nil {
+		logger.Error(ctx, fmt.Sprintf("OutgoingTx.Execute(): linking parent sync transaction failed. Reason: %s, TxID: %s, ParentTxID: %s", err, tx.ID, parentTxID))
+	}
+}
+
 func _outgoingNotifyP2p(ctx context.Context, logger zLogger.GormLoggerInterface, tx *Transaction) error {
 	logger.Info(ctx, fmt.Sprintf("OutgoingTx.Execute(): start p2p, TxID: %s", tx.ID))
 
@@ -0,0 +1,288 @@
+package bux
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoinschema/go-bitcoin/v2"
+	"github.com/libsv/go-bk/bec"
+	"github.com/libsv/go-bk/bip32"
+	"github.com/libsv/go-bt/v2"
+	"github.com/libsv/go-bt/v2/bscript"
+	"github.com/libsv/go-bt/v2/sighash"
+)
+
+// Signer is the interface for signing the sighash of an input with the key of a derivation path of an xPub
+//
+// The keys can be held in-process (see NewXPrivSigner) or by a separate process like an HSM, a KMS or
+// a remote signing service (see NewHTTPSigner, or wrap a gRPC client with SignerFunc).
+// Drafts are signed with DraftTransaction.SignInputsWithSigner.
+type Signer interface {
+	// Sign will sign the sighash with the key of the derivation path (xPub ID / chain / num)
+	Sign(ctx context.Context, request *SignRequest) (*SignResponse, error)
+}
+
+// SignRequest is the sighash of an input and the derivation path of its key
+//
+// The draft and the transaction (with the previous output of the input) are included, so the signer can review
+// the outputs and recompute the sighash before signing
+type SignRequest struct {
+	Chain         uint32 `json:"chain"`          // Chain of the derivation path (internal/external)
+	DraftID       string `json:"draft_id"`       // ID of the draft being signed
+	Hex           string `json:"hex"`            // Unsigned transaction (hex)
+	InputIndex    uint32 `json:"input_index"`    // Index of the input in the transaction
+	LockingScript string `json:"locking_script"` // Locking script of the previous output (hex)
+	Num           uint32 `json:"num"`            // Num of the derivation path
+	Satoshis      uint64 `json:"satoshis"`       // Satoshis of the previous output
+	SigHash       string `json:"sig_hash"`       // Sighash of the input (hex)
+	XpubID        string `json:"xpub_id"`        // ID of the xPub
+}
+
+// SignResponse is the signature of the sighash and the public key of the derived key
+type SignResponse struct {
+	PubKey    string `json:"pub_key"`   // Compressed public key of the derived key (hex)
+	Signature string `json:"signature"` // DER signature of the sighash (hex)
+}
+
+// SignerFunc is a function that implements the Signer interface
+type SignerFunc func(ctx context.Context, request *SignRequest) (*SignResponse, error)
+
+// Sign will call the function
+func (f SignerFunc) Sign(ctx context.Context, request *SignRequest) (*SignResponse, error) {
+	return f(ctx, request)
+}
+
+// xPrivSigner signs with xPrivs held in-process
+type xPrivSigner struct {
+	xPrivs map[string]*bip32.ExtendedKey // xPriv by xPub ID
+}
+
+// NewXPrivSigner will return a signer for the given (in-process) xPrivs
+func NewXPrivSigner(rawXPrivs ...string) (Signer, error) {
+	signer := &xPrivSigner{
+		xPrivs: make(map[string]*bip32.ExtendedKey, len(rawXPrivs)),
+	}
+	for _, rawXPriv := range rawXPrivs {
+		xPriv, err := bip32.NewKeyFromString(rawXPriv)
+		if err != nil {
+			return nil, err
+		}
+
+		var rawXPub string
+		if rawXPub, err = bitcoin.GetExtendedPublicKey(xPriv); err != nil {
+			return nil, err
+		}
+		signer.xPrivs[utils.Hash(rawXPub)] = xPriv
+	}
+	return signer, nil
+}
+
+// Sign will derive the key of the derivation path and sign the sighash
+func (s *xPrivSigner) Sign(_ context.Context, request *SignRequest) (*SignResponse, error) {
+	xPriv, ok := s.xPrivs[request.XpubID]
+	if !ok {
+		return nil, ErrMissingSigningKey
+	}
+
+	sigHash, err := hex.DecodeString(request.SigHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Derive the child key (chain / num)
+	var chainKey, numKey *bip32.ExtendedKey
+	if chainKey, err = xPriv.Child(request.Chain); err != nil {
+		return nil, err
+	} else if numKey, err = chainKey.Child(request.Num); err != nil {
+		return nil, err
+	}
+
+	var privateKey *bec.PrivateKey
+	if privateKey, err = bitcoin.GetPrivateKeyFromHDKey(numKey); err != nil {
+		return nil, err
+	}
+
+	var signature *bec.Signature
+	if signature, err = privateKey.Sign(sigHash); err != nil {
+		return nil, err
+	}
+
+	return &SignResponse{
+		PubKey:    hex.EncodeToString(privateKey.PubKey().SerialiseCompressed()),
+		Signature: hex.EncodeToString(signature.Serialise()),
+	}, nil
+}
+
+// httpSigner delegates the signing to a remote signing service (see NewSignerHandler)
+type httpSigner struct {
+	endpoint   string
+	headers    map[string]string
+	httpClient HTTPInterface
+}
+
+// NewHTTPSigner will return a signer that POSTs the sign requests (JSON) to the endpoint of a signing service
+//
+// headers are set on every request (authentication of the service), httpClient defaults to http.DefaultClient
+func NewHTTPSigner(endpoint string, headers map[string]string, httpClient HTTPInterface) Signer {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &httpSigner{
+		endpoint:   endpoint,
+		headers:    headers,
+		httpClient: httpClient,
+	}
+}
+
+// Sign will POST the request to the signing service, any non-2xx response is returned as an error
+func (s *httpSigner) Sign(ctx context.Context, request *SignRequest) (*SignResponse, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(
+		ctx, http.MethodPost, s.endpoint, bytes.NewBuffer(payload),
+	); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+
+	var response *http.Response
+	if response, err = s.httpClient.Do(req); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("%w: %d", ErrSignerRequestFailed, response.StatusCode)
+	}
+
+	signResponse := new(SignResponse)
+	if err = json.NewDecoder(response.Body).Decode(signResponse); err != nil {
+		return nil, err
+	}
+	return signResponse, nil
+}
+
+// NewSignerHandler will return the HTTP handler of a signing service for the signer (see NewHTTPSigner)
+//
+// The handler does not authenticate the requests, wrap it with the authentication of the service
+func NewSignerHandler(signer Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		request := new(SignRequest)
+		if err := json.NewDecoder(req.Body).Decode(request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := signer.Sign(req.Context(), request)
+		if errors.Is(err, ErrMissingSigningKey) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
+
+// verifySignResponse will check the signature of the sighash and return the public key and the signature (DER)
+func verifySignResponse(response *SignResponse, sigHash []byte) (pubKey, signature []byte, err error) {
+	if response == nil {
+		return nil, nil, ErrInvalidSignResponse
+	}
+	if pubKey, err = hex.DecodeString(response.PubKey); err != nil {
+		return nil, nil, ErrInvalidSignResponse
+	} else if signature, err = hex.DecodeString(response.Signature); err != nil {
+		return nil, nil, ErrInvalidSignResponse
+	}
+
+	var key *bec.PublicKey
+	if key, err = bec.ParsePubKey(pubKey, bec.S256()); err != nil {
+		return nil, nil, ErrInvalidSignResponse
+	}
+	var sig *bec.Signature
+	if sig, err = bec.ParseDERSignature(signature, bec.S256()); err != nil || !sig.Verify(sigHash, key) {
+		return nil, nil, ErrInvalidSignResponse
+	}
+	return key.SerialiseCompressed(), signature, nil
+}
+
+// signTxInput will sign the input of the (unsigned) draft transaction with the signer (P2PKH)
+//
+// The signature and the key are verified against the sighash and the locking script of the previous output
+func signTxInput(ctx context.Context, signer Signer, tx *bt.Tx, index int, draftID, xPubID, unsignedHex string,
+	input *SigningPackageInput) error {
+
+	// Get the locking script
+	ls, err := bscript.NewFromHexString(input.LockingScript)
+	if err != nil {
+		return err
+	}
+	tx.Inputs[index].PreviousTxScript = ls
+	tx.Inputs[index].PreviousTxSatoshis = input.Satoshis
+
+	// Get the sighash of the input
+	var sigHash []byte
+	if sigHash, err = tx.CalcInputSignatureHash(uint32(index), sighash.AllForkID); err != nil {
+		return err
+	}
+
+	// Sign the sighash with the key of the destination
+	var response *SignResponse
+	if response, err = signer.Sign(ctx, &SignRequest{
+		Chain:         input.Chain,
+		DraftID:       draftID,
+		Hex:           unsignedHex,
+		InputIndex:    uint32(index),
+		LockingScript: input.LockingScript,
+		Num:           input.Num,
+		Satoshis:      input.Satoshis,
+		SigHash:       hex.EncodeToString(sigHash),
+		XpubID:        xPubID,
+	}); err != nil {
+		return err
+	}
+
+	// Make sure the signature is valid, and the key is the key of the locking script
+	var pubKey, signature []byte
+	if pubKey, signature, err = verifySignResponse(response, sigHash); err != nil {
+		return err
+	}
+	var keyScript *bscript.Script
+	if keyScript, err = bscript.NewP2PKHFromPubKeyBytes(pubKey); err != nil {
+		return err
+	} else if !keyScript.Equals(ls) {
+		return ErrInvalidSignResponse
+	}
+
+	// Insert the unlocking script
+	var unlockingScript *bscript.Script
+	if unlockingScript, err = bscript.NewP2PKHUnlockingScript(
+		pubKey, signature, sighash.AllForkID,
+	); err != nil {
+		return err
+	}
+	return tx.InsertInputUnlockingScript(uint32(index), unlockingScript)
+}
@@ -0,0 +1,157 @@
+package bux
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/bitcoinschema/go-bitcoin/v2"
+	"github.com/libsv/go-bk/bip32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initSignerTestCase will create a draft of the test xPub to sign
+func initSignerTestCase(t *testing.T) (context.Context, *DraftTransaction, func()) {
+	ctx, client, deferMe := initConsolidationTestCase(t, []uint64{100000})
+
+	draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+		Outputs: []*TransactionOutput{{To: testExternalAddress, Satoshis: 1000}},
+	})
+	require.NoError(t, err)
+
+	return ctx, draft, deferMe
+}
+
+func TestXPrivSigner_Sign(t *testing.T) {
+	signer, err := NewXPrivSigner(testXPriv)
+	require.NoError(t, err)
+
+	sigHash := utils.Hash("sighash")
+	var response *SignResponse
+	response, err = signer.Sign(context.Background(), &SignRequest{
+		Chain: utils.ChainExternal, Num: 1, SigHash: sigHash, XpubID: testXPubID,
+	})
+	require.NoError(t, err)
+
+	var sigHashBytes []byte
+	sigHashBytes, err = hex.DecodeString(sigHash)
+	require.NoError(t, err)
+	_, _, err = verifySignResponse(response, sigHashBytes)
+	require.NoError(t, err)
+
+	_, err = signer.Sign(context.Background(), &SignRequest{SigHash: sigHash, XpubID: utils.Hash("unknown")})
+	assert.ErrorIs(t, err, ErrMissingSigningKey)
+}
+
+func TestDraftTransaction_SignInputsWithSigner(t *testing.T) {
+	t.Run("local signer", func(t *testing.T) {
+		ctx, draft, deferMe := initSignerTestCase(t)
+		defer deferMe()
+
+		xPriv, err := bip32.NewKeyFromString(testXPriv)
+		require.NoError(t, err)
+
+		var expectedHex string
+		expectedHex, err = draft.SignInputs(xPriv)
+		require.NoError(t, err)
+
+		var signer Signer
+		signer, err = NewXPrivSigner(testXPriv)
+		require.NoError(t, err)
+
+		var signedHex string
+		signedHex, err = draft.SignInputsWithSigner(ctx, signer)
+		require.NoError(t, err)
+		assert.Equal(t, expectedHex, signedHex)
+	})
+
+	t.Run("remote signer", func(t *testing.T) {
+		ctx, draft, deferMe := initSignerTestCase(t)
+		defer deferMe()
+
+		localSigner, err := NewXPrivSigner(testXPriv)
+		require.NoError(t, err)
+
+		// the signing service, the xPriv is not known to the signer of the draft
+		var authorization string
+		requests := make([]*SignRequest, 0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			authorization = req.Header.Get("Authorization")
+			NewSignerHandler(SignerFunc(func(ctx context.Context, request *SignRequest) (*SignResponse, error) {
+				requests = append(requests, request)
+				return localSigner.Sign(ctx, request)
+			})).ServeHTTP(w, req)
+		}))
+		defer server.Close()
+
+		var expectedHex string
+		expectedHex, err = draft.SignInputsWithSigner(ctx, localSigner)
+		require.NoError(t, err)
+
+		var signedHex string
+		signedHex, err = draft.SignInputsWithSigner(ctx, NewHTTPSigner(
+			server.URL, map[string]string{"Authorization": "Bearer token"}, nil,
+		))
+		require.NoError(t, err)
+		assert.Equal(t, expectedHex, signedHex)
+		assert.Equal(t, "Bearer token", authorization)
+
+		// the signing service gets the draft and the transaction it signs
+		require.Len(t, requests, len(draft.Configuration.Inputs))
+		assert.Equal(t, draft.ID, requests[0].DraftID)
+		assert.Equal(t, draft.Hex, requests[0].Hex)
+		assert.Equal(t, uint32(0), requests[0].InputIndex)
+		assert.Equal(t, draft.Configuration.Inputs[0].Satoshis, requests[0].Satoshis)
+		assert.Equal(t, draft.Configuration.Inputs[0].Destination.LockingScript, requests[0].LockingScript)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		ctx, draft, deferMe := initSignerTestCase(t)
+		defer deferMe()
+
+		server := httptest.NewServer(NewSignerHandler(&xPrivSigner{}))
+		defer server.Close()
+
+		_, err := draft.SignInputsWithSigner(ctx, NewHTTPSigner(server.URL, nil, nil))
+		assert.ErrorIs(t, err, ErrSignerRequestFailed)
+	})
+
+	t.Run("key of another xPub", func(t *testing.T) {
+		ctx, draft, deferMe := initSignerTestCase(t)
+		defer deferMe()
+
+		otherXPriv, err := bitcoin.GenerateHDKey(bitcoin.RecommendedSeedLength)
+		require.NoError(t, err)
+
+		// a signer that signs everything with the wrong key
+		signer := SignerFunc(func(ctx context.Context, request *SignRequest) (*SignResponse, error) {
+			return (&xPrivSigner{
+				xPrivs: map[string]*bip32.ExtendedKey{request.XpubID: otherXPriv},
+			}).Sign(ctx, request)
+		})
+
+		_, err = draft.SignInputsWithSigner(ctx, signer)
+		assert.ErrorIs(t, err, ErrInvalidSignResponse)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		ctx, draft, deferMe := initSignerTestCase(t)
+		defer deferMe()
+
+		localSigner, err := NewXPrivSigner(testXPriv)
+		require.NoError(t, err)
+
+		// a signer that signs another sighash
+		signer := SignerFunc(func(ctx context.Context, request *SignRequest) (*SignResponse, error) {
+			request.SigHash = utils.Hash("other")
+			return localSigner.Sign(ctx, request)
+		})
+
+		_, err = draft.SignInputsWithSigner(ctx, signer)
+		assert.ErrorIs(t, err, ErrInvalidSignResponse)
+	})
+}
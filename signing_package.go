@@ -0,0 +1,258 @@
+package bux
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/libsv/go-bt/v2"
+	"github.com/libsv/go-bt/v2/bscript"
+	"github.com/libsv/go-bt/v2/bscript/interpreter"
+)
+
+// SigningPackageVersion is the version of the signing packages exported by the engine
+const SigningPackageVersion uint32 = 1
+
+// SigningPackage is a self-contained draft for signing on an air-gapped machine (see ExportSigningPackage)
+//
+// The signing machine signs the package (see SigningPackage.Sign) without any access to the engine,
+// and the signed package is imported back with ImportSigningPackage
+type SigningPackage struct {
+	Version   uint32                  `json:"version"`
+	DraftID   string                  `json:"draft_id"`
+	XpubID    string                  `json:"xpub_id"`
+	Hex       string                  `json:"hex"` // Unsigned transaction (hex)
+	Fee       uint64                  `json:"fee"`
+	ExpiresAt time.Time               `json:"expires_at"`
+	Inputs    []*SigningPackageInput  `json:"inputs"`
+	Outputs   []*SigningPackageOutput `json:"outputs"`
+	SignedHex string                  `json:"signed_hex,omitempty"` // Signed transaction (hex), set by the signing machine
+}
+
+// SigningPackageInput is an input of the package: the previous output and the derivation path of its key
+type SigningPackageInput struct {
+	Chain         uint32 `json:"chain"`
+	LockingScript string `json:"locking_script"` // Locking script of the previous output (hex)
+	Num           uint32 `json:"num"`
+	OutputIndex   uint32 `json:"output_index"`
+	Satoshis      uint64 `json:"satoshis"` // Satoshis of the previous output
+	TransactionID string `json:"transaction_id"`
+}
+
+// SigningPackageOutput is an output of the package, for reviewing the transaction before signing
+type SigningPackageOutput struct {
+	Address       string `json:"address,omitempty"` // Address of P2PKH outputs
+	Change        bool   `json:"change"`            // Output to a change destination of the xPub
+	LockingScript string `json:"locking_script"`
+	Satoshis      uint64 `json:"satoshis"`
+}
+
+// newSigningPackageInput will get the package input of a draft input
+func newSigningPackageInput(input *TransactionInput) *SigningPackageInput {
+	return &SigningPackageInput{
+		Chain:         input.Destination.Chain,
+		LockingScript: input.Destination.LockingScript,
+		Num:           input.Destination.Num,
+		OutputIndex:   input.OutputIndex,
+		Satoshis:      input.Satoshis,
+		TransactionID: input.TransactionID,
+	}
+}
+
+// newSigningPackage will export the draft as a signing package
+func newSigningPackage(draft *DraftTransaction) (*SigningPackage, error) {
+	tx, err := bt.NewTxFromString(draft.Hex)
+	if err != nil {
+		return nil, err
+	}
+
+	signingPackage := &SigningPackage{
+		DraftID:   draft.ID,
+		ExpiresAt: draft.ExpiresAt,
+		Fee:       draft.Configuration.Fee,
+		Hex:       draft.Hex,
+		Version:   SigningPackageVersion,
+		XpubID:    draft.XpubID,
+	}
+	for _, input := range draft.Configuration.Inputs {
+		signingPackage.Inputs = append(signingPackage.Inputs, newSigningPackageInput(input))
+	}
+
+	changeAddresses := make(map[string]bool)
+	for _, destination := range draft.Configuration.ChangeDestinations {
+		changeAddresses[destination.Address] = true
+	}
+	for _, output := range tx.Outputs {
+		packageOutput := &SigningPackageOutput{
+			LockingScript: output.LockingScript.String(),
+			Satoshis:      output.Satoshis,
+		}
+		if output.LockingScript.IsP2PKH() {
+			if addresses, _ := output.LockingScript.Addresses(); len(addresses) > 0 {
+				packageOutput.Address = addresses[0]
+				packageOutput.Change = changeAddresses[addresses[0]]
+			}
+		}
+		signingPackage.Outputs = append(signingPackage.Outputs, packageOutput)
+	}
+	return signingPackage, nil
+}
+
+// ParseSigningPackage will parse a (JSON) signing package, the version must be supported
+func ParseSigningPackage(data []byte) (*SigningPackage, error) {
+	signingPackage := new(SigningPackage)
+	if err := json.Unmarshal(data, signingPackage); err != nil {
+		return nil, err
+	} else if err = signingPackage.validate(); err != nil {
+		return nil, err
+	}
+	return signingPackage, nil
+}
+
+// validate will check the version and that the inputs are the inputs of the transaction
+func (p *SigningPackage) validate() error {
+	if p.Version != SigningPackageVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSigningPackageVersion, p.Version)
+	}
+
+	tx, err := bt.NewTxFromString(p.Hex)
+	if err != nil {
+		return err
+	} else if len(tx.Inputs) != len(p.Inputs) {
+		return ErrSigningPackageMismatch
+	}
+	for index, input := range p.Inputs {
+		if tx.Inputs[index].PreviousTxIDStr() != input.TransactionID ||
+			tx.Inputs[index].PreviousTxOutIndex != input.OutputIndex {
+			return ErrSigningPackageMismatch
+		}
+	}
+	return nil
+}
+
+// Sign will sign the inputs of the package with the signer and set the signed hex
+//
+// This is run on the signing machine, the engine is not needed (see NewXPrivSigner)
+func (p *SigningPackage) Sign(ctx context.Context, signer Signer) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	tx, err := bt.NewTxFromString(p.Hex)
+	if err != nil {
+		return err
+	}
+	for index, input := range p.Inputs {
+		if err = signTxInput(ctx, signer, tx, index, p.DraftID, p.XpubID, p.Hex, input); err != nil {
+			return err
+		}
+	}
+
+	p.SignedHex = tx.String()
+	return nil
+}
+
+// validateSignedHex will check that the signed transaction is the draft transaction (see validateDraftTx),
+// and that the unlocking scripts are valid for the previous outputs
+func (m *DraftTransaction) validateSignedHex(signedHex string) error {
+	signedTx, err := bt.NewTxFromString(signedHex)
+	if err != nil {
+		return err
+	}
+
+	if err = m.validateDraftTx(signedTx); err != nil {
+		return err
+	} else if len(signedTx.Inputs) != len(m.Configuration.Inputs) {
+		return ErrDraftTransactionMismatch
+	}
+
+	for index := range signedTx.Inputs {
+
+		// Execute the unlocking script against the previous output
+		var lockingScript *bscript.Script
+		if lockingScript, err = bscript.NewFromHexString(
+			m.Configuration.Inputs[index].Destination.LockingScript,
+		); err != nil {
+			return err
+		}
+		if err = interpreter.NewEngine().Execute(
+			interpreter.WithTx(signedTx, index, &bt.Output{
+				LockingScript: lockingScript,
+				Satoshis:      m.Configuration.Inputs[index].Satoshis,
+			}),
+			interpreter.WithForkID(),
+			interpreter.WithAfterGenesis(),
+		); err != nil {
+			return fmt.Errorf("%w: input %d: %s", ErrInvalidSignedTransaction, index, err.Error())
+		}
+	}
+	return nil
+}
+
+// ExportSigningPackage will export the draft (of the xPub) as a signing package for an air-gapped signing machine
+//
+// The draft must not be canceled, complete or expired
+func (c *Client) ExportSigningPackage(ctx context.Context, rawXpubKey, draftID string,
+	opts ...ModelOps) (*SigningPackage, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "export_signing_package")
+
+	if _, err := utils.ValidateXPub(rawXpubKey); err != nil {
+		return nil, err
+	}
+
+	// Get the draft (of the xPub)
+	draftTransaction, err := getDraftTransactionID(
+		ctx, utils.Hash(rawXpubKey), draftID, c.DefaultModelOptions(opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	} else if draftTransaction == nil {
+		return nil, ErrDraftNotFound
+	} else if draftTransaction.Status != DraftStatusDraft {
+		return nil, ErrDraftNotOpen
+	} else if time.Now().UTC().After(draftTransaction.ExpiresAt) {
+		return nil, ErrDraftExpired
+	}
+
+	return newSigningPackage(draftTransaction)
+}
+
+// ImportSigningPackage will import a signed package (see SigningPackage.Sign) and record the transaction
+//
+// The signed hex must be the transaction of the draft, with valid unlocking scripts for all the inputs
+func (c *Client) ImportSigningPackage(ctx context.Context, rawXpubKey string, signingPackage *SigningPackage,
+	opts ...ModelOps) (*Transaction, error) {
+
+	// Check for existing NewRelic transaction
+	ctx = c.GetOrStartTxn(ctx, "import_signing_package")
+
+	if _, err := utils.ValidateXPub(rawXpubKey); err != nil {
+		return nil, err
+	} else if err = signingPackage.validate(); err != nil {
+		return nil, err
+	} else if len(signingPackage.SignedHex) == 0 {
+		return nil, ErrMissingFieldHex
+	}
+
+	// Get the draft (of the xPub)
+	draftTransaction, err := getDraftTransactionID(
+		ctx, utils.Hash(rawXpubKey), signingPackage.DraftID, c.DefaultModelOptions(opts...)...,
+	)
+	if err != nil {
+		return nil, err
+	} else if draftTransaction == nil {
+		return nil, ErrDraftNotFound
+	} else if draftTransaction.Hex != signingPackage.Hex {
+		return nil, ErrSigningPackageMismatch
+	}
+
+	if err = draftTransaction.validateSignedHex(signingPackage.SignedHex); err != nil {
+		return nil, err
+	}
+
+	return c.RecordTransaction(ctx, rawXpubKey, signingPackage.SignedHex, draftTransaction.ID, opts...)
+}
@@ -0,0 +1,146 @@
+package bux
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/BuxOrg/bux/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportTestSigningPackage will export the draft and parse it like the signing machine (JSON)
+func exportTestSigningPackage(ctx context.Context, t *testing.T, client ClientInterface,
+	draftID string) *SigningPackage {
+
+	signingPackage, err := client.ExportSigningPackage(ctx, testXPub, draftID)
+	require.NoError(t, err)
+
+	var data []byte
+	data, err = json.Marshal(signingPackage)
+	require.NoError(t, err)
+
+	signingPackage, err = ParseSigningPackage(data)
+	require.NoError(t, err)
+	return signingPackage
+}
+
+func TestClient_ExportSigningPackage(t *testing.T) {
+	t.Run("export", func(t *testing.T) {
+		ctx, client, deferMe := initSpendingPolicyTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+
+		signingPackage := exportTestSigningPackage(ctx, t, client, draft.ID)
+		assert.Equal(t, SigningPackageVersion, signingPackage.Version)
+		assert.Equal(t, draft.Hex, signingPackage.Hex)
+		assert.Equal(t, draft.Configuration.Fee, signingPackage.Fee)
+
+		require.Len(t, signingPackage.Inputs, 1)
+		assert.Equal(t, testTxID, signingPackage.Inputs[0].TransactionID)
+		assert.Equal(t, testLockingScript, signingPackage.Inputs[0].LockingScript)
+		assert.Equal(t, uint64(100000), signingPackage.Inputs[0].Satoshis)
+
+		require.Len(t, signingPackage.Outputs, 2)
+		assert.Equal(t, testPolicyAddress, signingPackage.Outputs[0].Address)
+		assert.False(t, signingPackage.Outputs[0].Change)
+		assert.True(t, signingPackage.Outputs[1].Change)
+	})
+
+	t.Run("draft of another xPub", func(t *testing.T) {
+		ctx, client, deferMe := initSpendingPolicyTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+
+		_, err = client.ExportSigningPackage(ctx, "", draft.ID)
+		assert.ErrorIs(t, err, utils.ErrXpubInvalidLength)
+
+		_, err = client.ExportSigningPackage(ctx, testXpubAuth, draft.ID)
+		assert.ErrorIs(t, err, ErrDraftNotFound)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		_, err := ParseSigningPackage([]byte(`{"version":2}`))
+		assert.ErrorIs(t, err, ErrUnsupportedSigningPackageVersion)
+	})
+
+	t.Run("canceled draft", func(t *testing.T) {
+		ctx, client, deferMe := initSpendingPolicyTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+
+		draft.Status = DraftStatusCanceled
+		require.NoError(t, draft.Save(ctx))
+
+		_, err = client.ExportSigningPackage(ctx, testXPub, draft.ID)
+		assert.ErrorIs(t, err, ErrDraftNotOpen)
+	})
+}
+
+func TestClient_ImportSigningPackage(t *testing.T) {
+	t.Run("sign and import", func(t *testing.T) {
+		ctx, client, deferMe := initSpendingPolicyTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+
+		// the signing machine
+		signingPackage := exportTestSigningPackage(ctx, t, client, draft.ID)
+		var signer Signer
+		signer, err = NewXPrivSigner(testXPriv)
+		require.NoError(t, err)
+		require.NoError(t, signingPackage.Sign(context.Background(), signer))
+
+		var transaction *Transaction
+		transaction, err = client.ImportSigningPackage(ctx, testXPub, signingPackage)
+		require.NoError(t, err)
+		assert.Equal(t, draft.ID, transaction.DraftID)
+	})
+
+	t.Run("signed transaction does not match the draft", func(t *testing.T) {
+		ctx, client, deferMe := initSpendingPolicyTestCase(t)
+		defer deferMe()
+
+		draft, err := client.NewTransaction(ctx, testXPub, &TransactionConfig{
+			Outputs: []*TransactionOutput{{To: testPolicyAddress, Satoshis: 1000}},
+		})
+		require.NoError(t, err)
+
+		signingPackage := exportTestSigningPackage(ctx, t, client, draft.ID)
+
+		// not signed
+		signingPackage.SignedHex = signingPackage.Hex
+		_, err = client.ImportSigningPackage(ctx, testXPub, signingPackage)
+		assert.ErrorIs(t, err, ErrInvalidSignedTransaction)
+
+		// a different transaction
+		signingPackage.SignedHex = testTxHex
+		_, err = client.ImportSigningPackage(ctx, testXPub, signingPackage)
+		assert.ErrorIs(t, err, ErrDraftTransactionMismatch)
+
+		// an invalid xPub
+		_, err = client.ImportSigningPackage(ctx, "", signingPackage)
+		assert.ErrorIs(t, err, utils.ErrXpubInvalidLength)
+
+		// the package of another draft
+		signingPackage.DraftID = testTxID
+		_, err = client.ImportSigningPackage(ctx, testXPub, signingPackage)
+		assert.ErrorIs(t, err, ErrDraftNotFound)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"time"
 
 	"github.com/libsv/go-bt/v2"
 	"github.com/mrz1836/go-datastore"
@@ -82,6 +83,22 @@ func getTransactionsToBroadcast(ctx context.Context, queryParams *datastore.Quer
 	return res, nil
 }
 
+// getScheduledTransactionsDue will get the scheduled sync transactions that are due for broadcasting
+func getScheduledTransactionsDue(ctx context.Context, queryParams *datastore.QueryParams,
+	opts ...ModelOps,
+) ([]*SyncTransaction, error) {
+	return _getSyncTransactionsByConditions(
+		ctx,
+		map[string]interface{}{
+			broadcastStatusField: SyncStatusScheduled.String(),
+			broadcastAtField: map[string]interface{}{
+				"$lte": time.Now().UTC(),
+			},
+		},
+		queryParams, opts...,
+	)
+}
+
 // getTransactionsToSync will get the sync transactions to sync
 func getTransactionsToSync(ctx context.Context, queryParams *datastore.QueryParams,
 	opts ...ModelOps,
@@ -57,6 +57,19 @@ func processBroadcastTransactions(ctx context.Context, maxTransactions int, opts
 		SortDirection: datastore.SortAsc,
 	}
 
+	// Release the scheduled transactions that are due
+	scheduledTxs, err := getScheduledTransactionsDue(ctx, queryParams, opts...)
+	if err != nil {
+		return err
+	}
+	for _, syncTx := range scheduledTxs {
+		if err = _readyScheduledSyncTransaction(ctx, syncTx); err != nil {
+			syncTx.Client().Logger().Error(ctx,
+				fmt.Sprintf("error releasing scheduled tx %s: %s", syncTx.ID, err.Error()),
+			)
+		}
+	}
+
 	// Get maxTransactions records, grouped by xpub
 	snTxs, err := getTransactionsToBroadcast(ctx, queryParams, opts...)
 	if err != nil {
@@ -96,6 +109,30 @@ func processBroadcastTransactions(ctx context.Context, maxTransactions int, opts
 	return nil
 }
 
+// _readyScheduledSyncTransaction will set a scheduled sync transaction (that is due) ready for broadcasting
+func _readyScheduledSyncTransaction(ctx context.Context, syncTx *SyncTransaction) error {
+	// Same lock as broadcasting and canceling the transaction
+	unlock, err := newWriteLock(
+		ctx, fmt.Sprintf(lockKeyProcessBroadcastTx, syncTx.GetID()), syncTx.Client().Cachestore(),
+	)
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	// The transaction might have been canceled in the meantime
+	if syncTx, err = GetSyncTransactionByID(
+		ctx, syncTx.ID, syncTx.GetOptions(false)...,
+	); err != nil {
+		return err
+	} else if syncTx == nil || syncTx.BroadcastStatus != SyncStatusScheduled {
+		return nil
+	}
+
+	syncTx.BroadcastStatus = SyncStatusReady
+	return syncTx.Save(ctx)
+}
+
 // broadcastSyncTransaction will broadcast transaction related to syncTx record
 func broadcastSyncTransaction(ctx context.Context, syncTx *SyncTransaction) error {
 	// Successfully capture any panics, convert to readable string and log the error
@@ -134,8 +171,8 @@ func broadcastSyncTransaction(ctx context.Context, syncTx *SyncTransaction) erro
 
 	// Broadcast
 	var provider string
-	if provider, err = syncTx.Client().Chainstate().Broadcast(
-		ctx, syncTx.ID, txHex, defaultBroadcastTimeout,
+	if provider, err = syncTx.Client().Chainstate().BroadcastWithPolicy(
+		ctx, syncTx.ID, txHex, syncTx.Configuration.broadcastPolicy(), defaultBroadcastTimeout,
 	); err != nil {
 		_bailAndSaveSyncTransaction(
 			ctx, syncTx, SyncStatusError, syncActionBroadcast, provider, "broadcast error: "+err.Error(),
@@ -148,6 +185,7 @@ func broadcastSyncTransaction(ctx context.Context, syncTx *SyncTransaction) erro
 
 	// Update the sync information
 	syncTx.BroadcastStatus = SyncStatusComplete
+	syncTx.Results.BroadcastProvider = provider
 	syncTx.Results.LastMessage = message
 	syncTx.LastAttempt = customTypes.NullTime{
 		NullTime: sql.NullTime{
@@ -352,7 +390,7 @@ func _notifyPaymailProviders(ctx context.Context, transaction *Transaction) ([]*
 	var payload *paymail.P2PTransactionPayload
 
 	for _, out := range draftTx.Configuration.Outputs {
-		if out.PaymailP4 != nil && out.PaymailP4.ResolutionType == ResolutionTypeP2P {
+		if out.PaymailP4.notifiesP2P() {
 
 			// Notify each provider with the transaction
 			if payload, err = finalizeP2PTransaction(
@@ -3,8 +3,10 @@ package bux
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/BuxOrg/bux/utils"
 	"github.com/mrz1836/go-datastore"
 	zLogger "github.com/mrz1836/go-logger"
 )
@@ -55,6 +57,48 @@ func taskCleanupDraftTransactions(ctx context.Context, logClient zLogger.GormLog
 	return nil
 }
 
+// taskCleanupPaymentReferences will expire the issued payment references that were not paid before they expired
+func taskCleanupPaymentReferences(ctx context.Context, logClient zLogger.GormLoggerInterface, opts ...ModelOps) error {
+
+	logClient.Info(ctx, "running cleanup payment references task...")
+
+	// Construct an empty model
+	var models []PaymentReference
+	conditions := map[string]interface{}{
+		statusField:    PaymentReferenceStatusIssued,
+		expiresAtField: map[string]interface{}{"$lt": time.Now().UTC()},
+	}
+
+	queryParams := &datastore.QueryParams{
+		Page:          1,
+		PageSize:      100,
+		OrderByField:  idField,
+		SortDirection: datastore.SortAsc,
+	}
+
+	// Get the records
+	if err := getModels(
+		ctx, NewBaseModel(ModelNameEmpty, opts...).Client().Datastore(),
+		&models, conditions, queryParams, defaultDatabaseReadTimeout,
+	); err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil
+		}
+		return err
+	}
+
+	// Loop and update
+	for index := range models {
+		models[index].enrich(ModelPaymentReference, opts...)
+		models[index].Status = PaymentReferenceStatusExpired
+		if err := models[index].Save(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // taskProcessIncomingTransactions will process any incoming transactions found
 func taskProcessIncomingTransactions(ctx context.Context, logClient zLogger.GormLoggerInterface, opts ...ModelOps) error {
 
@@ -67,6 +111,94 @@ func taskProcessIncomingTransactions(ctx context.Context, logClient zLogger.Gorm
 	return err
 }
 
+// taskProcessNotificationDeliveries will retry any pending notification deliveries
+func taskProcessNotificationDeliveries(ctx context.Context, logClient zLogger.GormLoggerInterface, opts ...ModelOps) error {
+
+	logClient.Info(ctx, "running process notification deliveries task...")
+
+	err := processNotificationDeliveries(ctx, 100, opts...)
+	if err == nil || errors.Is(err, datastore.ErrNoResults) {
+		return nil
+	}
+	return err
+}
+
+// taskConsolidateUtxos will consolidate the utxos of the xPubs that reached their utxo threshold
+func taskConsolidateUtxos(ctx context.Context, c ClientInterface) error {
+
+	logClient := c.Logger()
+	logClient.Info(ctx, "running consolidate utxos task...")
+
+	// Get the consolidations
+	consolidations, err := getUtxoConsolidations(ctx, nil, c.DefaultModelOptions()...)
+	if err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil
+		}
+		return err
+	}
+
+	// Only consolidate in the low-fee window
+	feeUnit := c.Chainstate().FeeUnit()
+	timeNow := time.Now().UTC()
+	for _, consolidation := range consolidations {
+		if !consolidation.Config.isInWindow(timeNow) || !consolidation.Config.isFeeAllowed(feeUnit) {
+			continue
+		}
+
+		var count int64
+		if count, err = getUtxosCount(ctx, nil, &map[string]interface{}{
+			draftIDField:      nil,
+			spendingTxIDField: nil,
+			typeField:         utils.ScriptTypePubKeyHash,
+			xPubIDField:       consolidation.ID,
+		}, c.DefaultModelOptions()...); err != nil {
+			return err
+		} else if count < int64(consolidation.Config.UtxoThreshold) {
+			continue
+		}
+
+		// One failing xPub should not stop the others
+		var config *TransactionConfig
+		if config, err = c.ConsolidateUtxos(
+			ctx, consolidation.ID, consolidation.Config.DryRun,
+		); err != nil {
+			logClient.Error(ctx, "failed to consolidate the utxos of xPub "+consolidation.ID+": "+err.Error())
+		} else if consolidation.Config.DryRun {
+			logClient.Info(ctx, fmt.Sprintf(
+				"dry-run consolidation of xPub %s: %d utxos, fee %d", consolidation.ID, len(config.FromUtxos), config.Fee,
+			))
+		}
+	}
+
+	return nil
+}
+
+// taskTopUpUtxoPools will top up the utxo pools that are missing utxos
+func taskTopUpUtxoPools(ctx context.Context, c ClientInterface) error {
+
+	logClient := c.Logger()
+	logClient.Info(ctx, "running top up utxo pools task...")
+
+	// Get the pools
+	pools, err := getUtxoPools(ctx, nil, c.DefaultModelOptions()...)
+	if err != nil {
+		if errors.Is(err, datastore.ErrNoResults) {
+			return nil
+		}
+		return err
+	}
+
+	// One failing xPub should not stop the others
+	for _, pool := range pools {
+		if _, err = c.TopUpUtxoPool(ctx, pool.ID); err != nil {
+			logClient.Error(ctx, "failed to top up the utxo pool of xPub "+pool.ID+": "+err.Error())
+		}
+	}
+
+	return nil
+}
+
 // taskBroadcastTransactions will broadcast any transactions
 func taskBroadcastTransactions(ctx context.Context, logClient zLogger.GormLoggerInterface, opts ...ModelOps) error {
 
@@ -13,6 +13,9 @@ var ErrXpubNoMatch = errors.New("xpub key does not match raw key")
 // ErrHDKeyNil is when the HD Key is nil
 var ErrHDKeyNil = errors.New("hd key is nil")
 
+// ErrMissingKey is when a key for deriving is missing
+var ErrMissingKey = errors.New("missing key")
+
 // ErrDeriveFailed is when the address derivation failed
 var ErrDeriveFailed = errors.New("derive addresses failed, missing addresses")
 
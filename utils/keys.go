@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+
 	"github.com/bitcoinschema/go-bitcoin/v2"
 	"github.com/libsv/go-bk/bec"
 	"github.com/libsv/go-bk/bip32"
@@ -145,3 +149,57 @@ func DerivePublicKeyFromHex(hdKey *bip32.ExtendedKey, hexString string) (*bec.Pu
 
 	return pubKey, nil
 }
+
+// DeriveInvoicePrivateKey will derive the private key for an invoice number shared with the counterparty (BRC-42)
+//
+// The counterparty derives the matching public key with DeriveInvoicePublicKey
+func DeriveInvoicePrivateKey(privateKey *bec.PrivateKey, counterparty *bec.PublicKey,
+	invoiceNumber string) (*bec.PrivateKey, error) {
+
+	if privateKey == nil || counterparty == nil {
+		return nil, ErrMissingKey
+	}
+
+	// The scalar of the invoice number, keyed by the shared secret
+	scalar := invoiceScalar(privateKey, counterparty, invoiceNumber)
+
+	curve := bec.S256()
+	d := new(big.Int).Add(privateKey.D, scalar)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, ErrDeriveFailed // Should never happen
+	}
+
+	childKey, _ := bec.PrivKeyFromBytes(curve, d.FillBytes(make([]byte, 32)))
+	return childKey, nil
+}
+
+// DeriveInvoicePublicKey will derive the public key of the counterparty for an invoice number (BRC-42)
+//
+// The counterparty derives the matching private key with DeriveInvoicePrivateKey
+func DeriveInvoicePublicKey(publicKey *bec.PublicKey, counterparty *bec.PrivateKey,
+	invoiceNumber string) (*bec.PublicKey, error) {
+
+	if publicKey == nil || counterparty == nil {
+		return nil, ErrMissingKey
+	}
+
+	// The scalar of the invoice number, keyed by the shared secret
+	scalar := invoiceScalar(counterparty, publicKey, invoiceNumber)
+
+	curve := bec.S256()
+	x, y := curve.ScalarBaseMult(scalar.FillBytes(make([]byte, 32)))
+	x, y = curve.Add(publicKey.X, publicKey.Y, x, y)
+	return &bec.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// invoiceScalar will create the HMAC of the invoice number keyed by the (compressed) ECDH shared secret
+func invoiceScalar(privateKey *bec.PrivateKey, publicKey *bec.PublicKey, invoiceNumber string) *big.Int {
+	curve := bec.S256()
+	x, y := curve.ScalarMult(publicKey.X, publicKey.Y, privateKey.D.Bytes())
+	sharedSecret := (&bec.PublicKey{Curve: curve, X: x, Y: y}).SerialiseCompressed()
+
+	mac := hmac.New(sha256.New, sharedSecret)
+	_, _ = mac.Write([]byte(invoiceNumber))
+	return new(big.Int).SetBytes(mac.Sum(nil))
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"testing"
 
+	"github.com/libsv/go-bk/bec"
 	"github.com/libsv/go-bk/bip32"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -87,3 +88,38 @@ func Benchmark_DeriveAddress(b *testing.B) {
 		_, _ = DeriveAddress(xPub, ChainInternal, uint32(i))
 	}
 }
+
+// Test_DeriveInvoiceKeys will test the methods DeriveInvoicePrivateKey() and DeriveInvoicePublicKey()
+func Test_DeriveInvoiceKeys(t *testing.T) {
+
+	t.Run("missing key", func(t *testing.T) {
+		_, err := DeriveInvoicePrivateKey(nil, nil, "1-test-1")
+		assert.ErrorIs(t, err, ErrMissingKey)
+
+		_, err = DeriveInvoicePublicKey(nil, nil, "1-test-1")
+		assert.ErrorIs(t, err, ErrMissingKey)
+	})
+
+	t.Run("keys match", func(t *testing.T) {
+		alice, err := bec.NewPrivateKey(bec.S256())
+		require.NoError(t, err)
+		var bob *bec.PrivateKey
+		bob, err = bec.NewPrivateKey(bec.S256())
+		require.NoError(t, err)
+
+		var privateKey *bec.PrivateKey
+		privateKey, err = DeriveInvoicePrivateKey(alice, bob.PubKey(), "1-test-1")
+		require.NoError(t, err)
+
+		var publicKey *bec.PublicKey
+		publicKey, err = DeriveInvoicePublicKey(alice.PubKey(), bob, "1-test-1")
+		require.NoError(t, err)
+		assert.True(t, privateKey.PubKey().IsEqual(publicKey))
+		assert.False(t, privateKey.PubKey().IsEqual(alice.PubKey()))
+
+		// another invoice number is another key
+		publicKey, err = DeriveInvoicePublicKey(alice.PubKey(), bob, "1-test-2")
+		require.NoError(t, err)
+		assert.False(t, privateKey.PubKey().IsEqual(publicKey))
+	})
+}
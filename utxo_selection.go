@@ -0,0 +1,197 @@
+package bux
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sort"
+)
+
+// UtxoSelectionStrategy is the strategy for selecting the utxos that fund a draft transaction
+type UtxoSelectionStrategy string
+
+// Types of utxo selection strategies
+const (
+	// UtxoSelectionDefault selects the utxos in datastore order until the amount is covered
+	UtxoSelectionDefault UtxoSelectionStrategy = ""
+
+	// UtxoSelectionLargestFirst selects the largest utxos first (fewest inputs)
+	UtxoSelectionLargestFirst UtxoSelectionStrategy = "largest_first"
+
+	// UtxoSelectionSmallestFirst selects the smallest utxos first (consolidates the wallet)
+	UtxoSelectionSmallestFirst UtxoSelectionStrategy = "smallest_first"
+
+	// UtxoSelectionBranchAndBound searches for an exact match to avoid a change output (falls back to largest first)
+	UtxoSelectionBranchAndBound UtxoSelectionStrategy = "branch_and_bound"
+
+	// UtxoSelectionRandom selects random utxos (privacy)
+	UtxoSelectionRandom UtxoSelectionStrategy = "random"
+
+	// UtxoSelectionOldestFirst selects the oldest utxos first
+	UtxoSelectionOldestFirst UtxoSelectionStrategy = "oldest_first"
+)
+
+// maxBranchAndBoundTries is the max number of branches the branch-and-bound search will visit
+const maxBranchAndBoundTries = 100000
+
+// UtxoSelectionTarget is the amount the selected utxos need to cover
+type UtxoSelectionTarget struct {
+	ChangeCost uint64                      // Cost of a change output (creating and spending it), the waste allowed when avoiding change
+	Fee        func(inputs []*Utxo) uint64 // Fee of the transaction when spending the given inputs
+	Satoshis   uint64                      // Satoshis of the outputs (without the fee)
+}
+
+// IsCovered will return true if the inputs cover the outputs and the fee
+func (t *UtxoSelectionTarget) IsCovered(inputs []*Utxo) bool {
+	return sumUtxoSatoshis(inputs) >= t.Satoshis+t.Fee(inputs)
+}
+
+// UtxoSelector is the interface for selecting the utxos that fund a draft transaction
+//
+// Custom selectors can be added to the client using WithUtxoSelector()
+type UtxoSelector interface {
+	// SelectUtxos will select the utxos from the candidates (the spendable utxos of the xPub) that cover the target
+	SelectUtxos(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error)
+}
+
+// UtxoSelectorFunc is a function that implements the UtxoSelector interface
+type UtxoSelectorFunc func(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error)
+
+// SelectUtxos will call the function
+func (f UtxoSelectorFunc) SelectUtxos(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+	return f(candidates, target)
+}
+
+// getBuiltinUtxoSelector will return the selector of a built-in strategy (nil for the default strategy or an unknown strategy)
+func getBuiltinUtxoSelector(strategy UtxoSelectionStrategy) UtxoSelector {
+	switch strategy {
+	case UtxoSelectionLargestFirst:
+		return UtxoSelectorFunc(selectLargestFirst)
+	case UtxoSelectionSmallestFirst:
+		return UtxoSelectorFunc(selectSmallestFirst)
+	case UtxoSelectionBranchAndBound:
+		return UtxoSelectorFunc(selectBranchAndBound)
+	case UtxoSelectionRandom:
+		return UtxoSelectorFunc(selectRandom)
+	case UtxoSelectionOldestFirst:
+		return UtxoSelectorFunc(selectOldestFirst)
+	case UtxoSelectionDefault:
+	}
+	return nil
+}
+
+// selectLargestFirst will select the largest utxos until the target is covered
+func selectLargestFirst(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+	sorted := sortUtxos(candidates, func(a, b *Utxo) bool {
+		return a.Satoshis > b.Satoshis
+	})
+	return selectUntilCovered(sorted, target)
+}
+
+// selectSmallestFirst will select the smallest utxos until the target is covered
+func selectSmallestFirst(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+	sorted := sortUtxos(candidates, func(a, b *Utxo) bool {
+		return a.Satoshis < b.Satoshis
+	})
+	return selectUntilCovered(sorted, target)
+}
+
+// selectOldestFirst will select the oldest utxos until the target is covered
+func selectOldestFirst(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+	sorted := sortUtxos(candidates, func(a, b *Utxo) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return selectUntilCovered(sorted, target)
+}
+
+// selectRandom will select random utxos until the target is covered
+func selectRandom(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+	shuffled := append([]*Utxo{}, candidates...)
+
+	// Fisher-Yates shuffle
+	for i := len(shuffled) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		shuffled[i], shuffled[j.Int64()] = shuffled[j.Int64()], shuffled[i]
+	}
+	return selectUntilCovered(shuffled, target)
+}
+
+// selectBranchAndBound will search for the utxos that cover the target without leaving more than the change cost
+//
+// If no match is found, the largest utxos are selected (and the remainder goes to change)
+func selectBranchAndBound(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+	sorted := sortUtxos(candidates, func(a, b *Utxo) bool {
+		return a.Satoshis > b.Satoshis
+	})
+
+	// remaining[i] is the sum of the satoshis from index i to the end
+	remaining := make([]uint64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Satoshis
+	}
+
+	var match []*Utxo
+	tries := 0
+	var search func(index int, selected []*Utxo, satoshis uint64) bool
+	search = func(index int, selected []*Utxo, satoshis uint64) bool {
+		if tries++; tries > maxBranchAndBoundTries {
+			return false
+		}
+
+		needed := target.Satoshis + target.Fee(selected)
+		if len(selected) > 0 && satoshis >= needed {
+			if satoshis <= needed+target.ChangeCost {
+				match = append([]*Utxo{}, selected...)
+				return true
+			}
+			return false // adding more inputs only adds waste
+		}
+
+		// Not enough left to cover the target
+		if index >= len(sorted) || satoshis+remaining[index] < needed {
+			return false
+		}
+
+		// Include the utxo, then try without it
+		if search(index+1, append(selected, sorted[index]), satoshis+sorted[index].Satoshis) {
+			return true
+		}
+		return search(index+1, selected, satoshis)
+	}
+
+	if search(0, make([]*Utxo, 0, len(sorted)), 0) {
+		return match, nil
+	}
+	return selectUntilCovered(sorted, target)
+}
+
+// selectUntilCovered will select the utxos in the given order until the target is covered
+func selectUntilCovered(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+	selected := make([]*Utxo, 0)
+	for _, utxo := range candidates {
+		selected = append(selected, utxo)
+		if target.IsCovered(selected) {
+			return selected, nil
+		}
+	}
+	return nil, ErrNotEnoughUtxos
+}
+
+// sortUtxos will return a sorted copy of the utxos
+func sortUtxos(utxos []*Utxo, less func(a, b *Utxo) bool) []*Utxo {
+	sorted := append([]*Utxo{}, utxos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// sumUtxoSatoshis will return the total satoshis of the utxos
+func sumUtxoSatoshis(utxos []*Utxo) (satoshis uint64) {
+	for _, utxo := range utxos {
+		satoshis += utxo.Satoshis
+	}
+	return
+}
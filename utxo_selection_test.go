@@ -0,0 +1,212 @@
+package bux
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUtxoSelectionTarget will return a target with a fee per P2PKH input
+func testUtxoSelectionTarget(satoshis uint64, feePerByte float64) *UtxoSelectionTarget {
+	return &UtxoSelectionTarget{
+		Fee: func(inputs []*Utxo) uint64 {
+			return uint64(len(inputs)) * uint64(148*feePerByte)
+		},
+		Satoshis: satoshis,
+	}
+}
+
+// testSelectionUtxos will return utxos with the given satoshis, the first one is the oldest
+func testSelectionUtxos(satoshis ...uint64) []*Utxo {
+	utxos := make([]*Utxo, 0, len(satoshis))
+	now := time.Now().UTC()
+	for index, value := range satoshis {
+		utxo := newUtxo(testXPubID, testTxID, testLockingScript, uint32(index), value)
+		utxo.CreatedAt = now.Add(time.Duration(index) * time.Minute)
+		utxos = append(utxos, utxo)
+	}
+	return utxos
+}
+
+// TestUtxoSelectors will test the built-in utxo selection strategies
+func TestUtxoSelectors(t *testing.T) {
+	candidates := testSelectionUtxos(500, 3000, 1000, 10000, 2000)
+
+	t.Run("largest first", func(t *testing.T) {
+		selected, err := selectLargestFirst(candidates, testUtxoSelectionTarget(11000, 1))
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+		assert.Equal(t, uint64(10000), selected[0].Satoshis)
+		assert.Equal(t, uint64(3000), selected[1].Satoshis)
+	})
+
+	t.Run("smallest first", func(t *testing.T) {
+		selected, err := selectSmallestFirst(candidates, testUtxoSelectionTarget(3000, 0))
+		require.NoError(t, err)
+		require.Len(t, selected, 3)
+		assert.Equal(t, uint64(3500), sumUtxoSatoshis(selected))
+	})
+
+	t.Run("oldest first", func(t *testing.T) {
+		selected, err := selectOldestFirst(candidates, testUtxoSelectionTarget(3000, 0))
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+		assert.Equal(t, uint64(500), selected[0].Satoshis)
+		assert.Equal(t, uint64(3000), selected[1].Satoshis)
+	})
+
+	t.Run("random", func(t *testing.T) {
+		target := testUtxoSelectionTarget(12000, 1)
+		selected, err := selectRandom(candidates, target)
+		require.NoError(t, err)
+		assert.True(t, target.IsCovered(selected))
+	})
+
+	t.Run("branch and bound - exact match", func(t *testing.T) {
+		selected, err := selectBranchAndBound(candidates, testUtxoSelectionTarget(2500, 0))
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+		assert.Equal(t, uint64(2500), sumUtxoSatoshis(selected))
+	})
+
+	t.Run("branch and bound - within the change cost", func(t *testing.T) {
+		target := testUtxoSelectionTarget(2800, 1)
+		target.ChangeCost = 100
+		selected, err := selectBranchAndBound(candidates, target)
+		require.NoError(t, err)
+		require.Len(t, selected, 1)
+		assert.Equal(t, uint64(3000), selected[0].Satoshis)
+	})
+
+	t.Run("branch and bound - no match, largest first", func(t *testing.T) {
+		selected, err := selectBranchAndBound(candidates, testUtxoSelectionTarget(9000, 0))
+		require.NoError(t, err)
+		require.Len(t, selected, 1)
+		assert.Equal(t, uint64(10000), selected[0].Satoshis)
+	})
+
+	t.Run("not enough utxos", func(t *testing.T) {
+		for _, strategy := range []UtxoSelectionStrategy{
+			UtxoSelectionLargestFirst, UtxoSelectionSmallestFirst, UtxoSelectionBranchAndBound,
+			UtxoSelectionRandom, UtxoSelectionOldestFirst,
+		} {
+			_, err := getBuiltinUtxoSelector(strategy).SelectUtxos(candidates, testUtxoSelectionTarget(17000, 0))
+			assert.ErrorIs(t, err, ErrNotEnoughUtxos, strategy)
+		}
+	})
+}
+
+// TestClient_GetUtxoSelector will test getting the selectors from the client
+func TestClient_GetUtxoSelector(t *testing.T) {
+	custom := UtxoSelectorFunc(selectSmallestFirst)
+	_, client, deferMe := CreateTestSQLiteClient(t, false, false,
+		WithCustomTaskManager(&taskManagerMockBase{}),
+		WithUtxoSelector("custom", custom),
+	)
+	defer deferMe()
+
+	selector, err := client.GetUtxoSelector(UtxoSelectionDefault)
+	require.NoError(t, err)
+	assert.Nil(t, selector)
+
+	selector, err = client.GetUtxoSelector(UtxoSelectionLargestFirst)
+	require.NoError(t, err)
+	assert.NotNil(t, selector)
+
+	selector, err = client.GetUtxoSelector("custom")
+	require.NoError(t, err)
+	assert.NotNil(t, selector)
+
+	_, err = client.GetUtxoSelector("unknown")
+	assert.ErrorIs(t, err, ErrUnknownUtxoSelectionStrategy)
+}
+
+// TestDraftTransaction_utxoSelectionStrategy will test creating drafts with a utxo selection strategy
+func TestDraftTransaction_utxoSelectionStrategy(t *testing.T) {
+	createUtxos := func(ctx context.Context, t *testing.T, client ClientInterface) {
+		opts := append(client.DefaultModelOptions(), New())
+		require.NoError(t, newXpub(testXPub, opts...).Save(ctx))
+		require.NoError(t, newDestination(testXPubID, testLockingScript, opts...).Save(ctx))
+		require.NoError(t, newTransaction(testTxHex, opts...).Save(ctx))
+		for index, satoshis := range []uint64{100000, 3000, 500} {
+			require.NoError(t, newUtxo(testXPubID, testTxID, testLockingScript, uint32(index), satoshis, opts...).Save(ctx))
+		}
+	}
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+		createUtxos(ctx, t, client)
+
+		draftTransaction := newDraftTransaction(testXPub, &TransactionConfig{
+			Outputs:               []*TransactionOutput{{To: testExternalAddress, Satoshis: 1000}},
+			UtxoSelectionStrategy: "unknown",
+		}, append(client.DefaultModelOptions(), New())...)
+
+		err := draftTransaction.createTransactionHex(ctx)
+		require.ErrorIs(t, err, ErrUnknownUtxoSelectionStrategy)
+	})
+
+	t.Run("branch and bound - no change", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+		createUtxos(ctx, t, client)
+
+		draftTransaction := newDraftTransaction(testXPub, &TransactionConfig{
+			Outputs:               []*TransactionOutput{{To: testExternalAddress, Satoshis: 2980}},
+			UtxoSelectionStrategy: UtxoSelectionBranchAndBound,
+		}, append(client.DefaultModelOptions(), New())...)
+
+		err := draftTransaction.createTransactionHex(ctx)
+		require.NoError(t, err)
+		require.Len(t, draftTransaction.Configuration.Inputs, 1)
+		assert.Equal(t, uint64(3000), draftTransaction.Configuration.Inputs[0].Satoshis)
+		assert.Len(t, draftTransaction.Configuration.Outputs, 1)
+		assert.Equal(t, uint64(20), draftTransaction.Configuration.Fee)
+	})
+
+	t.Run("smallest first", func(t *testing.T) {
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false, WithCustomTaskManager(&taskManagerMockBase{}))
+		defer deferMe()
+		createUtxos(ctx, t, client)
+
+		draftTransaction := newDraftTransaction(testXPub, &TransactionConfig{
+			Outputs:               []*TransactionOutput{{To: testExternalAddress, Satoshis: 3000}},
+			UtxoSelectionStrategy: UtxoSelectionSmallestFirst,
+		}, append(client.DefaultModelOptions(), New())...)
+
+		err := draftTransaction.createTransactionHex(ctx)
+		require.NoError(t, err)
+		require.Len(t, draftTransaction.Configuration.Inputs, 2)
+		assert.Equal(t, uint64(500), draftTransaction.Configuration.Inputs[0].Satoshis)
+		assert.Equal(t, uint64(3000), draftTransaction.Configuration.Inputs[1].Satoshis)
+		assert.Equal(t, uint64(500-draftTransaction.Configuration.Fee), draftTransaction.Configuration.ChangeSatoshis)
+	})
+
+	t.Run("custom selector", func(t *testing.T) {
+		var called bool
+		ctx, client, deferMe := CreateTestSQLiteClient(t, false, false,
+			WithCustomTaskManager(&taskManagerMockBase{}),
+			WithUtxoSelector("custom", UtxoSelectorFunc(func(candidates []*Utxo, target *UtxoSelectionTarget) ([]*Utxo, error) {
+				called = true
+				return selectLargestFirst(candidates, target)
+			})),
+		)
+		defer deferMe()
+		createUtxos(ctx, t, client)
+
+		draftTransaction := newDraftTransaction(testXPub, &TransactionConfig{
+			Outputs:               []*TransactionOutput{{To: testExternalAddress, Satoshis: 1000}},
+			UtxoSelectionStrategy: "custom",
+		}, append(client.DefaultModelOptions(), New())...)
+
+		err := draftTransaction.createTransactionHex(ctx)
+		require.NoError(t, err)
+		assert.True(t, called)
+		require.Len(t, draftTransaction.Configuration.Inputs, 1)
+		assert.Equal(t, uint64(100000), draftTransaction.Configuration.Inputs[0].Satoshis)
+	})
+}